@@ -2,16 +2,30 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	_ "github.com/aaanger/music-library/docs"
+	"github.com/aaanger/music-library/internal/grpchandler"
 	"github.com/aaanger/music-library/internal/handler"
+	"github.com/aaanger/music-library/internal/linkcheck"
+	"github.com/aaanger/music-library/internal/metadata"
 	"github.com/aaanger/music-library/internal/repository"
 	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/internal/webhook"
+	"github.com/aaanger/music-library/pkg/cache"
 	"github.com/aaanger/music-library/pkg/db"
+	"github.com/aaanger/music-library/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -19,7 +33,7 @@ import (
 // @title Онлайн библиотека песен
 // @version 1.0
 // @description Swagger API для бибилотеки песен
-// @BasePath /api/v1
+// @BasePath /
 
 type server struct {
 	httpServer *http.Server
@@ -40,6 +54,97 @@ func (srv *server) shutdown(ctx context.Context) error {
 	return srv.httpServer.Shutdown(ctx)
 }
 
+func envInt(key string) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func envDuration(key string) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func envBool(key string) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return false
+	}
+	return value
+}
+
+func envList(key string) []string {
+	var list []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// newMetadataRegistry registers the providers of METADATA_PROVIDERS in the
+// listed order, just the upstream API of API_URL by default. The responses of
+// the API and MusicBrainz are cached in upstreamCache if UPSTREAM_CACHE_TTL is set.
+func newMetadataRegistry(upstreamCache repository.IUpstreamCacheRepository, log *logrus.Logger) (*metadata.Registry, error) {
+	registry := metadata.NewRegistry(log)
+
+	cacheTTL := envDuration("UPSTREAM_CACHE_TTL")
+	cached := func(provider metadata.Provider) metadata.Provider {
+		if cacheTTL <= 0 {
+			return provider
+		}
+		return metadata.NewCachedProvider(provider, upstreamCache, cacheTTL, envDuration("UPSTREAM_CACHE_NOT_FOUND_TTL"), log)
+	}
+
+	names := envList("METADATA_PROVIDERS")
+	if len(names) == 0 {
+		names = []string{"api"}
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	for priority, name := range names {
+		switch name {
+		case "api":
+			registry.Register(cached(metadata.NewAPIProvider(os.Getenv("API_URL"), httpClient)), priority)
+		case "file":
+			dir := os.Getenv("METADATA_DIR")
+			if dir == "" {
+				return nil, errors.New("file metadata provider requires METADATA_DIR")
+			}
+			registry.Register(metadata.NewFileProvider(dir), priority)
+		case "musicbrainz":
+			userAgent := os.Getenv("MUSICBRAINZ_USER_AGENT")
+			if userAgent == "" {
+				userAgent = "music-library/1.0"
+			}
+			registry.Register(cached(metadata.NewMusicBrainzProvider(os.Getenv("MUSICBRAINZ_URL"), userAgent, httpClient)), priority)
+		default:
+			return nil, fmt.Errorf("unknown metadata provider %q", name)
+		}
+	}
+
+	for field, key := range map[string]string{
+		metadata.FieldReleaseDate: "METADATA_RELEASE_DATE_FROM",
+		metadata.FieldText:        "METADATA_TEXT_FROM",
+		metadata.FieldLink:        "METADATA_LINK_FROM",
+	} {
+		err := registry.SetRule(field, envList(key)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
 func main() {
 	err := godotenv.Load()
 	if err != nil {
@@ -54,24 +159,225 @@ func main() {
 	log := logrus.New()
 	log.SetLevel(logLevel)
 
-	db, err := db.Open(db.PostgresConfig{
-		Host:     os.Getenv("PSQL_HOST"),
-		Port:     os.Getenv("PSQL_PORT"),
-		Username: os.Getenv("PSQL_USER"),
-		Password: os.Getenv("PSQL_PASSWORD"),
-		DBName:   os.Getenv("PSQL_DBNAME"),
-		SSLMode:  os.Getenv("PSQL_SSLMODE"),
-	})
+	dbCfg := db.PostgresConfig{
+		Host:                   os.Getenv("PSQL_HOST"),
+		Port:                   os.Getenv("PSQL_PORT"),
+		Username:               os.Getenv("PSQL_USER"),
+		Password:               os.Getenv("PSQL_PASSWORD"),
+		DBName:                 os.Getenv("PSQL_DBNAME"),
+		SSLMode:                os.Getenv("PSQL_SSLMODE"),
+		MaxConns:               envInt("PSQL_MAX_CONNS"),
+		MinConns:               envInt("PSQL_MIN_CONNS"),
+		MaxConnLifetime:        envDuration("PSQL_MAX_CONN_LIFETIME"),
+		MaxConnIdleTime:        envDuration("PSQL_MAX_CONN_IDLE_TIME"),
+		StatementCacheCapacity: envInt("PSQL_STATEMENT_CACHE_CAPACITY"),
+	}
+
+	var repo repository.IMusicRepository
+	var webhookRepo repository.IWebhookRepository
+	var upstreamCacheRepo repository.IUpstreamCacheRepository
+	// postgresDB is set if the service runs on Postgres, for the rate limit store.
+	var postgresDB *sql.DB
+	var closeDB func() error
+
+	switch os.Getenv("DB_DRIVER") {
+	case "memory":
+		memoryRepo := repository.NewMemoryMusicRepository(log)
+		repo = memoryRepo
+		webhookRepo = repository.NewMemoryWebhookRepository(memoryRepo)
+		upstreamCacheRepo = repository.NewMemoryUpstreamCacheRepository(log)
+		closeDB = func() error { return nil }
+	case "sqlite":
+		sqliteDB, err := db.OpenSQLite(db.SQLiteConfig{
+			Path: os.Getenv("SQLITE_PATH"),
+		})
+		if err != nil {
+			log.Fatalf("Error connecting to database: %s", err)
+		}
+		repo = repository.NewSQLiteMusicRepository(sqliteDB, log)
+		webhookRepo = repository.NewSQLiteWebhookRepository(sqliteDB, log)
+		upstreamCacheRepo = repository.NewUpstreamCacheRepository(sqliteDB, log)
+		closeDB = sqliteDB.Close
+	case "pgx":
+		pool, err := db.OpenPool(context.Background(), dbCfg)
+		if err != nil {
+			log.Fatalf("Error connecting to database: %s", err)
+		}
+		repo = repository.NewPgxMusicRepository(pool, log)
+		// The webhook and upstream cache repositories are not on the hot path,
+		// they share the pool through database/sql.
+		poolDB := stdlib.OpenDBFromPool(pool)
+		webhookRepo = repository.NewWebhookRepository(poolDB, log)
+		upstreamCacheRepo = repository.NewUpstreamCacheRepository(poolDB, log)
+		postgresDB = poolDB
+		closeDB = func() error {
+			poolDB.Close()
+			pool.Close()
+			return nil
+		}
+	default:
+		sqlDB, err := db.Open(dbCfg)
+		if err != nil {
+			log.Fatalf("Error connecting to database: %s", err)
+		}
+		repo = repository.NewMusicRepository(sqlDB, log)
+		webhookRepo = repository.NewWebhookRepository(sqlDB, log)
+		upstreamCacheRepo = repository.NewUpstreamCacheRepository(sqlDB, log)
+		postgresDB = sqlDB
+		closeDB = sqlDB.Close
+	}
+
+	if backfiller, ok := repo.(repository.TranslitBackfiller); ok {
+		_, err := backfiller.BackfillTranslit(context.Background())
+		if err != nil {
+			log.Fatalf("Error backfilling transliterated titles: %s", err)
+		}
+	}
+
+	cacheTTL := envDuration("CACHE_TTL")
+	if cacheTTL == 0 {
+		cacheTTL = 5 * time.Minute
+	}
+
+	switch os.Getenv("CACHE_BACKEND") {
+	case "memory":
+		cacheSize := envInt("CACHE_SIZE")
+		if cacheSize == 0 {
+			cacheSize = 1000
+		}
+		repo = repository.NewCachedMusicRepository(repo, cache.NewLRU(cacheSize), cacheTTL, log)
+	case "redis":
+		redisCache, err := cache.NewRedis(context.Background(), cache.RedisConfig{
+			Addr:     os.Getenv("REDIS_ADDR"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       envInt("REDIS_DB"),
+		})
+		if err != nil {
+			log.Fatalf("Error connecting to redis: %s", err)
+		}
+		defer redisCache.Close()
+		repo = repository.NewCachedMusicRepository(repo, redisCache, cacheTTL, log)
+	}
+
+	var middleware []gin.HandlerFunc
+
+	rateLimitPeriod := envDuration("RATE_LIMIT_PERIOD")
+	if rateLimitPeriod == 0 {
+		rateLimitPeriod = time.Minute
+	}
+	readLimit := ratelimit.Limit{Requests: envInt("RATE_LIMIT_READ"), Period: rateLimitPeriod}
+	writeLimit := ratelimit.Limit{Requests: envInt("RATE_LIMIT_WRITE"), Period: rateLimitPeriod}
+
+	if readLimit.Requests > 0 || writeLimit.Requests > 0 {
+		var store ratelimit.Store
+
+		switch os.Getenv("RATE_LIMIT_STORE") {
+		case "redis":
+			redisStore, err := ratelimit.NewRedisStore(context.Background(), cache.RedisConfig{
+				Addr:     os.Getenv("REDIS_ADDR"),
+				Password: os.Getenv("REDIS_PASSWORD"),
+				DB:       envInt("REDIS_DB"),
+			})
+			if err != nil {
+				log.Fatalf("Error connecting to redis: %s", err)
+			}
+			defer redisStore.Close()
+			store = redisStore
+		case "postgres":
+			if postgresDB == nil {
+				log.Fatalf("RATE_LIMIT_STORE=postgres requires a Postgres DB_DRIVER")
+			}
+			store = ratelimit.NewPostgresStore(postgresDB)
+		default:
+			store = ratelimit.NewMemoryStore()
+		}
+
+		limiter := handler.NewRateLimiter(store, readLimit, writeLimit, log)
+		limiter.SetAPIKeys(append(envList("RATE_LIMIT_API_KEYS"), os.Getenv("ADMIN_API_KEY"))...)
+		middleware = append(middleware, limiter.Handle)
+	}
+
+	webhookService := service.NewWebhookService(webhookRepo, log)
+	allowPrivateWebhooks := envBool("WEBHOOK_ALLOW_PRIVATE_TARGETS")
+	webhookService.SetAllowPrivateTargets(allowPrivateWebhooks)
+	upstreamCacheService := service.NewUpstreamCacheService(upstreamCacheRepo, log)
+	metadataRegistry, err := newMetadataRegistry(upstreamCacheRepo, log)
 	if err != nil {
-		log.Fatalf("Error connecting to database: %s", err)
+		log.Fatalf("Invalid metadata providers: %s", err)
 	}
 
-	apiURL := os.Getenv("API_URL")
+	service := service.NewMusicService(repo, metadataRegistry, log)
+	if interval := envDuration("REFRESH_INTERVAL"); interval > 0 {
+		service.SetRefreshInterval(interval)
+	}
+	webhookHandler := handler.NewWebhookHandler(webhookService, log)
+	upstreamCacheHandler := handler.NewUpstreamCacheHandler(upstreamCacheService, log)
+
+	adminKey := os.Getenv("ADMIN_API_KEY")
+	adminAuth := handler.RequireAPIKey(adminKey)
 
-	repo := repository.NewMusicRepository(db, log)
-	service := service.NewMusicService(repo, apiURL, log)
 	handler := handler.NewMusicHandler(service, log)
 
+	router := handler.InitRoutes(middleware...)
+	// Subscriptions receive the whole catalogue and the upstream cache is the
+	// offline fallback of the metadata providers, so the webhook and admin
+	// endpoints are only served behind ADMIN_API_KEY.
+	if adminKey != "" {
+		webhookHandler.InitRoutes(router, adminAuth)
+		upstreamCacheHandler.InitRoutes(router, adminAuth)
+	} else {
+		log.Warnf("ADMIN_API_KEY is not set, the webhook and admin endpoints are disabled")
+	}
+
+	// Client IPs are taken from X-Forwarded-For only behind trusted proxies,
+	// otherwise clients could dodge the rate limits by setting it.
+	var trustedProxies []string
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		trustedProxies = strings.Split(value, ",")
+	}
+	err = router.SetTrustedProxies(trustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %s", err)
+	}
+
+	dispatcher := webhook.NewDispatcher(webhookRepo, webhook.Config{
+		Interval:            envDuration("WEBHOOK_DISPATCH_INTERVAL"),
+		MaxAttempts:         envInt("WEBHOOK_MAX_ATTEMPTS"),
+		Timeout:             envDuration("WEBHOOK_TIMEOUT"),
+		AllowPrivateTargets: allowPrivateWebhooks,
+	}, log)
+
+	dispatchCtx, stopDispatcher := context.WithCancel(context.Background())
+	dispatcherDone := make(chan struct{})
+
+	go func() {
+		defer close(dispatcherDone)
+		dispatcher.Run(dispatchCtx)
+	}()
+
+	// The link checker is optional, it sends requests to the streaming
+	// services on its own.
+	var linkCheckerDone chan struct{}
+	if interval := envDuration("LINK_CHECK_INTERVAL"); interval > 0 {
+		var domains []string
+		if value := os.Getenv("LINK_CHECK_HOSTS"); value != "" {
+			domains = strings.Split(value, ",")
+		}
+
+		checker := linkcheck.NewChecker(repo, linkcheck.Config{
+			Interval: interval,
+			MaxAge:   envDuration("LINK_CHECK_MAX_AGE"),
+			Timeout:  envDuration("LINK_CHECK_TIMEOUT"),
+			Domains:  domains,
+		}, log)
+
+		linkCheckerDone = make(chan struct{})
+		go func() {
+			defer close(linkCheckerDone)
+			checker.Run(dispatchCtx)
+		}()
+	}
+
 	srv := new(server)
 
 	port := os.Getenv("PORT")
@@ -79,8 +385,28 @@ func main() {
 		port = "8080"
 	}
 
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "9090"
+	}
+
+	grpcListener, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		log.Fatalf("Error listening on gRPC port: %s", err)
+	}
+
+	grpcServer := grpchandler.NewMusicServer(service, log).NewGRPCServer()
+
+	go func() {
+		log.Infof("gRPC server running on port :%s", grpcPort)
+		err := grpcServer.Serve(grpcListener)
+		if err != nil {
+			log.Fatalf("Error running the gRPC server: %s", err)
+		}
+	}()
+
 	go func() {
-		err := srv.run(":"+port, handler.InitRoutes())
+		err := srv.run(":"+port, router)
 		if err != nil {
 			log.Fatalf("Error running the server: %s", err)
 		}
@@ -91,13 +417,23 @@ func main() {
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 	<-quit
 
+	grpcServer.GracefulStop()
+
+	stopDispatcher()
+	<-dispatcherDone
+	if linkCheckerDone != nil {
+		<-linkCheckerDone
+	}
+
+	handler.CloseStreams()
+
 	err = srv.shutdown(context.Background())
 	log.Infof("Shutting down the server")
 	if err != nil {
 		log.Errorf("Error shutting down the server: %s", err)
 	}
 
-	err = db.Close()
+	err = closeDB()
 	if err != nil {
 		log.Errorf("Error closing database: %s", err)
 	}
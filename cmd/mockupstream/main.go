@@ -0,0 +1,78 @@
+// Command mockupstream serves the mock upstream music API for local
+// development, configured with MOCK_* environment variables.
+package main
+
+import (
+	"context"
+	"github.com/aaanger/music-library/internal/mockupstream"
+	"github.com/sirupsen/logrus"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+func envFloat(key string) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func main() {
+	logLevel, err := logrus.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		logLevel = logrus.InfoLevel
+	}
+
+	log := logrus.New()
+	log.SetLevel(logLevel)
+
+	var fixtures fs.FS = mockupstream.Fixtures()
+	if dir := os.Getenv("MOCK_FIXTURES_DIR"); dir != "" {
+		fixtures = os.DirFS(dir)
+	}
+
+	latency, _ := time.ParseDuration(os.Getenv("MOCK_LATENCY"))
+	errorStatus, _ := strconv.Atoi(os.Getenv("MOCK_ERROR_STATUS"))
+
+	server := mockupstream.NewServer(fixtures, mockupstream.Faults{
+		Latency:       latency,
+		ErrorRate:     envFloat("MOCK_ERROR_RATE"),
+		ErrorStatus:   errorStatus,
+		MalformedRate: envFloat("MOCK_MALFORMED_RATE"),
+	}, log)
+
+	port := os.Getenv("MOCK_PORT")
+	if port == "" {
+		port = "8081"
+	}
+
+	srv := &http.Server{
+		Addr:        ":" + port,
+		Handler:     server,
+		ReadTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Infof("Mock upstream running on port :%s", port)
+		err := srv.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Error running the mock upstream: %s", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+	<-quit
+
+	log.Infof("Shutting down the mock upstream")
+	err = srv.Shutdown(context.Background())
+	if err != nil {
+		log.Errorf("Error shutting down the mock upstream: %s", err)
+	}
+}
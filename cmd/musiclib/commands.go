@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/pkg/client"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+	"strconv"
+)
+
+// exportPageSize is the number of songs requested per page while exporting.
+const exportPageSize = 100
+
+var addCommand = &cli.Command{
+	Name:  "add",
+	Usage: "add a song, details and lyrics are fetched by the service",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "group", Usage: "artist name", Required: true},
+		&cli.StringFlag{Name: "song", Usage: "song title", Required: true},
+	},
+	Action: func(c *cli.Context) error {
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		song, err := cl.AddSong(c.Context, c.String("group"), c.String("song"))
+		if err != nil {
+			return err
+		}
+
+		return printSong(c, song)
+	},
+}
+
+var lsCommand = &cli.Command{
+	Name:  "ls",
+	Usage: "list songs",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "song", Usage: "filter by song title"},
+		&cli.StringFlag{Name: "group", Usage: "filter by artist"},
+		&cli.StringFlag{Name: "release-date", Usage: "filter by release date"},
+		&cli.StringSliceFlag{Name: "tag", Usage: "filter by tag or genre, may be repeated"},
+		&cli.BoolFlag{Name: "all-tags", Usage: "keep only songs with all of the tags"},
+		&cli.IntFlag{Name: "limit", Usage: "songs per page", Value: 10},
+		&cli.IntFlag{Name: "page", Usage: "page number", Value: 1},
+	},
+	Action: func(c *cli.Context) error {
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		params := songsFilter(c)
+		params.Limit = c.Int("limit")
+		params.Page = c.Int("page")
+
+		page, err := cl.ListSongs(c.Context, params)
+		if err != nil {
+			return err
+		}
+
+		return printSongs(c, page.Songs, &page.Pagination)
+	},
+}
+
+var lyricsCommand = &cli.Command{
+	Name:      "lyrics",
+	Usage:     "print the lyrics of a song",
+	ArgsUsage: "SONG_ID",
+	Flags: []cli.Flag{
+		&cli.IntFlag{Name: "limit", Usage: "verses per page, all verses if not set"},
+		&cli.IntFlag{Name: "page", Usage: "page number", Value: 1},
+		&cli.StringFlag{Name: "lang", Usage: "print the translation to this language, the original lyrics if there is none"},
+	},
+	Action: func(c *cli.Context) error {
+		songID, err := songIDArg(c)
+		if err != nil {
+			return err
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		lang := c.String("lang")
+
+		if c.IsSet("limit") {
+			page, err := cl.ListTranslatedVerses(c.Context, songID, lang, c.Int("limit"), c.Int("page"))
+			if err != nil {
+				return err
+			}
+			return printVerses(c, page.Verses)
+		}
+
+		if lang != "" {
+			var verses []*client.Verse
+			for verse, err := range cl.TranslatedVerses(c.Context, songID, lang, 0) {
+				if err != nil {
+					return err
+				}
+				verses = append(verses, verse)
+			}
+			return printVerses(c, verses)
+		}
+
+		song, err := cl.GetSongWithLyrics(c.Context, songID, true)
+		if err != nil {
+			return err
+		}
+
+		return printVerses(c, song.Lyrics)
+	},
+}
+
+var updateCommand = &cli.Command{
+	Name:      "update",
+	Usage:     "change song fields",
+	ArgsUsage: "SONG_ID",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "song", Usage: "new song title"},
+		&cli.StringFlag{Name: "group", Usage: "new artist"},
+		&cli.StringFlag{Name: "release-date", Usage: "new release date"},
+		&cli.StringFlag{Name: "link", Usage: "new link"},
+		&cli.IntFlag{Name: "version", Usage: "apply only if the song still has this version"},
+	},
+	Action: func(c *cli.Context) error {
+		songID, err := songIDArg(c)
+		if err != nil {
+			return err
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		var version *int
+		if c.IsSet("version") {
+			v := c.Int("version")
+			version = &v
+		}
+
+		song, err := cl.UpdateSong(c.Context, songID, &client.SongUpdate{
+			Song:        optionalString(c, "song"),
+			Group:       optionalString(c, "group"),
+			ReleaseDate: optionalString(c, "release-date"),
+			Link:        optionalString(c, "link"),
+		}, version)
+		if err != nil {
+			return err
+		}
+
+		return printSong(c, song)
+	},
+}
+
+var rmCommand = &cli.Command{
+	Name:      "rm",
+	Usage:     "delete a song",
+	ArgsUsage: "SONG_ID",
+	Action: func(c *cli.Context) error {
+		songID, err := songIDArg(c)
+		if err != nil {
+			return err
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		return cl.DeleteSong(c.Context, songID)
+	},
+}
+
+var duplicatesCommand = &cli.Command{
+	Name:  "duplicates",
+	Usage: "list pairs of songs which are likely the same song",
+	Flags: []cli.Flag{
+		&cli.Float64Flag{Name: "threshold", Usage: "minimal similarity from 0 to 1", Value: 0.5},
+		&cli.IntFlag{Name: "limit", Usage: "number of pairs", Value: 20},
+	},
+	Action: func(c *cli.Context) error {
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		duplicates, err := cl.FindDuplicates(c.Context, c.Float64("threshold"), c.Int("limit"))
+		if err != nil {
+			return err
+		}
+
+		return printDuplicates(c, duplicates)
+	},
+}
+
+var mergeCommand = &cli.Command{
+	Name:      "merge",
+	Usage:     "merge a song into another one and delete it",
+	ArgsUsage: "TARGET_ID SOURCE_ID",
+	Description: "The target keeps its title and artist. Its empty release date and link are taken from the source,\n" +
+		"and so are the lyrics if the target has none.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{Name: "version", Usage: "merge only if the target still has this version"},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return errors.New("expected target and source song id arguments, flags go before them")
+		}
+
+		var ids [2]int
+		for i, arg := range c.Args().Slice() {
+			songID, err := strconv.Atoi(arg)
+			if err != nil || songID <= 0 {
+				return fmt.Errorf("invalid song id %q", arg)
+			}
+			ids[i] = songID
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		var version *int
+		if c.IsSet("version") {
+			v := c.Int("version")
+			version = &v
+		}
+
+		song, err := cl.MergeSongs(c.Context, ids[0], ids[1], version)
+		if err != nil {
+			return err
+		}
+
+		return printSong(c, song)
+	},
+}
+
+// exportedSong is the record format of export and import.
+type exportedSong struct {
+	Group       string          `json:"group"`
+	Song        string          `json:"song"`
+	ReleaseDate string          `json:"release_date,omitempty"`
+	Link        string          `json:"link,omitempty"`
+	Lyrics      []*client.Verse `json:"lyrics,omitempty"`
+}
+
+var exportCommand = &cli.Command{
+	Name:      "export",
+	Usage:     "write all songs with lyrics as JSON",
+	ArgsUsage: "[FILE]",
+	Action: func(c *cli.Context) error {
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		songs := make([]*exportedSong, 0)
+
+		for listed, err := range cl.Songs(c.Context, &client.ListSongsParams{Limit: exportPageSize}) {
+			if err != nil {
+				return err
+			}
+
+			song, err := cl.GetSongWithLyrics(c.Context, listed.ID, true)
+			if err != nil {
+				return err
+			}
+
+			songs = append(songs, &exportedSong{
+				Group:       song.Group,
+				Song:        song.Song.Song,
+				ReleaseDate: song.ReleaseDate,
+				Link:        song.Link,
+				Lyrics:      song.Lyrics,
+			})
+		}
+
+		w := c.App.Writer
+		if path := c.Args().First(); path != "" && path != "-" {
+			f, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		return writeJSON(w, songs)
+	},
+}
+
+var importCommand = &cli.Command{
+	Name:      "import",
+	Usage:     "add songs from a JSON file written by export",
+	ArgsUsage: "FILE",
+	Description: "Every song is added through the API, so its lyrics are fetched by the service again.\n" +
+		"Release date and link from the file replace the fetched ones, songs already in the library are only updated.",
+	Action: func(c *cli.Context) error {
+		path := c.Args().First()
+		if path == "" {
+			return errors.New("file argument required, use - for stdin")
+		}
+
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		var records []*exportedSong
+		err := json.NewDecoder(r).Decode(&records)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		imported := make([]*client.Song, 0, len(records))
+
+		for i, record := range records {
+			song, err := cl.AddSong(c.Context, record.Group, record.Song)
+			var apiErr *client.Error
+			if errors.As(err, &apiErr) && apiErr.Existing != nil {
+				// Importing a file again only updates the songs.
+				song, err = apiErr.Existing, nil
+			}
+			if err != nil {
+				return fmt.Errorf("import song %d (%s - %s): %w", i+1, record.Group, record.Song, err)
+			}
+
+			var update client.SongUpdate
+			if record.ReleaseDate != "" && record.ReleaseDate != song.ReleaseDate {
+				update.ReleaseDate = &record.ReleaseDate
+			}
+			if record.Link != "" && record.Link != song.Link {
+				update.Link = &record.Link
+			}
+
+			if update.ReleaseDate != nil || update.Link != nil {
+				song, err = cl.UpdateSong(c.Context, song.ID, &update, nil)
+				if err != nil {
+					return fmt.Errorf("import song %d (%s - %s): %w", i+1, record.Group, record.Song, err)
+				}
+			}
+
+			imported = append(imported, song)
+		}
+
+		return printSongs(c, imported, nil)
+	},
+}
+
+func songIDArg(c *cli.Context) (int, error) {
+	if c.NArg() == 0 {
+		return 0, errors.New("song id argument required")
+	}
+	if c.NArg() > 1 {
+		return 0, errors.New("expected a single song id argument, flags go before it")
+	}
+
+	songID, err := strconv.Atoi(c.Args().First())
+	if err != nil || songID <= 0 {
+		return 0, fmt.Errorf("invalid song id %q", c.Args().First())
+	}
+
+	return songID, nil
+}
+
+// songsFilter returns the filters of the ls and facets commands.
+func songsFilter(c *cli.Context) *client.ListSongsParams {
+	params := &client.ListSongsParams{
+		Song:        optionalString(c, "song"),
+		Group:       optionalString(c, "group"),
+		ReleaseDate: optionalString(c, "release-date"),
+		Tags:        c.StringSlice("tag"),
+	}
+	if c.Bool("all-tags") {
+		params.TagMode = client.TagModeAll
+	}
+
+	return params
+}
+
+func optionalString(c *cli.Context, name string) *string {
+	if !c.IsSet(name) {
+		return nil
+	}
+
+	value := c.String(name)
+	return &value
+}
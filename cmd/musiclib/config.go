@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/pkg/client"
+	"github.com/urfave/cli/v2"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// config is read from the config file, flags and environment variables take precedence.
+type config struct {
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "musiclib", "config.json")
+}
+
+func loadConfig(path string) (*config, error) {
+	var cfg config
+	if path == "" {
+		return &cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+func newClient(c *cli.Context) (*client.Client, error) {
+	cfg, err := loadConfig(c.String("config"))
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := c.String("url")
+	if !c.IsSet("url") && cfg.URL != "" {
+		baseURL = cfg.URL
+	}
+
+	apiKey := c.String("api-key")
+	if !c.IsSet("api-key") {
+		apiKey = cfg.APIKey
+	}
+
+	return client.New(baseURL, client.WithAPIKey(apiKey)), nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/pkg/client"
+	"github.com/urfave/cli/v2"
+	"strconv"
+)
+
+var linksCommand = &cli.Command{
+	Name:      "links",
+	Usage:     "list the media links of a song",
+	ArgsUsage: "SONG_ID",
+	Action: func(c *cli.Context) error {
+		songID, err := songIDArg(c)
+		if err != nil {
+			return err
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		links, err := cl.SongLinks(c.Context, songID)
+		if err != nil {
+			return err
+		}
+
+		return printLinks(c, links)
+	},
+}
+
+var linkCommand = &cli.Command{
+	Name:      "link",
+	Usage:     "add a media link to a song",
+	ArgsUsage: "SONG_ID URL",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "provider", Usage: "provider of the link, detected from its host by default"},
+	},
+	Action: func(c *cli.Context) error {
+		songID, args, err := songArgs(c, "URL")
+		if err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return errors.New("expected a single URL argument")
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		link, err := cl.AddLink(c.Context, songID, args[0], c.String("provider"))
+		if err != nil {
+			return err
+		}
+
+		return printLinks(c, []*client.Link{link})
+	},
+}
+
+var unlinkCommand = &cli.Command{
+	Name:      "unlink",
+	Usage:     "remove media links from a song",
+	ArgsUsage: "SONG_ID LINK_ID...",
+	Action: func(c *cli.Context) error {
+		songID, args, err := songArgs(c, "link id")
+		if err != nil {
+			return err
+		}
+
+		linkIDs := make([]int, 0, len(args))
+		for _, arg := range args {
+			linkID, err := strconv.Atoi(arg)
+			if err != nil || linkID <= 0 {
+				return fmt.Errorf("invalid link id %q", arg)
+			}
+			linkIDs = append(linkIDs, linkID)
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		for _, linkID := range linkIDs {
+			err = cl.DeleteLink(c.Context, songID, linkID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
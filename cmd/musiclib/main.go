@@ -0,0 +1,75 @@
+// Command musiclib is a command line client for the music library API.
+package main
+
+import (
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"os"
+)
+
+func main() {
+	app := &cli.App{
+		Name:  "musiclib",
+		Usage: "manage songs of the music library",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "url",
+				Usage:   "base URL of the service",
+				Value:   defaultURL,
+				EnvVars: []string{"MUSICLIB_URL"},
+			},
+			&cli.StringFlag{
+				Name:    "api-key",
+				Usage:   "API key sent in the X-API-Key header",
+				EnvVars: []string{"MUSICLIB_API_KEY"},
+			},
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "JSON config file with url and api_key",
+				Value:   defaultConfigPath(),
+				EnvVars: []string{"MUSICLIB_CONFIG"},
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "output format: table or json",
+				Value:   formatTable,
+			},
+		},
+		Before: func(c *cli.Context) error {
+			format := c.String("output")
+			if format != formatTable && format != formatJSON {
+				return fmt.Errorf("unknown output format %q", format)
+			}
+			return nil
+		},
+		Commands: []*cli.Command{
+			addCommand,
+			lsCommand,
+			lyricsCommand,
+			updateCommand,
+			rmCommand,
+			duplicatesCommand,
+			mergeCommand,
+			tagsCommand,
+			tagCommand,
+			untagCommand,
+			facetsCommand,
+			translationsCommand,
+			translateCommand,
+			untranslateCommand,
+			linksCommand,
+			linkCommand,
+			unlinkCommand,
+			refreshCommand,
+			importCommand,
+			exportCommand,
+		},
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "musiclib:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aaanger/music-library/pkg/client"
+	"github.com/urfave/cli/v2"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printSongs(c *cli.Context, songs []*client.Song, pagination *client.Pagination) error {
+	if c.String("output") == formatJSON {
+		if pagination == nil {
+			return writeJSON(c.App.Writer, songs)
+		}
+		return writeJSON(c.App.Writer, map[string]any{"data": songs, "pagination": pagination})
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tGROUP\tSONG\tRELEASE DATE\tLINK\tVERSION")
+	for _, song := range songs {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\n", song.ID, song.Group, song.Song, song.ReleaseDate, song.Link, song.Version)
+	}
+
+	err := w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if pagination != nil {
+		fmt.Fprintf(c.App.Writer, "page %d of %d, %d songs\n", pagination.Page, pagination.Pages, pagination.Total)
+	}
+	return nil
+}
+
+func printSong(c *cli.Context, song *client.Song) error {
+	if c.String("output") == formatJSON {
+		return writeJSON(c.App.Writer, song)
+	}
+	return printSongs(c, []*client.Song{song}, nil)
+}
+
+func printVerses(c *cli.Context, verses []*client.Verse) error {
+	if c.String("output") == formatJSON {
+		return writeJSON(c.App.Writer, verses)
+	}
+
+	for i, verse := range verses {
+		if i > 0 {
+			fmt.Fprintln(c.App.Writer)
+		}
+		fmt.Fprintf(c.App.Writer, "[%d]\n%s\n", verse.Number, verse.Lyrics)
+	}
+	return nil
+}
+
+func printDuplicates(c *cli.Context, duplicates []*client.DuplicateSongs) error {
+	if c.String("output") == formatJSON {
+		return writeJSON(c.App.Writer, duplicates)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SIMILARITY\tID\tGROUP\tSONG\tDUPLICATE ID\tDUPLICATE GROUP\tDUPLICATE SONG")
+	for _, pair := range duplicates {
+		fmt.Fprintf(w, "%.2f\t%d\t%s\t%s\t%d\t%s\t%s\n", pair.Similarity,
+			pair.Song.ID, pair.Song.Group, pair.Song.Song, pair.Duplicate.ID, pair.Duplicate.Group, pair.Duplicate.Song)
+	}
+
+	return w.Flush()
+}
+
+func printTags(c *cli.Context, tags []*client.Tag) error {
+	if c.String("output") == formatJSON {
+		return writeJSON(c.App.Writer, tags)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tKIND")
+	for _, tag := range tags {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", tag.ID, tag.Name, tag.Kind)
+	}
+
+	return w.Flush()
+}
+
+func printRefresh(c *cli.Context, results []*client.SongRefresh) error {
+	if c.String("output") == formatJSON {
+		return writeJSON(c.App.Writer, results)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tFIELD\tOLD\tNEW\tAPPLIED")
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(w, "%d\terror\t\t%s\t\n", result.SongID, result.Error)
+			continue
+		}
+		for _, change := range result.Changes {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\n", result.SongID, change.Field, shorten(change.Old), shorten(change.New), result.Applied)
+		}
+	}
+
+	return w.Flush()
+}
+
+// shorten fits a value, e.g. lyrics, into a table cell on one line.
+func shorten(value string) string {
+	const maxLength = 40
+
+	value = strings.Join(strings.Fields(value), " ")
+	if runes := []rune(value); len(runes) > maxLength {
+		return string(runes[:maxLength-1]) + "…"
+	}
+	return value
+}
+
+func printLinks(c *cli.Context, links []*client.Link) error {
+	if c.String("output") == formatJSON {
+		return writeJSON(c.App.Writer, links)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPROVIDER\tSTATUS\tURL")
+	for _, link := range links {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", link.ID, link.Provider, link.Status, link.URL)
+	}
+
+	return w.Flush()
+}
+
+func printLanguages(c *cli.Context, languages *client.LyricsLanguages) error {
+	if c.String("output") == formatJSON {
+		return writeJSON(c.App.Writer, languages)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LANGUAGE\tLYRICS")
+	fmt.Fprintf(w, "%s\toriginal\n", languages.Original)
+	for _, lang := range languages.Translations {
+		fmt.Fprintf(w, "%s\ttranslation\n", lang)
+	}
+
+	return w.Flush()
+}
+
+func printFacets(c *cli.Context, facets *client.Facets) error {
+	if c.String("output") == formatJSON {
+		return writeJSON(c.App.Writer, facets)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FACET\tVALUE\tCOUNT")
+	for _, facet := range []struct {
+		name   string
+		counts []*client.FacetCount
+	}{
+		{"tag", facets.Tags},
+		{"artist", facets.Artists},
+		{"year", facets.Years},
+	} {
+		for _, count := range facet.counts {
+			name := facet.name
+			if count.Kind != "" {
+				name = count.Kind
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\n", name, count.Value, count.Count)
+		}
+	}
+
+	return w.Flush()
+}
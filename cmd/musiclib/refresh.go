@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aaanger/music-library/pkg/client"
+	"github.com/urfave/cli/v2"
+	"strconv"
+)
+
+var refreshCommand = &cli.Command{
+	Name:      "refresh",
+	Usage:     "fetch the details of songs from the metadata providers again",
+	ArgsUsage: "[SONG_ID...]",
+	Description: "Refreshes the given songs, or all songs matching the filters. The changed fields are\n" +
+		"printed and applied unless --dry-run is set. The service throttles the requests to the providers.",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{Name: "field", Usage: "field to refresh: release_date, text or link, may be repeated, all by default"},
+		&cli.BoolFlag{Name: "dry-run", Usage: "only print the changes"},
+		&cli.StringFlag{Name: "song", Usage: "filter by song title"},
+		&cli.StringFlag{Name: "group", Usage: "filter by artist"},
+		&cli.StringFlag{Name: "release-date", Usage: "filter by release date"},
+		&cli.StringSliceFlag{Name: "tag", Usage: "filter by tag or genre, may be repeated"},
+		&cli.BoolFlag{Name: "all-tags", Usage: "keep only songs with all of the tags"},
+	},
+	Action: func(c *cli.Context) error {
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		songIDs := make([]int, 0, c.NArg())
+		for _, arg := range c.Args().Slice() {
+			songID, err := strconv.Atoi(arg)
+			if err != nil || songID <= 0 {
+				return fmt.Errorf("invalid song id %q", arg)
+			}
+			songIDs = append(songIDs, songID)
+		}
+
+		// The matching songs are listed first, refreshing them may change
+		// which songs match the filters.
+		if len(songIDs) == 0 {
+			for song, err := range cl.Songs(c.Context, songsFilter(c)) {
+				if err != nil {
+					return err
+				}
+				songIDs = append(songIDs, song.ID)
+			}
+		}
+
+		opts := client.RefreshOptions{
+			Fields: c.StringSlice("field"),
+			DryRun: c.Bool("dry-run"),
+		}
+
+		results := make([]*client.SongRefresh, 0, len(songIDs))
+		for _, songID := range songIDs {
+			result, err := cl.RefreshSong(c.Context, songID, opts)
+			if err != nil {
+				result = &client.SongRefresh{SongID: songID, Error: err.Error()}
+			}
+			results = append(results, result)
+		}
+
+		return printRefresh(c, results)
+	},
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aaanger/music-library/pkg/client"
+	"github.com/urfave/cli/v2"
+	"strconv"
+)
+
+var tagsCommand = &cli.Command{
+	Name:      "tags",
+	Usage:     "list tags and genres, or the tags of a song",
+	ArgsUsage: "[SONG_ID]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "kind", Usage: "list only tags of this kind: tag or genre"},
+	},
+	Action: func(c *cli.Context) error {
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		if c.NArg() == 0 {
+			tags, err := cl.ListTags(c.Context, c.String("kind"))
+			if err != nil {
+				return err
+			}
+			return printTags(c, tags)
+		}
+
+		songID, err := songIDArg(c)
+		if err != nil {
+			return err
+		}
+
+		tags, err := cl.SongTags(c.Context, songID)
+		if err != nil {
+			return err
+		}
+
+		return printTags(c, tags)
+	},
+}
+
+var tagCommand = &cli.Command{
+	Name:      "tag",
+	Usage:     "add tags to a song",
+	ArgsUsage: "SONG_ID TAG...",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{Name: "genre", Usage: "add the tags as genres"},
+	},
+	Action: func(c *cli.Context) error {
+		songID, names, err := songArgs(c, "tag")
+		if err != nil {
+			return err
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		var kind string
+		if c.Bool("genre") {
+			kind = client.TagKindGenre
+		}
+
+		var tags []*client.Tag
+		for _, name := range names {
+			tags, err = cl.TagSong(c.Context, songID, name, kind)
+			if err != nil {
+				return err
+			}
+		}
+
+		return printTags(c, tags)
+	},
+}
+
+var untagCommand = &cli.Command{
+	Name:      "untag",
+	Usage:     "remove tags from a song",
+	ArgsUsage: "SONG_ID TAG...",
+	Action: func(c *cli.Context) error {
+		songID, names, err := songArgs(c, "tag")
+		if err != nil {
+			return err
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			err = cl.UntagSong(c.Context, songID, name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+var facetsCommand = &cli.Command{
+	Name:  "facets",
+	Usage: "count songs by tag, artist and release year",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "song", Usage: "filter by song title"},
+		&cli.StringFlag{Name: "group", Usage: "filter by artist"},
+		&cli.StringFlag{Name: "release-date", Usage: "filter by release date"},
+		&cli.StringSliceFlag{Name: "tag", Usage: "filter by tag or genre, may be repeated"},
+		&cli.BoolFlag{Name: "all-tags", Usage: "keep only songs with all of the tags"},
+		&cli.IntFlag{Name: "limit", Usage: "values per facet", Value: 20},
+	},
+	Action: func(c *cli.Context) error {
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		facets, err := cl.GetFacets(c.Context, songsFilter(c), c.Int("limit"))
+		if err != nil {
+			return err
+		}
+
+		return printFacets(c, facets)
+	},
+}
+
+// songArgs parses the SONG_ID NAME... arguments of commands like tag and
+// untag, name is what the arguments following the song id are.
+func songArgs(c *cli.Context, name string) (int, []string, error) {
+	if c.NArg() < 2 {
+		return 0, nil, fmt.Errorf("expected song id and %s arguments, flags go before them", name)
+	}
+
+	songID, err := strconv.Atoi(c.Args().First())
+	if err != nil || songID <= 0 {
+		return 0, nil, fmt.Errorf("invalid song id %q", c.Args().First())
+	}
+
+	return songID, c.Args().Tail(), nil
+}
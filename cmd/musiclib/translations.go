@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+)
+
+var translationsCommand = &cli.Command{
+	Name:      "translations",
+	Usage:     "print the language of the lyrics of a song and of its translations",
+	ArgsUsage: "SONG_ID",
+	Action: func(c *cli.Context) error {
+		songID, err := songIDArg(c)
+		if err != nil {
+			return err
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		languages, err := cl.LyricsLanguages(c.Context, songID)
+		if err != nil {
+			return err
+		}
+
+		return printLanguages(c, languages)
+	},
+}
+
+var translateCommand = &cli.Command{
+	Name:      "translate",
+	Usage:     "add or replace a translation of the lyrics of a song",
+	ArgsUsage: "SONG_ID LANG FILE",
+	Description: "The translation is read from FILE, - for stdin. Verses are separated by blank lines\n" +
+		"and there must be as many of them as in the original lyrics.",
+	Action: func(c *cli.Context) error {
+		songID, args, err := songArgs(c, "language and file")
+		if err != nil {
+			return err
+		}
+		if len(args) != 2 {
+			return errors.New("expected language and file arguments, use - for stdin")
+		}
+		lang, path := args[0], args[1]
+
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		lyrics, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		languages, err := cl.SetTranslation(c.Context, songID, lang, string(lyrics))
+		if err != nil {
+			return err
+		}
+
+		return printLanguages(c, languages)
+	},
+}
+
+var untranslateCommand = &cli.Command{
+	Name:      "untranslate",
+	Usage:     "delete translations of the lyrics of a song",
+	ArgsUsage: "SONG_ID LANG...",
+	Action: func(c *cli.Context) error {
+		songID, langs, err := songArgs(c, "language")
+		if err != nil {
+			return err
+		}
+
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+
+		for _, lang := range langs {
+			err = cl.DeleteTranslation(c.Context, songID, lang)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
@@ -1,16 +1,25 @@
 package dto
 
 type AddSongReq struct {
-	Group string `json:"group" binding:"required"`
-	Song  string `json:"song" binding:"required"`
+	Group string `json:"group" binding:"required,max=255"`
+	Song  string `json:"song" binding:"required,max=255"`
 }
 
+const (
+	TagModeAny = "any"
+	TagModeAll = "all"
+)
+
 type GetSongsListReq struct {
 	Song        *string `json:"song,omitempty"`
 	Group       *string `json:"group,omitempty"`
 	ReleaseDate *string `json:"releaseDate,omitempty"`
-	Limit       int
-	Offset      int
+	// Tags keeps the songs with any of the tags, or all of them if TagMode
+	// is TagModeAll.
+	Tags    []string `json:"tags,omitempty"`
+	TagMode string   `json:"tagMode,omitempty"`
+	Limit   int
+	Offset  int
 }
 
 type SongDetail struct {
@@ -20,9 +29,52 @@ type SongDetail struct {
 }
 
 type UpdateSongReq struct {
-	Song        *string `json:"song,omitempty"`
-	Group       *string `json:"group,omitempty"`
-	ReleaseDate *string `json:"releaseDate,omitempty"`
+	Song        *string `json:"song,omitempty" binding:"omitnil,min=1,max=255"`
+	Group       *string `json:"group,omitempty" binding:"omitnil,min=1,max=255"`
+	ReleaseDate *string `json:"releaseDate,omitempty" binding:"omitnil,release_date"`
 	Text        *string `json:"text,omitempty"`
-	Link        *string `json:"link,omitempty"`
+	Link        *string `json:"link,omitempty" binding:"omitnil,link"`
+
+	// Version is taken from the If-Match header, the update is rejected
+	// if the stored song has a different version.
+	Version *int `json:"-"`
+}
+
+// SongDocument is the JSON representation of a song that patches are applied to.
+type SongDocument struct {
+	Song        string `json:"song" binding:"required,max=255"`
+	Group       string `json:"group" binding:"required,max=255"`
+	ReleaseDate string `json:"release_date" binding:"omitempty,release_date"`
+	Link        string `json:"link" binding:"omitempty,link"`
+}
+
+type MergeSongsReq struct {
+	// SourceID is the song merged into the song of the path and deleted.
+	SourceID int `json:"source_id" binding:"required,gt=0"`
+}
+
+type SetTranslationReq struct {
+	// Lyrics are split into verses by blank lines like the original lyrics
+	// and must have as many verses.
+	Lyrics string `json:"lyrics" binding:"required"`
+}
+
+type RefreshSongsReq struct {
+	// Fields are the fields to refresh, all of them if it's empty.
+	Fields []string `json:"fields" binding:"dive,oneof=release_date text link"`
+	// DryRun only reports the changes without applying them.
+	DryRun bool `json:"dry_run"`
+}
+
+type AddLinkReq struct {
+	URL string `json:"url" binding:"required,link"`
+	// Provider is detected from the host of the URL if it's empty.
+	Provider string `json:"provider" binding:"omitempty,provider"`
+}
+
+type TagSongReq struct {
+	Name string `json:"name" binding:"required,tag"`
+	// Kind is set when the tag is created or changes the kind of an existing tag,
+	// new tags without it are of the tag kind.
+	Kind string `json:"kind" binding:"omitempty,oneof=tag genre"`
 }
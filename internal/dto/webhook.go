@@ -0,0 +1,9 @@
+package dto
+
+type AddWebhookReq struct {
+	URL string `json:"url" binding:"required,link"`
+	// Secret signs the payloads, one is generated if it's empty.
+	Secret string `json:"secret" binding:"omitempty,min=16,max=255"`
+	// Events to deliver, all of them if it's empty.
+	Events []string `json:"events" binding:"dive,oneof=song.created song.updated song.deleted song.enriched"`
+}
@@ -0,0 +1,74 @@
+package gqlhandler
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/internal/validation"
+)
+
+const (
+	codeBadUserInput    = "BAD_USER_INPUT"
+	codeNotFound        = "NOT_FOUND"
+	codeVersionMismatch = "VERSION_MISMATCH"
+	codeConflict        = "CONFLICT"
+	codeInternal        = "INTERNAL"
+)
+
+// resolverError is returned by resolvers, its code and field errors end up
+// in the extensions of the GraphQL error.
+type resolverError struct {
+	message string
+	code    string
+	fields  []validation.FieldError
+	// existingID is the id of the song a new song duplicates.
+	existingID int
+}
+
+func (e *resolverError) Error() string {
+	return e.message
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.code}
+	if e.fields != nil {
+		ext["fields"] = e.fields
+	}
+	if e.existingID != 0 {
+		ext["existingId"] = e.existingID
+	}
+	return ext
+}
+
+func badUserInput(message string) error {
+	return &resolverError{message: message, code: codeBadUserInput}
+}
+
+// error maps service and repository errors to resolver errors.
+func (h *Handler) error(resolver string, err error) error {
+	if fields, ok := validation.FieldErrors(err); ok {
+		h.log.Debugf("%s resolver validation failed: %+v", resolver, fields)
+		return &resolverError{message: "validation failed", code: codeBadUserInput, fields: fields}
+	}
+
+	var duplicate *service.DuplicateSongError
+	if errors.As(err, &duplicate) {
+		return &resolverError{message: "song already exists", code: codeConflict, existingID: duplicate.Song.ID}
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound), errors.Is(err, metadata.ErrNotFound):
+		return &resolverError{message: "song not found", code: codeNotFound}
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return &resolverError{message: "song was modified", code: codeVersionMismatch}
+	case errors.Is(err, repository.ErrDuplicateSong):
+		return &resolverError{message: "song already exists", code: codeConflict}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	}
+
+	h.log.Errorf("%s resolver failure: %s", resolver, err)
+	return &resolverError{message: "internal error", code: codeInternal}
+}
@@ -0,0 +1,76 @@
+package gqlhandler
+
+import (
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// Handler serves the GraphQL API on top of IMusicService.
+type Handler struct {
+	service service.IMusicService
+	schema  graphql.Schema
+	log     *logrus.Logger
+}
+
+// NewHandler builds the GraphQL schema. The schema is static, so an error
+// building it is a programming error and NewHandler panics.
+func NewHandler(service service.IMusicService, log *logrus.Logger) *Handler {
+	h := &Handler{
+		service: service,
+		log:     log,
+	}
+
+	schema, err := h.newSchema()
+	if err != nil {
+		panic(err)
+	}
+	h.schema = schema
+
+	return h
+}
+
+type request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Serve godoc
+// @Summary GraphQL запрос
+// @Description Запросы песен, исполнителей и текстов, мутации добавления, изменения и удаления песен
+// @Tags GraphQL
+// @Accept json
+// @Produce json
+// @Param request body request true "GraphQL запрос"
+// @Success 200 {object} map[string]interface{} "Результат запроса: data и errors"
+// @Failure 400 {string} string "Неверное тело запроса"
+// @Router /graphql [post]
+func (h *Handler) Serve(c *gin.Context) {
+	var req request
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		h.log.Debugf("GraphQL handler: invalid request: %s", err)
+		response.Error(c, http.StatusBadRequest, "invalid graphql request")
+		return
+	}
+
+	h.log.Debugf("GraphQL handler request: operation - %q variables - %v", req.OperationName, req.Variables)
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        withLyricsLoader(c.Request.Context(), newLyricsLoader(h.service)),
+	})
+	if result.HasErrors() {
+		h.log.Debugf("GraphQL handler: request finished with errors: %v", result.Errors)
+	}
+
+	response.JSON(c, result)
+}
@@ -0,0 +1,411 @@
+package gqlhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/internal/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// lyricsCounter counts the GetLyricsBySongIDs calls, which the lyrics loader batches.
+type lyricsCounter struct {
+	service.IMusicService
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *lyricsCounter) GetLyricsBySongIDs(ctx context.Context, songIDs []int) (map[int][]*model.Verse, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+
+	return s.IMusicService.GetLyricsBySongIDs(ctx, songIDs)
+}
+
+type testServer struct {
+	router *gin.Engine
+	repo   *repository.MemoryMusicRepository
+	lyrics *lyricsCounter
+}
+
+// newTestServer serves the GraphQL API backed by a memory repository. The
+// metadata provider knows Muse - Uprising.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewMemoryMusicRepository(testLogger())
+	registry := metadata.NewRegistry(testLogger())
+	registry.Register(metadata.NewFSProvider(fstest.MapFS{
+		"songs.json": {Data: []byte(`[{"group": "Muse", "song": "Uprising", "releaseDate": "07.09.2009", "text": "First verse\n\nSecond verse"}]`)},
+	}), 0)
+	lyrics := &lyricsCounter{IMusicService: service.NewMusicService(repo, registry, testLogger())}
+
+	router := gin.New()
+	router.POST("/graphql", NewHandler(lyrics, testLogger()).Serve)
+
+	return &testServer{router: router, repo: repo, lyrics: lyrics}
+}
+
+func (s *testServer) ingest(t *testing.T, group, song, lyrics string) *model.Song {
+	t.Helper()
+
+	created, err := s.repo.IngestSong(context.Background(), &model.Song{Group: group, Song: song, ReleaseDate: "16.07.2006"}, lyrics, nil)
+	if err != nil {
+		t.Fatalf("IngestSong: %s", err)
+	}
+	return created
+}
+
+type gqlError struct {
+	Message    string                 `json:"message"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+type gqlResult struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []gqlError      `json:"errors"`
+}
+
+func (s *testServer) do(t *testing.T, query string, variables map[string]interface{}) gqlResult {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatalf("marshal request: %s", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	var result gqlResult
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	if err != nil {
+		t.Fatalf("unmarshal response %s: %s", w.Body, err)
+	}
+	return result
+}
+
+// assertJSON compares two JSON documents regardless of formatting.
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("unmarshal %s: %s", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("unmarshal %s: %s", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Fatalf("data = %s, want %s", got, want)
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	h := &Handler{log: testLogger()}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "validation", err: validation.Struct(dto.AddSongReq{}), want: codeBadUserInput},
+		{name: "duplicate with the existing song", err: &service.DuplicateSongError{Song: &model.Song{ID: 7}}, want: codeConflict},
+		{name: "duplicate", err: repository.ErrDuplicateSong, want: codeConflict},
+		{name: "not found", err: fmt.Errorf("get: %w", repository.ErrSongNotFound), want: codeNotFound},
+		{name: "upstream not found", err: fmt.Errorf("fetch: %w", metadata.ErrNotFound), want: codeNotFound},
+		{name: "version mismatch", err: repository.ErrVersionMismatch, want: codeVersionMismatch},
+		{name: "unknown", err: errors.New("pq: connection refused"), want: codeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resolverErr *resolverError
+			if !errors.As(h.error("test", tt.err), &resolverErr) {
+				t.Fatalf("error(%v) isn't a resolver error", tt.err)
+			}
+			if resolverErr.code != tt.want {
+				t.Fatalf("code = %s, want %s", resolverErr.code, tt.want)
+			}
+			if tt.want == codeInternal && resolverErr.message != "internal error" {
+				t.Fatalf("message = %q, the cause mustn't leak", resolverErr.message)
+			}
+		})
+	}
+
+	for _, err := range []error{context.Canceled, context.DeadlineExceeded} {
+		if got := h.error("test", err); got != err {
+			t.Fatalf("error(%v) = %v, want it unchanged", err, got)
+		}
+	}
+}
+
+func TestQueries(t *testing.T) {
+	s := newTestServer(t)
+	hysteria := s.ingest(t, "Muse", "Hysteria", "It's bugging me\n\nGrating me\n\nAnd twisting me around")
+	s.ingest(t, "Muse", "Starlight", "Far away")
+	s.ingest(t, "Кино", "Группа крови", "Тёплое место")
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+		code      string
+	}{
+		{
+			name:      "song with lyrics range",
+			query:     `query($id: Int!) { song(id: $id) { song group versesCount lyrics(from: 2) { number lyrics } } }`,
+			variables: map[string]interface{}{"id": hysteria.ID},
+			want:      `{"song": {"song": "Hysteria", "group": "Muse", "versesCount": 3, "lyrics": [{"number": 2, "lyrics": "Grating me"}, {"number": 3, "lyrics": "And twisting me around"}]}}`,
+		},
+		{
+			name:  "missing song",
+			query: `{ song(id: 999) { song } }`,
+			want:  `{"song": null}`,
+		},
+		{
+			name:  "songs across scripts",
+			query: `{ songs(group: "kino") { total items { song } } }`,
+			want:  `{"songs": {"total": 1, "items": [{"song": "Группа крови"}]}}`,
+		},
+		{
+			name:  "songs page",
+			query: `{ songs(group: "MUSE", limit: 1, page: 2) { total page limit items { song } } }`,
+			want:  `{"songs": {"total": 2, "page": 2, "limit": 1, "items": [{"song": "Starlight"}]}}`,
+		},
+		{
+			name:  "empty page",
+			query: `{ songs(limit: 5, page: 3) { total items { song } } }`,
+			want:  `{"songs": {"total": 3, "items": []}}`,
+		},
+		{
+			name:  "invalid limit",
+			query: `{ songs(limit: 0) { total } }`,
+			code:  codeBadUserInput,
+		},
+		{
+			name:  "invalid page",
+			query: `{ songs(page: -1) { total } }`,
+			code:  codeBadUserInput,
+		},
+		{
+			name:      "invalid verse range",
+			query:     `query($id: Int!) { song(id: $id) { lyrics(from: 3, to: 2) { number } } }`,
+			variables: map[string]interface{}{"id": hysteria.ID},
+			code:      codeBadUserInput,
+		},
+		{
+			name:  "artist songs",
+			query: `{ artist(name: "muse") { name songs(limit: 1) { total items { song artist { name } } } } }`,
+			want:  `{"artist": {"name": "muse", "songs": {"total": 2, "items": [{"song": "Hysteria", "artist": {"name": "Muse"}}]}}}`,
+		},
+		{
+			name:  "unknown artist",
+			query: `{ artist(name: "Nobody") { name } }`,
+			want:  `{"artist": null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := s.do(t, tt.query, tt.variables)
+			assertResult(t, result, tt.want, tt.code)
+		})
+	}
+}
+
+func assertResult(t *testing.T, result gqlResult, want, code string) {
+	t.Helper()
+
+	if code != "" {
+		if len(result.Errors) == 0 {
+			t.Fatalf("no errors, want %s: %s", code, result.Data)
+		}
+		if got := result.Errors[0].Extensions["code"]; got != code {
+			t.Fatalf("error %+v, want code %s", result.Errors[0], code)
+		}
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		t.Fatalf("errors = %+v", result.Errors)
+	}
+	assertJSON(t, result.Data, want)
+}
+
+func TestMutations(t *testing.T) {
+	s := newTestServer(t)
+	song := s.ingest(t, "Muse", "Hysteria", "It's bugging me")
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+		code      string
+		check     func(t *testing.T, result gqlResult)
+	}{
+		{
+			name:  "addSong",
+			query: `mutation { addSong(group: "Muse", song: "Uprising") { song releaseDate versesCount } }`,
+			want:  `{"addSong": {"song": "Uprising", "releaseDate": "07.09.2009", "versesCount": 2}}`,
+		},
+		{
+			name:  "addSong duplicate",
+			query: `mutation { addSong(group: "muse", song: "hysteria") { id } }`,
+			code:  codeConflict,
+			check: func(t *testing.T, result gqlResult) {
+				if got := result.Errors[0].Extensions["existingId"]; got != float64(song.ID) {
+					t.Fatalf("existingId = %v, want %d", got, song.ID)
+				}
+			},
+		},
+		{
+			name:  "addSong unknown upstream",
+			query: `mutation { addSong(group: "Muse", song: "Unknown") { id } }`,
+			code:  codeNotFound,
+		},
+		{
+			name:  "addSong invalid",
+			query: `mutation { addSong(group: "", song: "Uprising") { id } }`,
+			code:  codeBadUserInput,
+			check: func(t *testing.T, result gqlResult) {
+				if result.Errors[0].Extensions["fields"] == nil {
+					t.Fatalf("error %+v, want the field errors", result.Errors[0])
+				}
+			},
+		},
+		{
+			name:      "updateSong stale version",
+			query:     `mutation($id: Int!, $version: Int) { updateSong(id: $id, releaseDate: "01.01.2010", version: $version) { id } }`,
+			variables: map[string]interface{}{"id": song.ID, "version": song.Version - 1},
+			code:      codeVersionMismatch,
+		},
+		{
+			name:      "updateSong invalid",
+			query:     `mutation($id: Int!) { updateSong(id: $id, releaseDate: "yesterday") { id } }`,
+			variables: map[string]interface{}{"id": song.ID},
+			code:      codeBadUserInput,
+		},
+		{
+			name:  "updateSong missing",
+			query: `mutation { updateSong(id: 999, releaseDate: "01.01.2010") { id } }`,
+			code:  codeNotFound,
+		},
+		{
+			name:      "updateSong",
+			query:     `mutation($id: Int!, $version: Int) { updateSong(id: $id, releaseDate: "01.01.2010", version: $version) { releaseDate } }`,
+			variables: map[string]interface{}{"id": song.ID, "version": song.Version},
+			want:      `{"updateSong": {"releaseDate": "01.01.2010"}}`,
+		},
+		{
+			name:      "deleteSong",
+			query:     `mutation($id: Int!) { deleteSong(id: $id) }`,
+			variables: map[string]interface{}{"id": song.ID},
+			want:      `{"deleteSong": true}`,
+		},
+		{
+			name:      "deleteSong missing",
+			query:     `mutation($id: Int!) { deleteSong(id: $id) }`,
+			variables: map[string]interface{}{"id": song.ID},
+			code:      codeNotFound,
+		},
+	}
+
+	// The cases run in order, later ones see the changes of earlier ones.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := s.do(t, tt.query, tt.variables)
+			assertResult(t, result, tt.want, tt.code)
+			if tt.check != nil {
+				tt.check(t, result)
+			}
+		})
+	}
+}
+
+func TestLyricsLoaderBatches(t *testing.T) {
+	s := newTestServer(t)
+	for i := 1; i <= 5; i++ {
+		s.ingest(t, "Muse", fmt.Sprintf("Song %d", i), strings.TrimSuffix(strings.Repeat("Verse\n\n", i), "\n\n"))
+	}
+
+	result := s.do(t, `{ songs { items { versesCount lyrics { number } artist { songs(limit: 2) { items { versesCount } } } } } }`, nil)
+	if len(result.Errors) > 0 {
+		t.Fatalf("errors = %+v", result.Errors)
+	}
+
+	var data struct {
+		Songs struct {
+			Items []struct {
+				VersesCount int `json:"versesCount"`
+				Lyrics      []struct {
+					Number int `json:"number"`
+				} `json:"lyrics"`
+			} `json:"items"`
+		} `json:"songs"`
+	}
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		t.Fatalf("unmarshal %s: %s", result.Data, err)
+	}
+	if len(data.Songs.Items) != 5 {
+		t.Fatalf("got %d songs, want 5", len(data.Songs.Items))
+	}
+	for i, item := range data.Songs.Items {
+		if item.VersesCount != i+1 || len(item.Lyrics) != i+1 {
+			t.Fatalf("song %d: versesCount = %d with %d verses, want %d", i+1, item.VersesCount, len(item.Lyrics), i+1)
+		}
+	}
+
+	// One call for the songs and one for the nested songs of their artist,
+	// which are already loaded.
+	if s.lyrics.calls > 2 {
+		t.Fatalf("GetLyricsBySongIDs calls = %d, want the levels batched", s.lyrics.calls)
+	}
+}
+
+func TestServeInvalidRequest(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, body := range []string{``, `{"query": ""}`, `not json`} {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("body %q: status = %d, want %d", body, w.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package gqlhandler
+
+import (
+	"context"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/service"
+	"sync"
+)
+
+// lyricsLoader batches the lyrics lookups of a single GraphQL request.
+// graphql-go resolves every field of a level before it calls the thunks
+// returned by the resolvers, so by the time the first thunk runs all songs
+// of the level are queued and their lyrics are read with one service call.
+type lyricsLoader struct {
+	service service.IMusicService
+
+	mu      sync.Mutex
+	pending []int
+	queued  map[int]bool
+	loaded  map[int][]*model.Verse
+	errs    map[int]error
+}
+
+func newLyricsLoader(service service.IMusicService) *lyricsLoader {
+	return &lyricsLoader{
+		service: service,
+		queued:  make(map[int]bool),
+		loaded:  make(map[int][]*model.Verse),
+		errs:    make(map[int]error),
+	}
+}
+
+// load queues songID and returns a thunk yielding its lyrics.
+func (l *lyricsLoader) load(ctx context.Context, songID int) func() ([]*model.Verse, error) {
+	l.mu.Lock()
+	if !l.queued[songID] {
+		l.queued[songID] = true
+		l.pending = append(l.pending, songID)
+	}
+	l.mu.Unlock()
+
+	return func() ([]*model.Verse, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			l.fetch(ctx)
+		}
+
+		if err, ok := l.errs[songID]; ok {
+			return nil, err
+		}
+		return l.loaded[songID], nil
+	}
+}
+
+// fetch loads the lyrics of all pending songs, l.mu must be held.
+func (l *lyricsLoader) fetch(ctx context.Context) {
+	songIDs := l.pending
+	l.pending = nil
+
+	lyrics, err := l.service.GetLyricsBySongIDs(ctx, songIDs)
+	for _, songID := range songIDs {
+		if err != nil {
+			l.errs[songID] = err
+			continue
+		}
+		l.loaded[songID] = lyrics[songID]
+	}
+}
+
+type loaderKey struct{}
+
+func withLyricsLoader(ctx context.Context, loader *lyricsLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+func lyricsLoaderFrom(ctx context.Context) *lyricsLoader {
+	return ctx.Value(loaderKey{}).(*lyricsLoader)
+}
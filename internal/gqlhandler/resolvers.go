@@ -0,0 +1,211 @@
+package gqlhandler
+
+import (
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/validation"
+	"github.com/graphql-go/graphql"
+)
+
+func (h *Handler) resolveSong(p graphql.ResolveParams) (interface{}, error) {
+	song, err := h.service.GetSong(p.Context, p.Args["id"].(int))
+	if errors.Is(err, repository.ErrSongNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, h.error("song", err)
+	}
+
+	return song, nil
+}
+
+func (h *Handler) resolveSongs(p graphql.ResolveParams) (interface{}, error) {
+	var req dto.GetSongsListReq
+
+	if song, ok := p.Args["song"].(string); ok {
+		req.Song = &song
+	}
+	if group, ok := p.Args["group"].(string); ok {
+		req.Group = &group
+	}
+	if releaseDate, ok := p.Args["releaseDate"].(string); ok {
+		req.ReleaseDate = &releaseDate
+	}
+
+	return h.listSongs(p, "songs", &req)
+}
+
+func (h *Handler) resolveArtist(p graphql.ResolveParams) (interface{}, error) {
+	name := p.Args["name"].(string)
+
+	_, total, err := h.service.ListSongs(p.Context, &dto.GetSongsListReq{Group: &name, Limit: 1})
+	if err != nil {
+		return nil, h.error("artist", err)
+	}
+	if total == 0 {
+		return nil, nil
+	}
+
+	return &artist{Name: name}, nil
+}
+
+func (h *Handler) resolveArtistSongs(p graphql.ResolveParams) (interface{}, error) {
+	name := p.Source.(*artist).Name
+	return h.listSongs(p, "artist.songs", &dto.GetSongsListReq{Group: &name})
+}
+
+// listSongs completes req with the limit and page arguments and returns the page.
+func (h *Handler) listSongs(p graphql.ResolveParams, resolver string, req *dto.GetSongsListReq) (*songPage, error) {
+	limit := p.Args["limit"].(int)
+	if limit <= 0 {
+		return nil, badUserInput("invalid limit")
+	}
+	page := p.Args["page"].(int)
+	if page <= 0 {
+		return nil, badUserInput("invalid page")
+	}
+
+	req.Limit = limit
+	req.Offset = (page - 1) * limit
+
+	songs, total, err := h.service.ListSongs(p.Context, req)
+	if err != nil {
+		return nil, h.error(resolver, err)
+	}
+
+	if songs == nil {
+		songs = []*model.Song{}
+	}
+
+	return &songPage{Items: songs, Total: total, Page: page, Limit: limit}, nil
+}
+
+// resolveLyrics returns a thunk, the lyrics of all songs in the response are
+// loaded together by the request's lyricsLoader.
+func (h *Handler) resolveLyrics(p graphql.ResolveParams) (interface{}, error) {
+	song := p.Source.(*model.Song)
+
+	from, hasFrom := p.Args["from"].(int)
+	to, hasTo := p.Args["to"].(int)
+	if hasFrom && from <= 0 || hasTo && to <= 0 || hasFrom && hasTo && from > to {
+		return nil, badUserInput("invalid verse range")
+	}
+
+	thunk := lyricsLoaderFrom(p.Context).load(p.Context, song.ID)
+
+	return func() (interface{}, error) {
+		verses, err := thunk()
+		if err != nil {
+			return nil, h.error("lyrics", err)
+		}
+
+		inRange := make([]*model.Verse, 0, len(verses))
+		for _, verse := range verses {
+			if hasFrom && verse.Number < from || hasTo && verse.Number > to {
+				continue
+			}
+			inRange = append(inRange, verse)
+		}
+
+		return inRange, nil
+	}, nil
+}
+
+func (h *Handler) resolveVersesCount(p graphql.ResolveParams) (interface{}, error) {
+	song := p.Source.(*model.Song)
+
+	thunk := lyricsLoaderFrom(p.Context).load(p.Context, song.ID)
+
+	return func() (interface{}, error) {
+		verses, err := thunk()
+		if err != nil {
+			return nil, h.error("versesCount", err)
+		}
+
+		return len(verses), nil
+	}, nil
+}
+
+func (h *Handler) resolveAddSong(p graphql.ResolveParams) (interface{}, error) {
+	req := dto.AddSongReq{
+		Group: p.Args["group"].(string),
+		Song:  p.Args["song"].(string),
+	}
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, h.error("addSong", err)
+	}
+
+	song, err := h.service.AddSong(p.Context, &req)
+	if err != nil {
+		return nil, h.error("addSong", err)
+	}
+
+	h.log.Infof("addSong resolver: added song %+v", song)
+	return song, nil
+}
+
+func (h *Handler) resolveUpdateSong(p graphql.ResolveParams) (interface{}, error) {
+	songID := p.Args["id"].(int)
+
+	var req dto.UpdateSongReq
+
+	if song, ok := p.Args["song"].(string); ok {
+		req.Song = &song
+	}
+	if group, ok := p.Args["group"].(string); ok {
+		req.Group = &group
+	}
+	if releaseDate, ok := p.Args["releaseDate"].(string); ok {
+		req.ReleaseDate = &releaseDate
+	}
+	if link, ok := p.Args["link"].(string); ok {
+		req.Link = &link
+	}
+	if version, ok := p.Args["version"].(int); ok {
+		req.Version = &version
+	}
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, h.error("updateSong", err)
+	}
+
+	_, err = h.service.GetSong(p.Context, songID)
+	if err != nil {
+		return nil, h.error("updateSong", err)
+	}
+
+	err = h.service.UpdateSong(p.Context, songID, &req)
+	if err != nil {
+		return nil, h.error("updateSong", err)
+	}
+
+	song, err := h.service.GetSong(p.Context, songID)
+	if err != nil {
+		return nil, h.error("updateSong", err)
+	}
+
+	h.log.Infof("updateSong resolver: updated song %+v", song)
+	return song, nil
+}
+
+func (h *Handler) resolveDeleteSong(p graphql.ResolveParams) (interface{}, error) {
+	songID := p.Args["id"].(int)
+
+	_, err := h.service.GetSong(p.Context, songID)
+	if err != nil {
+		return nil, h.error("deleteSong", err)
+	}
+
+	err = h.service.DeleteSong(p.Context, songID)
+	if err != nil {
+		return nil, h.error("deleteSong", err)
+	}
+
+	h.log.Infof("deleteSong resolver: deleted song id %d", songID)
+	return true, nil
+}
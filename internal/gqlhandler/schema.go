@@ -0,0 +1,180 @@
+package gqlhandler
+
+import (
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/graphql-go/graphql"
+)
+
+// songPage is a page of songs together with the number of matching songs.
+type songPage struct {
+	Items []*model.Song
+	Total int
+	Page  int
+	Limit int
+}
+
+// artist is identified by name, its songs are resolved on demand.
+type artist struct {
+	Name string
+}
+
+func (h *Handler) newSchema() (graphql.Schema, error) {
+	verseType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Verse",
+		Fields: graphql.Fields{
+			"number": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: verseField(func(v *model.Verse) interface{} { return v.Number })},
+			"lyrics": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: verseField(func(v *model.Verse) interface{} { return v.Lyrics })},
+		},
+	})
+
+	songType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Song",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: songField(func(s *model.Song) interface{} { return s.ID })},
+			"song":        &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: songField(func(s *model.Song) interface{} { return s.Song })},
+			"group":       &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: songField(func(s *model.Song) interface{} { return s.Group })},
+			"releaseDate": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: songField(func(s *model.Song) interface{} { return s.ReleaseDate })},
+			"link":        &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: songField(func(s *model.Song) interface{} { return s.Link })},
+			"version":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: songField(func(s *model.Song) interface{} { return s.Version })},
+			"updatedAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: songField(func(s *model.Song) interface{} { return s.UpdatedAt })},
+			"versesCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: h.resolveVersesCount},
+			"lyrics": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(verseType))),
+				Description: "Verses of the song, from and to are inclusive verse numbers.",
+				Args: graphql.FieldConfigArgument{
+					"from": &graphql.ArgumentConfig{Type: graphql.Int},
+					"to":   &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: h.resolveLyrics,
+			},
+		},
+	})
+
+	songPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SongPage",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(songType))), Resolve: pageField(func(p *songPage) interface{} { return p.Items })},
+			"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: pageField(func(p *songPage) interface{} { return p.Total })},
+			"page":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: pageField(func(p *songPage) interface{} { return p.Page })},
+			"limit": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: pageField(func(p *songPage) interface{} { return p.Limit })},
+		},
+	})
+
+	pageArgs := graphql.FieldConfigArgument{
+		"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10},
+		"page":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+	}
+
+	artistType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Artist",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*artist).Name, nil
+				},
+			},
+			"songs": &graphql.Field{
+				Type:    graphql.NewNonNull(songPageType),
+				Args:    pageArgs,
+				Resolve: h.resolveArtistSongs,
+			},
+		},
+	})
+
+	songType.AddFieldConfig("artist", &graphql.Field{
+		Type: graphql.NewNonNull(artistType),
+		Resolve: songField(func(s *model.Song) interface{} {
+			return &artist{Name: s.Group}
+		}),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"song": &graphql.Field{
+				Type: songType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: h.resolveSong,
+			},
+			"songs": &graphql.Field{
+				Type: graphql.NewNonNull(songPageType),
+				Args: graphql.FieldConfigArgument{
+					"song":        &graphql.ArgumentConfig{Type: graphql.String},
+					"group":       &graphql.ArgumentConfig{Type: graphql.String},
+					"releaseDate": &graphql.ArgumentConfig{Type: graphql.String},
+					"limit":       pageArgs["limit"],
+					"page":        pageArgs["page"],
+				},
+				Resolve: h.resolveSongs,
+			},
+			"artist": &graphql.Field{
+				Type:        artistType,
+				Description: "Artist with at least one song in the library.",
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: h.resolveArtist,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"addSong": &graphql.Field{
+				Type: graphql.NewNonNull(songType),
+				Args: graphql.FieldConfigArgument{
+					"group": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"song":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: h.resolveAddSong,
+			},
+			"updateSong": &graphql.Field{
+				Type:        graphql.NewNonNull(songType),
+				Description: "Updates the given fields, version makes the update conditional.",
+				Args: graphql.FieldConfigArgument{
+					"id":          &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"song":        &graphql.ArgumentConfig{Type: graphql.String},
+					"group":       &graphql.ArgumentConfig{Type: graphql.String},
+					"releaseDate": &graphql.ArgumentConfig{Type: graphql.String},
+					"link":        &graphql.ArgumentConfig{Type: graphql.String},
+					"version":     &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: h.resolveUpdateSong,
+			},
+			"deleteSong": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: h.resolveDeleteSong,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+func songField(get func(*model.Song) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*model.Song)), nil
+	}
+}
+
+func verseField(get func(*model.Verse) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*model.Verse)), nil
+	}
+}
+
+func pageField(get func(*songPage) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*songPage)), nil
+	}
+}
@@ -0,0 +1,236 @@
+package grpchandler
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/internal/validation"
+	"github.com/aaanger/music-library/pkg/pb/musicv1"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// lyricsBatchSize is the number of verses read from the service per query while streaming.
+const lyricsBatchSize = 50
+
+type MusicServer struct {
+	musicv1.UnimplementedMusicServiceServer
+	service service.IMusicService
+	log     *logrus.Logger
+}
+
+func NewMusicServer(service service.IMusicService, log *logrus.Logger) *MusicServer {
+	return &MusicServer{
+		service: service,
+		log:     log,
+	}
+}
+
+// NewGRPCServer returns a gRPC server with the music service and reflection registered.
+func (s *MusicServer) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	srv := grpc.NewServer(opts...)
+	musicv1.RegisterMusicServiceServer(srv, s)
+	reflection.Register(srv)
+	return srv
+}
+
+func (s *MusicServer) AddSong(ctx context.Context, req *musicv1.AddSongRequest) (*musicv1.AddSongResponse, error) {
+	addReq := dto.AddSongReq{
+		Group: req.GetGroup(),
+		Song:  req.GetSong(),
+	}
+
+	err := validation.Struct(addReq)
+	if err != nil {
+		return nil, s.error("AddSong", err)
+	}
+
+	song, err := s.service.AddSong(ctx, &addReq)
+	if err != nil {
+		return nil, s.error("AddSong", err)
+	}
+
+	return &musicv1.AddSongResponse{Song: toSong(song)}, nil
+}
+
+func (s *MusicServer) GetSong(ctx context.Context, req *musicv1.GetSongRequest) (*musicv1.GetSongResponse, error) {
+	song, err := s.service.GetSongWithLyrics(ctx, int(req.GetId()), req.GetIncludeLyrics())
+	if err != nil {
+		return nil, s.error("GetSong", err)
+	}
+
+	res := &musicv1.GetSongResponse{
+		Song:        toSong(&song.Song),
+		VersesCount: int32(song.VersesCount),
+	}
+	for _, verse := range song.Lyrics {
+		res.Lyrics = append(res.Lyrics, toVerse(verse))
+	}
+
+	return res, nil
+}
+
+func (s *MusicServer) ListSongs(ctx context.Context, req *musicv1.ListSongsRequest) (*musicv1.ListSongsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = 10
+	}
+	page := int(req.GetPage())
+	if page == 0 {
+		page = 1
+	}
+	if limit < 0 || page < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid limit or page")
+	}
+
+	songs, total, err := s.service.ListSongs(ctx, &dto.GetSongsListReq{
+		Song:        req.Song,
+		Group:       req.Group,
+		ReleaseDate: req.ReleaseDate,
+		Limit:       limit,
+		Offset:      (page - 1) * limit,
+	})
+	if err != nil {
+		return nil, s.error("ListSongs", err)
+	}
+
+	res := &musicv1.ListSongsResponse{Total: int32(total)}
+	for _, song := range songs {
+		res.Songs = append(res.Songs, toSong(song))
+	}
+
+	return res, nil
+}
+
+func (s *MusicServer) StreamLyrics(req *musicv1.StreamLyricsRequest, stream grpc.ServerStreamingServer[musicv1.StreamLyricsResponse]) error {
+	ctx := stream.Context()
+	offset := int(req.GetOffset())
+	if offset < 0 {
+		return status.Error(codes.InvalidArgument, "invalid offset")
+	}
+
+	for {
+		verses, total, err := s.service.ListVerses(ctx, int(req.GetSongId()), "", lyricsBatchSize, offset)
+		if err != nil {
+			return s.error("StreamLyrics", err)
+		}
+
+		for _, verse := range verses {
+			err = stream.Send(&musicv1.StreamLyricsResponse{Verse: toVerse(verse)})
+			if err != nil {
+				return err
+			}
+		}
+
+		offset += len(verses)
+		if len(verses) < lyricsBatchSize || offset >= total {
+			return nil
+		}
+	}
+}
+
+func (s *MusicServer) UpdateSong(ctx context.Context, req *musicv1.UpdateSongRequest) (*musicv1.UpdateSongResponse, error) {
+	songID := int(req.GetId())
+
+	updateReq := dto.UpdateSongReq{
+		Song:        req.Song,
+		Group:       req.Group,
+		ReleaseDate: req.ReleaseDate,
+		Link:        req.Link,
+	}
+	if req.Version != nil {
+		version := int(req.GetVersion())
+		updateReq.Version = &version
+	}
+
+	err := validation.Struct(updateReq)
+	if err != nil {
+		return nil, s.error("UpdateSong", err)
+	}
+
+	_, err = s.service.GetSong(ctx, songID)
+	if err != nil {
+		return nil, s.error("UpdateSong", err)
+	}
+
+	err = s.service.UpdateSong(ctx, songID, &updateReq)
+	if err != nil {
+		return nil, s.error("UpdateSong", err)
+	}
+
+	song, err := s.service.GetSong(ctx, songID)
+	if err != nil {
+		return nil, s.error("UpdateSong", err)
+	}
+
+	return &musicv1.UpdateSongResponse{Song: toSong(song)}, nil
+}
+
+func (s *MusicServer) DeleteSong(ctx context.Context, req *musicv1.DeleteSongRequest) (*musicv1.DeleteSongResponse, error) {
+	songID := int(req.GetId())
+
+	_, err := s.service.GetSong(ctx, songID)
+	if err != nil {
+		return nil, s.error("DeleteSong", err)
+	}
+
+	err = s.service.DeleteSong(ctx, songID)
+	if err != nil {
+		return nil, s.error("DeleteSong", err)
+	}
+
+	return &musicv1.DeleteSongResponse{}, nil
+}
+
+// error maps service and repository errors to gRPC status codes. Unknown
+// errors are logged and reported as a generic Internal error, their text
+// may describe the database or the upstream.
+func (s *MusicServer) error(method string, err error) error {
+	if fields, ok := validation.FieldErrors(err); ok {
+		s.log.Debugf("%s gRPC validation failed: %+v", method, fields)
+		return status.Errorf(codes.InvalidArgument, "validation failed: %s", err)
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound), errors.Is(err, metadata.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, repository.ErrDuplicateSong):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	s.log.Errorf("%s gRPC failure: %s", method, err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func toSong(song *model.Song) *musicv1.Song {
+	return &musicv1.Song{
+		Id:          int64(song.ID),
+		Song:        song.Song,
+		Group:       song.Group,
+		ReleaseDate: song.ReleaseDate,
+		Link:        song.Link,
+		Version:     int64(song.Version),
+		UpdatedAt:   timestamppb.New(song.UpdatedAt),
+	}
+}
+
+func toVerse(verse *model.Verse) *musicv1.Verse {
+	return &musicv1.Verse{
+		Number: int32(verse.Number),
+		Lyrics: verse.Lyrics,
+	}
+}
@@ -0,0 +1,279 @@
+package grpchandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/internal/validation"
+	"github.com/aaanger/music-library/pkg/pb/musicv1"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// newTestClient serves a MusicServer backed by a memory repository over an
+// in-memory connection. The metadata provider knows Muse - Uprising.
+func newTestClient(t *testing.T) (musicv1.MusicServiceClient, *repository.MemoryMusicRepository) {
+	t.Helper()
+
+	repo := repository.NewMemoryMusicRepository(testLogger())
+	registry := metadata.NewRegistry(testLogger())
+	registry.Register(metadata.NewFSProvider(fstest.MapFS{
+		"songs.json": {Data: []byte(`[{"group": "Muse", "song": "Uprising", "releaseDate": "07.09.2009", "text": "First verse\n\nSecond verse"}]`)},
+	}), 0)
+
+	srv := NewMusicServer(service.NewMusicService(repo, registry, testLogger()), testLogger()).NewGRPCServer()
+	listener := bufconn.Listen(1 << 20)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return musicv1.NewMusicServiceClient(conn), repo
+}
+
+func ingestSong(t *testing.T, repo repository.IMusicRepository, group, song, lyrics string) *model.Song {
+	t.Helper()
+
+	created, err := repo.IngestSong(context.Background(), &model.Song{Group: group, Song: song, ReleaseDate: "16.07.2006"}, lyrics, nil)
+	if err != nil {
+		t.Fatalf("IngestSong: %s", err)
+	}
+	return created
+}
+
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+
+	if got := status.Code(err); got != want {
+		t.Fatalf("code = %s, want %s: %v", got, want, err)
+	}
+}
+
+func TestMusicServerError(t *testing.T) {
+	s := NewMusicServer(nil, testLogger())
+
+	tests := []struct {
+		name    string
+		err     error
+		want    codes.Code
+		message string
+	}{
+		{name: "validation", err: validation.Struct(dto.AddSongReq{}), want: codes.InvalidArgument},
+		{name: "song not found", err: repository.ErrSongNotFound, want: codes.NotFound},
+		{name: "upstream not found", err: fmt.Errorf("fetch: %w", metadata.ErrNotFound), want: codes.NotFound},
+		{name: "version mismatch", err: repository.ErrVersionMismatch, want: codes.FailedPrecondition},
+		{name: "duplicate", err: repository.ErrDuplicateSong, want: codes.AlreadyExists},
+		{name: "duplicate with the existing song", err: &service.DuplicateSongError{Song: &model.Song{ID: 1}}, want: codes.AlreadyExists},
+		{name: "canceled", err: context.Canceled, want: codes.Canceled},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: codes.DeadlineExceeded},
+		{name: "unknown", err: errors.New("pq: connection refused"), want: codes.Internal, message: "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.error("Test", tt.err)
+			assertCode(t, err, tt.want)
+			if tt.message != "" && status.Convert(err).Message() != tt.message {
+				t.Fatalf("message = %q, want %q", status.Convert(err).Message(), tt.message)
+			}
+		})
+	}
+}
+
+func TestMusicServer(t *testing.T) {
+	client, repo := newTestClient(t)
+	ctx := context.Background()
+	song := ingestSong(t, repo, "Muse", "Hysteria", "It's bugging me\n\nGrating me")
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{name: "AddSong", want: codes.OK, call: func() error {
+			res, err := client.AddSong(ctx, &musicv1.AddSongRequest{Group: "Muse", Song: "Uprising"})
+			if err == nil && (res.Song.ReleaseDate != "07.09.2009" || res.Song.Version == 0) {
+				return fmt.Errorf("AddSong = %+v, want the fetched song", res.Song)
+			}
+			return err
+		}},
+		{name: "AddSong duplicate", want: codes.AlreadyExists, call: func() error {
+			_, err := client.AddSong(ctx, &musicv1.AddSongRequest{Group: "muse", Song: "hysteria"})
+			return err
+		}},
+		{name: "AddSong unknown upstream", want: codes.NotFound, call: func() error {
+			_, err := client.AddSong(ctx, &musicv1.AddSongRequest{Group: "Muse", Song: "Unknown"})
+			return err
+		}},
+		{name: "AddSong invalid", want: codes.InvalidArgument, call: func() error {
+			_, err := client.AddSong(ctx, &musicv1.AddSongRequest{Song: "Uprising"})
+			return err
+		}},
+		{name: "GetSong with lyrics", want: codes.OK, call: func() error {
+			res, err := client.GetSong(ctx, &musicv1.GetSongRequest{Id: int64(song.ID), IncludeLyrics: true})
+			if err == nil && (res.VersesCount != 2 || len(res.Lyrics) != 2 || res.Lyrics[1].Lyrics != "Grating me") {
+				return fmt.Errorf("GetSong = %+v, want 2 verses", res)
+			}
+			return err
+		}},
+		{name: "GetSong without lyrics", want: codes.OK, call: func() error {
+			res, err := client.GetSong(ctx, &musicv1.GetSongRequest{Id: int64(song.ID)})
+			if err == nil && (res.VersesCount != 2 || len(res.Lyrics) != 0) {
+				return fmt.Errorf("GetSong = %+v, want the count only", res)
+			}
+			return err
+		}},
+		{name: "GetSong missing", want: codes.NotFound, call: func() error {
+			_, err := client.GetSong(ctx, &musicv1.GetSongRequest{Id: 999})
+			return err
+		}},
+		{name: "ListSongs filtered", want: codes.OK, call: func() error {
+			res, err := client.ListSongs(ctx, &musicv1.ListSongsRequest{Song: proto.String("HYSTERIA")})
+			if err == nil && (res.Total != 1 || len(res.Songs) != 1 || res.Songs[0].Id != int64(song.ID)) {
+				return fmt.Errorf("ListSongs = %+v, want song %d", res, song.ID)
+			}
+			return err
+		}},
+		{name: "ListSongs page", want: codes.OK, call: func() error {
+			res, err := client.ListSongs(ctx, &musicv1.ListSongsRequest{Limit: 1, Page: 3})
+			if err == nil && (res.Total != 2 || len(res.Songs) != 0) {
+				return fmt.Errorf("ListSongs = %+v, want an empty page of 2 songs", res)
+			}
+			return err
+		}},
+		{name: "ListSongs invalid limit", want: codes.InvalidArgument, call: func() error {
+			_, err := client.ListSongs(ctx, &musicv1.ListSongsRequest{Limit: -1})
+			return err
+		}},
+		{name: "UpdateSong stale version", want: codes.FailedPrecondition, call: func() error {
+			_, err := client.UpdateSong(ctx, &musicv1.UpdateSongRequest{Id: int64(song.ID), ReleaseDate: proto.String("01.01.2010"), Version: proto.Int64(int64(song.Version) - 1)})
+			return err
+		}},
+		{name: "UpdateSong invalid", want: codes.InvalidArgument, call: func() error {
+			_, err := client.UpdateSong(ctx, &musicv1.UpdateSongRequest{Id: int64(song.ID), ReleaseDate: proto.String("yesterday")})
+			return err
+		}},
+		{name: "UpdateSong missing", want: codes.NotFound, call: func() error {
+			_, err := client.UpdateSong(ctx, &musicv1.UpdateSongRequest{Id: 999, ReleaseDate: proto.String("01.01.2010")})
+			return err
+		}},
+		{name: "UpdateSong", want: codes.OK, call: func() error {
+			res, err := client.UpdateSong(ctx, &musicv1.UpdateSongRequest{Id: int64(song.ID), ReleaseDate: proto.String("01.01.2010"), Version: proto.Int64(int64(song.Version))})
+			if err == nil && (res.Song.ReleaseDate != "01.01.2010" || res.Song.Version <= int64(song.Version)) {
+				return fmt.Errorf("UpdateSong = %+v, want the new release date and version", res.Song)
+			}
+			return err
+		}},
+		{name: "DeleteSong missing", want: codes.NotFound, call: func() error {
+			_, err := client.DeleteSong(ctx, &musicv1.DeleteSongRequest{Id: 999})
+			return err
+		}},
+		{name: "DeleteSong", want: codes.OK, call: func() error {
+			_, err := client.DeleteSong(ctx, &musicv1.DeleteSongRequest{Id: int64(song.ID)})
+			if err != nil {
+				return err
+			}
+			_, err = client.GetSong(ctx, &musicv1.GetSongRequest{Id: int64(song.ID)})
+			if status.Code(err) != codes.NotFound {
+				return fmt.Errorf("GetSong after DeleteSong: %v, want NotFound", err)
+			}
+			return nil
+		}},
+	}
+
+	// The cases run in order, later ones see the changes of earlier ones.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if _, ok := status.FromError(err); !ok {
+				t.Fatal(err)
+			}
+			assertCode(t, err, tt.want)
+		})
+	}
+}
+
+func TestStreamLyrics(t *testing.T) {
+	client, repo := newTestClient(t)
+
+	verses := make([]string, lyricsBatchSize*2+5)
+	for i := range verses {
+		verses[i] = fmt.Sprintf("Verse %d", i+1)
+	}
+	song := ingestSong(t, repo, "Muse", "Knights of Cydonia", strings.Join(verses, "\n\n"))
+
+	tests := []struct {
+		name   string
+		songID int
+		offset int32
+		want   int
+		code   codes.Code
+	}{
+		{name: "all verses in batches", songID: song.ID, want: len(verses)},
+		{name: "from an offset", songID: song.ID, offset: lyricsBatchSize + 3, want: len(verses) - lyricsBatchSize - 3},
+		{name: "offset past the end", songID: song.ID, offset: int32(len(verses)), want: 0},
+		{name: "negative offset", songID: song.ID, offset: -1, code: codes.InvalidArgument},
+		{name: "missing song", songID: 999, code: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.StreamLyrics(context.Background(), &musicv1.StreamLyricsRequest{SongId: int64(tt.songID), Offset: tt.offset})
+			if err != nil {
+				t.Fatalf("StreamLyrics: %s", err)
+			}
+
+			var got []*musicv1.Verse
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					assertCode(t, err, tt.code)
+					return
+				}
+				got = append(got, res.Verse)
+			}
+
+			if tt.code != codes.OK {
+				t.Fatalf("stream ended without an error, want %s", tt.code)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("got %d verses, want %d", len(got), tt.want)
+			}
+			for i, verse := range got {
+				if number := int(tt.offset) + i + 1; int(verse.Number) != number || verse.Lyrics != fmt.Sprintf("Verse %d", number) {
+					t.Fatalf("verse %d = %+v, want number %d", i, verse, number)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"github.com/aaanger/music-library/internal/validation"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		err := validation.Register(v)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// bindJSON decodes and validates the request body. Malformed JSON is answered
+// with 400, values breaking the binding rules with 422 and a list of field errors.
+func bindJSON(c *gin.Context, log *logrus.Logger, obj any) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	if fields, ok := validation.FieldErrors(err); ok {
+		log.Debugf("Validation failed: %+v", fields)
+		response.ValidationError(c, fields)
+		return false
+	}
+
+	log.Debugf("Invalid input parameters: %s", err)
+	response.Error(c, http.StatusBadRequest, "invalid input parameters")
+	return false
+}
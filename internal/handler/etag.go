@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/aaanger/music-library/pkg/translit"
+	"github.com/gin-gonic/gin"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// songETag is the ETag of the song itself, If-Match headers are checked
+// against its version.
+func songETag(song *model.Song) string {
+	return fmt.Sprintf(`"%d-%d"`, song.ID, song.Version)
+}
+
+// songVariantETag is the ETag of a representation of the song which depends
+// on the request, e.g. a page of its lyrics, so caches don't answer one
+// variant with another. It still starts with the id and version of the song.
+func songVariantETag(song *model.Song, variant string) string {
+	if variant == "" {
+		return songETag(song)
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(variant))
+	return fmt.Sprintf(`"%d-%d-%x"`, song.ID, song.Version, h.Sum32())
+}
+
+// songsListETag changes whenever a song on the page is added, removed or
+// updated. variant names the rest of the representation, e.g. the page of
+// songsPageVariant.
+func songsListETag(songs []*model.Song, variant string) string {
+	h := fnv.New64a()
+	for _, song := range songs {
+		fmt.Fprintf(h, "%d-%d;", song.ID, song.Version)
+	}
+	if variant != "" {
+		h.Write([]byte(variant))
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum64())
+}
+
+// songsPageVariant names a page of the v2 list: its envelope carries the
+// page, limit and total, and the filters are normalized like the repository
+// does, so equivalent queries share the ETag.
+func songsPageVariant(req *dto.GetSongsListReq, page, limit, total int) string {
+	values := url.Values{}
+	values.Set("page", strconv.Itoa(page))
+	values.Set("limit", strconv.Itoa(limit))
+	values.Set("total", strconv.Itoa(total))
+
+	if req.Song != nil {
+		values.Set("song", translit.Fold(*req.Song))
+	}
+	if req.Group != nil {
+		values.Set("group", translit.Fold(*req.Group))
+	}
+	if req.ReleaseDate != nil {
+		values.Set("release_date", *req.ReleaseDate)
+	}
+
+	seen := make(map[string]bool, len(req.Tags))
+	for _, tag := range req.Tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		values["tag"] = append(values["tag"], tag)
+	}
+	if tags := values["tag"]; len(tags) > 0 {
+		sort.Strings(tags)
+		values.Set("tag_mode", req.TagMode)
+	}
+
+	return values.Encode()
+}
+
+func lastModified(songs ...*model.Song) time.Time {
+	var latest time.Time
+	for _, song := range songs {
+		if song.UpdatedAt.After(latest) {
+			latest = song.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// setValidators writes the ETag and Last-Modified headers and reports whether
+// the client's If-None-Match already matches, in which case 304 is sent.
+func setValidators(c *gin.Context, etag string, modified time.Time) bool {
+	c.Header("ETag", etag)
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if matchETag(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersions parses the song versions out of an If-Match header, a
+// comma separated list of ETags produced by songETag or songVariantETag.
+// wildcard is set for "*" or a missing header. ETags of other songs are
+// skipped, ok is false if none is left and the header can't match the song.
+func ifMatchVersions(header string, songID int) (versions []int, wildcard, ok bool) {
+	if strings.TrimSpace(header) == "" {
+		return nil, true, true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return nil, true, true
+		}
+
+		id, v, found := strings.Cut(strings.Trim(candidate, `"`), "-")
+		if !found || id != strconv.Itoa(songID) {
+			continue
+		}
+		// ETags of variants append a hash of the representation.
+		v, _, _ = strings.Cut(v, "-")
+
+		if version, err := strconv.Atoi(v); err == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, false, len(versions) > 0
+}
+
+// ifMatchVersion returns the version the If-Match header binds a change of
+// the song to, nil if any version matches, writing a 412 response if none
+// can. If several versions are listed, the current one is picked if present.
+func (h *MusicHandler) ifMatchVersion(c *gin.Context, songID int) (*int, bool) {
+	header := c.GetHeader("If-Match")
+	versions, wildcard, ok := ifMatchVersions(header, songID)
+	if !ok {
+		h.log.Debugf("If-Match %s doesn't match song id %d", header, songID)
+		response.Error(c, http.StatusPreconditionFailed, "song was modified")
+		return nil, false
+	}
+	if wildcard {
+		return nil, true
+	}
+
+	version := versions[0]
+	if len(versions) > 1 {
+		song, err := h.service.GetSong(c, songID)
+		if err != nil && !errors.Is(err, repository.ErrSongNotFound) {
+			h.log.Errorf("If-Match failure: %s", err)
+			response.Error(c, http.StatusInternalServerError, "failed to get song")
+			return nil, false
+		}
+		for _, v := range versions {
+			if song != nil && v == song.Version {
+				version = v
+			}
+		}
+	}
+
+	return &version, true
+}
+
+// includeVariant names the representation of a song with the include query.
+func includeVariant(withLyrics bool) string {
+	if withLyrics {
+		return "include=lyrics"
+	}
+	return ""
+}
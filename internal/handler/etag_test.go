@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// newTestRouter returns the routes of a handler backed by a memory repository.
+func newTestRouter(t *testing.T) (*gin.Engine, *repository.MemoryMusicRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewMemoryMusicRepository(testLogger())
+	svc := service.NewMusicService(repo, metadata.NewRegistry(testLogger()), testLogger())
+
+	return NewMusicHandler(svc, testLogger()).InitRoutes(), repo
+}
+
+func ingestTestSong(t *testing.T, repo repository.IMusicRepository, group, song, lyrics string) *model.Song {
+	t.Helper()
+
+	created, err := repo.IngestSong(context.Background(), &model.Song{Group: group, Song: song, ReleaseDate: "16.07.2006"}, lyrics, nil)
+	if err != nil {
+		t.Fatalf("IngestSong: %s", err)
+	}
+	return created
+}
+
+func serve(r *gin.Engine, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestSongsPageVariant(t *testing.T) {
+	str := func(s string) *string { return &s }
+	base := func() *dto.GetSongsListReq {
+		return &dto.GetSongsListReq{Group: str("Кино"), Tags: []string{"rock", "Post Punk"}, TagMode: dto.TagModeAny}
+	}
+
+	tests := []struct {
+		name  string
+		req   *dto.GetSongsListReq
+		page  int
+		limit int
+		total int
+		same  bool
+	}{
+		{name: "same query", req: base(), page: 1, limit: 10, total: 3, same: true},
+		{name: "folded group", req: &dto.GetSongsListReq{Group: str("  KINO "), Tags: []string{"rock", "Post Punk"}, TagMode: dto.TagModeAny}, page: 1, limit: 10, total: 3, same: true},
+		{name: "tags reordered and repeated", req: &dto.GetSongsListReq{Group: str("Kino"), Tags: []string{"post   punk", "ROCK", "rock"}, TagMode: dto.TagModeAny}, page: 1, limit: 10, total: 3, same: true},
+		{name: "other page", req: base(), page: 2, limit: 10, total: 3},
+		{name: "other limit", req: base(), page: 1, limit: 5, total: 3},
+		{name: "other total", req: base(), page: 1, limit: 10, total: 4},
+		{name: "other group", req: &dto.GetSongsListReq{Group: str("Muse"), Tags: []string{"rock", "Post Punk"}, TagMode: dto.TagModeAny}, page: 1, limit: 10, total: 3},
+		{name: "empty group filter", req: &dto.GetSongsListReq{Group: str(""), Tags: []string{"rock", "Post Punk"}, TagMode: dto.TagModeAny}, page: 1, limit: 10, total: 3},
+		{name: "other tag mode", req: &dto.GetSongsListReq{Group: str("Кино"), Tags: []string{"rock", "Post Punk"}, TagMode: dto.TagModeAll}, page: 1, limit: 10, total: 3},
+		{name: "release date", req: &dto.GetSongsListReq{Group: str("Кино"), ReleaseDate: str("1988"), Tags: []string{"rock", "Post Punk"}, TagMode: dto.TagModeAny}, page: 1, limit: 10, total: 3},
+	}
+
+	want := songsPageVariant(base(), 1, 10, 3)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := songsPageVariant(tt.req, tt.page, tt.limit, tt.total)
+			if (got == want) != tt.same {
+				t.Fatalf("variant = %q, base variant = %q, want same = %v", got, want, tt.same)
+			}
+		})
+	}
+}
+
+func TestListSongsV2ETag(t *testing.T) {
+	r, repo := newTestRouter(t)
+	ingestTestSong(t, repo, "Muse", "Supermassive Black Hole", "Ooh baby")
+	ingestTestSong(t, repo, "Muse", "Uprising", "Paranoia is in bloom")
+
+	first := serve(r, http.MethodGet, "/api/v2/songs?limit=1", "", nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", first.Code, http.StatusOK)
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag on the list")
+	}
+
+	if w := serve(r, http.MethodGet, "/api/v2/songs?limit=1", "", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Fatalf("unchanged list: status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+	if w := serve(r, http.MethodGet, "/api/v2/songs?limit=2", "", map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+		t.Fatalf("other limit: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := serve(r, http.MethodGet, "/api/v2/songs?limit=1&group=muse", "", map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+		t.Fatalf("filtered list: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// A song added after the page changes the total in the envelope.
+	ingestTestSong(t, repo, "Muse", "Hysteria", "It's bugging me")
+
+	w := serve(r, http.MethodGet, "/api/v2/songs?limit=1", "", map[string]string{"If-None-Match": etag})
+	if w.Code != http.StatusOK {
+		t.Fatalf("list with a new song: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Fatalf("ETag %s didn't change with the total", etag)
+	}
+}
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		versions []int
+		wildcard bool
+		ok       bool
+	}{
+		{name: "missing", header: "", wildcard: true, ok: true},
+		{name: "wildcard", header: "*", wildcard: true, ok: true},
+		{name: "wildcard in list", header: `"7-1", *`, wildcard: true, ok: true},
+		{name: "song ETag", header: `"3-5"`, versions: []int{5}, ok: true},
+		{name: "variant ETag", header: `"3-5-1a2b3c4d"`, versions: []int{5}, ok: true},
+		{name: "list", header: `"3-4", "3-5-1a2b3c4d" ,"3-6"`, versions: []int{4, 5, 6}, ok: true},
+		{name: "other songs skipped", header: `"7-1","3-2","13-2"`, versions: []int{2}, ok: true},
+		{name: "other song", header: `"7-1"`},
+		{name: "weak ETag", header: `W/"3-5"`},
+		{name: "garbage", header: `"3-x", "abc", ,`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, wildcard, ok := ifMatchVersions(tt.header, 3)
+			if ok != tt.ok || wildcard != tt.wildcard || fmt.Sprint(versions) != fmt.Sprint(tt.versions) {
+				t.Fatalf("ifMatchVersions(%q) = %v, %v, %v, want %v, %v, %v",
+					tt.header, versions, wildcard, ok, tt.versions, tt.wildcard, tt.ok)
+			}
+		})
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	r, repo := newTestRouter(t)
+	song := ingestTestSong(t, repo, "Muse", "Uprising", "Paranoia is in bloom")
+	target := fmt.Sprintf("/api/v2/songs/%d", song.ID)
+
+	get := serve(r, http.MethodGet, target, "", nil)
+	if get.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d", get.Code, http.StatusOK)
+	}
+	etag := get.Header().Get("ETag")
+	if etag != songETag(song) {
+		t.Fatalf("ETag = %s, want %s", etag, songETag(song))
+	}
+
+	getTests := []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+	}{
+		{name: "no validator", want: http.StatusOK},
+		{name: "current ETag", ifNoneMatch: etag, want: http.StatusNotModified},
+		{name: "current ETag in list", ifNoneMatch: `"0-0", ` + etag, want: http.StatusNotModified},
+		{name: "weak current ETag", ifNoneMatch: "W/" + etag, want: http.StatusNotModified},
+		{name: "wildcard", ifNoneMatch: "*", want: http.StatusNotModified},
+		{name: "stale ETag", ifNoneMatch: fmt.Sprintf(`"%d-%d"`, song.ID, song.Version-1), want: http.StatusOK},
+	}
+
+	for _, tt := range getTests {
+		t.Run("GET "+tt.name, func(t *testing.T) {
+			header := map[string]string{}
+			if tt.ifNoneMatch != "" {
+				header["If-None-Match"] = tt.ifNoneMatch
+			}
+			if w := serve(r, http.MethodGet, target, "", header); w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	// Every successful PATCH bumps the version, the ETags are built from the
+	// song as it is before each request.
+	patchTests := []struct {
+		name    string
+		ifMatch func(current string, version int) string
+		want    int
+	}{
+		{name: "no validator", want: http.StatusOK},
+		{name: "current ETag", ifMatch: func(current string, _ int) string { return current }, want: http.StatusOK},
+		{name: "wildcard", ifMatch: func(string, int) string { return "*" }, want: http.StatusOK},
+		{name: "stale ETag", ifMatch: func(_ string, version int) string {
+			return fmt.Sprintf(`"%d-%d"`, song.ID, version-1)
+		}, want: http.StatusPreconditionFailed},
+		{name: "stale ETags in list", ifMatch: func(_ string, version int) string {
+			return fmt.Sprintf(`"%d-%d", "%d-%d"`, song.ID, version-2, song.ID, version-1)
+		}, want: http.StatusPreconditionFailed},
+		{name: "current ETag in list", ifMatch: func(current string, version int) string {
+			return fmt.Sprintf(`"%d-%d", %s, "%d-%d"`, song.ID, version-1, current, song.ID, version+1)
+		}, want: http.StatusOK},
+		{name: "ETag of another song", ifMatch: func(string, int) string { return `"999-1"` }, want: http.StatusPreconditionFailed},
+	}
+
+	for i, tt := range patchTests {
+		t.Run("PATCH "+tt.name, func(t *testing.T) {
+			current, err := repo.GetSong(context.Background(), song.ID)
+			if err != nil {
+				t.Fatalf("GetSong: %s", err)
+			}
+
+			header := map[string]string{}
+			if tt.ifMatch != nil {
+				header["If-Match"] = tt.ifMatch(songETag(current), current.Version)
+			}
+			body := fmt.Sprintf(`{"releaseDate": "%02d.09.2009"}`, i+1)
+
+			w := serve(r, http.MethodPatch, target, body, header)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if w.Code == http.StatusOK && w.Header().Get("ETag") == songETag(current) {
+				t.Fatalf("ETag %s didn't change with the update", w.Header().Get("ETag"))
+			}
+		})
+	}
+
+	missing := map[string]string{"If-Match": "*"}
+	if w := serve(r, http.MethodPut, "/api/v1/999", `{"releaseDate": "01.01.2000"}`, missing); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PUT with If-Match * on a missing song: status = %d, want %d", w.Code, http.StatusPreconditionFailed)
+	}
+}
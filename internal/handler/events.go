@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// eventsPollInterval is how often a stream checks the event log for new events.
+	eventsPollInterval = time.Second
+	// eventsHeartbeat is the interval of comments keeping idle streams open behind proxies.
+	eventsHeartbeat = 15 * time.Second
+	eventsBatchSize = 100
+)
+
+// StreamEvents godoc
+// @Summary Поток изменений библиотеки (Server-Sent Events)
+// @Description События song.created, song.updated, song.deleted и song.enriched (текст и детали песни загружены из источников метаданных).
+// @Description Поле id события можно передать в заголовке Last-Event-ID (или параметре last_event_id) при переподключении,
+// @Description тогда поток продолжится со следующего события. Без него передаются только новые события.
+// @Tags Events
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "ID последнего полученного события"
+// @Param last_event_id query int false "ID последнего полученного события, если заголовок нельзя передать"
+// @Success 200 {object} model.Event "Поток событий, data содержит событие в JSON"
+// @Failure 400 {string} string "Неверный ID события"
+// @Failure 500 {string} string "Ошибка чтения журнала событий"
+// @Router /api/v1/events [get]
+// @Router /api/v2/events [get]
+func (h *MusicHandler) StreamEvents(c *gin.Context) {
+	lastID, resume, ok := h.lastEventIDParam(c)
+	if !ok {
+		return
+	}
+
+	if !resume {
+		var err error
+		lastID, err = h.service.LastEventID(c)
+		if err != nil {
+			h.log.Errorf("StreamEvents failure: %s", err)
+			response.Error(c, http.StatusInternalServerError, "failed to get events")
+			return
+		}
+	}
+
+	// The stream outlives the write timeout of the server.
+	err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	if err != nil {
+		h.log.Debugf("StreamEvents handler: can't reset write deadline: %s", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	h.log.Infof("StreamEvents handler: streaming events after %d", lastID)
+
+	poll := time.NewTicker(eventsPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+
+	for {
+		events, err := h.service.GetEvents(ctx, lastID, eventsBatchSize)
+		if err != nil {
+			// The status is already sent, ending the stream makes the client
+			// reconnect with the id of the last event it got.
+			if ctx.Err() == nil {
+				h.log.Errorf("StreamEvents failure: %s", err)
+			}
+			return
+		}
+
+		for _, event := range events {
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatInt(event.ID, 10),
+				Event: event.Type,
+				Data:  event,
+			})
+			lastID = event.ID
+		}
+
+		if len(events) > 0 {
+			c.Writer.Flush()
+			heartbeat.Reset(eventsHeartbeat)
+		}
+		if len(events) == eventsBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.streams.Done():
+			return
+		case <-poll.C:
+		case <-heartbeat.C:
+			_, _ = io.WriteString(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// lastEventIDParam reads the id of the last event received by a reconnecting
+// client, resume is false for new clients.
+func (h *MusicHandler) lastEventIDParam(c *gin.Context) (lastID int64, resume bool, ok bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, false, true
+	}
+
+	lastID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || lastID < 0 {
+		h.log.Debugf("Invalid last event id: %s", value)
+		response.Error(c, http.StatusBadRequest, "invalid last event id")
+		return 0, false, false
+	}
+
+	return lastID, true, true
+}
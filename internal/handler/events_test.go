@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRecorder records the number of events of every GetEvents call.
+type batchRecorder struct {
+	service.IMusicService
+
+	mu      sync.Mutex
+	batches []int
+}
+
+func (s *batchRecorder) GetEvents(ctx context.Context, afterID int64, limit int) ([]*model.Event, error) {
+	events, err := s.IMusicService.GetEvents(ctx, afterID, limit)
+
+	s.mu.Lock()
+	s.batches = append(s.batches, len(events))
+	s.mu.Unlock()
+
+	return events, err
+}
+
+func (s *batchRecorder) reset() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batches := s.batches
+	s.batches = nil
+	return batches
+}
+
+type streamedEvent struct {
+	id    int64
+	event string
+}
+
+// eventStream is an open connection to the events endpoint.
+type eventStream struct {
+	body    io.Closer
+	scanner *bufio.Scanner
+	cancel  context.CancelFunc
+}
+
+func openEventStream(t *testing.T, url string, lastEventID string) *eventStream {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		t.Fatalf("new request: %s", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		t.Fatalf("GET %s: %s", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		t.Fatalf("GET %s: status = %d, want %d", url, resp.StatusCode, http.StatusOK)
+	}
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/event-stream") {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	stream := &eventStream{body: resp.Body, scanner: bufio.NewScanner(resp.Body), cancel: cancel}
+	t.Cleanup(stream.close)
+	return stream
+}
+
+// next reads n events from the stream, skipping heartbeat comments.
+func (s *eventStream) next(t *testing.T, n int) []streamedEvent {
+	t.Helper()
+
+	var events []streamedEvent
+	var event streamedEvent
+
+	for len(events) < n && s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			if event.event != "" {
+				events = append(events, event)
+			}
+			event = streamedEvent{}
+		case strings.HasPrefix(line, "id:"):
+			id, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "id:")), 10, 64)
+			if err != nil {
+				t.Fatalf("invalid event id line %q", line)
+			}
+			event.id = id
+		case strings.HasPrefix(line, "event:"):
+			event.event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		}
+	}
+
+	if len(events) < n {
+		t.Fatalf("stream ended after %d events, want %d: %v", len(events), n, s.scanner.Err())
+	}
+	return events
+}
+
+func (s *eventStream) close() {
+	s.cancel()
+	s.body.Close()
+}
+
+func TestStreamEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewMemoryMusicRepository(testLogger())
+	recorder := &batchRecorder{IMusicService: service.NewMusicService(repo, metadata.NewRegistry(testLogger()), testLogger())}
+	h := NewMusicHandler(recorder, testLogger())
+
+	server := httptest.NewServer(h.InitRoutes())
+	t.Cleanup(server.Close)
+	t.Cleanup(h.CloseStreams)
+	url := server.URL + "/api/v2/events"
+
+	// Every added song writes a single song.created event.
+	addSong := func(t *testing.T, title string) *model.Song {
+		song, err := repo.AddSong(context.Background(), &model.Song{Group: "Muse", Song: title, ReleaseDate: "16.07.2006"})
+		if err != nil {
+			t.Fatalf("AddSong: %s", err)
+		}
+		return song
+	}
+
+	songs := eventsBatchSize*2 + eventsBatchSize/2
+	for i := 0; i < songs; i++ {
+		addSong(t, fmt.Sprintf("Song %d", i+1))
+	}
+
+	t.Run("resume from the start", func(t *testing.T) {
+		recorder.reset()
+
+		stream := openEventStream(t, url, "0")
+		events := stream.next(t, songs)
+		stream.close()
+
+		for i, event := range events {
+			if event.id != int64(i+1) || event.event != model.EventSongCreated {
+				t.Fatalf("event %d = %+v, want id %d of type %s", i, event, i+1, model.EventSongCreated)
+			}
+		}
+
+		batches := recorder.reset()
+		want := []int{eventsBatchSize, eventsBatchSize, eventsBatchSize / 2}
+		if len(batches) < len(want) || fmt.Sprint(batches[:len(want)]) != fmt.Sprint(want) {
+			t.Fatalf("GetEvents batches = %v, want %v first", batches, want)
+		}
+	})
+
+	t.Run("new client gets new events only", func(t *testing.T) {
+		stream := openEventStream(t, url, "")
+		song := addSong(t, "Hysteria")
+
+		events := stream.next(t, 1)
+		if events[0].id != int64(songs+1) || events[0].event != model.EventSongCreated {
+			t.Fatalf("event = %+v, want the song.created event %d of song %d", events[0], songs+1, song.ID)
+		}
+	})
+
+	t.Run("reconnect with Last-Event-ID", func(t *testing.T) {
+		stream := openEventStream(t, url, strconv.Itoa(songs-1))
+		events := stream.next(t, 2)
+
+		if events[0].id != int64(songs) || events[1].id != int64(songs+1) {
+			t.Fatalf("events = %+v, want ids %d and %d", events, songs, songs+1)
+		}
+	})
+
+	t.Run("reconnect with last_event_id query", func(t *testing.T) {
+		stream := openEventStream(t, url+"?last_event_id="+strconv.Itoa(songs), "")
+		events := stream.next(t, 1)
+
+		if events[0].id != int64(songs+1) {
+			t.Fatalf("event = %+v, want id %d", events[0], songs+1)
+		}
+	})
+
+	for _, id := range []string{"abc", "-1", "1.5"} {
+		t.Run("invalid id "+id, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				t.Fatalf("new request: %s", err)
+			}
+			req.Header.Set("Last-Event-ID", id)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s: %s", url, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+			}
+		})
+	}
+}
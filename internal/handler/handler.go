@@ -1,28 +1,47 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	_ "github.com/aaanger/music-library/docs"
 	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
 	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/internal/validation"
 	"github.com/aaanger/music-library/pkg/response"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 type MusicHandler struct {
 	service service.IMusicService
-	log     *logrus.Logger
+	// streams is cancelled by CloseStreams to end the event streams.
+	streams      context.Context
+	closeStreams context.CancelFunc
+	log          *logrus.Logger
 }
 
 func NewMusicHandler(service service.IMusicService, log *logrus.Logger) *MusicHandler {
+	streams, closeStreams := context.WithCancel(context.Background())
+
 	return &MusicHandler{
-		service: service,
-		log:     log,
+		service:      service,
+		streams:      streams,
+		closeStreams: closeStreams,
+		log:          log,
 	}
 }
 
+// CloseStreams ends all open event streams, http.Server.Shutdown would wait for them forever.
+func (h *MusicHandler) CloseStreams() {
+	h.closeStreams()
+}
+
 // AddSong godoc
 // @Summary Добавление новой песни
 // @Tags Songs
@@ -30,21 +49,26 @@ func NewMusicHandler(service service.IMusicService, log *logrus.Logger) *MusicHa
 // @Param song body dto.AddSongReq true "Данные для добавления песни"
 // @Success 200 {object} model.Song "Данные песни"
 // @Failure 400 {string} string "Неверное тело запроса"
+// @Failure 409 {object} response.ConflictResponse{existing=model.Song} "Песня уже есть в библиотеке"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей"
 // @Failure 500 {string} string "Ошибка добавления песни на сервере"
 // @Router /api/v1/add [post]
 func (h *MusicHandler) AddSong(c *gin.Context) {
 	var req dto.AddSongReq
 
-	err := c.BindJSON(&req)
-	if err != nil {
-		h.log.Debugf("AddSong handler: Invalid input parameters: %s", err)
-		response.Error(c, http.StatusBadRequest, "invalid input parameters")
+	if !bindJSON(c, h.log, &req) {
 		return
 	}
 
 	h.log.Infof("AddSong handler request: song - %s, group - %s", req.Song, req.Group)
 
 	song, err := h.service.AddSong(c, &req)
+	var duplicate *service.DuplicateSongError
+	if errors.As(err, &duplicate) {
+		h.log.Debugf("AddSong handler: song already exists with id %d", duplicate.Song.ID)
+		response.Conflict(c, "song already exists", duplicate.Song)
+		return
+	}
 	if err != nil {
 		h.log.Errorf("AddSong failure: %s", err)
 		response.Error(c, http.StatusInternalServerError, err.Error())
@@ -59,26 +83,27 @@ func (h *MusicHandler) AddSong(c *gin.Context) {
 // @Summary Получение данных библиотеки с фильтрацией по всем полям и пагинацией
 // @Tags Songs
 // @Produce json
-// @Param song query string false "Фильтр по названию песни"
-// @Param group query string false "Фильтр по названию исполнителя"
+// @Param song query string false "Фильтр по названию песни без учета регистра, алфавита и диакритики"
+// @Param group query string false "Фильтр по названию исполнителя без учета регистра, алфавита и диакритики"
 // @Param release_date query int false "Фильтр по дате выпуска"
+// @Param tag query []string false "Фильтр по тегам и жанрам, параметр можно повторять" collectionFormat(multi)
+// @Param tag_mode query string false "any — песни хотя бы с одним из тегов, all — со всеми" Enums(any, all) default(any)
 // @Param limit query int false "Количество песен на странице" default(10)
 // @Param page query int false "Номер страницы" default(1)
+// @Param If-None-Match header string false "ETag ранее полученного списка"
 // @Success 200 {array} model.Song
+// @Success 304 "Список не изменился"
+// @Header 200 {string} ETag "Версия списка"
+// @Header 200 {string} Last-Modified "Время последнего изменения песен в списке"
 // @Failure 400 {string} string "Некорректный фильтр или параметры пагинации"
 // @Failure 500 {string} string "Ошибка получения данных"
 // @Router /api/v1/songs [get]
 func (h *MusicHandler) GetSongsList(c *gin.Context) {
 	var req dto.GetSongsListReq
 
-	song := c.Query("song")
-	req.Song = &song
-
-	group := c.Query("group")
-	req.Group = &group
-
-	releaseDate := c.Query("release_date")
-	req.ReleaseDate = &releaseDate
+	if !songsFilterParams(c, h.log, &req) {
+		return
+	}
 
 	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	if err != nil || limit <= 0 {
@@ -94,7 +119,7 @@ func (h *MusicHandler) GetSongsList(c *gin.Context) {
 		return
 	}
 
-	h.log.Debugf("GetSongsList handler request: song - %s, group - %s, releaseDate - %s, limit - %v, page - %v", song, group, releaseDate, limit, page)
+	h.log.Debugf("GetSongsList handler request: %+v, limit - %v, page - %v", req, limit, page)
 
 	req.Limit = limit
 	req.Offset = (page - 1) * limit
@@ -106,19 +131,91 @@ func (h *MusicHandler) GetSongsList(c *gin.Context) {
 		return
 	}
 
+	if setValidators(c, songsListETag(songs, ""), lastModified(songs...)) {
+		h.log.Debugf("GetSongsList handler: not modified")
+		return
+	}
+
 	h.log.Infof("GetSongsList handler successful response: %+v", songs)
 	response.JSON(c, songs)
 }
 
+// GetSong godoc
+// @Summary Получение песни по ID с количеством куплетов и, опционально, полным текстом
+// @Tags Songs
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Param include query string false "Дополнительные данные: lyrics" Enums(lyrics)
+// @Param If-None-Match header string false "ETag ранее полученной песни"
+// @Success 200 {object} model.SongWithLyrics
+// @Success 304 "Песня не изменилась"
+// @Header 200 {string} ETag "Версия песни"
+// @Header 200 {string} Last-Modified "Время последнего изменения песни"
+// @Failure 400 {string} string "Неверный ID песни или параметр include"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 500 {string} string "Ошибка получения песни"
+// @Router /api/v1/{songID} [get]
+func (h *MusicHandler) GetSong(c *gin.Context) {
+	songID, err := strconv.Atoi(c.Param("songID"))
+	if err != nil {
+		h.log.Debugf("GetSong handler: invalid song id: %s", err)
+		response.Error(c, http.StatusBadRequest, "invalid song id")
+		return
+	}
+
+	withLyrics := false
+	for _, include := range strings.Split(c.Query("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "":
+		case "lyrics":
+			withLyrics = true
+		default:
+			h.log.Debugf("GetSong handler: invalid include: %s", include)
+			response.Error(c, http.StatusBadRequest, "invalid include")
+			return
+		}
+	}
+
+	h.log.Debugf("GetSong handler request: songID - %v, withLyrics - %v", songID, withLyrics)
+
+	song, err := h.service.GetSongWithLyrics(c, songID, withLyrics)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		h.log.Debugf("GetSong handler: song id %d not found", songID)
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("GetSong failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get song")
+		return
+	}
+
+	if setValidators(c, songVariantETag(&song.Song, includeVariant(withLyrics)), song.UpdatedAt) {
+		h.log.Debugf("GetSong handler: not modified")
+		return
+	}
+
+	h.log.Infof("GetSong handler successful response: %+v", song)
+	response.JSON(c, song)
+}
+
 // GetSongLyrics godoc
 // @Summary Получение текста песни с пагинацией по куплетам
+// @Description Без перевода на язык lang возвращается оригинальный текст
 // @Tags Songs
 // @Produce json
 // @Param songID path int true "ID песни"
+// @Param lang query string false "Язык перевода, например en или pt-br"
 // @Param limit query int false "количество куплетов на странице" default(3)
 // @Param page query int false "номер страницы" default(1)
+// @Param If-None-Match header string false "ETag ранее полученного текста"
 // @Success 200 {array} model.Verse
-// @Failure 400 {string} string "Неверный ID песни или некорректные параметры пагинации"
+// @Success 304 "Текст не изменился"
+// @Header 200 {string} ETag "Версия песни"
+// @Header 200 {string} Last-Modified "Время последнего изменения песни"
+// @Header 200 {string} Content-Language "Язык куплетов, если он известен"
+// @Failure 400 {string} string "Неверный ID песни, язык или некорректные параметры пагинации"
+// @Failure 404 {string} string "Песня не найдена"
 // @Failure 500 {string} string "Ошибка получения текста песни"
 // @Router /api/v1/{songID}/lyrics [get]
 func (h *MusicHandler) GetSongLyrics(c *gin.Context) {
@@ -143,17 +240,41 @@ func (h *MusicHandler) GetSongLyrics(c *gin.Context) {
 		return
 	}
 
-	h.log.Debugf("GetSongsLyrics handler request: songID - %v, limit - %v, page - %v", songID, limit, page)
+	lang, ok := langQuery(c, h.log)
+	if !ok {
+		return
+	}
+
+	h.log.Debugf("GetSongsLyrics handler request: songID - %v, lang - %q, limit - %v, page - %v", songID, lang, limit, page)
 
 	offset := (page - 1) * limit
 
-	verses, err := h.service.GetSongLyrics(c, songID, limit, offset)
+	song, err := h.service.GetSong(c, songID)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		h.log.Debugf("GetSongLyrics handler: song id %d not found", songID)
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
 	if err != nil {
 		h.log.Errorf("GetSongLyrics failure: %s", err)
 		response.Error(c, http.StatusInternalServerError, "failed to get text")
 		return
 	}
 
+	variant := fmt.Sprintf("lyrics;lang=%s;limit=%d;page=%d", lang, limit, page)
+	if setValidators(c, songVariantETag(song, variant), song.UpdatedAt) {
+		h.log.Debugf("GetSongLyrics handler: not modified")
+		return
+	}
+
+	verses, err := h.service.GetSongLyrics(c, songID, lang, limit, offset)
+	if err != nil {
+		h.log.Errorf("GetSongLyrics failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get text")
+		return
+	}
+
+	setContentLanguage(c, verses)
 	h.log.Infof("GetSongsLyrics handler successful response: %+v", verses)
 	response.JSON(c, verses)
 }
@@ -164,17 +285,19 @@ func (h *MusicHandler) GetSongLyrics(c *gin.Context) {
 // @Produce json
 // @Param songID path int true "ID песни"
 // @Param song body dto.UpdateSongReq true "Данные для изменения"
+// @Param If-Match header string false "ETag песни, изменение применяется только если песня не менялась"
 // @Success 200 {string} string "Данные песни изменены"
+// @Header 200 {string} ETag "Новая версия песни"
 // @Failure 400 {string} string "Неверное тело запроса или ID песни"
+// @Failure 409 {string} string "Песня с таким названием и исполнителем уже есть"
+// @Failure 412 {string} string "Песня была изменена"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей"
 // @Failure 500 {string} string "Ошибка изменения песни на сервере"
 // @Router /api/v1/{songID} [put]
 func (h *MusicHandler) UpdateSong(c *gin.Context) {
 	var req dto.UpdateSongReq
 
-	err := c.BindJSON(&req)
-	if err != nil {
-		h.log.Debugf("UpdateSong handler: invalid input parameters: %s", err)
-		response.Error(c, http.StatusBadRequest, "invalid input parameters")
+	if !bindJSON(c, h.log, &req) {
 		return
 	}
 
@@ -185,19 +308,143 @@ func (h *MusicHandler) UpdateSong(c *gin.Context) {
 		return
 	}
 
-	h.log.Debugf("UpdateSong handler request: songID - %v", songID)
+	ifMatch := c.GetHeader("If-Match")
+	version, ok := h.ifMatchVersion(c, songID)
+	if !ok {
+		return
+	}
+	req.Version = version
+
+	h.log.Debugf("UpdateSong handler request: songID - %v, If-Match - %s", songID, ifMatch)
+
+	if ifMatch == "*" {
+		_, err = h.service.GetSong(c, songID)
+		if errors.Is(err, repository.ErrSongNotFound) {
+			response.Error(c, http.StatusPreconditionFailed, "song was modified")
+			return
+		}
+	}
 
 	err = h.service.UpdateSong(c, songID, &req)
+	if errors.Is(err, repository.ErrVersionMismatch) {
+		h.log.Debugf("UpdateSong handler: stale version for song id %d", songID)
+		response.Error(c, http.StatusPreconditionFailed, "song was modified")
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicateSong) {
+		response.Error(c, http.StatusConflict, "song already exists")
+		return
+	}
 	if err != nil {
 		h.log.Errorf("UpdateSong failure: %s", err)
 		response.Error(c, http.StatusInternalServerError, "failed to update song")
 		return
 	}
 
+	song, err := h.service.GetSong(c, songID)
+	if err == nil {
+		c.Header("ETag", songETag(song))
+	}
+
 	h.log.Infof("UpdateSong handler successful response")
 	response.JSON(c, "successfully updated song")
 }
 
+// PatchSong godoc
+// @Summary Частичное изменение песни через JSON Merge Patch или JSON Patch
+// @Description Патч применяется к документу {"song", "group", "release_date", "link"} текущей песни
+// @Tags Songs
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Param patch body object true "Merge patch (RFC 7386) или JSON patch (RFC 6902)"
+// @Param If-Match header string false "ETag песни"
+// @Success 200 {object} model.Song "Изменённая песня"
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {string} string "Неверный ID песни или документ патча"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 409 {string} string "Песня с таким названием и исполнителем уже есть"
+// @Failure 412 {string} string "Песня была изменена"
+// @Failure 415 {string} string "Неподдерживаемый Content-Type"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Песня после применения патча некорректна"
+// @Failure 500 {string} string "Ошибка изменения песни на сервере"
+// @Router /api/v1/{songID} [patch]
+func (h *MusicHandler) PatchSong(c *gin.Context) {
+	songID, err := strconv.Atoi(c.Param("songID"))
+	if err != nil {
+		h.log.Debugf("PatchSong handler: invalid song id: %s", err)
+		response.Error(c, http.StatusBadRequest, "invalid song id")
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != service.MergePatchContentType && contentType != service.JSONPatchContentType {
+		h.log.Debugf("PatchSong handler: unsupported content type: %s", contentType)
+		response.Error(c, http.StatusUnsupportedMediaType, "unsupported content type")
+		return
+	}
+
+	song, ok := h.patchSong(c, songID, contentType)
+	if !ok {
+		return
+	}
+
+	h.log.Infof("PatchSong handler successful response: %+v", song)
+	c.Header("ETag", songETag(song))
+	response.JSON(c, song)
+}
+
+// patchSong applies a merge patch or JSON patch document from the request
+// body to the song, writing the error response on failure. It is shared by
+// the v1 and v2 PATCH routes.
+func (h *MusicHandler) patchSong(c *gin.Context, songID int, contentType string) (*model.Song, bool) {
+	version, ok := h.ifMatchVersion(c, songID)
+	if !ok {
+		return nil, false
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		h.log.Debugf("PatchSong handler: failed to read body: %s", err)
+		response.Error(c, http.StatusBadRequest, "invalid input parameters")
+		return nil, false
+	}
+
+	h.log.Debugf("PatchSong handler request: songID - %v, contentType - %s", songID, contentType)
+
+	song, err := h.service.PatchSong(c, songID, contentType, patch, version)
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound):
+		response.Error(c, http.StatusNotFound, "song not found")
+		return nil, false
+	case errors.Is(err, repository.ErrVersionMismatch):
+		response.Error(c, http.StatusPreconditionFailed, "song was modified")
+		return nil, false
+	case errors.Is(err, repository.ErrDuplicateSong):
+		response.Error(c, http.StatusConflict, "song already exists")
+		return nil, false
+	case errors.Is(err, service.ErrInvalidPatch):
+		h.log.Debugf("PatchSong handler: %s", err)
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	case errors.Is(err, service.ErrPatchResult):
+		h.log.Debugf("PatchSong handler: %s", err)
+		if fields, ok := validation.FieldErrors(err); ok {
+			response.ValidationError(c, fields)
+			return nil, false
+		}
+		response.Error(c, http.StatusUnprocessableEntity, err.Error())
+		return nil, false
+	case err != nil:
+		h.log.Errorf("PatchSong failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to update song")
+		return nil, false
+	}
+
+	return song, true
+}
+
 // DeleteSong godoc
 // @Summary Удаление песни
 // @Tags Songs
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// ListSongLinksV2 godoc
+// @Summary Ссылки песни на стриминговые сервисы
+// @Description Статус ссылки — unknown, пока ее не проверили, ok или dead
+// @Tags Links v2
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Success 200 {object} response.Envelope{data=[]model.Link}
+// @Failure 400 {string} string "Неверный ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 500 {string} string "Ошибка получения ссылок"
+// @Router /api/v2/songs/{songID}/links [get]
+func (h *MusicHandler) ListSongLinksV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.service.GetSongLinks(c, songID)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("ListSongLinksV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get links")
+		return
+	}
+
+	response.Data(c, http.StatusOK, links)
+}
+
+// AddSongLinkV2 godoc
+// @Summary Добавление ссылки песне
+// @Description Сервис определяется по домену ссылки. Указанный provider должен с ним совпадать, для неизвестных доменов он задает сервис вместо other
+// @Tags Links v2
+// @Accept json
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Param link body dto.AddLinkReq true "Ссылка"
+// @Success 201 {object} response.Envelope{data=model.Link}
+// @Failure 400 {string} string "Неверное тело запроса или ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 409 {string} string "У песни уже есть эта ссылка"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей или сервис не совпадает с доменом ссылки"
+// @Failure 500 {string} string "Ошибка добавления ссылки"
+// @Router /api/v2/songs/{songID}/links [post]
+func (h *MusicHandler) AddSongLinkV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.AddLinkReq
+
+	if !bindJSON(c, h.log, &req) {
+		return
+	}
+
+	link, err := h.service.AddSongLink(c, songID, &req)
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound):
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	case errors.Is(err, repository.ErrDuplicateLink):
+		response.Error(c, http.StatusConflict, "song already has the link")
+		return
+	case errors.Is(err, service.ErrProviderMismatch):
+		response.Error(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		h.log.Errorf("AddSongLinkV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to add link")
+		return
+	}
+
+	h.log.Infof("AddSongLinkV2 handler: added %s link id %d to song id %d", link.Provider, link.ID, songID)
+	response.Data(c, http.StatusCreated, link)
+}
+
+// DeleteSongLinkV2 godoc
+// @Summary Удаление ссылки песни
+// @Tags Links v2
+// @Param songID path int true "ID песни"
+// @Param linkID path int true "ID ссылки"
+// @Success 204 "Ссылка удалена"
+// @Failure 400 {string} string "Неверный ID песни или ссылки"
+// @Failure 404 {string} string "Ссылка не найдена"
+// @Failure 500 {string} string "Ошибка удаления ссылки"
+// @Router /api/v2/songs/{songID}/links/{linkID} [delete]
+func (h *MusicHandler) DeleteSongLinkV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	linkID, err := strconv.Atoi(c.Param("linkID"))
+	if err != nil || linkID <= 0 {
+		h.log.Debugf("Invalid link id: %s", c.Param("linkID"))
+		response.Error(c, http.StatusBadRequest, "invalid link id")
+		return
+	}
+
+	err = h.service.DeleteSongLink(c, songID, linkID)
+	if errors.Is(err, repository.ErrLinkNotFound) {
+		response.Error(c, http.StatusNotFound, "link not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("DeleteSongLinkV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to delete link")
+		return
+	}
+
+	h.log.Infof("DeleteSongLinkV2 handler: deleted link id %d of song id %d", linkID, songID)
+	c.Status(http.StatusNoContent)
+}
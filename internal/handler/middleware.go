@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// deprecated marks every response of a route group as deprecated and points
+// clients to the successor API version.
+func deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		c.Next()
+	}
+}
+
+// RequireAPIKey rejects requests without the key in the X-API-Key header.
+// An empty key rejects every request.
+func RequireAPIKey(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("X-API-Key")), []byte(key)) != 1 {
+			response.Error(c, http.StatusUnauthorized, "invalid api key")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		key    string
+		header string
+		want   int
+	}{
+		{name: "valid key", key: "secret", header: "secret", want: http.StatusOK},
+		{name: "wrong key", key: "secret", header: "guess", want: http.StatusUnauthorized},
+		{name: "missing key", key: "secret", want: http.StatusUnauthorized},
+		{name: "empty configured key", key: "", want: http.StatusUnauthorized},
+		{name: "empty configured key with header", key: "", header: "anything", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/admin", RequireAPIKey(tt.key), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.header != "" {
+				req.Header.Set("X-API-Key", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/aaanger/music-library/pkg/ratelimit"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimiter limits the requests of every client, identified by its
+// X-API-Key header if the key is known or by its IP address. Reads and
+// writes have separate budgets, so a client hammering POST /api/v1/add can
+// still browse the library.
+type RateLimiter struct {
+	store ratelimit.Store
+	read  ratelimit.Limit
+	write ratelimit.Limit
+	// keys holds the hashes of the known API keys.
+	keys map[string]bool
+	log  *logrus.Logger
+}
+
+// NewRateLimiter returns a limiter using read for GET, HEAD and OPTIONS requests
+// and write for all other methods. A limit with zero requests is not enforced.
+func NewRateLimiter(store ratelimit.Store, read, write ratelimit.Limit, log *logrus.Logger) *RateLimiter {
+	return &RateLimiter{
+		store: store,
+		read:  read,
+		write: write,
+		keys:  make(map[string]bool),
+		log:   log,
+	}
+}
+
+// SetAPIKeys sets the API keys which get a budget of their own. Requests
+// with other keys are limited by IP address, otherwise a client could send
+// a new key with every request to get a fresh budget.
+func (l *RateLimiter) SetAPIKeys(keys ...string) {
+	for _, key := range keys {
+		if key != "" {
+			l.keys[hashKey(key)] = true
+		}
+	}
+}
+
+// Handle is the middleware. It sets the RateLimit-Limit, RateLimit-Remaining
+// and RateLimit-Reset headers and answers 429 with Retry-After once the
+// budget is spent. Requests are let through if the store fails.
+func (l *RateLimiter) Handle(c *gin.Context) {
+	class, limit := "write", l.write
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		class, limit = "read", l.read
+	}
+
+	if limit.Requests <= 0 {
+		c.Next()
+		return
+	}
+
+	client := l.clientKey(c)
+
+	res, err := l.store.Take(c, class+":"+client, limit)
+	if err != nil {
+		l.log.Errorf("RateLimiter error: %s", err)
+		c.Next()
+		return
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", ceilSeconds(res.Reset))
+
+	if !res.Allowed {
+		l.log.Debugf("RateLimiter: %s budget of %s spent", class, client)
+		c.Header("Retry-After", ceilSeconds(res.RetryAfter))
+		response.Error(c, http.StatusTooManyRequests, "rate limit exceeded")
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// clientKey identifies the client by its API key if it is a known one, by
+// its IP address otherwise. API keys are hashed, so they aren't stored in a
+// shared rate limit store.
+func (l *RateLimiter) clientKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		hash := hashKey(key)
+		if l.keys[hash] {
+			return "key:" + hash
+		}
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"github.com/aaanger/music-library/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newLimitedRouter(limiter *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(limiter.Handle)
+	r.GET("/songs", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/songs", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	return r
+}
+
+func newTestLimiter(read, write ratelimit.Limit) *RateLimiter {
+	return NewRateLimiter(ratelimit.NewMemoryStore(), read, write, testLogger())
+}
+
+func limitedRequest(r *gin.Engine, method, ip, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/songs", nil)
+	req.RemoteAddr = ip + ":1234"
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestRateLimiterTooManyRequests(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute}
+	r := newLimitedRouter(newTestLimiter(limit, limit))
+
+	for i, remaining := range []string{"1", "0"} {
+		w := limitedRequest(r, http.MethodGet, "10.0.0.1", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, http.StatusOK)
+		}
+		if got := w.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Fatalf("request %d: RateLimit-Limit = %q, want %q", i+1, got, "2")
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != remaining {
+			t.Fatalf("request %d: RateLimit-Remaining = %q, want %q", i+1, got, remaining)
+		}
+		if got := w.Header().Get("Retry-After"); got != "" {
+			t.Fatalf("request %d: Retry-After = %q on an allowed request", i+1, got)
+		}
+	}
+
+	w := limitedRequest(r, http.MethodGet, "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	// A token comes back every 30 seconds.
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 30 {
+		t.Fatalf("Retry-After = %q, want 1..30 seconds", w.Header().Get("Retry-After"))
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Fatalf("RateLimit-Remaining = %q, want %q", got, "0")
+	}
+
+	// Writes have a budget of their own, as do other clients.
+	if w := limitedRequest(r, http.MethodPost, "10.0.0.1", ""); w.Code != http.StatusCreated {
+		t.Fatalf("write status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if w := limitedRequest(r, http.MethodGet, "10.0.0.2", ""); w.Code != http.StatusOK {
+		t.Fatalf("other client status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimiterClientKey(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Period: time.Minute}
+
+	tests := []struct {
+		name string
+		// keys are the X-API-Key headers of two requests from the same IP.
+		keys [2]string
+		want int
+	}{
+		{name: "no keys", keys: [2]string{"", ""}, want: http.StatusTooManyRequests},
+		{name: "unknown keys share the IP budget", keys: [2]string{"random-1", "random-2"}, want: http.StatusTooManyRequests},
+		{name: "unknown key after no key", keys: [2]string{"", "random"}, want: http.StatusTooManyRequests},
+		{name: "known key has its own budget", keys: [2]string{"", "known"}, want: http.StatusOK},
+		{name: "known keys have separate budgets", keys: [2]string{"known", "other-known"}, want: http.StatusOK},
+		{name: "same known key", keys: [2]string{"known", "known"}, want: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newTestLimiter(limit, limit)
+			limiter.SetAPIKeys("known", "other-known", "")
+			r := newLimitedRouter(limiter)
+
+			if w := limitedRequest(r, http.MethodGet, "10.0.0.1", tt.keys[0]); w.Code != http.StatusOK {
+				t.Fatalf("first status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if w := limitedRequest(r, http.MethodGet, "10.0.0.1", tt.keys[1]); w.Code != tt.want {
+				t.Fatalf("second status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterDisabledLimit(t *testing.T) {
+	r := newLimitedRouter(newTestLimiter(ratelimit.Limit{}, ratelimit.Limit{Requests: 1, Period: time.Minute}))
+
+	for i := 0; i < 3; i++ {
+		w := limitedRequest(r, http.MethodGet, "10.0.0.1", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, http.StatusOK)
+		}
+		if got := w.Header().Get("RateLimit-Limit"); got != "" {
+			t.Fatalf("request %d: RateLimit-Limit = %q on a disabled limit", i+1, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// maxRefreshLimit caps the songs refreshed by one request, the metadata
+// providers are throttled and the request has to finish within the write timeout.
+const maxRefreshLimit = 20
+
+// bindRefresh decodes the optional body of the refresh endpoints, an empty
+// body refreshes all fields.
+func (h *MusicHandler) bindRefresh(c *gin.Context, req *dto.RefreshSongsReq) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	return bindJSON(c, h.log, req)
+}
+
+// RefreshSongV2 godoc
+// @Summary Обновление данных песни из источников метаданных
+// @Description Дата выпуска, текст и ссылка загружаются заново, в ответе изменившиеся поля. С dry_run изменения не применяются. Тело запроса можно не передавать
+// @Tags Songs v2
+// @Accept json
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Param refresh body dto.RefreshSongsReq false "Поля и режим обновления"
+// @Success 200 {object} response.Envelope{data=model.SongRefresh}
+// @Failure 400 {string} string "Неверное тело запроса или ID песни"
+// @Failure 404 {string} string "Песня не найдена в библиотеке или в источниках метаданных"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей"
+// @Failure 502 {string} string "Ошибка источников метаданных"
+// @Router /api/v2/songs/{songID}/refresh [post]
+func (h *MusicHandler) RefreshSongV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.RefreshSongsReq
+
+	if !h.bindRefresh(c, &req) {
+		return
+	}
+
+	result, err := h.service.RefreshSong(c, songID, &req)
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound):
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	case errors.Is(err, metadata.ErrNotFound):
+		response.Error(c, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		h.log.Errorf("RefreshSongV2 failure: %s", err)
+		response.Error(c, http.StatusBadGateway, "failed to fetch song details")
+		return
+	}
+
+	h.log.Infof("RefreshSongV2 handler: %d changes of song id %d, applied - %t", len(result.Changes), songID, result.Applied)
+	response.Data(c, http.StatusOK, result)
+}
+
+// RefreshSongsV2 godoc
+// @Summary Обновление данных песен из источников метаданных
+// @Description Обновляется страница песен, подходящих под фильтры, как в списке песен. Ошибка загрузки отдельной песни указывается в поле error результата. Запросы к источникам выполняются не чаще REFRESH_INTERVAL
+// @Tags Songs v2
+// @Accept json
+// @Produce json
+// @Param song query string false "Фильтр по названию песни без учета регистра, алфавита и диакритики"
+// @Param group query string false "Фильтр по названию исполнителя без учета регистра, алфавита и диакритики"
+// @Param release_date query string false "Фильтр по дате выпуска"
+// @Param tag query []string false "Фильтр по тегам и жанрам, параметр можно повторять" collectionFormat(multi)
+// @Param tag_mode query string false "any — песни хотя бы с одним из тегов, all — со всеми" Enums(any, all) default(any)
+// @Param limit query int false "Количество песен на странице, не больше 20" default(10)
+// @Param page query int false "Номер страницы" default(1)
+// @Param refresh body dto.RefreshSongsReq false "Поля и режим обновления"
+// @Success 200 {object} response.Envelope{data=[]model.SongRefresh}
+// @Failure 400 {string} string "Неверное тело запроса, фильтр или параметры пагинации"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей"
+// @Failure 500 {string} string "Ошибка обновления песен"
+// @Router /api/v2/songs/refresh [post]
+func (h *MusicHandler) RefreshSongsV2(c *gin.Context) {
+	var filter dto.GetSongsListReq
+
+	if !songsFilterParams(c, h.log, &filter) {
+		return
+	}
+
+	limit, page, ok := pageParams(c, h.log, 10)
+	if !ok {
+		return
+	}
+	if limit > maxRefreshLimit {
+		h.log.Debugf("Refresh limit %d over %d", limit, maxRefreshLimit)
+		response.Error(c, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	var req dto.RefreshSongsReq
+
+	if !h.bindRefresh(c, &req) {
+		return
+	}
+
+	results, total, err := h.service.RefreshSongs(c, &filter, &req)
+	if err != nil {
+		h.log.Errorf("RefreshSongsV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to refresh songs")
+		return
+	}
+
+	h.log.Infof("RefreshSongsV2 handler: refreshed %d of %d songs, dry run - %t", len(results), total, req.DryRun)
+	response.Page(c, http.StatusOK, results, page, limit, total)
+}
@@ -1,22 +1,62 @@
 package handler
 
 import (
+	"expvar"
+	"github.com/aaanger/music-library/internal/gqlhandler"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func (h *MusicHandler) InitRoutes() *gin.Engine {
+// InitRoutes returns the router of the service. The middleware, e.g.
+// RateLimiter.Handle, applies to the API routes and to routes added to the
+// router later on, but not to the swagger UI and /debug/vars.
+func (h *MusicHandler) InitRoutes(middleware ...gin.HandlerFunc) *gin.Engine {
 	r := gin.New()
 
-	api := r.Group("/api/v1")
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	r.Use(middleware...)
+
+	api := r.Group("/api/v1", deprecated("/api/v2"))
 
 	api.POST("/add", h.AddSong)
 	api.GET("/songs", h.GetSongsList)
+	api.GET("/songs/facets", h.GetSongsFacets)
+	api.GET("/events", h.StreamEvents)
+	api.GET("/:songID", h.GetSong)
 	api.GET("/:songID/lyrics", h.GetSongLyrics)
 	api.PUT("/:songID", h.UpdateSong)
+	api.PATCH("/:songID", h.PatchSong)
 	api.DELETE("/:songID", h.DeleteSong)
 
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	v2 := r.Group("/api/v2")
+
+	v2.GET("/songs", h.ListSongsV2)
+	v2.POST("/songs", h.CreateSongV2)
+	v2.GET("/songs/duplicates", h.FindDuplicatesV2)
+	v2.GET("/songs/facets", h.GetFacetsV2)
+	v2.POST("/songs/refresh", h.RefreshSongsV2)
+	v2.GET("/songs/:songID", h.GetSongV2)
+	v2.PATCH("/songs/:songID", h.PatchSongV2)
+	v2.DELETE("/songs/:songID", h.DeleteSongV2)
+	v2.GET("/songs/:songID/verses", h.ListVersesV2)
+	v2.POST("/songs/:songID/merge", h.MergeSongsV2)
+	v2.POST("/songs/:songID/refresh", h.RefreshSongV2)
+	v2.GET("/songs/:songID/tags", h.ListSongTagsV2)
+	v2.POST("/songs/:songID/tags", h.TagSongV2)
+	v2.DELETE("/songs/:songID/tags/:tag", h.UntagSongV2)
+	v2.GET("/tags", h.ListTagsV2)
+	v2.GET("/songs/:songID/translations", h.ListTranslationsV2)
+	v2.PUT("/songs/:songID/translations/:lang", h.SetTranslationV2)
+	v2.DELETE("/songs/:songID/translations/:lang", h.DeleteTranslationV2)
+	v2.GET("/songs/:songID/links", h.ListSongLinksV2)
+	v2.POST("/songs/:songID/links", h.AddSongLinkV2)
+	v2.DELETE("/songs/:songID/links/:linkID", h.DeleteSongLinkV2)
+	v2.GET("/events", h.StreamEvents)
+
+	r.POST("/graphql", gqlhandler.NewHandler(h.service, h.log).Serve)
+
 	return r
 }
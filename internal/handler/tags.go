@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// facets parses the filters and the limit of the facets endpoints and counts
+// the songs, writing an error response on failure.
+func (h *MusicHandler) facets(c *gin.Context) (*model.Facets, bool) {
+	var req dto.GetSongsListReq
+
+	if !songsFilterParams(c, h.log, &req) {
+		return nil, false
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		h.log.Debugf("Invalid limit query: %s", c.Query("limit"))
+		response.Error(c, http.StatusBadRequest, "invalid limit")
+		return nil, false
+	}
+
+	facets, err := h.service.GetFacets(c, &req, limit)
+	if err != nil {
+		h.log.Errorf("GetFacets failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get facets")
+		return nil, false
+	}
+
+	return facets, true
+}
+
+// GetSongsFacets godoc
+// @Summary Количество песен по тегам, исполнителям и годам выпуска
+// @Description Считаются песни, подходящие под фильтры, как в списке песен
+// @Tags Songs
+// @Produce json
+// @Param song query string false "Фильтр по названию песни без учета регистра, алфавита и диакритики"
+// @Param group query string false "Фильтр по названию исполнителя без учета регистра, алфавита и диакритики"
+// @Param release_date query string false "Фильтр по дате выпуска"
+// @Param tag query []string false "Фильтр по тегам и жанрам, параметр можно повторять" collectionFormat(multi)
+// @Param tag_mode query string false "any — песни хотя бы с одним из тегов, all — со всеми" Enums(any, all) default(any)
+// @Param limit query int false "Количество значений каждого фасета" default(20)
+// @Success 200 {object} model.Facets
+// @Failure 400 {string} string "Некорректный фильтр или лимит"
+// @Failure 500 {string} string "Ошибка получения фасетов"
+// @Router /api/v1/songs/facets [get]
+func (h *MusicHandler) GetSongsFacets(c *gin.Context) {
+	facets, ok := h.facets(c)
+	if !ok {
+		return
+	}
+
+	response.JSON(c, facets)
+}
+
+// GetFacetsV2 godoc
+// @Summary Количество песен по тегам, исполнителям и годам выпуска
+// @Description Считаются песни, подходящие под фильтры, как в списке песен
+// @Tags Songs v2
+// @Produce json
+// @Param song query string false "Фильтр по названию песни без учета регистра, алфавита и диакритики"
+// @Param group query string false "Фильтр по названию исполнителя без учета регистра, алфавита и диакритики"
+// @Param release_date query string false "Фильтр по дате выпуска"
+// @Param tag query []string false "Фильтр по тегам и жанрам, параметр можно повторять" collectionFormat(multi)
+// @Param tag_mode query string false "any — песни хотя бы с одним из тегов, all — со всеми" Enums(any, all) default(any)
+// @Param limit query int false "Количество значений каждого фасета" default(20)
+// @Success 200 {object} response.Envelope{data=model.Facets}
+// @Failure 400 {string} string "Некорректный фильтр или лимит"
+// @Failure 500 {string} string "Ошибка получения фасетов"
+// @Router /api/v2/songs/facets [get]
+func (h *MusicHandler) GetFacetsV2(c *gin.Context) {
+	facets, ok := h.facets(c)
+	if !ok {
+		return
+	}
+
+	response.Data(c, http.StatusOK, facets)
+}
+
+// ListTagsV2 godoc
+// @Summary Список тегов и жанров
+// @Tags Tags v2
+// @Produce json
+// @Param kind query string false "Только теги или только жанры" Enums(tag, genre)
+// @Success 200 {object} response.Envelope{data=[]model.Tag}
+// @Failure 400 {string} string "Неизвестный вид тега"
+// @Failure 500 {string} string "Ошибка получения тегов"
+// @Router /api/v2/tags [get]
+func (h *MusicHandler) ListTagsV2(c *gin.Context) {
+	kind := c.Query("kind")
+	if kind != "" && kind != model.TagKindTag && kind != model.TagKindGenre {
+		h.log.Debugf("Invalid kind query: %s", kind)
+		response.Error(c, http.StatusBadRequest, "invalid kind")
+		return
+	}
+
+	tags, err := h.service.ListTags(c, kind)
+	if err != nil {
+		h.log.Errorf("ListTagsV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get tags")
+		return
+	}
+
+	response.Data(c, http.StatusOK, tags)
+}
+
+// ListSongTagsV2 godoc
+// @Summary Теги и жанры песни
+// @Tags Tags v2
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Success 200 {object} response.Envelope{data=[]model.Tag}
+// @Failure 400 {string} string "Неверный ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 500 {string} string "Ошибка получения тегов"
+// @Router /api/v2/songs/{songID}/tags [get]
+func (h *MusicHandler) ListSongTagsV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	tags, err := h.service.GetSongTags(c, songID)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("ListSongTagsV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get tags")
+		return
+	}
+
+	response.Data(c, http.StatusOK, tags)
+}
+
+// TagSongV2 godoc
+// @Summary Добавление тега или жанра песне
+// @Description Новый тег создается с видом kind (по умолчанию tag), у существующего kind меняет вид. Имя приводится к нижнему регистру
+// @Tags Tags v2
+// @Accept json
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Param tag body dto.TagSongReq true "Тег"
+// @Success 200 {object} response.Envelope{data=[]model.Tag}
+// @Failure 400 {string} string "Неверное тело запроса или ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей"
+// @Failure 500 {string} string "Ошибка добавления тега"
+// @Router /api/v2/songs/{songID}/tags [post]
+func (h *MusicHandler) TagSongV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.TagSongReq
+
+	if !bindJSON(c, h.log, &req) {
+		return
+	}
+
+	tags, err := h.service.TagSong(c, songID, &req)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("TagSongV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to tag song")
+		return
+	}
+
+	h.log.Infof("TagSongV2 handler: tagged song id %d with %q", songID, req.Name)
+	response.Data(c, http.StatusOK, tags)
+}
+
+// UntagSongV2 godoc
+// @Summary Удаление тега или жанра у песни
+// @Tags Tags v2
+// @Param songID path int true "ID песни"
+// @Param tag path string true "Имя тега"
+// @Success 204 "Тег удален"
+// @Failure 400 {string} string "Неверный ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 500 {string} string "Ошибка удаления тега"
+// @Router /api/v2/songs/{songID}/tags/{tag} [delete]
+func (h *MusicHandler) UntagSongV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.service.UntagSong(c, songID, c.Param("tag"))
+	if errors.Is(err, repository.ErrSongNotFound) {
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("UntagSongV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to untag song")
+		return
+	}
+
+	h.log.Infof("UntagSongV2 handler: untagged song id %d from %q", songID, c.Param("tag"))
+	c.Status(http.StatusNoContent)
+}
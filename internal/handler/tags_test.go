@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aaanger/music-library/internal/model"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// tagList decodes an envelope of tags into "name:kind" pairs.
+func tagList(t *testing.T, body []byte) string {
+	t.Helper()
+
+	var envelope struct {
+		Data []*model.Tag `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("unmarshal %s: %s", body, err)
+	}
+
+	pairs := make([]string, 0, len(envelope.Data))
+	for _, tag := range envelope.Data {
+		pairs = append(pairs, tag.Name+":"+tag.Kind)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func TestTagsHandlers(t *testing.T) {
+	r, repo := newTestRouter(t)
+	song := ingestTestSong(t, repo, "Joy Division", "Disorder", "I've been waiting for a guide")
+	other := ingestTestSong(t, repo, "Muse", "Uprising", "Paranoia is in bloom")
+	tags := fmt.Sprintf("/api/v2/songs/%d/tags", song.ID)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+		// tags are the expected "name:kind" pairs of the response, if any.
+		tags string
+	}{
+		{name: "no tags yet", method: http.MethodGet, target: tags, want: http.StatusOK, tags: ""},
+		{name: "tag", method: http.MethodPost, target: tags, body: `{"name": "  Cold  Wave "}`, want: http.StatusOK, tags: "cold wave:tag"},
+		{name: "genre", method: http.MethodPost, target: tags, body: `{"name": "Post-Punk", "kind": "genre"}`, want: http.StatusOK, tags: "cold wave:tag,post-punk:genre"},
+		{name: "same tag again", method: http.MethodPost, target: tags, body: `{"name": "cold wave"}`, want: http.StatusOK, tags: "cold wave:tag,post-punk:genre"},
+		{name: "kind changed", method: http.MethodPost, target: tags, body: `{"name": "cold wave", "kind": "genre"}`, want: http.StatusOK, tags: "cold wave:genre,post-punk:genre"},
+		{name: "tag another song", method: http.MethodPost, target: fmt.Sprintf("/api/v2/songs/%d/tags", other.ID), body: `{"name": "rock"}`, want: http.StatusOK, tags: "rock:tag"},
+		{name: "invalid kind", method: http.MethodPost, target: tags, body: `{"name": "rock", "kind": "mood"}`, want: http.StatusUnprocessableEntity},
+		{name: "missing name", method: http.MethodPost, target: tags, body: `{"kind": "genre"}`, want: http.StatusUnprocessableEntity},
+		{name: "name with a slash", method: http.MethodPost, target: tags, body: `{"name": "rock/pop"}`, want: http.StatusUnprocessableEntity},
+		{name: "invalid body", method: http.MethodPost, target: tags, body: `{"name":`, want: http.StatusBadRequest},
+		{name: "tag missing song", method: http.MethodPost, target: "/api/v2/songs/999/tags", body: `{"name": "rock"}`, want: http.StatusNotFound},
+		{name: "invalid song id", method: http.MethodPost, target: "/api/v2/songs/abc/tags", body: `{"name": "rock"}`, want: http.StatusBadRequest},
+		{name: "song tags", method: http.MethodGet, target: tags, want: http.StatusOK, tags: "cold wave:genre,post-punk:genre"},
+		{name: "tags of missing song", method: http.MethodGet, target: "/api/v2/songs/999/tags", want: http.StatusNotFound},
+		{name: "all tags", method: http.MethodGet, target: "/api/v2/tags", want: http.StatusOK, tags: "cold wave:genre,post-punk:genre,rock:tag"},
+		{name: "genres", method: http.MethodGet, target: "/api/v2/tags?kind=genre", want: http.StatusOK, tags: "cold wave:genre,post-punk:genre"},
+		{name: "invalid kind query", method: http.MethodGet, target: "/api/v2/tags?kind=mood", want: http.StatusBadRequest},
+		{name: "untag", method: http.MethodDelete, target: tags + "/Cold%20Wave", want: http.StatusNoContent},
+		{name: "untag missing tag", method: http.MethodDelete, target: tags + "/jazz", want: http.StatusNoContent},
+		{name: "untag missing song", method: http.MethodDelete, target: "/api/v2/songs/999/tags/rock", want: http.StatusNotFound},
+		{name: "tags after untag", method: http.MethodGet, target: tags, want: http.StatusOK, tags: "post-punk:genre"},
+		{name: "untagged tag is kept", method: http.MethodGet, target: "/api/v2/tags?kind=genre", want: http.StatusOK, tags: "cold wave:genre,post-punk:genre"},
+	}
+
+	// The cases run in order, later ones see the changes of earlier ones.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, tt.target, tt.body, nil)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK {
+				if got := tagList(t, w.Body.Bytes()); got != tt.tags {
+					t.Fatalf("tags = %q, want %q", got, tt.tags)
+				}
+			}
+		})
+	}
+}
+
+func TestTagFilters(t *testing.T) {
+	r, repo := newTestRouter(t)
+
+	songs := []struct {
+		group, song string
+		tags        []string
+	}{
+		{group: "Joy Division", song: "Disorder", tags: []string{"post-punk", "rock"}},
+		{group: "Joy Division", song: "Shadowplay", tags: []string{"post-punk"}},
+		{group: "Muse", song: "Uprising", tags: []string{"rock"}},
+		{group: "Miles Davis", song: "So What"},
+	}
+	for _, s := range songs {
+		song := ingestTestSong(t, repo, s.group, s.song, "Verse")
+		for _, tag := range s.tags {
+			w := serve(r, http.MethodPost, fmt.Sprintf("/api/v2/songs/%d/tags", song.ID), fmt.Sprintf(`{"name": %q}`, tag), nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("tag song: status = %d: %s", w.Code, w.Body)
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		// songs are the expected titles, in order.
+		songs string
+		// facets are the expected tag facets as "value:count" pairs.
+		facets string
+	}{
+		{name: "no filter", query: "", want: http.StatusOK, songs: "Disorder,Shadowplay,Uprising,So What", facets: "post-punk:2,rock:2"},
+		{name: "any tag", query: "tag=rock&tag=jazz", want: http.StatusOK, songs: "Disorder,Uprising", facets: "rock:2,post-punk:1"},
+		{name: "all tags", query: "tag=rock&tag=post-punk&tag_mode=all", want: http.StatusOK, songs: "Disorder", facets: "post-punk:1,rock:1"},
+		{name: "tag case and repeats", query: "tag=ROCK&tag=rock&tag_mode=all", want: http.StatusOK, songs: "Disorder,Uprising", facets: "rock:2,post-punk:1"},
+		{name: "tag and group", query: "tag=rock&group=joy%20division", want: http.StatusOK, songs: "Disorder", facets: "post-punk:1,rock:1"},
+		{name: "unknown tag", query: "tag=jazz", want: http.StatusOK, songs: "", facets: ""},
+		{name: "invalid tag mode", query: "tag=rock&tag_mode=none", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/api/v2/songs?"+tt.query, "", nil)
+			if w.Code != tt.want {
+				t.Fatalf("list status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK {
+				var list struct {
+					Data []*model.Song `json:"data"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+					t.Fatalf("unmarshal %s: %s", w.Body, err)
+				}
+				titles := make([]string, 0, len(list.Data))
+				for _, song := range list.Data {
+					titles = append(titles, song.Song)
+				}
+				if got := strings.Join(titles, ","); got != tt.songs {
+					t.Fatalf("songs = %q, want %q", got, tt.songs)
+				}
+			}
+
+			w = serve(r, http.MethodGet, "/api/v2/songs/facets?"+tt.query, "", nil)
+			if w.Code != tt.want {
+				t.Fatalf("facets status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK {
+				var facets struct {
+					Data model.Facets `json:"data"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &facets); err != nil {
+					t.Fatalf("unmarshal %s: %s", w.Body, err)
+				}
+				counts := make([]string, 0, len(facets.Data.Tags))
+				for _, count := range facets.Data.Tags {
+					counts = append(counts, fmt.Sprintf("%s:%d", count.Value, count.Count))
+				}
+				if got := strings.Join(counts, ","); got != tt.facets {
+					t.Fatalf("tag facets = %q, want %q", got, tt.facets)
+				}
+			}
+		})
+	}
+
+	for _, query := range []string{"limit=0", "limit=x"} {
+		if w := serve(r, http.MethodGet, "/api/v2/songs/facets?"+query, "", nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("facets %s: status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
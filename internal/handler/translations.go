@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/validation"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// langParam parses the language path parameter of the translation endpoints,
+// writing a 400 response on failure.
+func (h *MusicHandler) langParam(c *gin.Context) (string, bool) {
+	lang := strings.ToLower(c.Param("lang"))
+	if !validation.IsLanguage(lang) {
+		h.log.Debugf("Invalid language: %s", c.Param("lang"))
+		response.Error(c, http.StatusBadRequest, "invalid language")
+		return "", false
+	}
+
+	return lang, true
+}
+
+// ListTranslationsV2 godoc
+// @Summary Язык текста песни и языки его переводов
+// @Description Язык оригинала определяется автоматически при добавлении текста и пуст, если его не удалось определить
+// @Tags Translations v2
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Success 200 {object} response.Envelope{data=model.LyricsLanguages}
+// @Failure 400 {string} string "Неверный ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 500 {string} string "Ошибка получения языков"
+// @Router /api/v2/songs/{songID}/translations [get]
+func (h *MusicHandler) ListTranslationsV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	languages, err := h.service.GetLyricsLanguages(c, songID)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("ListTranslationsV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get languages")
+		return
+	}
+
+	response.Data(c, http.StatusOK, languages)
+}
+
+// SetTranslationV2 godoc
+// @Summary Добавление или замена перевода текста песни
+// @Description Перевод делится на куплеты пустыми строками, как и оригинал, и должен содержать столько же куплетов
+// @Tags Translations v2
+// @Accept json
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Param lang path string true "Язык перевода, например en или pt-br"
+// @Param translation body dto.SetTranslationReq true "Текст перевода"
+// @Success 200 {object} response.Envelope{data=model.LyricsLanguages}
+// @Failure 400 {string} string "Неверное тело запроса, ID песни или язык"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 409 {string} string "У песни нет текста или язык совпадает с языком оригинала"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей или число куплетов не совпадает с оригиналом"
+// @Failure 500 {string} string "Ошибка сохранения перевода"
+// @Router /api/v2/songs/{songID}/translations/{lang} [put]
+func (h *MusicHandler) SetTranslationV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	lang, ok := h.langParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SetTranslationReq
+
+	if !bindJSON(c, h.log, &req) {
+		return
+	}
+
+	languages, err := h.service.SetTranslation(c, songID, lang, &req)
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound):
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	case errors.Is(err, repository.ErrNoLyrics):
+		response.Error(c, http.StatusConflict, "song has no lyrics")
+		return
+	case errors.Is(err, repository.ErrOriginalLanguage):
+		response.Error(c, http.StatusConflict, "lyrics are already in this language")
+		return
+	case errors.Is(err, repository.ErrVerseCountMismatch):
+		response.Error(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		h.log.Errorf("SetTranslationV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to set translation")
+		return
+	}
+
+	h.log.Infof("SetTranslationV2 handler: set %s translation of song id %d", lang, songID)
+	response.Data(c, http.StatusOK, languages)
+}
+
+// DeleteTranslationV2 godoc
+// @Summary Удаление перевода текста песни
+// @Tags Translations v2
+// @Param songID path int true "ID песни"
+// @Param lang path string true "Язык перевода"
+// @Success 204 "Перевод удален"
+// @Failure 400 {string} string "Неверный ID песни или язык"
+// @Failure 404 {string} string "Перевод не найден"
+// @Failure 500 {string} string "Ошибка удаления перевода"
+// @Router /api/v2/songs/{songID}/translations/{lang} [delete]
+func (h *MusicHandler) DeleteTranslationV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	lang, ok := h.langParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.service.DeleteTranslation(c, songID, lang)
+	if errors.Is(err, repository.ErrTranslationNotFound) {
+		response.Error(c, http.StatusNotFound, "translation not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("DeleteTranslationV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to delete translation")
+		return
+	}
+
+	h.log.Infof("DeleteTranslationV2 handler: deleted %s translation of song id %d", lang, songID)
+	c.Status(http.StatusNoContent)
+}
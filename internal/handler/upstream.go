@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type UpstreamCacheHandler struct {
+	service service.IUpstreamCacheService
+	log     *logrus.Logger
+}
+
+func NewUpstreamCacheHandler(service service.IUpstreamCacheService, log *logrus.Logger) *UpstreamCacheHandler {
+	return &UpstreamCacheHandler{
+		service: service,
+		log:     log,
+	}
+}
+
+// InitRoutes registers the admin endpoints of the upstream cache on the router
+// returned by MusicHandler.InitRoutes, behind the middleware, e.g. RequireAPIKey.
+func (h *UpstreamCacheHandler) InitRoutes(r *gin.Engine, middleware ...gin.HandlerFunc) {
+	admin := r.Group("/api/v2/admin", middleware...)
+
+	admin.DELETE("/upstream-cache", h.PurgeUpstreamCache)
+}
+
+// PurgeUpstreamCache godoc
+// @Summary Очистка кэша ответов источников метаданных
+// @Description Удаляются сохранённые ответы, подходящие под все переданные фильтры, без фильтров — все. Удалённые песни при следующем добавлении загружаются из источников заново
+// @Tags Admin
+// @Produce json
+// @Param X-API-Key header string true "Ключ администратора ADMIN_API_KEY"
+// @Param provider query string false "Источник метаданных, например api"
+// @Param group query string false "Исполнитель без учета регистра"
+// @Param song query string false "Название песни без учета регистра"
+// @Param not_found query bool false "Только ответы о том, что песня не найдена"
+// @Param older_than query string false "Только ответы старше, например 24h"
+// @Success 200 {object} response.Envelope{data=model.UpstreamCachePurge}
+// @Failure 400 {string} string "Неверные параметры"
+// @Failure 401 {string} string "Неверный ключ администратора"
+// @Failure 500 {string} string "Ошибка очистки кэша"
+// @Router /api/v2/admin/upstream-cache [delete]
+func (h *UpstreamCacheHandler) PurgeUpstreamCache(c *gin.Context) {
+	filter := model.UpstreamCacheFilter{
+		Provider: c.Query("provider"),
+		Group:    c.Query("group"),
+		Song:     c.Query("song"),
+	}
+
+	if value := c.Query("not_found"); value != "" {
+		notFound, err := strconv.ParseBool(value)
+		if err != nil {
+			h.log.Debugf("Invalid not_found query: %s", value)
+			response.Error(c, http.StatusBadRequest, "invalid not_found")
+			return
+		}
+		filter.NotFoundOnly = notFound
+	}
+
+	if value := c.Query("older_than"); value != "" {
+		olderThan, err := time.ParseDuration(value)
+		if err != nil || olderThan <= 0 {
+			h.log.Debugf("Invalid older_than query: %s", value)
+			response.Error(c, http.StatusBadRequest, "invalid older_than")
+			return
+		}
+		filter.FetchedBefore = time.Now().Add(-olderThan)
+	}
+
+	purge, err := h.service.PurgeUpstreamCache(c, &filter)
+	if err != nil {
+		h.log.Errorf("PurgeUpstreamCache failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to purge upstream cache")
+		return
+	}
+
+	h.log.Infof("PurgeUpstreamCache handler successful response: purged %d responses", purge.Purged)
+	response.Data(c, http.StatusOK, purge)
+}
@@ -0,0 +1,504 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/internal/validation"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// pageParams parses the limit and page query parameters, writing a 400 response on failure.
+func pageParams(c *gin.Context, log *logrus.Logger, defaultLimit int) (limit, page int, ok bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit <= 0 {
+		log.Debugf("Invalid limit query: %s", c.Query("limit"))
+		response.Error(c, http.StatusBadRequest, "invalid limit")
+		return 0, 0, false
+	}
+
+	page, err = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		log.Debugf("Invalid page query: %s", c.Query("page"))
+		response.Error(c, http.StatusBadRequest, "invalid page")
+		return 0, 0, false
+	}
+
+	return limit, page, true
+}
+
+// songsFilterParams parses the song filters of the list and facets endpoints,
+// writing a 400 response on failure. The tag parameter may be repeated.
+func songsFilterParams(c *gin.Context, log *logrus.Logger, req *dto.GetSongsListReq) bool {
+	if song, ok := c.GetQuery("song"); ok {
+		req.Song = &song
+	}
+	if group, ok := c.GetQuery("group"); ok {
+		req.Group = &group
+	}
+	if releaseDate, ok := c.GetQuery("release_date"); ok {
+		req.ReleaseDate = &releaseDate
+	}
+
+	req.Tags = c.QueryArray("tag")
+	req.TagMode = c.DefaultQuery("tag_mode", dto.TagModeAny)
+	if req.TagMode != dto.TagModeAny && req.TagMode != dto.TagModeAll {
+		log.Debugf("Invalid tag_mode query: %s", req.TagMode)
+		response.Error(c, http.StatusBadRequest, "invalid tag_mode")
+		return false
+	}
+
+	return true
+}
+
+// langQuery parses the optional lang query parameter of the lyrics endpoints,
+// writing a 400 response on failure.
+func langQuery(c *gin.Context, log *logrus.Logger) (string, bool) {
+	lang := strings.ToLower(c.Query("lang"))
+	if lang != "" && !validation.IsLanguage(lang) {
+		log.Debugf("Invalid lang query: %s", c.Query("lang"))
+		response.Error(c, http.StatusBadRequest, "invalid lang")
+		return "", false
+	}
+
+	return lang, true
+}
+
+// setContentLanguage reports the language of the returned verses, which is
+// the original one if the requested translation doesn't exist.
+func setContentLanguage(c *gin.Context, verses []*model.Verse) {
+	if len(verses) > 0 && verses[0].Language != "" {
+		c.Header("Content-Language", verses[0].Language)
+	}
+}
+
+func (h *MusicHandler) songIDParam(c *gin.Context) (int, bool) {
+	songID, err := strconv.Atoi(c.Param("songID"))
+	if err != nil || songID <= 0 {
+		h.log.Debugf("Invalid song id: %s", c.Param("songID"))
+		response.Error(c, http.StatusBadRequest, "invalid song id")
+		return 0, false
+	}
+
+	return songID, true
+}
+
+// ListSongsV2 godoc
+// @Summary Список песен с фильтрацией и пагинацией
+// @Tags Songs v2
+// @Produce json
+// @Param song query string false "Фильтр по названию песни без учета регистра, алфавита и диакритики"
+// @Param group query string false "Фильтр по названию исполнителя без учета регистра, алфавита и диакритики"
+// @Param release_date query string false "Фильтр по дате выпуска"
+// @Param tag query []string false "Фильтр по тегам и жанрам, параметр можно повторять" collectionFormat(multi)
+// @Param tag_mode query string false "any — песни хотя бы с одним из тегов, all — со всеми" Enums(any, all) default(any)
+// @Param limit query int false "Количество песен на странице" default(10)
+// @Param page query int false "Номер страницы" default(1)
+// @Success 200 {object} response.Envelope{data=[]model.Song}
+// @Header 200 {string} ETag "Версия списка"
+// @Failure 400 {string} string "Некорректный фильтр или параметры пагинации"
+// @Failure 500 {string} string "Ошибка получения данных"
+// @Router /api/v2/songs [get]
+func (h *MusicHandler) ListSongsV2(c *gin.Context) {
+	var req dto.GetSongsListReq
+
+	if !songsFilterParams(c, h.log, &req) {
+		return
+	}
+
+	limit, page, ok := pageParams(c, h.log, 10)
+	if !ok {
+		return
+	}
+
+	req.Limit = limit
+	req.Offset = (page - 1) * limit
+
+	h.log.Debugf("ListSongsV2 handler request: %+v", req)
+
+	songs, total, err := h.service.ListSongs(c, &req)
+	if err != nil {
+		h.log.Errorf("ListSongsV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get songs")
+		return
+	}
+
+	if songs == nil {
+		songs = []*model.Song{}
+	}
+
+	if setValidators(c, songsListETag(songs, songsPageVariant(&req, page, limit, total)), lastModified(songs...)) {
+		return
+	}
+
+	h.log.Infof("ListSongsV2 handler successful response: %d of %d songs", len(songs), total)
+	response.Page(c, http.StatusOK, songs, page, limit, total)
+}
+
+// CreateSongV2 godoc
+// @Summary Добавление новой песни
+// @Tags Songs v2
+// @Accept json
+// @Produce json
+// @Param song body dto.AddSongReq true "Данные для добавления песни"
+// @Success 201 {object} response.Envelope{data=model.Song}
+// @Header 201 {string} Location "Адрес созданной песни"
+// @Failure 400 {string} string "Неверное тело запроса"
+// @Failure 409 {object} response.ConflictResponse{existing=model.Song} "Песня уже есть в библиотеке"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей"
+// @Failure 500 {string} string "Ошибка добавления песни на сервере"
+// @Router /api/v2/songs [post]
+func (h *MusicHandler) CreateSongV2(c *gin.Context) {
+	var req dto.AddSongReq
+
+	if !bindJSON(c, h.log, &req) {
+		return
+	}
+
+	song, err := h.service.AddSong(c, &req)
+	var duplicate *service.DuplicateSongError
+	if errors.As(err, &duplicate) {
+		h.log.Debugf("CreateSongV2 handler: song already exists with id %d", duplicate.Song.ID)
+		c.Header("Location", fmt.Sprintf("/api/v2/songs/%d", duplicate.Song.ID))
+		response.Conflict(c, "song already exists", duplicate.Song)
+		return
+	}
+	if err != nil {
+		h.log.Errorf("CreateSongV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.log.Infof("CreateSongV2 handler successful response: %+v", song)
+	c.Header("Location", fmt.Sprintf("/api/v2/songs/%d", song.ID))
+	c.Header("ETag", songETag(song))
+	response.Data(c, http.StatusCreated, song)
+}
+
+// GetSongV2 godoc
+// @Summary Получение песни
+// @Tags Songs v2
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Param include query string false "Дополнительные данные: lyrics" Enums(lyrics)
+// @Success 200 {object} response.Envelope{data=model.SongWithLyrics}
+// @Header 200 {string} ETag "Версия песни"
+// @Failure 400 {string} string "Неверный ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 500 {string} string "Ошибка получения песни"
+// @Router /api/v2/songs/{songID} [get]
+func (h *MusicHandler) GetSongV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	withLyrics := false
+	for _, include := range strings.Split(c.Query("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "":
+		case "lyrics":
+			withLyrics = true
+		default:
+			response.Error(c, http.StatusBadRequest, "invalid include")
+			return
+		}
+	}
+
+	song, err := h.service.GetSongWithLyrics(c, songID, withLyrics)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("GetSongV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get song")
+		return
+	}
+
+	if setValidators(c, songVariantETag(&song.Song, includeVariant(withLyrics)), song.UpdatedAt) {
+		return
+	}
+
+	response.Data(c, http.StatusOK, song)
+}
+
+// PatchSongV2 godoc
+// @Summary Частичное изменение песни
+// @Description С Content-Type application/json изменяются только переданные поля, application/merge-patch+json (RFC 7386) и application/json-patch+json (RFC 6902) применяются к документу {"song", "group", "release_date", "link"} текущей песни
+// @Tags Songs v2
+// @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Param song body dto.UpdateSongReq true "Изменяемые поля или патч"
+// @Param If-Match header string false "ETag песни"
+// @Success 200 {object} response.Envelope{data=model.Song}
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {string} string "Неверное тело запроса, документ патча или ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 409 {string} string "Песня с таким названием и исполнителем уже есть"
+// @Failure 412 {string} string "Песня была изменена"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей"
+// @Failure 500 {string} string "Ошибка изменения песни"
+// @Router /api/v2/songs/{songID} [patch]
+func (h *MusicHandler) PatchSongV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType == service.MergePatchContentType || contentType == service.JSONPatchContentType {
+		song, ok := h.patchSong(c, songID, contentType)
+		if !ok {
+			return
+		}
+
+		h.log.Infof("PatchSongV2 handler successful response: %+v", song)
+		c.Header("ETag", songETag(song))
+		response.Data(c, http.StatusOK, song)
+		return
+	}
+
+	var req dto.UpdateSongReq
+
+	if !bindJSON(c, h.log, &req) {
+		return
+	}
+
+	version, ok := h.ifMatchVersion(c, songID)
+	if !ok {
+		return
+	}
+	req.Version = version
+
+	_, err := h.service.GetSong(c, songID)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("PatchSongV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to update song")
+		return
+	}
+
+	err = h.service.UpdateSong(c, songID, &req)
+	if errors.Is(err, repository.ErrVersionMismatch) {
+		response.Error(c, http.StatusPreconditionFailed, "song was modified")
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicateSong) {
+		response.Error(c, http.StatusConflict, "song already exists")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("PatchSongV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to update song")
+		return
+	}
+
+	song, err := h.service.GetSong(c, songID)
+	if err != nil {
+		h.log.Errorf("PatchSongV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get updated song")
+		return
+	}
+
+	h.log.Infof("PatchSongV2 handler successful response: %+v", song)
+	c.Header("ETag", songETag(song))
+	response.Data(c, http.StatusOK, song)
+}
+
+// DeleteSongV2 godoc
+// @Summary Удаление песни
+// @Tags Songs v2
+// @Param songID path int true "ID песни"
+// @Success 204 "Песня удалена"
+// @Failure 400 {string} string "Неверный ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 500 {string} string "Ошибка удаления песни"
+// @Router /api/v2/songs/{songID} [delete]
+func (h *MusicHandler) DeleteSongV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	_, err := h.service.GetSong(c, songID)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("DeleteSongV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to delete song")
+		return
+	}
+
+	err = h.service.DeleteSong(c, songID)
+	if err != nil {
+		h.log.Errorf("DeleteSongV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to delete song")
+		return
+	}
+
+	h.log.Infof("DeleteSongV2 handler: deleted song id %d", songID)
+	c.Status(http.StatusNoContent)
+}
+
+// ListVersesV2 godoc
+// @Summary Куплеты песни с пагинацией
+// @Description Без перевода на язык lang возвращается оригинальный текст
+// @Tags Songs v2
+// @Produce json
+// @Param songID path int true "ID песни"
+// @Param lang query string false "Язык перевода, например en или pt-br"
+// @Param limit query int false "Количество куплетов на странице" default(3)
+// @Param page query int false "Номер страницы" default(1)
+// @Success 200 {object} response.Envelope{data=[]model.Verse}
+// @Header 200 {string} Content-Language "Язык куплетов, если он известен"
+// @Failure 400 {string} string "Неверный ID песни, язык или параметры пагинации"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 500 {string} string "Ошибка получения текста песни"
+// @Router /api/v2/songs/{songID}/verses [get]
+func (h *MusicHandler) ListVersesV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	lang, ok := langQuery(c, h.log)
+	if !ok {
+		return
+	}
+
+	limit, page, ok := pageParams(c, h.log, 3)
+	if !ok {
+		return
+	}
+
+	verses, total, err := h.service.ListVerses(c, songID, lang, limit, (page-1)*limit)
+	if errors.Is(err, repository.ErrSongNotFound) {
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("ListVersesV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get verses")
+		return
+	}
+
+	if verses == nil {
+		verses = []*model.Verse{}
+	}
+
+	setContentLanguage(c, verses)
+	response.Page(c, http.StatusOK, verses, page, limit, total)
+}
+
+// FindDuplicatesV2 godoc
+// @Summary Поиск дубликатов песен
+// @Description Пары песен с похожими исполнителем и названием по триграммному сходству, самые похожие первыми
+// @Tags Songs v2
+// @Produce json
+// @Param threshold query number false "Минимальное сходство от 0 до 1" default(0.5)
+// @Param limit query int false "Количество пар" default(20)
+// @Success 200 {object} response.Envelope{data=[]model.DuplicateSongs}
+// @Failure 400 {string} string "Некорректные параметры"
+// @Failure 500 {string} string "Ошибка поиска дубликатов"
+// @Router /api/v2/songs/duplicates [get]
+func (h *MusicHandler) FindDuplicatesV2(c *gin.Context) {
+	threshold, err := strconv.ParseFloat(c.DefaultQuery("threshold", "0.5"), 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		h.log.Debugf("Invalid threshold query: %s", c.Query("threshold"))
+		response.Error(c, http.StatusBadRequest, "invalid threshold")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		h.log.Debugf("Invalid limit query: %s", c.Query("limit"))
+		response.Error(c, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	duplicates, err := h.service.FindDuplicates(c, threshold, limit)
+	if err != nil {
+		h.log.Errorf("FindDuplicatesV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to find duplicates")
+		return
+	}
+
+	if duplicates == nil {
+		duplicates = []*model.DuplicateSongs{}
+	}
+
+	response.Data(c, http.StatusOK, duplicates)
+}
+
+// MergeSongsV2 godoc
+// @Summary Объединение песен
+// @Description Песня source_id объединяется с песней из пути и удаляется. Название и исполнитель не меняются, пустые дата выпуска и ссылка, а также куплеты, если у песни их нет, берутся из source_id
+// @Tags Songs v2
+// @Accept json
+// @Produce json
+// @Param songID path int true "ID песни, которая остается"
+// @Param merge body dto.MergeSongsReq true "Объединяемая песня"
+// @Param If-Match header string false "ETag песни из пути"
+// @Success 200 {object} response.Envelope{data=model.Song}
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {string} string "Неверное тело запроса или ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 412 {string} string "Песня была изменена"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей"
+// @Failure 500 {string} string "Ошибка объединения песен"
+// @Router /api/v2/songs/{songID}/merge [post]
+func (h *MusicHandler) MergeSongsV2(c *gin.Context) {
+	songID, ok := h.songIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.MergeSongsReq
+
+	if !bindJSON(c, h.log, &req) {
+		return
+	}
+
+	version, ok := h.ifMatchVersion(c, songID)
+	if !ok {
+		return
+	}
+
+	song, err := h.service.MergeSongs(c, songID, req.SourceID, version)
+	switch {
+	case errors.Is(err, service.ErrSelfMerge):
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, repository.ErrSongNotFound):
+		response.Error(c, http.StatusNotFound, "song not found")
+		return
+	case errors.Is(err, repository.ErrVersionMismatch):
+		response.Error(c, http.StatusPreconditionFailed, "song was modified")
+		return
+	case err != nil:
+		h.log.Errorf("MergeSongsV2 failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to merge songs")
+		return
+	}
+
+	h.log.Infof("MergeSongsV2 handler: merged song id %d into %d", req.SourceID, songID)
+	c.Header("ETag", songETag(song))
+	response.Data(c, http.StatusOK, song)
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/internal/validation"
+	webhookpkg "github.com/aaanger/music-library/internal/webhook"
+	"github.com/aaanger/music-library/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type WebhookHandler struct {
+	service service.IWebhookService
+	log     *logrus.Logger
+}
+
+func NewWebhookHandler(service service.IWebhookService, log *logrus.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+		log:     log,
+	}
+}
+
+// InitRoutes registers the webhook endpoints on the router returned by
+// MusicHandler.InitRoutes, behind the middleware, e.g. RequireAPIKey.
+func (h *WebhookHandler) InitRoutes(r *gin.Engine, middleware ...gin.HandlerFunc) {
+	webhooks := r.Group("/api/v2/webhooks", middleware...)
+
+	webhooks.POST("", h.AddWebhook)
+	webhooks.GET("", h.ListWebhooks)
+	webhooks.DELETE("/:webhookID", h.DeleteWebhook)
+	webhooks.GET("/dead-letters", h.ListDeadLetters)
+	webhooks.POST("/deliveries/:deliveryID/retry", h.RetryDelivery)
+}
+
+// AddWebhook godoc
+// @Summary Подписка на события каталога
+// @Description События song.created, song.updated, song.deleted и song.enriched отправляются POST запросом на url.
+// @Description Тело подписывается HMAC-SHA256 секретом: заголовок X-Webhook-Signature содержит
+// @Description sha256=hex(HMAC(secret, X-Webhook-Timestamp + "." + тело)).
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "Ключ администратора ADMIN_API_KEY"
+// @Param webhook body dto.AddWebhookReq true "Адрес, секрет и события подписки, без событий приходят все"
+// @Success 201 {object} response.Envelope{data=model.Webhook} "Подписка вместе с секретом, сгенерированным если он не был передан"
+// @Failure 400 {string} string "Неверное тело запроса"
+// @Failure 401 {string} string "Неверный ключ администратора"
+// @Failure 422 {object} response.ValidationErrorResponse{fields=[]validation.FieldError} "Ошибки валидации полей, url указывает на loopback, частный или link-local адрес"
+// @Failure 500 {string} string "Ошибка добавления подписки"
+// @Router /api/v2/webhooks [post]
+func (h *WebhookHandler) AddWebhook(c *gin.Context) {
+	var req dto.AddWebhookReq
+
+	if !bindJSON(c, h.log, &req) {
+		return
+	}
+
+	webhook, err := h.service.AddWebhook(c, &req)
+	if errors.Is(err, webhookpkg.ErrPrivateTarget) {
+		h.log.Debugf("AddWebhook handler: %s", err)
+		response.ValidationError(c, []validation.FieldError{{
+			Field:   "url",
+			Code:    "public_url",
+			Message: "must resolve to public addresses",
+		}})
+		return
+	}
+	if err != nil {
+		h.log.Errorf("AddWebhook failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to add webhook")
+		return
+	}
+
+	h.log.Infof("AddWebhook handler successful response: webhook %d", webhook.ID)
+	response.Data(c, http.StatusCreated, webhook)
+}
+
+// ListWebhooks godoc
+// @Summary Список подписок
+// @Tags Webhooks
+// @Produce json
+// @Param X-API-Key header string true "Ключ администратора ADMIN_API_KEY"
+// @Success 200 {object} response.Envelope{data=[]model.Webhook} "Подписки без секретов"
+// @Failure 401 {string} string "Неверный ключ администратора"
+// @Failure 500 {string} string "Ошибка получения подписок"
+// @Router /api/v2/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.service.ListWebhooks(c)
+	if err != nil {
+		h.log.Errorf("ListWebhooks failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get webhooks")
+		return
+	}
+
+	if webhooks == nil {
+		webhooks = []*model.Webhook{}
+	}
+
+	response.Data(c, http.StatusOK, webhooks)
+}
+
+// DeleteWebhook godoc
+// @Summary Удаление подписки
+// @Description Недоставленные события подписки удаляются вместе с ней.
+// @Tags Webhooks
+// @Param X-API-Key header string true "Ключ администратора ADMIN_API_KEY"
+// @Param webhookID path int true "ID подписки"
+// @Success 204 "Подписка удалена"
+// @Failure 400 {string} string "Неверный ID подписки"
+// @Failure 401 {string} string "Неверный ключ администратора"
+// @Failure 404 {string} string "Подписка не найдена"
+// @Failure 500 {string} string "Ошибка удаления подписки"
+// @Router /api/v2/webhooks/{webhookID} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhookID, err := strconv.Atoi(c.Param("webhookID"))
+	if err != nil || webhookID <= 0 {
+		h.log.Debugf("Invalid webhook id: %s", c.Param("webhookID"))
+		response.Error(c, http.StatusBadRequest, "invalid webhook id")
+		return
+	}
+
+	err = h.service.DeleteWebhook(c, webhookID)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		response.Error(c, http.StatusNotFound, "webhook not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("DeleteWebhook failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeadLetters godoc
+// @Summary Недоставленные события
+// @Description Доставки, исчерпавшие все попытки, с текстом последней ошибки.
+// @Tags Webhooks
+// @Produce json
+// @Param X-API-Key header string true "Ключ администратора ADMIN_API_KEY"
+// @Param limit query int false "Количество доставок на странице" default(10)
+// @Param page query int false "Номер страницы" default(1)
+// @Success 200 {object} response.Envelope{data=[]model.WebhookDelivery}
+// @Failure 400 {string} string "Некорректные параметры пагинации"
+// @Failure 401 {string} string "Неверный ключ администратора"
+// @Failure 500 {string} string "Ошибка получения доставок"
+// @Router /api/v2/webhooks/dead-letters [get]
+func (h *WebhookHandler) ListDeadLetters(c *gin.Context) {
+	limit, page, ok := pageParams(c, h.log, 10)
+	if !ok {
+		return
+	}
+
+	deliveries, total, err := h.service.ListDeadLetters(c, limit, (page-1)*limit)
+	if err != nil {
+		h.log.Errorf("ListDeadLetters failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to get dead letters")
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []*model.WebhookDelivery{}
+	}
+
+	response.Page(c, http.StatusOK, deliveries, page, limit, total)
+}
+
+// RetryDelivery godoc
+// @Summary Повторная доставка события
+// @Description Возвращает недоставленное событие в очередь с новым набором попыток.
+// @Tags Webhooks
+// @Param X-API-Key header string true "Ключ администратора ADMIN_API_KEY"
+// @Param deliveryID path int true "ID доставки"
+// @Success 204 "Доставка поставлена в очередь"
+// @Failure 400 {string} string "Неверный ID доставки"
+// @Failure 401 {string} string "Неверный ключ администратора"
+// @Failure 404 {string} string "Недоставленное событие не найдено"
+// @Failure 500 {string} string "Ошибка постановки в очередь"
+// @Router /api/v2/webhooks/deliveries/{deliveryID}/retry [post]
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryID"), 10, 64)
+	if err != nil || deliveryID <= 0 {
+		h.log.Debugf("Invalid delivery id: %s", c.Param("deliveryID"))
+		response.Error(c, http.StatusBadRequest, "invalid delivery id")
+		return
+	}
+
+	err = h.service.RetryDelivery(c, deliveryID)
+	if errors.Is(err, repository.ErrDeliveryNotFound) {
+		response.Error(c, http.StatusNotFound, "dead delivery not found")
+		return
+	}
+	if err != nil {
+		h.log.Errorf("RetryDelivery failure: %s", err)
+		response.Error(c, http.StatusInternalServerError, "failed to retry delivery")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,179 @@
+// Package linkcheck periodically checks the media links of the songs with HEAD
+// requests and marks the ones which are gone as dead.
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/pkg/medialink"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Interval between checks, the checker isn't run if it's not positive.
+	Interval time.Duration
+	// MaxAge after which a link is checked again, 24h by default.
+	MaxAge time.Duration
+	// BatchSize is the maximum number of links checked per run, 50 by default.
+	BatchSize int
+	// Timeout of a single request, 10s by default.
+	Timeout time.Duration
+	// Domains is the allowlist of the hosts which are requested, links and
+	// redirects to other hosts are never followed. The domains of the known
+	// providers by default.
+	Domains []string
+}
+
+type Checker struct {
+	repo       repository.IMusicRepository
+	cfg        Config
+	httpClient *http.Client
+	log        *logrus.Logger
+}
+
+func NewChecker(repo repository.IMusicRepository, cfg Config, log *logrus.Logger) *Checker {
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 24 * time.Hour
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	domains := make([]string, 0, len(cfg.Domains))
+	for _, domain := range cfg.Domains {
+		domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) == 0 {
+		domains = medialink.Domains()
+	}
+	cfg.Domains = domains
+
+	c := &Checker{
+		repo: repo,
+		cfg:  cfg,
+		log:  log,
+	}
+	c.httpClient = &http.Client{
+		Timeout:       cfg.Timeout,
+		CheckRedirect: c.checkRedirect,
+	}
+
+	return c
+}
+
+// Run checks links every Interval until ctx is cancelled.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := c.Check(ctx)
+			if err != nil && ctx.Err() == nil {
+				c.log.Errorf("Link checker error: %s", err)
+			}
+		}
+	}
+}
+
+// Check requests the links on the allowed domains which haven't been checked
+// within MaxAge, concurrently, and stores their status.
+func (c *Checker) Check(ctx context.Context) error {
+	links, err := c.repo.LinksToCheck(ctx, c.cfg.Domains, time.Now().Add(-c.cfg.MaxAge), c.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, link := range links {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.checkLink(ctx, link)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (c *Checker) checkLink(ctx context.Context, link *model.Link) {
+	status, err := c.status(ctx, link)
+	// Shutting down, the link is checked on the next start.
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		c.log.Debugf("Link %d of song %d (%s) check failed: %s", link.ID, link.SongID, link.URL, err)
+	}
+	if status == model.LinkStatusDead && link.Status != model.LinkStatusDead {
+		c.log.Warnf("Link %d of song %d (%s) is dead", link.ID, link.SongID, link.URL)
+	}
+
+	err = c.repo.SetLinkStatus(ctx, link.ID, status, time.Now())
+	if err != nil {
+		c.log.Errorf("Link checker error: %s", err)
+	}
+}
+
+// status requests the link and returns its new status. Only missing pages and
+// hosts mark a link dead, other failures keep its current status, a service
+// may well be down for a while or not answer HEAD requests.
+func (c *Checker) status(ctx context.Context, link *model.Link) (string, error) {
+	if !medialink.Allowed(medialink.Host(link.URL), c.cfg.Domains) {
+		return link.Status, fmt.Errorf("host of %s isn't allowed", link.URL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link.URL, nil)
+	if err != nil {
+		return model.LinkStatusDead, err
+	}
+	req.Header.Set("User-Agent", "music-library-linkcheck")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return model.LinkStatusDead, err
+		}
+		return link.Status, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return model.LinkStatusOK, nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return model.LinkStatusDead, nil
+	}
+	return link.Status, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// checkRedirect follows redirects within the allowlist only, the response of
+// a redirect elsewhere is returned as is.
+func (c *Checker) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !medialink.Allowed(medialink.Host(req.URL.String()), c.cfg.Domains) {
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
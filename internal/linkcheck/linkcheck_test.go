@@ -0,0 +1,282 @@
+package linkcheck
+
+import (
+	"context"
+	"fmt"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/pkg/medialink"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// linkServer answers HEAD requests on 127.0.0.1 and counts them per path.
+type linkServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newLinkServer(t *testing.T) *linkServer {
+	t.Helper()
+
+	s := &linkServer{requests: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		s.mu.Unlock()
+
+		if r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/no-content":
+			w.WriteHeader(http.StatusNoContent)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/redirect-missing":
+			http.Redirect(w, r, "/missing", http.StatusMovedPermanently)
+		case "/redirect-away":
+			// localhost isn't on the allowlist, so the redirect isn't followed.
+			http.Redirect(w, r, strings.Replace(s.URL, "127.0.0.1", "localhost", 1)+"/ok", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *linkServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[path]
+}
+
+// addLink adds a link to a new song with the given current status, a link with
+// a status was last checked two days ago.
+func addLink(t *testing.T, repo *repository.MemoryMusicRepository, rawURL, status string) *model.Link {
+	t.Helper()
+	ctx := context.Background()
+
+	song, err := repo.AddSong(ctx, &model.Song{Group: "Muse", Song: rawURL, ReleaseDate: "16.07.2006"})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+	link, err := repo.AddSongLink(ctx, &model.Link{SongID: song.ID, Provider: medialink.Other, URL: rawURL})
+	if err != nil {
+		t.Fatalf("AddSongLink: %s", err)
+	}
+	if status != "" {
+		err = repo.SetLinkStatus(ctx, link.ID, status, time.Now().Add(-48*time.Hour))
+		if err != nil {
+			t.Fatalf("SetLinkStatus: %s", err)
+		}
+	}
+	return link
+}
+
+func getLink(t *testing.T, repo *repository.MemoryMusicRepository, link *model.Link) *model.Link {
+	t.Helper()
+
+	links, err := repo.GetSongLinks(context.Background(), link.SongID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	if len(links) != 1 {
+		t.Fatalf("song %d has %d links, want 1", link.SongID, len(links))
+	}
+	return links[0]
+}
+
+func TestCheck(t *testing.T) {
+	server := newLinkServer(t)
+	away := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	tests := []struct {
+		name    string
+		url     string
+		current string
+		want    string
+		// checked is false for links which aren't on the allowlist.
+		checked bool
+	}{
+		{name: "ok", url: server.URL + "/ok", want: model.LinkStatusOK, checked: true},
+		{name: "no content", url: server.URL + "/no-content", current: model.LinkStatusDead, want: model.LinkStatusOK, checked: true},
+		{name: "not found", url: server.URL + "/missing", current: model.LinkStatusOK, want: model.LinkStatusDead, checked: true},
+		{name: "gone", url: server.URL + "/gone", want: model.LinkStatusDead, checked: true},
+		{name: "server error keeps ok", url: server.URL + "/error", current: model.LinkStatusOK, want: model.LinkStatusOK, checked: true},
+		{name: "server error keeps dead", url: server.URL + "/error", current: model.LinkStatusDead, want: model.LinkStatusDead, checked: true},
+		{name: "server error keeps unknown", url: server.URL + "/error", want: model.LinkStatusUnknown, checked: true},
+		{name: "redirect followed", url: server.URL + "/redirect", want: model.LinkStatusOK, checked: true},
+		{name: "redirect to a missing page", url: server.URL + "/redirect-missing", current: model.LinkStatusOK, want: model.LinkStatusDead, checked: true},
+		{name: "redirect to another host", url: server.URL + "/redirect-away", current: model.LinkStatusDead, want: model.LinkStatusDead, checked: true},
+		{name: "host not allowed", url: away + "/ok", current: model.LinkStatusDead, want: model.LinkStatusDead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryMusicRepository(testLogger())
+			link := addLink(t, repo, tt.url, tt.current)
+			before := getLink(t, repo, link)
+
+			start := time.Now()
+			c := NewChecker(repo, Config{Domains: []string{"127.0.0.1"}}, testLogger())
+			if err := c.Check(context.Background()); err != nil {
+				t.Fatalf("Check: %s", err)
+			}
+
+			got := getLink(t, repo, link)
+			if got.Status != tt.want {
+				t.Fatalf("status = %s, want %s", got.Status, tt.want)
+			}
+			if !tt.checked {
+				if fmt.Sprint(got.CheckedAt) != fmt.Sprint(before.CheckedAt) {
+					t.Fatalf("CheckedAt = %v, want it unchanged %v", got.CheckedAt, before.CheckedAt)
+				}
+				return
+			}
+			if got.CheckedAt == nil || got.CheckedAt.Before(start.Add(-time.Second)) {
+				t.Fatalf("CheckedAt = %v, want the time of the check", got.CheckedAt)
+			}
+		})
+	}
+}
+
+func TestCheckMaxAge(t *testing.T) {
+	server := newLinkServer(t)
+	repo := repository.NewMemoryMusicRepository(testLogger())
+	fresh := addLink(t, repo, server.URL+"/ok", "")
+	stale := addLink(t, repo, server.URL+"/no-content", model.LinkStatusOK)
+
+	c := NewChecker(repo, Config{MaxAge: time.Hour, BatchSize: 1, Domains: []string{"127.0.0.1"}}, testLogger())
+
+	tests := []struct {
+		name string
+		// requests are the expected total requests of /ok and /no-content.
+		requests [2]int
+	}{
+		{name: "never checked link first", requests: [2]int{1, 0}},
+		{name: "then the stale one", requests: [2]int{1, 1}},
+		{name: "both checked within MaxAge", requests: [2]int{1, 1}},
+	}
+
+	// The cases run in order, later ones see the changes of earlier ones.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.Check(context.Background()); err != nil {
+				t.Fatalf("Check: %s", err)
+			}
+
+			got := [2]int{server.count("/ok"), server.count("/no-content")}
+			if got != tt.requests {
+				t.Fatalf("requests = %v, want %v", got, tt.requests)
+			}
+		})
+	}
+
+	for _, link := range []*model.Link{fresh, stale} {
+		if got := getLink(t, repo, link); got.Status != model.LinkStatusOK {
+			t.Fatalf("link %s status = %s, want %s", link.URL, got.Status, model.LinkStatusOK)
+		}
+	}
+}
+
+func TestNewChecker(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want Config
+	}{
+		{
+			name: "defaults",
+			cfg:  Config{},
+			want: Config{MaxAge: 24 * time.Hour, BatchSize: 50, Timeout: 10 * time.Second, Domains: medialink.Domains()},
+		},
+		{
+			name: "negative values",
+			cfg:  Config{Interval: time.Minute, MaxAge: -time.Hour, BatchSize: -1, Timeout: -time.Second},
+			want: Config{Interval: time.Minute, MaxAge: 24 * time.Hour, BatchSize: 50, Timeout: 10 * time.Second, Domains: medialink.Domains()},
+		},
+		{
+			name: "custom",
+			cfg:  Config{MaxAge: time.Hour, BatchSize: 5, Timeout: time.Second, Domains: []string{" YouTube.com ", ".bandcamp.com.", "", " . "}},
+			want: Config{MaxAge: time.Hour, BatchSize: 5, Timeout: time.Second, Domains: []string{"youtube.com", "bandcamp.com"}},
+		},
+		{
+			name: "blank domains",
+			cfg:  Config{Domains: []string{"", "  "}},
+			want: Config{MaxAge: 24 * time.Hour, BatchSize: 50, Timeout: 10 * time.Second, Domains: medialink.Domains()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(nil, tt.cfg, testLogger())
+			if fmt.Sprintf("%+v", c.cfg) != fmt.Sprintf("%+v", tt.want) {
+				t.Fatalf("cfg = %+v, want %+v", c.cfg, tt.want)
+			}
+			if c.httpClient.Timeout != tt.want.Timeout {
+				t.Fatalf("client timeout = %s, want %s", c.httpClient.Timeout, tt.want.Timeout)
+			}
+		})
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	c := NewChecker(nil, Config{Domains: []string{"youtube.com"}}, testLogger())
+
+	tests := []struct {
+		name string
+		url  string
+		via  int
+		want error
+	}{
+		{name: "allowed", url: "https://www.youtube.com/watch?v=1", via: 1},
+		{name: "other host", url: "https://example.com/", via: 1, want: http.ErrUseLastResponse},
+		{name: "lookalike host", url: "https://notyoutube.com/", via: 1, want: http.ErrUseLastResponse},
+		{name: "too many redirects", url: "https://youtube.com/", via: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("parse %s: %s", tt.url, err)
+			}
+			err = c.checkRedirect(&http.Request{URL: u}, make([]*http.Request, tt.via))
+			switch {
+			case tt.via >= 10:
+				if err == nil || err == http.ErrUseLastResponse {
+					t.Fatalf("checkRedirect = %v, want the redirect limit error", err)
+				}
+			case err != tt.want:
+				t.Fatalf("checkRedirect = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"net/http"
+	"net/url"
+)
+
+// APIProvider fetches song details from the upstream music API with its
+// /info?group=&song= endpoint.
+type APIProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewAPIProvider(baseURL string, httpClient *http.Client) *APIProvider {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &APIProvider{
+		baseURL:    baseURL,
+		httpClient: httpClient,
+	}
+}
+
+func (p *APIProvider) Name() string {
+	return "api"
+}
+
+func (p *APIProvider) Fetch(ctx context.Context, group, song string) (*dto.SongDetail, error) {
+	urlAPI := fmt.Sprintf("%s/info?group=%s&song=%s", p.baseURL, url.QueryEscape(group), url.QueryEscape(song))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlAPI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch song from api error, status: %d", res.StatusCode)
+	}
+
+	var songDetail dto.SongDetail
+
+	err = json.NewDecoder(res.Body).Decode(&songDetail)
+	if err != nil {
+		return nil, fmt.Errorf("decoding response from api: %w", err)
+	}
+
+	return &songDetail, nil
+}
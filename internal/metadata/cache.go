@@ -0,0 +1,132 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// DefaultNotFoundTTL is how long CachedProvider remembers that the provider
+// doesn't know a song.
+const DefaultNotFoundTTL = time.Hour
+
+type skipCacheKey struct{}
+
+// SkipCache returns a context which makes CachedProvider ask the provider even
+// if the response is cached and not fall back to stale responses, for callers
+// which want fresh details. The response is cached all the same.
+func SkipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+func skipCache(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipCacheKey{}).(bool)
+	return skip
+}
+
+// CachedProvider caches the responses of another provider in the repository,
+// songs it knows for ttl and songs it doesn't for notFoundTTL. If the provider
+// fails, e.g. it is unavailable, an expired response is returned instead.
+// Cache errors are logged and don't fail lookups.
+type CachedProvider struct {
+	provider    Provider
+	repo        repository.IUpstreamCacheRepository
+	ttl         time.Duration
+	notFoundTTL time.Duration
+	log         *logrus.Logger
+}
+
+func NewCachedProvider(provider Provider, repo repository.IUpstreamCacheRepository, ttl, notFoundTTL time.Duration, log *logrus.Logger) *CachedProvider {
+	if notFoundTTL == 0 {
+		notFoundTTL = DefaultNotFoundTTL
+	}
+
+	return &CachedProvider{
+		provider:    provider,
+		repo:        repo,
+		ttl:         ttl,
+		notFoundTTL: notFoundTTL,
+		log:         log,
+	}
+}
+
+func (p *CachedProvider) Name() string {
+	return p.provider.Name()
+}
+
+func (p *CachedProvider) Fetch(ctx context.Context, group, song string) (*dto.SongDetail, error) {
+	name := p.provider.Name()
+
+	cached, err := p.repo.GetUpstreamResponse(ctx, name, group, song)
+	if err != nil && !errors.Is(err, repository.ErrUpstreamResponseNotFound) {
+		p.log.Warnf("Metadata cache error for %s, group=%s, song=%s: %s", name, group, song, err)
+	}
+	if err != nil || skipCache(ctx) {
+		cached = nil
+	}
+
+	if cached != nil && time.Since(cached.FetchedAt) < p.expiresAfter(cached) {
+		p.log.Debugf("Metadata cache hit for %s, group=%s, song=%s", name, group, song)
+		return cachedDetails(cached)
+	}
+
+	details, err := p.provider.Fetch(ctx, group, song)
+	switch {
+	case err == nil:
+		p.put(ctx, &model.UpstreamResponse{
+			Provider:    name,
+			Group:       group,
+			Song:        song,
+			ReleaseDate: details.ReleaseDate,
+			Text:        details.Text,
+			Link:        details.Link,
+			FetchedAt:   time.Now(),
+		})
+		return details, nil
+	case errors.Is(err, ErrNotFound):
+		p.put(ctx, &model.UpstreamResponse{
+			Provider:  name,
+			Group:     group,
+			Song:      song,
+			NotFound:  true,
+			FetchedAt: time.Now(),
+		})
+		return nil, err
+	case cached != nil && ctx.Err() == nil:
+		p.log.Warnf("Metadata provider %s failed, using the response for group=%s, song=%s cached at %s: %s",
+			name, group, song, cached.FetchedAt.Format(time.RFC3339), err)
+		return cachedDetails(cached)
+	default:
+		return nil, err
+	}
+}
+
+func (p *CachedProvider) expiresAfter(response *model.UpstreamResponse) time.Duration {
+	if response.NotFound {
+		return p.notFoundTTL
+	}
+	return p.ttl
+}
+
+func (p *CachedProvider) put(ctx context.Context, response *model.UpstreamResponse) {
+	err := p.repo.PutUpstreamResponse(ctx, response)
+	if err != nil {
+		p.log.Warnf("Metadata cache error for %s, group=%s, song=%s: %s", response.Provider, response.Group, response.Song, err)
+	}
+}
+
+func cachedDetails(response *model.UpstreamResponse) (*dto.SongDetail, error) {
+	if response.NotFound {
+		return nil, ErrNotFound
+	}
+
+	return &dto.SongDetail{
+		ReleaseDate: response.ReleaseDate,
+		Text:        response.Text,
+		Link:        response.Link,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/pkg/translit"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// FileProvider looks songs up in the JSON and YAML files of a directory.
+// A file holds a single song or a list of songs with the group and song keys
+// and the releaseDate, text and link keys of the upstream API responses.
+// Songs are matched by their transliterated group and title, like the song
+// filters do. The files are read on every lookup, so edits apply right away.
+type FileProvider struct {
+	fsys fs.FS
+}
+
+type fileSong struct {
+	Group       string `json:"group" yaml:"group"`
+	Song        string `json:"song" yaml:"song"`
+	ReleaseDate string `json:"releaseDate" yaml:"releaseDate"`
+	Text        string `json:"text" yaml:"text"`
+	Link        string `json:"link" yaml:"link"`
+}
+
+func NewFileProvider(dir string) *FileProvider {
+	return NewFSProvider(os.DirFS(dir))
+}
+
+// NewFSProvider returns a FileProvider reading the files in the root of fsys,
+// e.g. embedded ones.
+func NewFSProvider(fsys fs.FS) *FileProvider {
+	return &FileProvider{fsys: fsys}
+}
+
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+func (p *FileProvider) Fetch(ctx context.Context, group, song string) (*dto.SongDetail, error) {
+	entries, err := fs.ReadDir(p.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		switch strings.ToLower(path.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+
+	group, song = translit.Fold(group), translit.Fold(song)
+
+	for _, name := range names {
+		songs, err := readSongs(p.fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range songs {
+			if translit.Fold(s.Group) == group && translit.Fold(s.Song) == song {
+				return &dto.SongDetail{
+					ReleaseDate: s.ReleaseDate,
+					Text:        s.Text,
+					Link:        s.Link,
+				}, nil
+			}
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func readSongs(fsys fs.FS, name string) ([]fileSong, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var unmarshal func([]byte, any) error = yaml.Unmarshal
+	if strings.ToLower(path.Ext(name)) == ".json" {
+		unmarshal = json.Unmarshal
+	}
+
+	var songs []fileSong
+	err = unmarshal(data, &songs)
+	if err == nil {
+		return songs, nil
+	}
+
+	var s fileSong
+	if unmarshal(data, &s) == nil {
+		return []fileSong{s}, nil
+	}
+
+	return nil, fmt.Errorf("reading %s: %w", name, err)
+}
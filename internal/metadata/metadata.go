@@ -0,0 +1,207 @@
+// Package metadata looks up the details of songs, the release date, lyrics
+// and link, in one or more sources and merges them.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/sirupsen/logrus"
+	"sort"
+)
+
+// ErrNotFound is returned by providers which don't know the song, and by
+// Registry.Fetch if none of the providers does.
+var ErrNotFound = errors.New("song not found in metadata providers")
+
+// Provider is a source of song details. Fields it doesn't know are left empty.
+type Provider interface {
+	// Name identifies the provider in merge rules and logs.
+	Name() string
+	Fetch(ctx context.Context, group, song string) (*dto.SongDetail, error)
+}
+
+// The fields of dto.SongDetail merge rules are set for.
+const (
+	FieldReleaseDate = "release_date"
+	FieldText        = "text"
+	FieldLink        = "link"
+)
+
+var fields = []string{FieldReleaseDate, FieldText, FieldLink}
+
+type registered struct {
+	provider Provider
+	priority int
+}
+
+// Registry queries the registered providers in priority order. Every field is
+// taken from the first provider which has it, merge rules restrict a field to
+// some of the providers and change their order.
+type Registry struct {
+	providers []registered
+	rules     map[string][]string
+	log       *logrus.Logger
+}
+
+func NewRegistry(log *logrus.Logger) *Registry {
+	return &Registry{
+		rules: make(map[string][]string),
+		log:   log,
+	}
+}
+
+// Register adds the provider, providers with a lower priority are queried
+// first and ones with the same priority in the order they were added.
+func (r *Registry) Register(provider Provider, priority int) {
+	r.providers = append(r.providers, registered{provider: provider, priority: priority})
+	sort.SliceStable(r.providers, func(i, j int) bool {
+		return r.providers[i].priority < r.providers[j].priority
+	})
+}
+
+// Providers returns the names of the registered providers in priority order.
+func (r *Registry) Providers() []string {
+	names := make([]string, 0, len(r.providers))
+	for _, p := range r.providers {
+		names = append(names, p.provider.Name())
+	}
+	return names
+}
+
+// SetRule takes the field only from the named providers, in the given order
+// rather than by their priority. No providers remove the rule. The providers
+// must be registered before.
+func (r *Registry) SetRule(field string, providers ...string) error {
+	if !isField(field) {
+		return fmt.Errorf("unknown field %q", field)
+	}
+	for _, name := range providers {
+		if !r.registered(name) {
+			return fmt.Errorf("%s rule: unknown provider %q", field, name)
+		}
+	}
+
+	if len(providers) == 0 {
+		delete(r.rules, field)
+		return nil
+	}
+	r.rules[field] = providers
+	return nil
+}
+
+// Fetch merges the details of the song from the providers. The providers are
+// queried until every field is filled in, the ones without a field still
+// missing are skipped. Errors of single providers are logged, an error is
+// returned only if no provider has found the song.
+func (r *Registry) Fetch(ctx context.Context, group, song string) (*dto.SongDetail, error) {
+	found := make(map[string]*dto.SongDetail)
+	var lastErr error
+
+	for _, p := range r.providers {
+		name := p.provider.Name()
+		if !r.needs(name, found) {
+			continue
+		}
+
+		details, err := p.provider.Fetch(ctx, group, song)
+		if errors.Is(err, ErrNotFound) {
+			r.log.Debugf("Metadata provider %s: group=%s, song=%s not found", name, group, song)
+			continue
+		}
+		if err != nil {
+			r.log.Warnf("Metadata provider %s error for group=%s, song=%s: %s", name, group, song, err)
+			lastErr = err
+			continue
+		}
+
+		found[name] = details
+	}
+
+	if len(found) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, ErrNotFound
+	}
+
+	merged := &dto.SongDetail{}
+	for _, field := range fields {
+		for _, name := range r.order(field) {
+			details, ok := found[name]
+			if ok && value(details, field) != "" {
+				setValue(merged, field, value(details, field))
+				r.log.Debugf("Metadata: %s of group=%s, song=%s from %s", field, group, song, name)
+				break
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// order returns the names of the providers the field is taken from.
+func (r *Registry) order(field string) []string {
+	if names, ok := r.rules[field]; ok {
+		return names
+	}
+	return r.Providers()
+}
+
+// needs reports whether the provider may fill in one of the fields the found
+// details don't have yet.
+func (r *Registry) needs(provider string, found map[string]*dto.SongDetail) bool {
+	for _, field := range fields {
+		for _, name := range r.order(field) {
+			if details, ok := found[name]; ok && value(details, field) != "" {
+				break
+			}
+			if name == provider {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *Registry) registered(name string) bool {
+	for _, p := range r.providers {
+		if p.provider.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isField(field string) bool {
+	for _, known := range fields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+func value(details *dto.SongDetail, field string) string {
+	switch field {
+	case FieldReleaseDate:
+		return details.ReleaseDate
+	case FieldText:
+		return details.Text
+	case FieldLink:
+		return details.Link
+	}
+	return ""
+}
+
+func setValue(details *dto.SongDetail, field, v string) {
+	switch field {
+	case FieldReleaseDate:
+		details.ReleaseDate = v
+	case FieldText:
+		details.Text = v
+	case FieldLink:
+		details.Link = v
+	}
+}
@@ -0,0 +1,263 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// stubProvider returns fixed details or an error and records its queries.
+type stubProvider struct {
+	name    string
+	details *dto.SongDetail
+	err     error
+	queried *[]string
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) Fetch(ctx context.Context, group, song string) (*dto.SongDetail, error) {
+	*p.queried = append(*p.queried, p.name)
+	if p.err != nil {
+		return nil, p.err
+	}
+	details := *p.details
+	return &details, nil
+}
+
+type stub struct {
+	name     string
+	priority int
+	details  dto.SongDetail
+	err      error
+}
+
+func TestRegistryFetch(t *testing.T) {
+	errUpstream := errors.New("upstream is down")
+
+	tests := []struct {
+		name  string
+		stubs []stub
+		rules map[string][]string
+		want  dto.SongDetail
+		err   error
+		// queried are the providers queried, in order.
+		queried string
+	}{
+		{
+			name: "higher priority wins",
+			stubs: []stub{
+				{name: "file", priority: 1, details: dto.SongDetail{ReleaseDate: "01.01.2001", Text: "File text"}},
+				{name: "api", priority: 0, details: dto.SongDetail{ReleaseDate: "16.07.2006"}},
+			},
+			want:    dto.SongDetail{ReleaseDate: "16.07.2006", Text: "File text"},
+			queried: "api,file",
+		},
+		{
+			name: "same priority in the order registered",
+			stubs: []stub{
+				{name: "api", details: dto.SongDetail{ReleaseDate: "16.07.2006"}},
+				{name: "file", details: dto.SongDetail{ReleaseDate: "01.01.2001", Link: "https://example.com/file"}},
+			},
+			want:    dto.SongDetail{ReleaseDate: "16.07.2006", Link: "https://example.com/file"},
+			queried: "api,file",
+		},
+		{
+			name: "complete details skip the rest",
+			stubs: []stub{
+				{name: "api", details: dto.SongDetail{ReleaseDate: "16.07.2006", Text: "Api text", Link: "https://example.com/api"}},
+				{name: "file", priority: 1, details: dto.SongDetail{ReleaseDate: "01.01.2001"}},
+			},
+			want:    dto.SongDetail{ReleaseDate: "16.07.2006", Text: "Api text", Link: "https://example.com/api"},
+			queried: "api",
+		},
+		{
+			name: "lower priorities fill empty fields",
+			stubs: []stub{
+				{name: "api", details: dto.SongDetail{Text: "Api text"}},
+				{name: "file", priority: 1, details: dto.SongDetail{Text: "File text", Link: "https://example.com/file"}},
+				{name: "musicbrainz", priority: 2, details: dto.SongDetail{ReleaseDate: "16.07.2006", Link: "https://example.com/mb"}},
+			},
+			want:    dto.SongDetail{ReleaseDate: "16.07.2006", Text: "Api text", Link: "https://example.com/file"},
+			queried: "api,file,musicbrainz",
+		},
+		{
+			name: "not found in the first provider",
+			stubs: []stub{
+				{name: "api", err: ErrNotFound},
+				{name: "file", priority: 1, details: dto.SongDetail{ReleaseDate: "01.01.2001"}},
+			},
+			want:    dto.SongDetail{ReleaseDate: "01.01.2001"},
+			queried: "api,file",
+		},
+		{
+			name: "provider error is skipped",
+			stubs: []stub{
+				{name: "api", err: errUpstream},
+				{name: "file", priority: 1, details: dto.SongDetail{Text: "File text"}},
+			},
+			want:    dto.SongDetail{Text: "File text"},
+			queried: "api,file",
+		},
+		{
+			name: "not found anywhere",
+			stubs: []stub{
+				{name: "api", err: ErrNotFound},
+				{name: "file", priority: 1, err: ErrNotFound},
+			},
+			err:     ErrNotFound,
+			queried: "api,file",
+		},
+		{
+			name: "provider error and not found",
+			stubs: []stub{
+				{name: "api", err: errUpstream},
+				{name: "file", priority: 1, err: ErrNotFound},
+			},
+			err:     errUpstream,
+			queried: "api,file",
+		},
+		{
+			name:    "no providers",
+			err:     ErrNotFound,
+			queried: "",
+		},
+		{
+			name: "rule reorders a field",
+			stubs: []stub{
+				{name: "api", details: dto.SongDetail{ReleaseDate: "16.07.2006", Text: "Api text"}},
+				{name: "file", priority: 1, details: dto.SongDetail{ReleaseDate: "01.01.2001", Text: "File text"}},
+			},
+			rules:   map[string][]string{FieldText: {"file", "api"}},
+			want:    dto.SongDetail{ReleaseDate: "16.07.2006", Text: "File text"},
+			queried: "api,file",
+		},
+		{
+			name: "rule falls back to the next provider",
+			stubs: []stub{
+				{name: "api", details: dto.SongDetail{ReleaseDate: "16.07.2006", Text: "Api text"}},
+				{name: "file", priority: 1, err: ErrNotFound},
+			},
+			rules:   map[string][]string{FieldText: {"file", "api"}},
+			want:    dto.SongDetail{ReleaseDate: "16.07.2006", Text: "Api text"},
+			queried: "api,file",
+		},
+		{
+			name: "rule restricts a field",
+			stubs: []stub{
+				{name: "api", details: dto.SongDetail{ReleaseDate: "16.07.2006", Text: "Api text"}},
+				{name: "file", priority: 1, details: dto.SongDetail{ReleaseDate: "01.01.2001", Link: "https://example.com/file"}},
+			},
+			rules:   map[string][]string{FieldLink: {"api"}},
+			want:    dto.SongDetail{ReleaseDate: "16.07.2006", Text: "Api text"},
+			queried: "api",
+		},
+		{
+			name: "rules per field",
+			stubs: []stub{
+				{name: "api", details: dto.SongDetail{ReleaseDate: "16.07.2006", Text: "Api text", Link: "https://example.com/api"}},
+				{name: "file", priority: 1, details: dto.SongDetail{ReleaseDate: "01.01.2001", Text: "File text"}},
+				{name: "musicbrainz", priority: 2, details: dto.SongDetail{ReleaseDate: "07.09.2009", Link: "https://example.com/mb"}},
+			},
+			rules: map[string][]string{
+				FieldReleaseDate: {"musicbrainz", "file"},
+				FieldText:        {"file"},
+			},
+			want:    dto.SongDetail{ReleaseDate: "07.09.2009", Text: "File text", Link: "https://example.com/api"},
+			queried: "api,file,musicbrainz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queried []string
+			r := NewRegistry(testLogger())
+			for _, s := range tt.stubs {
+				details := s.details
+				r.Register(&stubProvider{name: s.name, details: &details, err: s.err, queried: &queried}, s.priority)
+			}
+			for field, providers := range tt.rules {
+				if err := r.SetRule(field, providers...); err != nil {
+					t.Fatalf("SetRule(%s): %s", field, err)
+				}
+			}
+
+			got, err := r.Fetch(context.Background(), "Muse", "Uprising")
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Fetch error = %v, want %v", err, tt.err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Fetch: %s", err)
+				}
+				if *got != tt.want {
+					t.Fatalf("Fetch = %+v, want %+v", *got, tt.want)
+				}
+			}
+			if got := strings.Join(queried, ","); got != tt.queried {
+				t.Fatalf("queried = %q, want %q", got, tt.queried)
+			}
+		})
+	}
+}
+
+func TestRegistrySetRule(t *testing.T) {
+	var queried []string
+	r := NewRegistry(testLogger())
+	r.Register(&stubProvider{name: "file", queried: &queried}, 1)
+	r.Register(&stubProvider{name: "musicbrainz", queried: &queried}, 2)
+	r.Register(&stubProvider{name: "api", queried: &queried}, 0)
+
+	if got := strings.Join(r.Providers(), ","); got != "api,file,musicbrainz" {
+		t.Fatalf("Providers = %q, want them in priority order", got)
+	}
+
+	tests := []struct {
+		name      string
+		field     string
+		providers []string
+		err       bool
+		// orders are the providers of the release date, text and link.
+		orders [3]string
+	}{
+		{name: "unknown field", field: "lyrics", providers: []string{"api"}, err: true,
+			orders: [3]string{"api,file,musicbrainz", "api,file,musicbrainz", "api,file,musicbrainz"}},
+		{name: "text from file first", field: FieldText, providers: []string{"file", "api"},
+			orders: [3]string{"api,file,musicbrainz", "file,api", "api,file,musicbrainz"}},
+		{name: "link from musicbrainz only", field: FieldLink, providers: []string{"musicbrainz"},
+			orders: [3]string{"api,file,musicbrainz", "file,api", "musicbrainz"}},
+		{name: "unknown provider keeps the rule", field: FieldText, providers: []string{"file", "genius"}, err: true,
+			orders: [3]string{"api,file,musicbrainz", "file,api", "musicbrainz"}},
+		{name: "rule replaced", field: FieldText, providers: []string{"musicbrainz"},
+			orders: [3]string{"api,file,musicbrainz", "musicbrainz", "musicbrainz"}},
+		{name: "rule removed", field: FieldText,
+			orders: [3]string{"api,file,musicbrainz", "api,file,musicbrainz", "musicbrainz"}},
+	}
+
+	// The cases run in order, later ones see the changes of earlier ones.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.SetRule(tt.field, tt.providers...)
+			if (err != nil) != tt.err {
+				t.Fatalf("SetRule error = %v, want error %v", err, tt.err)
+			}
+
+			for i, field := range fields {
+				if got := strings.Join(r.order(field), ","); got != tt.orders[i] {
+					t.Fatalf("%s order = %q, want %q", field, got, tt.orders[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultMusicBrainzURL is the public MusicBrainz server. It allows about one
+// request per second and requires a User-Agent identifying the application.
+const DefaultMusicBrainzURL = "https://musicbrainz.org"
+
+// musicBrainzMinScore is the search score a recording needs to be taken.
+const musicBrainzMinScore = 90
+
+// MusicBrainzProvider searches recordings with the MusicBrainz web service,
+// or a server with the same API. It knows release dates and links to the
+// recording pages, not lyrics.
+type MusicBrainzProvider struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+}
+
+type musicBrainzRecordings struct {
+	Recordings []struct {
+		ID               string `json:"id"`
+		Score            int    `json:"score"`
+		FirstReleaseDate string `json:"first-release-date"`
+	} `json:"recordings"`
+}
+
+func NewMusicBrainzProvider(baseURL, userAgent string, httpClient *http.Client) *MusicBrainzProvider {
+	if baseURL == "" {
+		baseURL = DefaultMusicBrainzURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &MusicBrainzProvider{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		userAgent:  userAgent,
+		httpClient: httpClient,
+	}
+}
+
+func (p *MusicBrainzProvider) Name() string {
+	return "musicbrainz"
+}
+
+func (p *MusicBrainzProvider) Fetch(ctx context.Context, group, song string) (*dto.SongDetail, error) {
+	query := url.Values{}
+	query.Set("query", fmt.Sprintf("recording:%s AND artist:%s", luceneQuote(song), luceneQuote(group)))
+	query.Set("fmt", "json")
+	query.Set("limit", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/ws/2/recording?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", p.userAgent)
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("musicbrainz search error, status: %d", res.StatusCode)
+	}
+
+	var recordings musicBrainzRecordings
+
+	err = json.NewDecoder(res.Body).Decode(&recordings)
+	if err != nil {
+		return nil, fmt.Errorf("decoding response from musicbrainz: %w", err)
+	}
+
+	if len(recordings.Recordings) == 0 || recordings.Recordings[0].Score < musicBrainzMinScore {
+		return nil, ErrNotFound
+	}
+	recording := recordings.Recordings[0]
+
+	return &dto.SongDetail{
+		ReleaseDate: releaseDate(recording.FirstReleaseDate),
+		Link:        p.baseURL + "/recording/" + recording.ID,
+	}, nil
+}
+
+// luceneQuote quotes s as a phrase of the MusicBrainz search syntax.
+func luceneQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// releaseDate converts a MusicBrainz date to the day.month.year format of the
+// upstream API. Dates without a day, e.g. just a year, are dropped.
+func releaseDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return ""
+	}
+	return t.Format("02.01.2006")
+}
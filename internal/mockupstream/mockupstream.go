@@ -0,0 +1,151 @@
+// Package mockupstream fakes the upstream music API for local development and
+// tests. GET /info?group=&song= answers with the details of the song from
+// fixture files, the server can be made slow or faulty.
+package mockupstream
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/sirupsen/logrus"
+	"io/fs"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// Fixtures returns the songs shipped with the package, in the JSON and YAML
+// format of metadata.FileProvider.
+func Fixtures() fs.FS {
+	sub, err := fs.Sub(fixtures, "fixtures")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// Faults makes the server misbehave like an unreliable upstream.
+type Faults struct {
+	// Latency delays every response.
+	Latency time.Duration
+	// ErrorRate is the share of requests, from 0 to 1, answered with
+	// ErrorStatus, 500 by default.
+	ErrorRate   float64
+	ErrorStatus int
+	// MalformedRate is the share of the remaining requests answered with
+	// truncated JSON.
+	MalformedRate float64
+}
+
+type Server struct {
+	songs *metadata.FileProvider
+	log   *logrus.Logger
+
+	mu       sync.Mutex
+	faults   Faults
+	requests int
+}
+
+// NewServer returns a server answering with the songs of the fixture files in
+// the root of fsys, matched like metadata.FileProvider does.
+func NewServer(fsys fs.FS, faults Faults, log *logrus.Logger) *Server {
+	return &Server{
+		songs:  metadata.NewFSProvider(fsys),
+		log:    log,
+		faults: faults,
+	}
+}
+
+// SetFaults replaces the faults, e.g. to take the upstream down in a test.
+func (s *Server) SetFaults(faults Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = faults
+}
+
+// Requests returns the number of /info requests served so far.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/info" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.mu.Lock()
+	faults := s.faults
+	s.requests++
+	s.mu.Unlock()
+
+	group, song := r.URL.Query().Get("group"), r.URL.Query().Get("song")
+	s.log.Debugf("Mock upstream: group=%s, song=%s", group, song)
+
+	if faults.Latency > 0 {
+		timer := time.NewTimer(faults.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+		}
+	}
+
+	if faults.ErrorRate > 0 && rand.Float64() < faults.ErrorRate {
+		status := faults.ErrorStatus
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		s.log.Debugf("Mock upstream: injected %d for group=%s, song=%s", status, group, song)
+		writeError(w, status, "injected failure")
+		return
+	}
+
+	if group == "" || song == "" {
+		writeError(w, http.StatusBadRequest, "group and song are required")
+		return
+	}
+
+	details, err := s.songs.Fetch(r.Context(), group, song)
+	if errors.Is(err, metadata.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		s.log.Errorf("Mock upstream fixtures error: %s", err)
+		writeError(w, http.StatusInternalServerError, "invalid fixtures")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if faults.MalformedRate > 0 && rand.Float64() < faults.MalformedRate {
+		s.log.Debugf("Mock upstream: injected malformed JSON for group=%s, song=%s", group, song)
+		_, _ = w.Write([]byte(`{"releaseDate": "` + details.ReleaseDate + `", "text": `))
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(details)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,39 @@
+package mockupstream
+
+import (
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestServer is a Server listening on a local httptest address.
+type TestServer struct {
+	*Server
+	// URL is the base URL, e.g. for metadata.NewAPIProvider or API_URL.
+	URL string
+}
+
+// Start starts a server without faults on the fixtures in fsys, or the ones of
+// the package if fsys is nil. It is closed when the test finishes.
+func Start(t testing.TB, fsys fs.FS) *TestServer {
+	t.Helper()
+
+	if fsys == nil {
+		fsys = Fixtures()
+	}
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	server := NewServer(fsys, Faults{}, log)
+
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+
+	return &TestServer{
+		Server: server,
+		URL:    srv.URL,
+	}
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventSongCreated = "song.created"
+	EventSongUpdated = "song.updated"
+	EventSongDeleted = "song.deleted"
+	// EventSongEnriched follows song.created once the details and lyrics
+	// fetched from the upstream API are stored.
+	EventSongEnriched = "song.enriched"
+)
+
+// EventTypes lists the event types a webhook can subscribe to.
+var EventTypes = []string{EventSongCreated, EventSongUpdated, EventSongDeleted, EventSongEnriched}
+
+// Event is a change of the catalogue recorded in the outbox together with the
+// change itself. Data holds the song after the change, or before it for deletions.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	SongID    int             `json:"song_id"`
+	Data      json.RawMessage `json:"data" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
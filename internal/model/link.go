@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+const (
+	LinkStatusUnknown = "unknown"
+	LinkStatusOK      = "ok"
+	LinkStatusDead    = "dead"
+)
+
+// Link is a link to a song on a streaming or video service. Status is set by
+// the link checker, CheckedAt is nil until the link is checked.
+type Link struct {
+	ID        int        `json:"id"`
+	SongID    int        `json:"song_id"`
+	Provider  string     `json:"provider"`
+	URL       string     `json:"url"`
+	Status    string     `json:"status"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
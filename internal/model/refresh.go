@@ -0,0 +1,22 @@
+package model
+
+// FieldChange is a field of a song whose stored value differs from the one
+// of the metadata providers.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// SongRefresh is the result of re-fetching the details of a song.
+type SongRefresh struct {
+	SongID  int            `json:"song_id"`
+	Song    string         `json:"song"`
+	Group   string         `json:"group"`
+	Changes []*FieldChange `json:"changes"`
+	// Applied is false for dry runs and songs without changes.
+	Applied bool `json:"applied"`
+	// Error is set if the details of the song couldn't be fetched, or if the
+	// song was modified while they were fetched.
+	Error string `json:"error,omitempty"`
+}
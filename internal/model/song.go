@@ -1,10 +1,36 @@
 package model
 
+import "time"
+
 type Song struct {
 	ID          int
-	Song        string `json:"song" binding:"required"`
-	Group       string `json:"group" binding:"required"`
-	ReleaseDate string `json:"release_date" time_format:"2006-01-02"`
-	Text        string `json:"text"`
-	Link        string `json:"link"`
+	Song        string    `json:"song" binding:"required"`
+	Group       string    `json:"group" binding:"required"`
+	ReleaseDate string    `json:"release_date" time_format:"2006-01-02"`
+	Text        string    `json:"text"`
+	Link        string    `json:"link"`
+	Version     int       `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SongWithLyrics struct {
+	Song
+	// Language is the detected language of the original lyrics.
+	Language    string   `json:"language,omitempty"`
+	VersesCount int      `json:"verses_count"`
+	Lyrics      []*Verse `json:"lyrics,omitempty"`
+}
+
+// DuplicateSongs is a pair of songs which are likely the same song spelled differently.
+type DuplicateSongs struct {
+	Song       *Song   `json:"song"`
+	Duplicate  *Song   `json:"duplicate"`
+	Similarity float64 `json:"similarity"`
+}
+
+// LyricsLanguages lists the languages a song has lyrics in.
+type LyricsLanguages struct {
+	// Original is the detected language of the original lyrics, empty if unknown.
+	Original     string   `json:"original"`
+	Translations []string `json:"translations"`
 }
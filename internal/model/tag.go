@@ -0,0 +1,30 @@
+package model
+
+const (
+	TagKindTag   = "tag"
+	TagKindGenre = "genre"
+)
+
+// Tag classifies songs, genres are tags of the genre kind. Names are stored
+// in lower case.
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// FacetCount is the number of songs with a tag, artist or release year.
+type FacetCount struct {
+	Value string `json:"value"`
+	// Kind is the kind of a tag, it's empty for the other facets.
+	Kind  string `json:"kind,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Facets counts the songs matching a filter by tag, artist and release year,
+// the most common values first.
+type Facets struct {
+	Tags    []*FacetCount `json:"tags"`
+	Artists []*FacetCount `json:"artists"`
+	Years   []*FacetCount `json:"years"`
+}
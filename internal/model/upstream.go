@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// UpstreamResponse is a cached response of a metadata provider for a song,
+// NotFound is set if the provider doesn't know the song.
+type UpstreamResponse struct {
+	Provider    string    `json:"provider"`
+	Group       string    `json:"group"`
+	Song        string    `json:"song"`
+	ReleaseDate string    `json:"release_date"`
+	Text        string    `json:"text"`
+	Link        string    `json:"link"`
+	NotFound    bool      `json:"not_found"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// UpstreamCacheFilter selects cached responses, empty fields match all of them.
+// Group and song are matched like the cache keys, ignoring case and whitespace.
+type UpstreamCacheFilter struct {
+	Provider      string
+	Group         string
+	Song          string
+	NotFoundOnly  bool
+	FetchedBefore time.Time
+}
+
+// UpstreamCachePurge is the number of cached responses purged.
+type UpstreamCachePurge struct {
+	Purged int `json:"purged"`
+}
@@ -3,4 +3,6 @@ package model
 type Verse struct {
 	Number int    `json:"number"`
 	Lyrics string `json:"lyrics"`
+	// Language is the language of the verse, empty if it's unknown.
+	Language string `json:"language,omitempty"`
 }
@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/pkg/cache"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheMetrics counts cache hits and misses, exposed on /debug/vars.
+var cacheMetrics = expvar.NewMap("repository_cache")
+
+const songsGenerationKey = "songs:gen"
+
+// CachedMusicRepository is a read-through cache in front of another IMusicRepository.
+// Cached keys embed a generation token, mutations replace the token instead
+// of looking for every key built from filters or pagination.
+type CachedMusicRepository struct {
+	IMusicRepository
+	cache cache.Cache
+	ttl   time.Duration
+	log   *logrus.Logger
+}
+
+func NewCachedMusicRepository(repo IMusicRepository, cache cache.Cache, ttl time.Duration, log *logrus.Logger) *CachedMusicRepository {
+	return &CachedMusicRepository{
+		IMusicRepository: repo,
+		cache:            cache,
+		ttl:              ttl,
+		log:              log,
+	}
+}
+
+func (r *CachedMusicRepository) AddSong(ctx context.Context, song *model.Song) (*model.Song, error) {
+	saved, err := r.IMusicRepository.AddSong(ctx, song)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx, songsGenerationKey)
+	return saved, nil
+}
+
+func (r *CachedMusicRepository) AddLyrics(ctx context.Context, songID int, lyrics string) error {
+	err := r.IMusicRepository.AddLyrics(ctx, songID, lyrics)
+	if err != nil {
+		return err
+	}
+
+	// Adding lyrics bumps the song version, so cached songs are stale as well.
+	r.invalidate(ctx, songsGenerationKey, lyricsGenerationKey(songID))
+	return nil
+}
+
+func (r *CachedMusicRepository) IngestSong(ctx context.Context, song *model.Song, lyrics string, link *model.Link) (*model.Song, error) {
+	saved, err := r.IMusicRepository.IngestSong(ctx, song, lyrics, link)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx, songsGenerationKey, lyricsGenerationKey(saved.ID))
+	return saved, nil
+}
+
+func (r *CachedMusicRepository) GetSongsList(ctx context.Context, req *dto.GetSongsListReq) ([]*model.Song, error) {
+	key := fmt.Sprintf("songs:%s:song=%s:group=%s:date=%s:tags=%s:%d:%d", r.generation(ctx, songsGenerationKey),
+		filterKey(req.Song), filterKey(req.Group), filterKey(req.ReleaseDate), tagsKey(req), req.Limit, req.Offset)
+
+	var songs []*model.Song
+	if r.load(ctx, "songs", key, &songs) {
+		return songs, nil
+	}
+
+	songs, err := r.IMusicRepository.GetSongsList(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(ctx, key, songs)
+	return songs, nil
+}
+
+func (r *CachedMusicRepository) CountSongs(ctx context.Context, req *dto.GetSongsListReq) (int, error) {
+	key := fmt.Sprintf("songs:%s:song=%s:group=%s:date=%s:tags=%s:count", r.generation(ctx, songsGenerationKey),
+		filterKey(req.Song), filterKey(req.Group), filterKey(req.ReleaseDate), tagsKey(req))
+
+	var count int
+	if r.load(ctx, "songs", key, &count) {
+		return count, nil
+	}
+
+	count, err := r.IMusicRepository.CountSongs(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	r.store(ctx, key, count)
+	return count, nil
+}
+
+func (r *CachedMusicRepository) GetSongLyrics(ctx context.Context, songID int, lang string, limit, offset int) ([]*model.Verse, error) {
+	key := fmt.Sprintf("lyrics:%d:%s:%s:%d:%d", songID, r.generation(ctx, lyricsGenerationKey(songID)), strconv.Quote(lang), limit, offset)
+
+	var verses []*model.Verse
+	if r.load(ctx, "lyrics", key, &verses) {
+		return verses, nil
+	}
+
+	verses, err := r.IMusicRepository.GetSongLyrics(ctx, songID, lang, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(ctx, key, verses)
+	return verses, nil
+}
+
+func (r *CachedMusicRepository) GetSong(ctx context.Context, songID int) (*model.Song, error) {
+	key := fmt.Sprintf("song:%d:%s", songID, r.generation(ctx, songsGenerationKey))
+
+	var song *model.Song
+	if r.load(ctx, "song", key, &song) {
+		return song, nil
+	}
+
+	song, err := r.IMusicRepository.GetSong(ctx, songID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(ctx, key, song)
+	return song, nil
+}
+
+func (r *CachedMusicRepository) GetSongWithLyrics(ctx context.Context, songID int, withLyrics bool) (*model.SongWithLyrics, error) {
+	key := fmt.Sprintf("song:%d:%s:%s:%t", songID, r.generation(ctx, songsGenerationKey),
+		r.generation(ctx, lyricsGenerationKey(songID)), withLyrics)
+
+	var song *model.SongWithLyrics
+	if r.load(ctx, "song", key, &song) {
+		return song, nil
+	}
+
+	song, err := r.IMusicRepository.GetSongWithLyrics(ctx, songID, withLyrics)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(ctx, key, song)
+	return song, nil
+}
+
+func (r *CachedMusicRepository) UpdateSong(ctx context.Context, songID int, req *dto.UpdateSongReq) error {
+	err := r.IMusicRepository.UpdateSong(ctx, songID, req)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, songsGenerationKey)
+	return nil
+}
+
+func (r *CachedMusicRepository) UpdateSongWithLyrics(ctx context.Context, songID int, req *dto.UpdateSongReq, link *model.Link) error {
+	err := r.IMusicRepository.UpdateSongWithLyrics(ctx, songID, req, link)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, songsGenerationKey, lyricsGenerationKey(songID))
+	return nil
+}
+
+func (r *CachedMusicRepository) DeleteSong(ctx context.Context, songID int) error {
+	err := r.IMusicRepository.DeleteSong(ctx, songID)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, songsGenerationKey, lyricsGenerationKey(songID))
+	return nil
+}
+
+func (r *CachedMusicRepository) MergeSongs(ctx context.Context, targetID, sourceID int, version *int) (*model.Song, error) {
+	merged, err := r.IMusicRepository.MergeSongs(ctx, targetID, sourceID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx, songsGenerationKey, lyricsGenerationKey(targetID), lyricsGenerationKey(sourceID))
+	return merged, nil
+}
+
+func (r *CachedMusicRepository) TagSong(ctx context.Context, songID int, name, kind string) error {
+	err := r.IMusicRepository.TagSong(ctx, songID, name, kind)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, songsGenerationKey)
+	return nil
+}
+
+func (r *CachedMusicRepository) UntagSong(ctx context.Context, songID int, name string) error {
+	err := r.IMusicRepository.UntagSong(ctx, songID, name)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, songsGenerationKey)
+	return nil
+}
+
+// SetTranslation changes the song version, so cached songs are stale as well as the lyrics.
+func (r *CachedMusicRepository) SetTranslation(ctx context.Context, songID int, lang, lyrics string) error {
+	err := r.IMusicRepository.SetTranslation(ctx, songID, lang, lyrics)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, songsGenerationKey, lyricsGenerationKey(songID))
+	return nil
+}
+
+func (r *CachedMusicRepository) DeleteTranslation(ctx context.Context, songID int, lang string) error {
+	err := r.IMusicRepository.DeleteTranslation(ctx, songID, lang)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, songsGenerationKey, lyricsGenerationKey(songID))
+	return nil
+}
+
+func (r *CachedMusicRepository) load(ctx context.Context, metric, key string, dest any) bool {
+	data, ok, err := r.cache.Get(ctx, key)
+	if err != nil {
+		r.log.Errorf("Cache get error for key %s: %s", key, err)
+	}
+
+	if ok {
+		err = json.Unmarshal(data, dest)
+		if err == nil {
+			cacheMetrics.Add(metric+"_hits", 1)
+			r.log.Debugf("Cache hit for key %s", key)
+			return true
+		}
+		r.log.Errorf("Cache decode error for key %s: %s", key, err)
+	}
+
+	cacheMetrics.Add(metric+"_misses", 1)
+	r.log.Debugf("Cache miss for key %s", key)
+	return false
+}
+
+func (r *CachedMusicRepository) store(ctx context.Context, key string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		r.log.Errorf("Cache encode error for key %s: %s", key, err)
+		return
+	}
+
+	err = r.cache.Set(ctx, key, data, r.ttl)
+	if err != nil {
+		r.log.Errorf("Cache set error for key %s: %s", key, err)
+	}
+}
+
+// generation returns the current token for genKey, creating one if it's missing.
+func (r *CachedMusicRepository) generation(ctx context.Context, genKey string) string {
+	data, ok, err := r.cache.Get(ctx, genKey)
+	if err != nil {
+		r.log.Errorf("Cache get error for key %s: %s", genKey, err)
+	}
+	if ok {
+		return string(data)
+	}
+
+	gen := newGeneration()
+	err = r.cache.Set(ctx, genKey, []byte(gen), 0)
+	if err != nil {
+		r.log.Errorf("Cache set error for key %s: %s", genKey, err)
+	}
+
+	return gen
+}
+
+func (r *CachedMusicRepository) invalidate(ctx context.Context, genKeys ...string) {
+	for _, genKey := range genKeys {
+		err := r.cache.Set(ctx, genKey, []byte(newGeneration()), 0)
+		if err != nil {
+			r.log.Errorf("Cache invalidation error for key %s: %s", genKey, err)
+			continue
+		}
+		cacheMetrics.Add("invalidations", 1)
+	}
+}
+
+func lyricsGenerationKey(songID int) string {
+	return fmt.Sprintf("lyrics:%d:gen", songID)
+}
+
+func newGeneration() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// tagsKey identifies the tag filter of req, the order of the tags doesn't matter.
+func tagsKey(req *dto.GetSongsListReq) string {
+	tags := tagNames(req.Tags)
+	if len(tags) == 0 {
+		return "-"
+	}
+	sort.Strings(tags)
+
+	mode := req.TagMode
+	if mode == "" {
+		mode = dto.TagModeAny
+	}
+	return mode + ":" + strconv.Quote(strings.Join(tags, ","))
+}
+
+func filterKey(value *string) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.Quote(*value)
+}
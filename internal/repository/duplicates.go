@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// normalizeKey returns the key a song is unique by: lower case, with runs of
+// whitespace collapsed to a single space.
+func normalizeKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// similarityText is the text the songs are compared by, as in songs_keys_trgm_idx.
+func similarityText(song *model.Song) string {
+	return normalizeKey(song.Group) + " " + normalizeKey(song.Song)
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation of
+// Postgres or SQLite, i.e. the song keys are taken.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+
+	return false
+}
+
+// trigrams returns the trigrams of s the way pg_trgm extracts them: every word
+// of letters and digits is lower cased and padded with two spaces in front
+// and one behind.
+func trigrams(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+// similarity is the share of trigrams a and b have in common, as the
+// similarity function of pg_trgm.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	common := 0
+	for trigram := range a {
+		if _, ok := b[trigram]; ok {
+			common++
+		}
+	}
+
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// findDuplicates pairs the songs with a similarity of at least threshold for
+// the repositories without pg_trgm. Only songs sharing a trigram are compared.
+func findDuplicates(songs []*model.Song, threshold float64, limit int) []*model.DuplicateSongs {
+	sort.Slice(songs, func(i, j int) bool { return songs[i].ID < songs[j].ID })
+
+	sets := make([]map[string]struct{}, len(songs))
+	index := make(map[string][]int)
+	for i, song := range songs {
+		sets[i] = trigrams(similarityText(song))
+		for trigram := range sets[i] {
+			index[trigram] = append(index[trigram], i)
+		}
+	}
+
+	var duplicates []*model.DuplicateSongs
+
+	for i := range songs {
+		compared := make(map[int]bool)
+		for trigram := range sets[i] {
+			for _, j := range index[trigram] {
+				if j <= i || compared[j] {
+					continue
+				}
+				compared[j] = true
+
+				sml := similarity(sets[i], sets[j])
+				if sml >= threshold {
+					duplicates = append(duplicates, &model.DuplicateSongs{Song: songs[i], Duplicate: songs[j], Similarity: sml})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		a, b := duplicates[i], duplicates[j]
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		if a.Song.ID != b.Song.ID {
+			return a.Song.ID < b.Song.ID
+		}
+		return a.Duplicate.ID < b.Duplicate.ID
+	})
+
+	if len(duplicates) > limit {
+		duplicates = duplicates[:limit]
+	}
+
+	return duplicates
+}
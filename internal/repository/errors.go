@@ -0,0 +1,18 @@
+package repository
+
+import "errors"
+
+var (
+	ErrSongNotFound             = errors.New("song not found")
+	ErrVersionMismatch          = errors.New("song version mismatch")
+	ErrDuplicateSong            = errors.New("song already exists")
+	ErrNoLyrics                 = errors.New("song has no lyrics")
+	ErrVerseCountMismatch       = errors.New("translation verse count mismatch")
+	ErrOriginalLanguage         = errors.New("translation language is the language of the original lyrics")
+	ErrTranslationNotFound      = errors.New("translation not found")
+	ErrLinkNotFound             = errors.New("link not found")
+	ErrDuplicateLink            = errors.New("song already has the link")
+	ErrWebhookNotFound          = errors.New("webhook not found")
+	ErrDeliveryNotFound         = errors.New("dead webhook delivery not found")
+	ErrUpstreamResponseNotFound = errors.New("upstream response not cached")
+)
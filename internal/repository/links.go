@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/pkg/medialink"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+func (r *MusicRepository) GetSongLinks(ctx context.Context, songID int) ([]*model.Link, error) {
+	rows, err := r.db.QueryContext(ctx, songLinksQuery, songID)
+	if err != nil {
+		r.log.Errorf("GetSongLinks repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links, err := scanLinks(rows)
+	if err != nil {
+		r.log.Errorf("GetSongLinks repository error: %s", err)
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// AddSongLink adds the link to its song, ErrDuplicateLink is returned if the
+// song has the URL already.
+func (r *MusicRepository) AddSongLink(ctx context.Context, link *model.Link) (*model.Link, error) {
+	var added *model.Link
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var id int
+		err := tx.QueryRowContext(ctx, `SELECT id FROM songs WHERE id=$1`, link.SongID).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		added, err = insertLink(ctx, tx, link)
+		return err
+	})
+	if errors.Is(err, ErrSongNotFound) {
+		return nil, err
+	}
+	if isUniqueViolation(err) {
+		r.log.Debugf("AddSongLink repository: song id %d already has link %s", link.SongID, link.URL)
+		return nil, ErrDuplicateLink
+	}
+	if err != nil {
+		r.log.Errorf("AddSongLink repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully added %s link to song id %d", added.Provider, added.SongID)
+	return added, nil
+}
+
+// insertLink inserts the link of an existing song.
+func insertLink(ctx context.Context, tx *sql.Tx, link *model.Link) (*model.Link, error) {
+	return scanLink(tx.QueryRowContext(ctx, insertLinkQuery, link.SongID, link.Provider, link.URL, medialink.Host(link.URL)))
+}
+
+func (r *MusicRepository) DeleteSongLink(ctx context.Context, songID, linkID int) error {
+	res, err := r.db.ExecContext(ctx, deleteLinkQuery, linkID, songID)
+	if err != nil {
+		r.log.Errorf("DeleteSongLink repository error: %s", err)
+		return err
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		r.log.Errorf("DeleteSongLink repository error: %s", err)
+		return err
+	}
+	if deleted == 0 {
+		return ErrLinkNotFound
+	}
+
+	r.log.Infof("Successfully deleted link id %d of song id %d", linkID, songID)
+	return nil
+}
+
+// LinksToCheck returns up to limit links on the domains, or their subdomains,
+// which haven't been checked since checkedBefore. Links never checked go first.
+func (r *MusicRepository) LinksToCheck(ctx context.Context, domains []string, checkedBefore time.Time, limit int) ([]*model.Link, error) {
+	if len(domains) == 0 {
+		return nil, nil
+	}
+
+	query, values := linksToCheckQuery(domains, checkedBefore, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		r.log.Errorf("LinksToCheck repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links, err := scanLinks(rows)
+	if err != nil {
+		r.log.Errorf("LinksToCheck repository error: %s", err)
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (r *MusicRepository) SetLinkStatus(ctx context.Context, linkID int, status string, checkedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, setLinkStatusQuery, status, checkedAt.UTC(), linkID)
+	if err != nil {
+		r.log.Errorf("SetLinkStatus repository error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *PgxMusicRepository) GetSongLinks(ctx context.Context, songID int) ([]*model.Link, error) {
+	rows, err := r.pool.Query(ctx, songLinksQuery, songID)
+	if err != nil {
+		r.log.Errorf("GetSongLinks repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links, err := scanLinks(rows)
+	if err != nil {
+		r.log.Errorf("GetSongLinks repository error: %s", err)
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// AddSongLink adds the link to its song, ErrDuplicateLink is returned if the
+// song has the URL already.
+func (r *PgxMusicRepository) AddSongLink(ctx context.Context, link *model.Link) (*model.Link, error) {
+	var added *model.Link
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var id int
+		err := tx.QueryRow(ctx, `SELECT id FROM songs WHERE id=$1`, link.SongID).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		added, err = scanLink(tx.QueryRow(ctx, insertLinkQuery, link.SongID, link.Provider, link.URL, medialink.Host(link.URL)))
+		return err
+	})
+	if errors.Is(err, ErrSongNotFound) {
+		return nil, err
+	}
+	if isUniqueViolation(err) {
+		r.log.Debugf("AddSongLink repository: song id %d already has link %s", link.SongID, link.URL)
+		return nil, ErrDuplicateLink
+	}
+	if err != nil {
+		r.log.Errorf("AddSongLink repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully added %s link to song id %d", added.Provider, added.SongID)
+	return added, nil
+}
+
+func (r *PgxMusicRepository) DeleteSongLink(ctx context.Context, songID, linkID int) error {
+	tag, err := r.pool.Exec(ctx, deleteLinkQuery, linkID, songID)
+	if err != nil {
+		r.log.Errorf("DeleteSongLink repository error: %s", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLinkNotFound
+	}
+
+	r.log.Infof("Successfully deleted link id %d of song id %d", linkID, songID)
+	return nil
+}
+
+// LinksToCheck returns up to limit links on the domains, or their subdomains,
+// which haven't been checked since checkedBefore. Links never checked go first.
+func (r *PgxMusicRepository) LinksToCheck(ctx context.Context, domains []string, checkedBefore time.Time, limit int) ([]*model.Link, error) {
+	if len(domains) == 0 {
+		return nil, nil
+	}
+
+	query, values := linksToCheckQuery(domains, checkedBefore, limit)
+
+	rows, err := r.pool.Query(ctx, query, values...)
+	if err != nil {
+		r.log.Errorf("LinksToCheck repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links, err := scanLinks(rows)
+	if err != nil {
+		r.log.Errorf("LinksToCheck repository error: %s", err)
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (r *PgxMusicRepository) SetLinkStatus(ctx context.Context, linkID int, status string, checkedAt time.Time) error {
+	_, err := r.pool.Exec(ctx, setLinkStatusQuery, status, checkedAt.UTC(), linkID)
+	if err != nil {
+		r.log.Errorf("SetLinkStatus repository error: %s", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,899 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/pkg/langdetect"
+	"github.com/aaanger/music-library/pkg/medialink"
+	"github.com/aaanger/music-library/pkg/translit"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryMusicRepository is a thread-safe IMusicRepository kept entirely in memory.
+// It mirrors the Postgres semantics: songs are ordered by id, lyrics are not
+// returned with songs, deleting a song cascades to its verses and mutations
+// append events to the outbox.
+type MemoryMusicRepository struct {
+	mu     sync.RWMutex
+	songs  map[int]*model.Song
+	verses map[int][]*model.Verse
+	// translations holds the translated verses of a song by language,
+	// languages the language of the original lyrics.
+	translations map[int]map[string][]*model.Verse
+	languages    map[int]string
+	events       []*model.Event
+	tags         map[string]*model.Tag
+	songTags     map[int]map[string]struct{}
+	links        map[int]*model.Link
+	nextID       int
+	nextTagID    int
+	nextLinkID   int
+	nextEventID  int64
+	log          *logrus.Logger
+}
+
+func NewMemoryMusicRepository(log *logrus.Logger) *MemoryMusicRepository {
+	return &MemoryMusicRepository{
+		songs:        make(map[int]*model.Song),
+		verses:       make(map[int][]*model.Verse),
+		translations: make(map[int]map[string][]*model.Verse),
+		languages:    make(map[int]string),
+		tags:         make(map[string]*model.Tag),
+		songTags:     make(map[int]map[string]struct{}),
+		links:        make(map[int]*model.Link),
+		nextID:       1,
+		nextTagID:    1,
+		nextLinkID:   1,
+		nextEventID:  1,
+		log:          log,
+	}
+}
+
+func (r *MemoryMusicRepository) AddSong(ctx context.Context, song *model.Song) (*model.Song, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.songByKey(normalizeKey(song.Group), normalizeKey(song.Song)) != nil {
+		r.log.Debugf("AddSong repository: song %s - %s already exists", song.Group, song.Song)
+		return nil, ErrDuplicateSong
+	}
+	r.addSong(song)
+
+	r.log.Infof("Successfully added song to memory: %+v", song)
+	return song, nil
+}
+
+// IngestSong adds the song with its lyrics and the link, if it isn't nil,
+// at once.
+func (r *MemoryMusicRepository) IngestSong(ctx context.Context, song *model.Song, lyrics string, link *model.Link) (*model.Song, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.songByKey(normalizeKey(song.Group), normalizeKey(song.Song)) != nil {
+		r.log.Debugf("IngestSong repository: song %s - %s already exists", song.Group, song.Song)
+		return nil, ErrDuplicateSong
+	}
+
+	stored := r.addSong(song)
+	r.addLyrics(stored, lyrics)
+	if link != nil {
+		link.SongID = stored.ID
+		r.addLink(link)
+	}
+
+	song.Version = stored.Version
+	song.UpdatedAt = stored.UpdatedAt
+
+	r.log.Infof("Successfully ingested song to memory: %+v", song)
+	return song, nil
+}
+
+// addSong stores a copy of the song without its text and returns it, r.mu
+// must be held for writing.
+func (r *MemoryMusicRepository) addSong(song *model.Song) *model.Song {
+	song.ID = r.nextID
+	song.Version = 1
+	song.UpdatedAt = time.Now().UTC()
+	r.nextID++
+
+	stored := *song
+	stored.Text = ""
+	r.songs[stored.ID] = &stored
+	r.addEvent(model.EventSongCreated, &stored)
+
+	return &stored
+}
+
+func (r *MemoryMusicRepository) AddLyrics(ctx context.Context, songID int, lyrics string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	song, ok := r.songs[songID]
+	if !ok {
+		return ErrSongNotFound
+	}
+	verses := r.addLyrics(song, lyrics)
+
+	r.log.Infof("Successfully added %d verses for song id %d", verses, songID)
+	return nil
+}
+
+// addLyrics replaces the lyrics of the stored song and returns the number of
+// its verses, r.mu must be held for writing.
+func (r *MemoryMusicRepository) addLyrics(song *model.Song, lyrics string) int {
+	verses := splitVerses(lyrics)
+	r.verses[song.ID] = make([]*model.Verse, 0, len(verses))
+	for i, verse := range verses {
+		r.verses[song.ID] = append(r.verses[song.ID], &model.Verse{Number: i + 1, Lyrics: verse})
+	}
+	for lang, translated := range r.translations[song.ID] {
+		if len(translated) != len(verses) {
+			delete(r.translations[song.ID], lang)
+		}
+	}
+	r.languages[song.ID] = langdetect.Detect(lyrics)
+	touch(song)
+	r.addEvent(model.EventSongEnriched, song)
+
+	return len(verses)
+}
+
+func (r *MemoryMusicRepository) GetSongsList(ctx context.Context, req *dto.GetSongsListReq) ([]*model.Song, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	r.log.Debugf("GetSongsList repository filters: song - %v group - %v releaseDate - %v limit - %v offset - %v", req.Song, req.Group, req.ReleaseDate, req.Limit, req.Offset)
+
+	songs := r.filterSongs(req)
+
+	songs = paginate(songs, req.Limit, req.Offset)
+
+	r.log.Infof("Successfully got songs list: %+v", songs)
+	return songs, nil
+}
+
+func (r *MemoryMusicRepository) CountSongs(ctx context.Context, req *dto.GetSongsListReq) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := len(r.filterSongs(req))
+
+	r.log.Debugf("CountSongs repository: %d songs", count)
+	return count, nil
+}
+
+// filterSongs returns copies of the songs matching req ordered by id, r.mu must be held.
+func (r *MemoryMusicRepository) filterSongs(req *dto.GetSongsListReq) []*model.Song {
+	var songs []*model.Song
+
+	tags := tagNames(req.Tags)
+
+	for id := 1; id < r.nextID; id++ {
+		song, ok := r.songs[id]
+		if !ok {
+			continue
+		}
+		if req.Song != nil && translit.Fold(song.Song) != translit.Fold(*req.Song) {
+			continue
+		}
+		if req.Group != nil && translit.Fold(song.Group) != translit.Fold(*req.Group) {
+			continue
+		}
+		if req.ReleaseDate != nil && song.ReleaseDate != *req.ReleaseDate {
+			continue
+		}
+		if len(tags) > 0 && !r.hasTags(id, tags, req.TagMode == dto.TagModeAll) {
+			continue
+		}
+
+		found := *song
+		songs = append(songs, &found)
+	}
+
+	return songs
+}
+
+func (r *MemoryMusicRepository) GetSongLyrics(ctx context.Context, songID int, lang string, limit, offset int) ([]*model.Verse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	r.log.Debugf("GetSongLyrics repository: songID - %d lang - %q limit - %d offset - %d", songID, lang, limit, offset)
+
+	lyrics, ok := r.translations[songID][lang]
+	if !ok {
+		lyrics, lang = r.verses[songID], r.languages[songID]
+	}
+
+	var verses []*model.Verse
+
+	for _, verse := range paginate(lyrics, limit, offset) {
+		found := *verse
+		found.Language = lang
+		verses = append(verses, &found)
+	}
+
+	r.log.Infof("Successfully got lyrics for song id %d: %+v", songID, verses)
+	return verses, nil
+}
+
+func (r *MemoryMusicRepository) GetLyricsBySongIDs(ctx context.Context, songIDs []int) (map[int][]*model.Verse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	r.log.Debugf("GetLyricsBySongIDs repository: songIDs - %v", songIDs)
+
+	lyrics := make(map[int][]*model.Verse, len(songIDs))
+
+	for _, songID := range songIDs {
+		for _, verse := range r.verses[songID] {
+			found := *verse
+			lyrics[songID] = append(lyrics[songID], &found)
+		}
+	}
+
+	r.log.Infof("Successfully got lyrics for %d songs", len(lyrics))
+	return lyrics, nil
+}
+
+func (r *MemoryMusicRepository) UpdateSong(ctx context.Context, songID int, req *dto.UpdateSongReq) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.log.Debugf("UpdateSong repository input parameters: song - %v group - %v releaseDate - %v link - %v", req.Song, req.Group, req.ReleaseDate, req.Link)
+
+	song, ok := r.songs[songID]
+	if req.Version != nil && (!ok || song.Version != *req.Version) {
+		r.log.Debugf("UpdateSong repository: song id %d doesn't have version %d", songID, *req.Version)
+		return ErrVersionMismatch
+	}
+	if !ok {
+		return nil
+	}
+
+	if !r.updateSong(song, req) {
+		r.log.Debugf("UpdateSong repository: song id %d would duplicate another song", songID)
+		return ErrDuplicateSong
+	}
+
+	r.log.Infof("Successfully updated song with id %d", songID)
+	return nil
+}
+
+// updateSong applies the fields of req to the stored song and records a
+// song.updated event. It returns false, changing nothing, if the song would
+// duplicate another one. r.mu must be held for writing.
+func (r *MemoryMusicRepository) updateSong(song *model.Song, req *dto.UpdateSongReq) bool {
+	updated := *song
+	if req.Song != nil {
+		updated.Song = *req.Song
+	}
+	if req.Group != nil {
+		updated.Group = *req.Group
+	}
+	if found := r.songByKey(normalizeKey(updated.Group), normalizeKey(updated.Song)); found != nil && found.ID != song.ID {
+		return false
+	}
+
+	if req.Song != nil {
+		song.Song = *req.Song
+	}
+	if req.Group != nil {
+		song.Group = *req.Group
+	}
+	if req.ReleaseDate != nil {
+		song.ReleaseDate = *req.ReleaseDate
+	}
+	if req.Link != nil {
+		song.Link = *req.Link
+	}
+	touch(song)
+	r.addEvent(model.EventSongUpdated, song)
+
+	return true
+}
+
+// UpdateSongWithLyrics applies the fields of req, the lyrics of req.Text and
+// the media link, each unless nil, at once. If req.Version is set the song
+// must still have it. A link the song already has is kept.
+func (r *MemoryMusicRepository) UpdateSongWithLyrics(ctx context.Context, songID int, req *dto.UpdateSongReq, link *model.Link) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.log.Debugf("UpdateSongWithLyrics repository input parameters: song - %v group - %v releaseDate - %v link - %v text - %t", req.Song, req.Group, req.ReleaseDate, req.Link, req.Text != nil)
+
+	song, ok := r.songs[songID]
+	if !ok && req.Version != nil && hasSongFields(req) {
+		r.log.Debugf("UpdateSongWithLyrics repository: song id %d doesn't have version %d", songID, *req.Version)
+		return ErrVersionMismatch
+	}
+	if !ok {
+		return ErrSongNotFound
+	}
+	if req.Version != nil && song.Version != *req.Version {
+		r.log.Debugf("UpdateSongWithLyrics repository: song id %d doesn't have version %d", songID, *req.Version)
+		return ErrVersionMismatch
+	}
+
+	if hasSongFields(req) && !r.updateSong(song, req) {
+		r.log.Debugf("UpdateSongWithLyrics repository: song id %d would duplicate another song", songID)
+		return ErrDuplicateSong
+	}
+	if req.Text != nil {
+		r.addLyrics(song, *req.Text)
+	}
+	if link != nil && r.songLink(songID, link.URL) == nil {
+		r.addLink(&model.Link{SongID: songID, Provider: link.Provider, URL: link.URL})
+	}
+
+	r.log.Infof("Successfully updated song with id %d", songID)
+	return nil
+}
+
+func (r *MemoryMusicRepository) DeleteSong(ctx context.Context, songID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	song, ok := r.songs[songID]
+	if ok {
+		delete(r.songs, songID)
+		delete(r.verses, songID)
+		delete(r.translations, songID)
+		delete(r.languages, songID)
+		delete(r.songTags, songID)
+		r.deleteLinks(songID)
+		r.addEvent(model.EventSongDeleted, song)
+	}
+
+	r.log.Infof("Successfully deleted song with id %d", songID)
+	return nil
+}
+
+func (r *MemoryMusicRepository) GetSong(ctx context.Context, songID int) (*model.Song, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	song, ok := r.songs[songID]
+	if !ok {
+		return nil, ErrSongNotFound
+	}
+
+	found := *song
+
+	r.log.Infof("Successfully got song with id %d", songID)
+	return &found, nil
+}
+
+func (r *MemoryMusicRepository) GetSongWithLyrics(ctx context.Context, songID int, withLyrics bool) (*model.SongWithLyrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	song, ok := r.songs[songID]
+	if !ok {
+		return nil, ErrSongNotFound
+	}
+
+	found := model.SongWithLyrics{
+		Song:        *song,
+		Language:    r.languages[songID],
+		VersesCount: len(r.verses[songID]),
+	}
+
+	if withLyrics {
+		for _, verse := range r.verses[songID] {
+			v := *verse
+			v.Language = found.Language
+			found.Lyrics = append(found.Lyrics, &v)
+		}
+	}
+
+	r.log.Infof("Successfully got song with id %d and %d verses", songID, found.VersesCount)
+	return &found, nil
+}
+
+func (r *MemoryMusicRepository) GetEvents(ctx context.Context, afterID int64, limit int) ([]*model.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Event ids are consecutive and start at 1.
+	var events []*model.Event
+	for _, event := range paginate(r.events, limit, int(max(afterID, 0))) {
+		copied := *event
+		events = append(events, &copied)
+	}
+
+	return events, nil
+}
+
+func (r *MemoryMusicRepository) LastEventID(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.nextEventID - 1, nil
+}
+
+func (r *MemoryMusicRepository) FindSong(ctx context.Context, group, song string) (*model.Song, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := r.songByKey(normalizeKey(group), normalizeKey(song))
+	if found == nil {
+		return nil, ErrSongNotFound
+	}
+
+	copied := *found
+	return &copied, nil
+}
+
+func (r *MemoryMusicRepository) FindDuplicates(ctx context.Context, threshold float64, limit int) ([]*model.DuplicateSongs, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	songs := make([]*model.Song, 0, len(r.songs))
+	for _, song := range r.songs {
+		copied := *song
+		songs = append(songs, &copied)
+	}
+
+	duplicates := findDuplicates(songs, threshold, limit)
+
+	r.log.Infof("Successfully found %d duplicate songs", len(duplicates))
+	return duplicates, nil
+}
+
+func (r *MemoryMusicRepository) MergeSongs(ctx context.Context, targetID, sourceID int, version *int) (*model.Song, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	source, ok := r.songs[sourceID]
+	if !ok {
+		return nil, ErrSongNotFound
+	}
+
+	target, ok := r.songs[targetID]
+	if version != nil && (!ok || target.Version != *version) {
+		r.log.Debugf("MergeSongs repository: song id %d doesn't have version %d", targetID, *version)
+		return nil, ErrVersionMismatch
+	}
+	if !ok {
+		return nil, ErrSongNotFound
+	}
+
+	if target.ReleaseDate == "" {
+		target.ReleaseDate = source.ReleaseDate
+	}
+	if target.Link == "" {
+		target.Link = source.Link
+	}
+	if len(r.verses[targetID]) == 0 && len(r.verses[sourceID]) > 0 {
+		r.verses[targetID] = r.verses[sourceID]
+		r.translations[targetID] = r.translations[sourceID]
+		r.languages[targetID] = r.languages[sourceID]
+	}
+	for name := range r.songTags[sourceID] {
+		r.tagSong(targetID, name)
+	}
+	for _, link := range r.links {
+		if link.SongID == sourceID && r.songLink(targetID, link.URL) == nil {
+			link.SongID = targetID
+		}
+	}
+	touch(target)
+
+	delete(r.songs, sourceID)
+	delete(r.verses, sourceID)
+	delete(r.translations, sourceID)
+	delete(r.languages, sourceID)
+	delete(r.songTags, sourceID)
+	r.deleteLinks(sourceID)
+
+	r.addEvent(model.EventSongUpdated, target)
+	r.addEvent(model.EventSongDeleted, source)
+
+	merged := *target
+
+	r.log.Infof("Successfully merged song id %d into %d", sourceID, targetID)
+	return &merged, nil
+}
+
+func (r *MemoryMusicRepository) TagSong(ctx context.Context, songID int, name, kind string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.songs[songID]; !ok {
+		return ErrSongNotFound
+	}
+
+	name = normalizeKey(name)
+
+	tag, ok := r.tags[name]
+	if !ok {
+		tag = &model.Tag{ID: r.nextTagID, Name: name, Kind: tagKind(kind)}
+		r.tags[name] = tag
+		r.nextTagID++
+	}
+	if kind != "" {
+		tag.Kind = kind
+	}
+	r.tagSong(songID, name)
+
+	r.log.Infof("Successfully tagged song id %d with %s", songID, name)
+	return nil
+}
+
+func (r *MemoryMusicRepository) UntagSong(ctx context.Context, songID int, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.songTags[songID], normalizeKey(name))
+
+	r.log.Infof("Successfully untagged song id %d", songID)
+	return nil
+}
+
+func (r *MemoryMusicRepository) GetSongTags(ctx context.Context, songID int) ([]*model.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := make([]*model.Tag, 0, len(r.songTags[songID]))
+	for name := range r.songTags[songID] {
+		tag := *r.tags[name]
+		tags = append(tags, &tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	return tags, nil
+}
+
+func (r *MemoryMusicRepository) ListTags(ctx context.Context, kind string) ([]*model.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := make([]*model.Tag, 0, len(r.tags))
+	for _, tag := range r.tags {
+		if kind != "" && tag.Kind != kind {
+			continue
+		}
+		copied := *tag
+		tags = append(tags, &copied)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	return tags, nil
+}
+
+func (r *MemoryMusicRepository) GetFacets(ctx context.Context, req *dto.GetSongsListReq, limit int) (*model.Facets, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := make(map[string]int)
+	artists := make(map[string]int)
+	years := make(map[string]int)
+
+	for _, song := range r.filterSongs(req) {
+		for name := range r.songTags[song.ID] {
+			tags[name]++
+		}
+		artists[song.Group]++
+		if year := releaseYear(song.ReleaseDate); year != "" {
+			years[year]++
+		}
+	}
+
+	facets := &model.Facets{
+		Tags:    facetCounts(tags, limit),
+		Artists: facetCounts(artists, limit),
+		Years:   facetCounts(years, limit),
+	}
+	for _, count := range facets.Tags {
+		count.Kind = r.tags[count.Value].Kind
+	}
+
+	return facets, nil
+}
+
+func (r *MemoryMusicRepository) GetLyricsLanguages(ctx context.Context, songID int) (*model.LyricsLanguages, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.songs[songID]; !ok {
+		return nil, ErrSongNotFound
+	}
+
+	languages := &model.LyricsLanguages{Original: r.languages[songID], Translations: make([]string, 0)}
+	for lang := range r.translations[songID] {
+		languages.Translations = append(languages.Translations, lang)
+	}
+	sort.Strings(languages.Translations)
+
+	return languages, nil
+}
+
+func (r *MemoryMusicRepository) SetTranslation(ctx context.Context, songID int, lang, lyrics string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	song, ok := r.songs[songID]
+	if !ok {
+		return ErrSongNotFound
+	}
+
+	verses := splitVerses(lyrics)
+
+	err := checkTranslation(r.languages[songID], lang, len(r.verses[songID]), len(verses))
+	if err != nil {
+		r.log.Debugf("SetTranslation repository: song id %d: %s", songID, err)
+		return err
+	}
+
+	translated := make([]*model.Verse, 0, len(verses))
+	for i, verse := range verses {
+		translated = append(translated, &model.Verse{Number: i + 1, Lyrics: verse})
+	}
+
+	if r.translations[songID] == nil {
+		r.translations[songID] = make(map[string][]*model.Verse)
+	}
+	r.translations[songID][lang] = translated
+	touch(song)
+	r.addEvent(model.EventSongUpdated, song)
+
+	r.log.Infof("Successfully set %s translation of song id %d", lang, songID)
+	return nil
+}
+
+func (r *MemoryMusicRepository) DeleteTranslation(ctx context.Context, songID int, lang string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	song, ok := r.songs[songID]
+	if _, found := r.translations[songID][lang]; !ok || !found {
+		return ErrTranslationNotFound
+	}
+
+	delete(r.translations[songID], lang)
+	touch(song)
+	r.addEvent(model.EventSongUpdated, song)
+
+	r.log.Infof("Successfully deleted %s translation of song id %d", lang, songID)
+	return nil
+}
+
+// tagSong adds an existing tag to the song, r.mu must be held for writing.
+func (r *MemoryMusicRepository) tagSong(songID int, name string) {
+	if r.songTags[songID] == nil {
+		r.songTags[songID] = make(map[string]struct{})
+	}
+	r.songTags[songID][name] = struct{}{}
+}
+
+// hasTags reports whether the song has any of the tags, or all of them, r.mu must be held.
+func (r *MemoryMusicRepository) hasTags(songID int, tags []string, all bool) bool {
+	for _, name := range tags {
+		_, ok := r.songTags[songID][name]
+		if ok && !all {
+			return true
+		}
+		if !ok && all {
+			return false
+		}
+	}
+	return all
+}
+
+// songByKey returns the song with the normalized artist and title, r.mu must be held.
+func (r *MemoryMusicRepository) songByKey(artistKey, songKey string) *model.Song {
+	for _, song := range r.songs {
+		if normalizeKey(song.Group) == artistKey && normalizeKey(song.Song) == songKey {
+			return song
+		}
+	}
+	return nil
+}
+
+// addEvent appends an event to the outbox, r.mu must be held for writing.
+func (r *MemoryMusicRepository) addEvent(eventType string, song *model.Song) {
+	// Encoding a model.Song can't fail.
+	data, _ := eventData(song)
+
+	r.events = append(r.events, &model.Event{
+		ID:        r.nextEventID,
+		Type:      eventType,
+		SongID:    song.ID,
+		Data:      json.RawMessage(data),
+		CreatedAt: time.Now().UTC(),
+	})
+	r.nextEventID++
+}
+
+// releaseYear extracts the year of both release date layouts like releaseYearExpr.
+func releaseYear(date string) string {
+	if len(date) != 10 {
+		return ""
+	}
+	if date[4] == '-' && date[7] == '-' {
+		return date[:4]
+	}
+	if date[2] == '.' && date[5] == '.' {
+		return date[6:]
+	}
+	return ""
+}
+
+// facetCounts orders counts like facetsQueries: most common values first.
+func facetCounts(counts map[string]int, limit int) []*model.FacetCount {
+	facet := make([]*model.FacetCount, 0, len(counts))
+	for value, count := range counts {
+		facet = append(facet, &model.FacetCount{Value: value, Count: count})
+	}
+
+	sort.Slice(facet, func(i, j int) bool {
+		if facet[i].Count != facet[j].Count {
+			return facet[i].Count > facet[j].Count
+		}
+		return facet[i].Value < facet[j].Value
+	})
+
+	if len(facet) > limit {
+		facet = facet[:limit]
+	}
+	return facet
+}
+
+func touch(song *model.Song) {
+	song.Version++
+	song.UpdatedAt = time.Now().UTC()
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	return items
+}
+
+func (r *MemoryMusicRepository) GetSongLinks(ctx context.Context, songID int) ([]*model.Link, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	links := make([]*model.Link, 0)
+	for _, link := range r.links {
+		if link.SongID == songID {
+			found := *link
+			links = append(links, &found)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+
+	return links, nil
+}
+
+func (r *MemoryMusicRepository) AddSongLink(ctx context.Context, link *model.Link) (*model.Link, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.songs[link.SongID]; !ok {
+		return nil, ErrSongNotFound
+	}
+	if r.songLink(link.SongID, link.URL) != nil {
+		r.log.Debugf("AddSongLink repository: song id %d already has link %s", link.SongID, link.URL)
+		return nil, ErrDuplicateLink
+	}
+
+	added := r.addLink(link)
+
+	r.log.Infof("Successfully added %s link to song id %d", added.Provider, added.SongID)
+
+	found := *added
+	return &found, nil
+}
+
+// addLink stores the link and returns it, r.mu must be held for writing.
+func (r *MemoryMusicRepository) addLink(link *model.Link) *model.Link {
+	added := &model.Link{
+		ID:       r.nextLinkID,
+		SongID:   link.SongID,
+		Provider: link.Provider,
+		URL:      link.URL,
+		Status:   model.LinkStatusUnknown,
+	}
+	r.links[added.ID] = added
+	r.nextLinkID++
+
+	return added
+}
+
+func (r *MemoryMusicRepository) DeleteSongLink(ctx context.Context, songID, linkID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.links[linkID]
+	if !ok || link.SongID != songID {
+		return ErrLinkNotFound
+	}
+	delete(r.links, linkID)
+
+	r.log.Infof("Successfully deleted link id %d of song id %d", linkID, songID)
+	return nil
+}
+
+func (r *MemoryMusicRepository) LinksToCheck(ctx context.Context, domains []string, checkedBefore time.Time, limit int) ([]*model.Link, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	links := make([]*model.Link, 0)
+	for _, link := range r.links {
+		if link.CheckedAt != nil && !link.CheckedAt.Before(checkedBefore) {
+			continue
+		}
+		if !medialink.Allowed(medialink.Host(link.URL), domains) {
+			continue
+		}
+		found := *link
+		links = append(links, &found)
+	}
+
+	// Never checked links first, then the ones checked longest ago.
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i].CheckedAt, links[j].CheckedAt
+		switch {
+		case a == nil && b == nil:
+			return links[i].ID < links[j].ID
+		case a == nil || b == nil:
+			return a == nil
+		case !a.Equal(*b):
+			return a.Before(*b)
+		}
+		return links[i].ID < links[j].ID
+	})
+	if len(links) > limit {
+		links = links[:limit]
+	}
+
+	return links, nil
+}
+
+func (r *MemoryMusicRepository) SetLinkStatus(ctx context.Context, linkID int, status string, checkedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if link, ok := r.links[linkID]; ok {
+		checkedAt = checkedAt.UTC()
+		link.Status = status
+		link.CheckedAt = &checkedAt
+	}
+
+	return nil
+}
+
+// songLink returns the link of the song with the URL, r.mu must be held.
+func (r *MemoryMusicRepository) songLink(songID int, url string) *model.Link {
+	for _, link := range r.links {
+		if link.SongID == songID && link.URL == url {
+			return link
+		}
+	}
+	return nil
+}
+
+// deleteLinks deletes the links of the song, r.mu must be held.
+func (r *MemoryMusicRepository) deleteLinks(songID int) {
+	for id, link := range r.links {
+		if link.SongID == songID {
+			delete(r.links, id)
+		}
+	}
+}
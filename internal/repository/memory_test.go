@@ -0,0 +1,34 @@
+package repository_test
+
+import (
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/repository/repotest"
+	"github.com/sirupsen/logrus"
+	"io"
+	"testing"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+func TestMemoryMusicRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.IMusicRepository {
+		return repository.NewMemoryMusicRepository(testLogger())
+	})
+}
+
+func TestMemoryWebhookRepository(t *testing.T) {
+	repotest.RunWebhooks(t, func(t *testing.T) (repository.IMusicRepository, repository.IWebhookRepository) {
+		music := repository.NewMemoryMusicRepository(testLogger())
+		return music, repository.NewMemoryWebhookRepository(music)
+	})
+}
+
+func TestMemoryUpstreamCacheRepository(t *testing.T) {
+	repotest.RunUpstreamCache(t, func(t *testing.T) repository.IUpstreamCacheRepository {
+		return repository.NewMemoryUpstreamCacheRepository(testLogger())
+	})
+}
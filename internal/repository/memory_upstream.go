@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+// MemoryUpstreamCacheRepository is an IUpstreamCacheRepository kept in memory.
+type MemoryUpstreamCacheRepository struct {
+	mu        sync.RWMutex
+	responses map[upstreamKey]*model.UpstreamResponse
+	log       *logrus.Logger
+}
+
+type upstreamKey struct {
+	provider, group, song string
+}
+
+func NewMemoryUpstreamCacheRepository(log *logrus.Logger) *MemoryUpstreamCacheRepository {
+	return &MemoryUpstreamCacheRepository{
+		responses: make(map[upstreamKey]*model.UpstreamResponse),
+		log:       log,
+	}
+}
+
+func (r *MemoryUpstreamCacheRepository) GetUpstreamResponse(ctx context.Context, provider, group, song string) (*model.UpstreamResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	response, ok := r.responses[upstreamKey{provider, normalizeKey(group), normalizeKey(song)}]
+	if !ok {
+		return nil, ErrUpstreamResponseNotFound
+	}
+
+	copied := *response
+	return &copied, nil
+}
+
+func (r *MemoryUpstreamCacheRepository) PutUpstreamResponse(ctx context.Context, response *model.UpstreamResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *response
+	stored.FetchedAt = stored.FetchedAt.UTC()
+	r.responses[upstreamKey{response.Provider, normalizeKey(response.Group), normalizeKey(response.Song)}] = &stored
+
+	return nil
+}
+
+func (r *MemoryUpstreamCacheRepository) PurgeUpstreamResponses(ctx context.Context, filter *model.UpstreamCacheFilter) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := 0
+	for key, response := range r.responses {
+		if filter.Provider != "" && key.provider != filter.Provider ||
+			filter.Group != "" && key.group != normalizeKey(filter.Group) ||
+			filter.Song != "" && key.song != normalizeKey(filter.Song) ||
+			filter.NotFoundOnly && !response.NotFound ||
+			!filter.FetchedBefore.IsZero() && !response.FetchedAt.Before(filter.FetchedBefore) {
+			continue
+		}
+
+		delete(r.responses, key)
+		purged++
+	}
+
+	r.log.Infof("Successfully purged %d cached upstream responses", purged)
+	return purged, nil
+}
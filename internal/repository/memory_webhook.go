@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// MemoryWebhookRepository is an IWebhookRepository kept in memory, which
+// dispatches the outbox events of a MemoryMusicRepository. It shares the lock
+// of the music repository, as both read and write its events.
+type MemoryWebhookRepository struct {
+	music          *MemoryMusicRepository
+	webhooks       map[int]*model.Webhook
+	deliveries     []*model.WebhookDelivery
+	dispatched     int
+	nextID         int
+	nextDeliveryID int64
+	log            *logrus.Logger
+}
+
+func NewMemoryWebhookRepository(music *MemoryMusicRepository) *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		music:          music,
+		webhooks:       make(map[int]*model.Webhook),
+		nextID:         1,
+		nextDeliveryID: 1,
+		log:            music.log,
+	}
+}
+
+func (r *MemoryWebhookRepository) AddWebhook(ctx context.Context, webhook *model.Webhook) (*model.Webhook, error) {
+	r.music.mu.Lock()
+	defer r.music.mu.Unlock()
+
+	webhook.ID = r.nextID
+	webhook.CreatedAt = time.Now().UTC()
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	r.nextID++
+
+	stored := *webhook
+	r.webhooks[stored.ID] = &stored
+
+	r.log.Infof("Successfully added webhook %d for %s", webhook.ID, webhook.URL)
+	return webhook, nil
+}
+
+func (r *MemoryWebhookRepository) ListWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	r.music.mu.RLock()
+	defer r.music.mu.RUnlock()
+
+	webhooks := make([]*model.Webhook, 0, len(r.webhooks))
+	for id := 1; id < r.nextID; id++ {
+		if webhook, ok := r.webhooks[id]; ok {
+			copied := *webhook
+			webhooks = append(webhooks, &copied)
+		}
+	}
+
+	return webhooks, nil
+}
+
+func (r *MemoryWebhookRepository) DeleteWebhook(ctx context.Context, webhookID int) error {
+	r.music.mu.Lock()
+	defer r.music.mu.Unlock()
+
+	if _, ok := r.webhooks[webhookID]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(r.webhooks, webhookID)
+
+	deliveries := r.deliveries[:0]
+	for _, delivery := range r.deliveries {
+		if delivery.Webhook.ID != webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	r.deliveries = deliveries
+
+	r.log.Infof("Successfully deleted webhook with id %d", webhookID)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) EnqueueDeliveries(ctx context.Context, limit int) (int, error) {
+	r.music.mu.Lock()
+	defer r.music.mu.Unlock()
+
+	events := r.music.events[r.dispatched:]
+	if limit < len(events) {
+		events = events[:limit]
+	}
+
+	now := time.Now().UTC()
+	var enqueued int
+
+	for _, event := range events {
+		for id := 1; id < r.nextID; id++ {
+			webhook, ok := r.webhooks[id]
+			if !ok || !webhook.Subscribed(event.Type) {
+				continue
+			}
+
+			r.deliveries = append(r.deliveries, &model.WebhookDelivery{
+				ID:            r.nextDeliveryID,
+				Webhook:       webhook,
+				Event:         event,
+				Status:        model.DeliveryPending,
+				NextAttemptAt: now,
+				UpdatedAt:     now,
+			})
+			r.nextDeliveryID++
+			enqueued++
+		}
+	}
+	r.dispatched += len(events)
+
+	if enqueued > 0 {
+		r.log.Infof("Successfully enqueued %d webhook deliveries", enqueued)
+	}
+	return enqueued, nil
+}
+
+func (r *MemoryWebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	r.music.mu.Lock()
+	defer r.music.mu.Unlock()
+
+	now := time.Now().UTC()
+	var deliveries []*model.WebhookDelivery
+
+	for _, delivery := range r.deliveries {
+		if len(deliveries) == limit {
+			break
+		}
+		if delivery.Status != model.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+
+		deliveries = append(deliveries, copyDelivery(delivery))
+		delivery.NextAttemptAt = now.Add(lease)
+	}
+
+	return deliveries, nil
+}
+
+func (r *MemoryWebhookRepository) MarkDelivered(ctx context.Context, deliveryID int64) error {
+	r.music.mu.Lock()
+	defer r.music.mu.Unlock()
+
+	if delivery := r.delivery(deliveryID); delivery != nil {
+		delivery.Status = model.DeliveryDelivered
+		delivery.Attempts++
+		delivery.LastError = ""
+		delivery.UpdatedAt = time.Now().UTC()
+	}
+
+	r.log.Debugf("MarkDelivered repository: delivery %d delivered", deliveryID)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) MarkFailed(ctx context.Context, deliveryID int64, lastError string, nextAttemptAt time.Time, dead bool) error {
+	r.music.mu.Lock()
+	defer r.music.mu.Unlock()
+
+	status := model.DeliveryPending
+	if dead {
+		status = model.DeliveryDead
+	}
+
+	if delivery := r.delivery(deliveryID); delivery != nil {
+		delivery.Status = status
+		delivery.Attempts++
+		delivery.LastError = lastError
+		delivery.NextAttemptAt = nextAttemptAt.UTC()
+		delivery.UpdatedAt = time.Now().UTC()
+	}
+
+	r.log.Debugf("MarkFailed repository: delivery %d is %s", deliveryID, status)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) ListDeadLetters(ctx context.Context, limit, offset int) ([]*model.WebhookDelivery, error) {
+	r.music.mu.RLock()
+	defer r.music.mu.RUnlock()
+
+	// Only the columns of the webhook_dead_letters view are returned.
+	var deliveries []*model.WebhookDelivery
+	for _, delivery := range r.dead() {
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			ID:        delivery.ID,
+			Webhook:   &model.Webhook{ID: delivery.Webhook.ID, URL: delivery.Webhook.URL},
+			Event:     &model.Event{ID: delivery.Event.ID, Type: delivery.Event.Type},
+			Status:    delivery.Status,
+			Attempts:  delivery.Attempts,
+			LastError: delivery.LastError,
+			UpdatedAt: delivery.UpdatedAt,
+		})
+	}
+
+	return paginate(deliveries, limit, offset), nil
+}
+
+func (r *MemoryWebhookRepository) CountDeadLetters(ctx context.Context) (int, error) {
+	r.music.mu.RLock()
+	defer r.music.mu.RUnlock()
+
+	return len(r.dead()), nil
+}
+
+func (r *MemoryWebhookRepository) RetryDelivery(ctx context.Context, deliveryID int64) error {
+	r.music.mu.Lock()
+	defer r.music.mu.Unlock()
+
+	delivery := r.delivery(deliveryID)
+	if delivery == nil || delivery.Status != model.DeliveryDead {
+		return ErrDeliveryNotFound
+	}
+
+	now := time.Now().UTC()
+	delivery.Status = model.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.UpdatedAt = now
+
+	r.log.Infof("Successfully requeued webhook delivery %d", deliveryID)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) delivery(deliveryID int64) *model.WebhookDelivery {
+	for _, delivery := range r.deliveries {
+		if delivery.ID == deliveryID {
+			return delivery
+		}
+	}
+	return nil
+}
+
+func (r *MemoryWebhookRepository) dead() []*model.WebhookDelivery {
+	var deliveries []*model.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == model.DeliveryDead {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries
+}
+
+// copyDelivery copies a delivery along with its webhook and event, so callers
+// can't race with later changes.
+func copyDelivery(delivery *model.WebhookDelivery) *model.WebhookDelivery {
+	copied := *delivery
+	webhook := *delivery.Webhook
+	event := *delivery.Event
+	copied.Webhook = &webhook
+	copied.Event = &event
+	return &copied
+}
@@ -0,0 +1,830 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/pkg/langdetect"
+	"github.com/aaanger/music-library/pkg/medialink"
+	"github.com/aaanger/music-library/pkg/translit"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"strconv"
+)
+
+// PgxMusicRepository is an IMusicRepository running directly on a pgx pool,
+// which allows COPY based batch inserts and pgx statement caching.
+type PgxMusicRepository struct {
+	pool *pgxpool.Pool
+	log  *logrus.Logger
+}
+
+func NewPgxMusicRepository(pool *pgxpool.Pool, log *logrus.Logger) *PgxMusicRepository {
+	return &PgxMusicRepository{
+		pool: pool,
+		log:  log,
+	}
+}
+
+// AddSong inserts the song and its song.created event in one transaction.
+// ErrDuplicateSong is returned if the library already has the song.
+func (r *PgxMusicRepository) AddSong(ctx context.Context, song *model.Song) (*model.Song, error) {
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		return insertPgxSong(ctx, tx, song)
+	})
+	if isUniqueViolation(err) {
+		r.log.Debugf("AddSong repository: song %s - %s already exists", song.Group, song.Song)
+		return nil, ErrDuplicateSong
+	}
+	if err != nil {
+		r.log.Errorf("AddSong repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully added song to DB: %+v", song)
+	return song, nil
+}
+
+// IngestSong adds a song with its lyrics and the link, if it isn't nil, in
+// one transaction like MusicRepository.IngestSong.
+func (r *PgxMusicRepository) IngestSong(ctx context.Context, song *model.Song, lyrics string, link *model.Link) (*model.Song, error) {
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		err := insertPgxSong(ctx, tx, song)
+		if err != nil {
+			return err
+		}
+
+		stored, err := insertPgxLyrics(ctx, tx, song.ID, lyrics, nil)
+		if err != nil {
+			return err
+		}
+		song.Version, song.UpdatedAt = stored.Version, stored.UpdatedAt
+
+		if link == nil {
+			return nil
+		}
+		link.SongID = song.ID
+		_, err = scanLink(tx.QueryRow(ctx, insertLinkQuery, link.SongID, link.Provider, link.URL, medialink.Host(link.URL)))
+		return err
+	})
+	if isUniqueViolation(err) {
+		r.log.Debugf("IngestSong repository: song %s - %s already exists", song.Group, song.Song)
+		return nil, ErrDuplicateSong
+	}
+	if err != nil {
+		r.log.Errorf("IngestSong repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully ingested song to DB: %+v", song)
+	return song, nil
+}
+
+// insertPgxSong inserts the song and its song.created event, setting its id and version.
+func insertPgxSong(ctx context.Context, tx pgx.Tx, song *model.Song) error {
+	row := tx.QueryRow(ctx, insertSongQuery, song.Song, song.Group, song.ReleaseDate, song.Link,
+		normalizeKey(song.Group), normalizeKey(song.Song), translit.Fold(song.Group), translit.Fold(song.Song))
+
+	err := row.Scan(&song.ID, &song.Version, &song.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return insertPgxEvent(ctx, tx, model.EventSongCreated, song)
+}
+
+// AddLyrics replaces the lyrics of a song, inserting all verses with COPY,
+// stores the detected language and bumps the song version in the same
+// transaction.
+func (r *PgxMusicRepository) AddLyrics(ctx context.Context, songID int, lyrics string) error {
+	verses := splitVerses(lyrics)
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := insertPgxLyrics(ctx, tx, songID, lyrics, nil)
+		return err
+	})
+	if errors.Is(err, ErrSongNotFound) {
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("AddLyrics repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully added %d verses for song id %d", len(verses), songID)
+	return nil
+}
+
+// insertPgxLyrics replaces the lyrics of the song like AddLyrics and returns
+// the song with its new version. Unless version is nil, ErrVersionMismatch
+// is returned if the song has a different version.
+func insertPgxLyrics(ctx context.Context, tx pgx.Tx, songID int, lyrics string, version *int) (*model.Song, error) {
+	verses := splitVerses(lyrics)
+
+	rows := make([][]interface{}, 0, len(verses))
+	for i, verse := range verses {
+		rows = append(rows, []interface{}{songID, i + 1, verse})
+	}
+
+	var id int
+	err := tx.QueryRow(ctx, `SELECT id FROM songs WHERE id=$1`, songID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSongNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, deleteLyricsQuery, songID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, deleteStaleTranslationsQuery, songID, len(verses))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"verses"}, []string{"song_id", "verse_number", "verse_lyrics"}, pgx.CopyFromRows(rows))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, setLanguageQuery, langdetect.Detect(lyrics), songID)
+	if err != nil {
+		return nil, err
+	}
+
+	var row pgx.Row
+	if version != nil {
+		row = tx.QueryRow(ctx, touchSongVersionQuery, songID, *version)
+	} else {
+		row = tx.QueryRow(ctx, touchSongQuery, songID)
+	}
+	song, err := scanSong(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVersionMismatch
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return song, insertPgxEvent(ctx, tx, model.EventSongEnriched, song)
+}
+
+func (r *PgxMusicRepository) GetSongsList(ctx context.Context, req *dto.GetSongsListReq) ([]*model.Song, error) {
+	query, values := songsListQuery(req)
+
+	r.log.Debugf("GetSongsList repository filters: song - %v group - %v releaseDate - %v limit - %v offset - %v", req.Song, req.Group, req.ReleaseDate, req.Limit, req.Offset)
+	r.log.Debugf("GetSongsList repository: executing sql query: %s", query)
+	rows, err := r.pool.Query(ctx, query, values...)
+	if err != nil {
+		r.log.Errorf("GetSongsList repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []*model.Song
+
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			r.log.Errorf("GetSongsList repository error: %s", err)
+			return nil, err
+		}
+
+		songs = append(songs, song)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("GetSongsList repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully got songs list: %+v", songs)
+	return songs, nil
+}
+
+// GetSongLyrics returns a page of the verses in language lang, or of the
+// original lyrics if lang is empty or the song has no such translation.
+func (r *PgxMusicRepository) GetSongLyrics(ctx context.Context, songID int, lang string, limit, offset int) ([]*model.Verse, error) {
+	r.log.Debugf("GetSongLyrics repository: songID - %d lang - %q limit - %d offset - %d", songID, lang, limit, offset)
+
+	rows, err := r.pool.Query(ctx, lyricsQuery, songID, lang, limit, offset)
+	if err != nil {
+		r.log.Errorf("GetSongLyrics repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	verses, err := scanLyrics(rows)
+	if err != nil {
+		r.log.Errorf("GetSongLyrics repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully got lyrics for song id %d: %+v", songID, verses)
+	return verses, nil
+}
+
+func (r *PgxMusicRepository) GetLyricsBySongIDs(ctx context.Context, songIDs []int) (map[int][]*model.Verse, error) {
+	lyrics := make(map[int][]*model.Verse, len(songIDs))
+	if len(songIDs) == 0 {
+		return lyrics, nil
+	}
+
+	r.log.Debugf("GetLyricsBySongIDs repository: songIDs - %v", songIDs)
+
+	query, values := lyricsBySongIDsQuery(songIDs)
+
+	rows, err := r.pool.Query(ctx, query, values...)
+	if err != nil {
+		r.log.Errorf("GetLyricsBySongIDs repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = scanLyricsBySongIDs(rows, lyrics)
+	if err != nil {
+		r.log.Errorf("GetLyricsBySongIDs repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully got lyrics for %d songs", len(lyrics))
+	return lyrics, nil
+}
+
+func (r *PgxMusicRepository) UpdateSong(ctx context.Context, songID int, req *dto.UpdateSongReq) error {
+	r.log.Debugf("UpdateSong repository input parameters: song - %v group - %v releaseDate - %v link - %v", req.Song, req.Group, req.ReleaseDate, req.Link)
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := updatePgxSong(ctx, tx, songID, req)
+		return err
+	})
+	if errors.Is(err, ErrVersionMismatch) {
+		r.log.Debugf("UpdateSong repository: song id %d doesn't have version %d", songID, *req.Version)
+		return err
+	}
+	if isUniqueViolation(err) {
+		r.log.Debugf("UpdateSong repository: song id %d would duplicate another song", songID)
+		return ErrDuplicateSong
+	}
+	if err != nil {
+		r.log.Errorf("UpdateSong repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully updated song with id %d", songID)
+	return nil
+}
+
+// updatePgxSong applies the fields of req to the song like updateSong.
+func updatePgxSong(ctx context.Context, tx pgx.Tx, songID int, req *dto.UpdateSongReq) (*model.Song, error) {
+	query, values := updateSongQuery(songID, req)
+
+	tag, err := tx.Exec(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag.RowsAffected() == 0 && req.Version != nil {
+		return nil, ErrVersionMismatch
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, nil
+	}
+
+	song, err := scanSong(tx.QueryRow(ctx, `SELECT `+songColumns+` FROM songs WHERE id=$1`, songID))
+	if err != nil {
+		return nil, err
+	}
+
+	return song, insertPgxEvent(ctx, tx, model.EventSongUpdated, song)
+}
+
+// UpdateSongWithLyrics applies the changes in one transaction like
+// MusicRepository.UpdateSongWithLyrics, inserting the verses with COPY.
+func (r *PgxMusicRepository) UpdateSongWithLyrics(ctx context.Context, songID int, req *dto.UpdateSongReq, link *model.Link) error {
+	r.log.Debugf("UpdateSongWithLyrics repository input parameters: song - %v group - %v releaseDate - %v link - %v text - %t", req.Song, req.Group, req.ReleaseDate, req.Link, req.Text != nil)
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		version := req.Version
+
+		if hasSongFields(req) {
+			song, err := updatePgxSong(ctx, tx, songID, req)
+			if err != nil {
+				return err
+			}
+			if song == nil {
+				return ErrSongNotFound
+			}
+			version = &song.Version
+		}
+
+		if req.Text != nil {
+			_, err := insertPgxLyrics(ctx, tx, songID, *req.Text, version)
+			if err != nil {
+				return err
+			}
+		}
+
+		if link == nil {
+			return nil
+		}
+		_, err := tx.Exec(ctx, keepLinkQuery, songID, link.Provider, link.URL, medialink.Host(link.URL))
+		return err
+	})
+	if errors.Is(err, ErrVersionMismatch) {
+		r.log.Debugf("UpdateSongWithLyrics repository: song id %d doesn't have version %d", songID, *req.Version)
+		return err
+	}
+	if errors.Is(err, ErrSongNotFound) {
+		return err
+	}
+	if isUniqueViolation(err) {
+		r.log.Debugf("UpdateSongWithLyrics repository: song id %d would duplicate another song", songID)
+		return ErrDuplicateSong
+	}
+	if err != nil {
+		r.log.Errorf("UpdateSongWithLyrics repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully updated song with id %d", songID)
+	return nil
+}
+
+// DeleteSong removes the song and records its last state in a song.deleted event.
+func (r *PgxMusicRepository) DeleteSong(ctx context.Context, songID int) error {
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		song, err := scanSong(tx.QueryRow(ctx, `SELECT `+songColumns+` FROM songs WHERE id=$1`, songID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM songs WHERE id = $1;`, songID)
+		if err != nil {
+			return err
+		}
+
+		return insertPgxEvent(ctx, tx, model.EventSongDeleted, song)
+	})
+	if err != nil {
+		r.log.Errorf("DeleteSong repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully deleted song with id %d", songID)
+	return nil
+}
+
+func (r *PgxMusicRepository) GetSong(ctx context.Context, songID int) (*model.Song, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+songColumns+` FROM songs WHERE id=$1`, songID)
+
+	song, err := scanSong(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSongNotFound
+	}
+	if err != nil {
+		r.log.Errorf("GetSong repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully got song with id %d", songID)
+	return song, nil
+}
+
+func (r *PgxMusicRepository) GetSongWithLyrics(ctx context.Context, songID int, withLyrics bool) (*model.SongWithLyrics, error) {
+	query := songWithVersesCountQuery
+	if withLyrics {
+		query = songWithLyricsQuery
+	}
+
+	rows, err := r.pool.Query(ctx, query, songID)
+	if err != nil {
+		r.log.Errorf("GetSongWithLyrics repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	song, err := scanSongWithLyrics(rows, withLyrics)
+	if errors.Is(err, ErrSongNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		r.log.Errorf("GetSongWithLyrics repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully got song with id %d and %d verses", songID, song.VersesCount)
+	return song, nil
+}
+
+func (r *PgxMusicRepository) CountSongs(ctx context.Context, req *dto.GetSongsListReq) (int, error) {
+	query, values := countSongsQuery(req)
+
+	var count int
+	err := r.pool.QueryRow(ctx, query, values...).Scan(&count)
+	if err != nil {
+		r.log.Errorf("CountSongs repository error: %s", err)
+		return 0, err
+	}
+
+	r.log.Debugf("CountSongs repository: %d songs", count)
+	return count, nil
+}
+
+func (r *PgxMusicRepository) GetEvents(ctx context.Context, afterID int64, limit int) ([]*model.Event, error) {
+	rows, err := r.pool.Query(ctx, eventsQuery, afterID, limit)
+	if err != nil {
+		r.log.Errorf("GetEvents repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		r.log.Errorf("GetEvents repository error: %s", err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *PgxMusicRepository) LastEventID(ctx context.Context) (int64, error) {
+	var id int64
+
+	err := r.pool.QueryRow(ctx, lastEventIDQuery).Scan(&id)
+	if err != nil {
+		r.log.Errorf("LastEventID repository error: %s", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *PgxMusicRepository) FindSong(ctx context.Context, group, song string) (*model.Song, error) {
+	found, err := scanSong(r.pool.QueryRow(ctx, findSongQuery, normalizeKey(group), normalizeKey(song)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSongNotFound
+	}
+	if err != nil {
+		r.log.Errorf("FindSong repository error: %s", err)
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func (r *PgxMusicRepository) FindDuplicates(ctx context.Context, threshold float64, limit int) ([]*model.DuplicateSongs, error) {
+	var duplicates []*model.DuplicateSongs
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `SELECT set_config('pg_trgm.similarity_threshold', $1, true)`, strconv.FormatFloat(threshold, 'f', -1, 64))
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, duplicatesQuery, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		duplicates, err = scanDuplicates(rows)
+		return err
+	})
+	if err != nil {
+		r.log.Errorf("FindDuplicates repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully found %d duplicate songs", len(duplicates))
+	return duplicates, nil
+}
+
+// MergeSongs merges song sourceID into song targetID, see MusicRepository.MergeSongs.
+func (r *PgxMusicRepository) MergeSongs(ctx context.Context, targetID, sourceID int, version *int) (*model.Song, error) {
+	var merged *model.Song
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		source, err := scanSong(tx.QueryRow(ctx, `SELECT `+songColumns+` FROM songs WHERE id=$1`, sourceID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		query, values := mergeSongQuery(targetID, sourceID, version)
+
+		merged, err = scanSong(tx.QueryRow(ctx, query, values...))
+		if errors.Is(err, pgx.ErrNoRows) && version != nil {
+			return ErrVersionMismatch
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, moveVersesQuery, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, mergeTagsQuery, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, moveLinksQuery, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+
+		// The source may have been deleted since it was read.
+		tag, err := tx.Exec(ctx, `DELETE FROM songs WHERE id = $1;`, sourceID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrSongNotFound
+		}
+
+		err = insertPgxEvent(ctx, tx, model.EventSongUpdated, merged)
+		if err != nil {
+			return err
+		}
+
+		return insertPgxEvent(ctx, tx, model.EventSongDeleted, source)
+	})
+	if errors.Is(err, ErrSongNotFound) || errors.Is(err, ErrVersionMismatch) {
+		r.log.Debugf("MergeSongs repository: can't merge song id %d into %d: %s", sourceID, targetID, err)
+		return nil, err
+	}
+	if err != nil {
+		r.log.Errorf("MergeSongs repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully merged song id %d into %d", sourceID, targetID)
+	return merged, nil
+}
+
+func (r *PgxMusicRepository) TagSong(ctx context.Context, songID int, name, kind string) error {
+	name = normalizeKey(name)
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var id int
+		err := tx.QueryRow(ctx, `SELECT id FROM songs WHERE id=$1`, songID).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, insertTagQuery, name, tagKind(kind))
+		if err != nil {
+			return err
+		}
+
+		if kind != "" {
+			_, err = tx.Exec(ctx, `UPDATE tags SET kind=$1 WHERE name=$2`, kind, name)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(ctx, tagSongQuery, songID, name)
+		return err
+	})
+	if errors.Is(err, ErrSongNotFound) {
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("TagSong repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully tagged song id %d with %s", songID, name)
+	return nil
+}
+
+func (r *PgxMusicRepository) UntagSong(ctx context.Context, songID int, name string) error {
+	_, err := r.pool.Exec(ctx, untagSongQuery, songID, normalizeKey(name))
+	if err != nil {
+		r.log.Errorf("UntagSong repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully untagged song id %d", songID)
+	return nil
+}
+
+func (r *PgxMusicRepository) GetSongTags(ctx context.Context, songID int) ([]*model.Tag, error) {
+	rows, err := r.pool.Query(ctx, songTagsQuery, songID)
+	if err != nil {
+		r.log.Errorf("GetSongTags repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags, err := scanTags(rows)
+	if err != nil {
+		r.log.Errorf("GetSongTags repository error: %s", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (r *PgxMusicRepository) ListTags(ctx context.Context, kind string) ([]*model.Tag, error) {
+	query, values := tagsQuery(kind)
+
+	rows, err := r.pool.Query(ctx, query, values...)
+	if err != nil {
+		r.log.Errorf("ListTags repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags, err := scanTags(rows)
+	if err != nil {
+		r.log.Errorf("ListTags repository error: %s", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// GetFacets sends the three facet queries in one batch.
+func (r *PgxMusicRepository) GetFacets(ctx context.Context, req *dto.GetSongsListReq, limit int) (*model.Facets, error) {
+	tags, artists, years, values := facetsQueries(req, limit)
+
+	batch := &pgx.Batch{}
+	batch.Queue(tags, values...)
+	batch.Queue(artists, values...)
+	batch.Queue(years, values...)
+
+	results := r.pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	var facets model.Facets
+	for _, dest := range []*[]*model.FacetCount{&facets.Tags, &facets.Artists, &facets.Years} {
+		rows, err := results.Query()
+		if err != nil {
+			r.log.Errorf("GetFacets repository error: %s", err)
+			return nil, err
+		}
+
+		*dest, err = scanFacet(rows)
+		rows.Close()
+		if err != nil {
+			r.log.Errorf("GetFacets repository error: %s", err)
+			return nil, err
+		}
+	}
+
+	return &facets, nil
+}
+
+func (r *PgxMusicRepository) GetLyricsLanguages(ctx context.Context, songID int) (*model.LyricsLanguages, error) {
+	var languages model.LyricsLanguages
+
+	err := r.pool.QueryRow(ctx, songLanguageQuery, songID).Scan(&languages.Original)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSongNotFound
+	}
+	if err != nil {
+		r.log.Errorf("GetLyricsLanguages repository error: %s", err)
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, translationsQuery, songID)
+	if err != nil {
+		r.log.Errorf("GetLyricsLanguages repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	languages.Translations, err = scanTranslations(rows)
+	if err != nil {
+		r.log.Errorf("GetLyricsLanguages repository error: %s", err)
+		return nil, err
+	}
+
+	return &languages, nil
+}
+
+// SetTranslation replaces the translation of the lyrics to lang, the verses
+// are inserted with COPY.
+func (r *PgxMusicRepository) SetTranslation(ctx context.Context, songID int, lang, lyrics string) error {
+	verses := splitVerses(lyrics)
+
+	rows := make([][]interface{}, 0, len(verses))
+	for i, verse := range verses {
+		rows = append(rows, []interface{}{songID, i + 1, verse, lang})
+	}
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var original string
+		err := tx.QueryRow(ctx, songLanguageQuery, songID).Scan(&original)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var count int
+		err = tx.QueryRow(ctx, originalVersesCountQuery, songID).Scan(&count)
+		if err != nil {
+			return err
+		}
+
+		err = checkTranslation(original, lang, count, len(verses))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, deleteTranslationQuery, songID, lang)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"verses"}, []string{"song_id", "verse_number", "verse_lyrics", "lang"}, pgx.CopyFromRows(rows))
+		if err != nil {
+			return err
+		}
+
+		song, err := scanSong(tx.QueryRow(ctx, touchSongQuery, songID))
+		if err != nil {
+			return err
+		}
+
+		return insertPgxEvent(ctx, tx, model.EventSongUpdated, song)
+	})
+	if isTranslationError(err) {
+		r.log.Debugf("SetTranslation repository: song id %d: %s", songID, err)
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("SetTranslation repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully set %s translation of song id %d", lang, songID)
+	return nil
+}
+
+func (r *PgxMusicRepository) DeleteTranslation(ctx context.Context, songID int, lang string) error {
+	if lang == "" {
+		return ErrTranslationNotFound
+	}
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, deleteTranslationQuery, songID, lang)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrTranslationNotFound
+		}
+
+		song, err := scanSong(tx.QueryRow(ctx, touchSongQuery, songID))
+		if err != nil {
+			return err
+		}
+
+		return insertPgxEvent(ctx, tx, model.EventSongUpdated, song)
+	})
+	if errors.Is(err, ErrTranslationNotFound) {
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("DeleteTranslation repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully deleted %s translation of song id %d", lang, songID)
+	return nil
+}
+
+// insertPgxEvent writes an event to the outbox within the transaction of the change.
+func insertPgxEvent(ctx context.Context, tx pgx.Tx, eventType string, song *model.Song) error {
+	data, err := eventData(song)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, insertEventQuery, eventType, song.ID, data)
+	return err
+}
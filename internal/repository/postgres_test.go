@@ -0,0 +1,239 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/repository/repotest"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"os"
+	"testing"
+)
+
+// openPostgres connects to the migrated database of TEST_POSTGRES_DSN, the
+// test is skipped if it isn't set. The tables are truncated by every subtest.
+func openPostgres(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = db.Ping()
+	if err != nil {
+		t.Fatalf("ping postgres: %s", err)
+	}
+
+	return db, dsn
+}
+
+func TestPostgresMusicRepository(t *testing.T) {
+	db, _ := openPostgres(t)
+
+	repotest.Run(t, func(t *testing.T) repository.IMusicRepository {
+		repotest.ResetPostgres(t, db)
+		return repository.NewMusicRepository(db, testLogger())
+	})
+}
+
+func TestPgxMusicRepository(t *testing.T) {
+	db, dsn := openPostgres(t)
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("open pgx pool: %s", err)
+	}
+	t.Cleanup(pool.Close)
+
+	repotest.Run(t, func(t *testing.T) repository.IMusicRepository {
+		repotest.ResetPostgres(t, db)
+		return repository.NewPgxMusicRepository(pool, testLogger())
+	})
+}
+
+func TestPostgresWebhookRepository(t *testing.T) {
+	db, _ := openPostgres(t)
+
+	repotest.RunWebhooks(t, func(t *testing.T) (repository.IMusicRepository, repository.IWebhookRepository) {
+		repotest.ResetPostgres(t, db)
+		return repository.NewMusicRepository(db, testLogger()), repository.NewWebhookRepository(db, testLogger())
+	})
+}
+
+func TestPostgresUpstreamCacheRepository(t *testing.T) {
+	db, _ := openPostgres(t)
+
+	repotest.RunUpstreamCache(t, func(t *testing.T) repository.IUpstreamCacheRepository {
+		repotest.ResetPostgres(t, db)
+		return repository.NewUpstreamCacheRepository(db, testLogger())
+	})
+}
+
+// testEventsCommitOrder checks that readers of the event log don't move past
+// an event whose transaction commits after a newer one.
+func testEventsCommitOrder(t *testing.T, db *sql.DB, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	repotest.ResetPostgres(t, db)
+
+	// The event of tx gets the lower id but is committed last.
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %s", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO song_events (type, song_id, data) VALUES ('song.updated', 0, '{}')`)
+	if err != nil {
+		t.Fatalf("insert event: %s", err)
+	}
+
+	_, err = repo.AddSong(ctx, &model.Song{Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009"})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+
+	events, err := repo.GetEvents(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("GetEvents: %s", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("GetEvents with an older transaction running = %d events, want none", len(events))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("commit: %s", err)
+	}
+
+	events, err = repo.GetEvents(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("GetEvents: %s", err)
+	}
+	if len(events) != 2 || events[0].ID != 1 || events[1].Type != model.EventSongCreated {
+		t.Fatalf("GetEvents after the commit = %+v, want both events in id order", events)
+	}
+
+	last, err := repo.LastEventID(ctx)
+	if err != nil {
+		t.Fatalf("LastEventID: %s", err)
+	}
+	if last != events[1].ID {
+		t.Fatalf("LastEventID = %d, want %d", last, events[1].ID)
+	}
+}
+
+func TestPostgresEventsCommitOrder(t *testing.T) {
+	db, dsn := openPostgres(t)
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("open pgx pool: %s", err)
+	}
+	t.Cleanup(pool.Close)
+
+	t.Run("MusicRepository", func(t *testing.T) {
+		testEventsCommitOrder(t, db, repository.NewMusicRepository(db, testLogger()))
+	})
+	t.Run("PgxMusicRepository", func(t *testing.T) {
+		testEventsCommitOrder(t, db, repository.NewPgxMusicRepository(pool, testLogger()))
+	})
+}
+
+// failPostgresInserts makes inserts into the table fail until the test ends.
+func failPostgresInserts(t *testing.T, db *sql.DB, table string) {
+	t.Helper()
+
+	_, err := db.Exec(`CREATE OR REPLACE FUNCTION fail_inserts() RETURNS trigger AS $$
+		BEGIN RAISE EXCEPTION 'insert failed'; END $$ LANGUAGE plpgsql`)
+	if err != nil {
+		t.Fatalf("create trigger function: %s", err)
+	}
+	_, err = db.Exec(`CREATE TRIGGER fail_inserts BEFORE INSERT ON ` + table + ` FOR EACH ROW EXECUTE FUNCTION fail_inserts()`)
+	if err != nil {
+		t.Fatalf("create trigger: %s", err)
+	}
+
+	t.Cleanup(func() {
+		_, err := db.Exec(`DROP TRIGGER fail_inserts ON ` + table)
+		if err != nil {
+			t.Errorf("drop trigger: %s", err)
+		}
+	})
+}
+
+func TestPostgresIngestSongRollback(t *testing.T) {
+	db, dsn := openPostgres(t)
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("open pgx pool: %s", err)
+	}
+	t.Cleanup(pool.Close)
+
+	failLinks := func(t *testing.T) { failPostgresInserts(t, db, "song_links") }
+
+	t.Run("MusicRepository", func(t *testing.T) {
+		repotest.ResetPostgres(t, db)
+		testIngestSongRollback(t, repository.NewMusicRepository(db, testLogger()), failLinks)
+	})
+	t.Run("PgxMusicRepository", func(t *testing.T) {
+		repotest.ResetPostgres(t, db)
+		testIngestSongRollback(t, repository.NewPgxMusicRepository(pool, testLogger()), failLinks)
+	})
+}
+
+func TestPostgresUpdateSongWithLyricsRollback(t *testing.T) {
+	db, dsn := openPostgres(t)
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("open pgx pool: %s", err)
+	}
+	t.Cleanup(pool.Close)
+
+	failVerses := func(t *testing.T) { failPostgresInserts(t, db, "verses") }
+
+	t.Run("MusicRepository", func(t *testing.T) {
+		repotest.ResetPostgres(t, db)
+		testUpdateSongWithLyricsRollback(t, repository.NewMusicRepository(db, testLogger()), failVerses)
+	})
+	t.Run("PgxMusicRepository", func(t *testing.T) {
+		repotest.ResetPostgres(t, db)
+		testUpdateSongWithLyricsRollback(t, repository.NewPgxMusicRepository(pool, testLogger()), failVerses)
+	})
+}
+
+func TestPostgresBackfillTranslit(t *testing.T) {
+	db, dsn := openPostgres(t)
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("open pgx pool: %s", err)
+	}
+	t.Cleanup(pool.Close)
+
+	markStale := func(t *testing.T, songID int) {
+		_, err := db.Exec(`UPDATE songs SET artist_translit=NULL, song_translit=NULL WHERE id=$1`, songID)
+		if err != nil {
+			t.Fatalf("mark song stale: %s", err)
+		}
+	}
+
+	t.Run("MusicRepository", func(t *testing.T) {
+		repotest.ResetPostgres(t, db)
+		testBackfillTranslit(t, repository.NewMusicRepository(db, testLogger()), markStale)
+	})
+	t.Run("PgxMusicRepository", func(t *testing.T) {
+		repotest.ResetPostgres(t, db)
+		testBackfillTranslit(t, repository.NewPgxMusicRepository(pool, testLogger()), markStale)
+	})
+}
@@ -0,0 +1,613 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/pkg/translit"
+	"strings"
+	"time"
+)
+
+const songColumns = `id, song, artist, release_date, link, version, updated_at`
+
+const insertSongQuery = `INSERT INTO songs (song, artist, release_date, link, artist_key, song_key, artist_translit, song_translit, updated_at)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP) RETURNING id, version, updated_at;`
+
+// staleTranslitQuery selects the songs added before the transliterated
+// columns, their forms are NULL until setTranslitQuery fills them in.
+const staleTranslitQuery = `SELECT id, song, artist FROM songs WHERE artist_translit IS NULL OR song_translit IS NULL`
+
+const setTranslitQuery = `UPDATE songs SET artist_translit=$1, song_translit=$2 WHERE id=$3`
+
+const insertEventQuery = `INSERT INTO song_events (type, song_id, data) VALUES($1, $2, $3)`
+
+// songWithLyricsQuery returns one row per verse of the original lyrics, or a
+// single row with NULL verse columns if the song has no lyrics.
+const songWithLyricsQuery = `SELECT s.id, s.song, s.artist, s.release_date, s.link, s.version, s.updated_at, s.language,
+	COUNT(v.id) OVER (), v.verse_number, v.verse_lyrics
+	FROM songs s LEFT JOIN verses v ON v.song_id = s.id AND v.lang = ''
+	WHERE s.id=$1 ORDER BY v.verse_number`
+
+const songWithVersesCountQuery = `SELECT s.id, s.song, s.artist, s.release_date, s.link, s.version, s.updated_at, s.language,
+	(SELECT COUNT(*) FROM verses v WHERE v.song_id = s.id AND v.lang = '')
+	FROM songs s WHERE s.id=$1`
+
+const insertVerseQuery = `INSERT INTO verses (song_id, verse_number, verse_lyrics, lang) VALUES($1, $2, $3, $4)`
+
+// lyricsQuery returns a page of the verses of song $1 in language $2 with
+// their language. Songs without a translation to $2 fall back to the original.
+const lyricsQuery = `SELECT v.verse_number, v.verse_lyrics, CASE WHEN v.lang = '' THEN s.language ELSE v.lang END
+	FROM verses v JOIN songs s ON s.id = v.song_id
+	WHERE v.song_id=$1 AND v.lang = CASE WHEN EXISTS (SELECT 1 FROM verses t WHERE t.song_id=$1 AND t.lang=$2) THEN $2 ELSE '' END
+	ORDER BY v.verse_number LIMIT $3 OFFSET $4`
+
+const setLanguageQuery = `UPDATE songs SET language=$1 WHERE id=$2`
+
+const songLanguageQuery = `SELECT language FROM songs WHERE id=$1`
+
+const translationsQuery = `SELECT DISTINCT lang FROM verses WHERE song_id=$1 AND lang <> '' ORDER BY lang`
+
+const originalVersesCountQuery = `SELECT COUNT(*) FROM verses WHERE song_id=$1 AND lang = ''`
+
+const deleteTranslationQuery = `DELETE FROM verses WHERE song_id=$1 AND lang=$2`
+
+const deleteLyricsQuery = `DELETE FROM verses WHERE song_id=$1 AND lang = ''`
+
+// deleteStaleTranslationsQuery deletes the translations of song $1 which
+// don't have $2 verses, the number of verses of its new lyrics.
+const deleteStaleTranslationsQuery = `DELETE FROM verses WHERE song_id=$1 AND lang IN
+	(SELECT lang FROM verses WHERE song_id=$1 AND lang <> '' GROUP BY lang HAVING COUNT(*) <> $2)`
+
+// songsFilter builds the WHERE clause shared by the list and count queries.
+// Titles and artists are compared by their transliterated forms, so the
+// filters match regardless of case, script and diacritics.
+func songsFilter(req *dto.GetSongsListReq) (string, []interface{}) {
+	keys := make([]string, 0)
+	values := make([]interface{}, 0)
+	arg := 1
+
+	if req.Song != nil {
+		keys = append(keys, fmt.Sprintf("song_translit=$%d", arg))
+		values = append(values, translit.Fold(*req.Song))
+		arg++
+	}
+	if req.Group != nil {
+		keys = append(keys, fmt.Sprintf("artist_translit=$%d", arg))
+		values = append(values, translit.Fold(*req.Group))
+		arg++
+	}
+	if req.ReleaseDate != nil {
+		keys = append(keys, fmt.Sprintf("release_date=$%d", arg))
+		values = append(values, *req.ReleaseDate)
+		arg++
+	}
+	if tags := tagNames(req.Tags); len(tags) > 0 {
+		key := `id IN (SELECT st.song_id FROM song_tags st JOIN tags t ON t.id = st.tag_id WHERE t.name IN (` + placeholders(arg, len(tags)) + `)`
+		for _, tag := range tags {
+			values = append(values, tag)
+		}
+		arg += len(tags)
+
+		if req.TagMode == dto.TagModeAll {
+			key += fmt.Sprintf(" GROUP BY st.song_id HAVING COUNT(*) = $%d", arg)
+			values = append(values, len(tags))
+			arg++
+		}
+
+		keys = append(keys, key+")")
+	}
+
+	if len(keys) == 0 {
+		return "", values
+	}
+
+	return " WHERE " + strings.Join(keys, " AND "), values
+}
+
+func songsListQuery(req *dto.GetSongsListReq) (string, []interface{}) {
+	where, values := songsFilter(req)
+	arg := len(values) + 1
+
+	query := `SELECT ` + songColumns + ` FROM songs` + where
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", arg, arg+1)
+
+	values = append(values, req.Limit, req.Offset)
+
+	return query, values
+}
+
+// releaseYearExpr extracts the year of both release date layouts, it's NULL
+// for other values. releaseYear does the same for the memory repository.
+const releaseYearExpr = `CASE WHEN release_date LIKE '____-__-__' THEN substr(release_date, 1, 4)
+	WHEN release_date LIKE '__.__.____' THEN substr(release_date, 7, 4) END`
+
+// facetsQueries returns the queries counting the songs matching req by tag,
+// artist and release year, limited to the limit most common values each.
+func facetsQueries(req *dto.GetSongsListReq, limit int) (tags, artists, years string, values []interface{}) {
+	where, values := songsFilter(req)
+	limitArg := fmt.Sprintf(" LIMIT $%d", len(values)+1)
+	values = append(values, limit)
+
+	tags = `SELECT t.name, t.kind, COUNT(*) FROM song_tags st JOIN tags t ON t.id = st.tag_id
+	WHERE st.song_id IN (SELECT id FROM songs` + where + `)
+	GROUP BY t.name, t.kind ORDER BY COUNT(*) DESC, t.name` + limitArg
+
+	artists = `SELECT artist, '', COUNT(*) FROM songs` + where + ` GROUP BY artist ORDER BY COUNT(*) DESC, artist` + limitArg
+
+	years = `SELECT release_year, '', COUNT(*) FROM (SELECT ` + releaseYearExpr + ` AS release_year FROM songs` + where + `) y
+	WHERE release_year IS NOT NULL GROUP BY release_year ORDER BY COUNT(*) DESC, release_year` + limitArg
+
+	return tags, artists, years, values
+}
+
+func countSongsQuery(req *dto.GetSongsListReq) (string, []interface{}) {
+	where, values := songsFilter(req)
+	return `SELECT COUNT(*) FROM songs` + where, values
+}
+
+func updateSongQuery(songID int, req *dto.UpdateSongReq) (string, []interface{}) {
+	keys := make([]string, 0)
+	values := make([]interface{}, 0)
+	arg := 1
+
+	if req.Song != nil {
+		keys = append(keys, fmt.Sprintf("song=$%d, song_key=$%d, song_translit=$%d", arg, arg+1, arg+2))
+		values = append(values, *req.Song, normalizeKey(*req.Song), translit.Fold(*req.Song))
+		arg += 3
+	}
+	if req.Group != nil {
+		keys = append(keys, fmt.Sprintf("artist=$%d, artist_key=$%d, artist_translit=$%d", arg, arg+1, arg+2))
+		values = append(values, *req.Group, normalizeKey(*req.Group), translit.Fold(*req.Group))
+		arg += 3
+	}
+	if req.ReleaseDate != nil {
+		keys = append(keys, fmt.Sprintf("release_date=$%d", arg))
+		values = append(values, *req.ReleaseDate)
+		arg++
+	}
+	if req.Link != nil {
+		keys = append(keys, fmt.Sprintf("link=$%d", arg))
+		values = append(values, *req.Link)
+		arg++
+	}
+
+	keys = append(keys, "version=version+1", "updated_at=CURRENT_TIMESTAMP")
+
+	query := fmt.Sprintf("UPDATE songs SET %s WHERE id=$%d", strings.Join(keys, ", "), arg)
+	values = append(values, songID)
+
+	if req.Version != nil {
+		query += fmt.Sprintf(" AND version=$%d", arg+1)
+		values = append(values, *req.Version)
+	}
+
+	return query, values
+}
+
+func lyricsBySongIDsQuery(songIDs []int) (string, []interface{}) {
+	values := make([]interface{}, 0, len(songIDs))
+	for _, songID := range songIDs {
+		values = append(values, songID)
+	}
+
+	query := `SELECT song_id, verse_number, verse_lyrics FROM verses WHERE song_id IN (` + placeholders(1, len(songIDs)) + `) AND lang = '' ORDER BY song_id, verse_number`
+
+	return query, values
+}
+
+// placeholders returns n comma separated placeholders starting at $from.
+func placeholders(from, n int) string {
+	keys := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		keys = append(keys, fmt.Sprintf("$%d", from+i))
+	}
+	return strings.Join(keys, ", ")
+}
+
+// touchSongQuery bumps the song version when its lyrics change, so cached
+// representations of the lyrics are invalidated as well. It returns the song
+// for the song.enriched event.
+const touchSongQuery = `UPDATE songs SET version=version+1, updated_at=CURRENT_TIMESTAMP WHERE id=$1 RETURNING ` + songColumns
+
+// touchSongVersionQuery is touchSongQuery for a song which must still have version $2.
+const touchSongVersionQuery = `UPDATE songs SET version=version+1, updated_at=CURRENT_TIMESTAMP WHERE id=$1 AND version=$2 RETURNING ` + songColumns
+
+// hasSongFields reports whether req changes any column of the song itself.
+func hasSongFields(req *dto.UpdateSongReq) bool {
+	return req.Song != nil || req.Group != nil || req.ReleaseDate != nil || req.Link != nil
+}
+
+const findSongQuery = `SELECT ` + songColumns + ` FROM songs WHERE artist_key=$1 AND song_key=$2`
+
+// duplicatesQuery pairs songs whose keys are more similar than the
+// pg_trgm.similarity_threshold setting, the % operator uses songs_keys_trgm_idx.
+const duplicatesQuery = `SELECT a.id, a.song, a.artist, a.release_date, a.link, a.version, a.updated_at,
+	b.id, b.song, b.artist, b.release_date, b.link, b.version, b.updated_at,
+	similarity(a.artist_key || ' ' || a.song_key, b.artist_key || ' ' || b.song_key) AS sml
+	FROM songs a JOIN songs b ON a.id < b.id AND (b.artist_key || ' ' || b.song_key) % (a.artist_key || ' ' || a.song_key)
+	ORDER BY sml DESC, a.id, b.id LIMIT $1`
+
+// mergeSongQuery fills the empty release date and link of song $1 from song $2.
+// The language follows the lyrics moved by moveVersesQuery.
+func mergeSongQuery(targetID, sourceID int, version *int) (string, []interface{}) {
+	query := `UPDATE songs SET
+	release_date = COALESCE(NULLIF(release_date, ''), (SELECT s.release_date FROM songs s WHERE s.id=$2)),
+	link = COALESCE(NULLIF(link, ''), (SELECT s.link FROM songs s WHERE s.id=$2)),
+	language = CASE WHEN EXISTS (SELECT 1 FROM verses v WHERE v.song_id=$1 AND v.lang = '') THEN language
+		ELSE (SELECT s.language FROM songs s WHERE s.id=$2) END,
+	version=version+1, updated_at=CURRENT_TIMESTAMP
+	WHERE id=$1`
+	values := []interface{}{targetID, sourceID}
+
+	if version != nil {
+		query += " AND version=$3"
+		values = append(values, *version)
+	}
+
+	return query + " RETURNING " + songColumns, values
+}
+
+// moveVersesQuery hands the verses of song $2 with their translations over to
+// song $1, unless song $1 has lyrics of its own.
+const moveVersesQuery = `UPDATE verses SET song_id=$1 WHERE song_id=$2 AND NOT EXISTS (SELECT 1 FROM verses v WHERE v.song_id=$1 AND v.lang = '')`
+
+const insertTagQuery = `INSERT INTO tags (name, kind) VALUES($1, $2) ON CONFLICT (name) DO NOTHING`
+
+const tagSongQuery = `INSERT INTO song_tags (song_id, tag_id) VALUES($1, (SELECT id FROM tags WHERE name=$2)) ON CONFLICT DO NOTHING`
+
+const untagSongQuery = `DELETE FROM song_tags WHERE song_id=$1 AND tag_id IN (SELECT id FROM tags WHERE name=$2)`
+
+const songTagsQuery = `SELECT t.id, t.name, t.kind FROM tags t JOIN song_tags st ON st.tag_id = t.id WHERE st.song_id=$1 ORDER BY t.name`
+
+// mergeTagsQuery adds the tags of song $2 to song $1.
+const mergeTagsQuery = `INSERT INTO song_tags (song_id, tag_id)
+	SELECT s.id, st.tag_id FROM songs s JOIN song_tags st ON st.song_id=$2 WHERE s.id=$1
+	ON CONFLICT DO NOTHING`
+
+const linkColumns = `id, song_id, provider, url, status, checked_at`
+
+const insertLinkQuery = `INSERT INTO song_links (song_id, provider, url, host) VALUES($1, $2, $3, $4) RETURNING ` + linkColumns
+
+// keepLinkQuery adds a link unless the song already has it.
+const keepLinkQuery = `INSERT INTO song_links (song_id, provider, url, host) VALUES($1, $2, $3, $4) ON CONFLICT DO NOTHING`
+
+const songLinksQuery = `SELECT ` + linkColumns + ` FROM song_links WHERE song_id=$1 ORDER BY id`
+
+const deleteLinkQuery = `DELETE FROM song_links WHERE id=$1 AND song_id=$2`
+
+const setLinkStatusQuery = `UPDATE song_links SET status=$1, checked_at=$2 WHERE id=$3`
+
+// moveLinksQuery hands the links of song $2 over to song $1, except the ones
+// song $1 has already.
+const moveLinksQuery = `UPDATE song_links SET song_id=$1 WHERE song_id=$2 AND url NOT IN (SELECT l.url FROM song_links l WHERE l.song_id=$1)`
+
+// linksToCheckQuery selects the links on the hosts of the domains, or their
+// subdomains, which haven't been checked since checkedBefore. Links never
+// checked go first, then the ones checked longest ago.
+func linksToCheckQuery(domains []string, checkedBefore time.Time, limit int) (string, []interface{}) {
+	hosts := make([]string, 0, len(domains))
+	values := []interface{}{checkedBefore.UTC()}
+
+	for _, domain := range domains {
+		arg := len(values) + 1
+		hosts = append(hosts, fmt.Sprintf("host = $%d OR host LIKE $%d", arg, arg+1))
+		values = append(values, domain, "%."+domain)
+	}
+
+	query := `SELECT ` + linkColumns + ` FROM song_links
+	WHERE (checked_at IS NULL OR checked_at < $1) AND (` + strings.Join(hosts, " OR ") + `)
+	ORDER BY checked_at IS NOT NULL, checked_at, id LIMIT ` + fmt.Sprintf("$%d", len(values)+1)
+	values = append(values, limit)
+
+	return query, values
+}
+
+func tagsQuery(kind string) (string, []interface{}) {
+	if kind == "" {
+		return `SELECT id, name, kind FROM tags ORDER BY name`, nil
+	}
+	return `SELECT id, name, kind FROM tags WHERE kind=$1 ORDER BY name`, []interface{}{kind}
+}
+
+// tagKind returns the kind of a new tag.
+func tagKind(kind string) string {
+	if kind == "" {
+		return model.TagKindTag
+	}
+	return kind
+}
+
+// tagNames normalizes tag names like the stored ones and drops repeated names.
+func tagNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+
+	for _, name := range names {
+		name = normalizeKey(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+
+	return tags
+}
+
+// eventsQuery returns only the events of transactions older than every
+// running one: ids are assigned before commit, so a reader could move past
+// the id of an event still to be committed otherwise.
+const eventsQuery = `SELECT id, type, song_id, data, created_at FROM song_events
+	WHERE id > $1 AND txid < pg_snapshot_xmin(pg_current_snapshot()) ORDER BY id LIMIT $2`
+
+const lastEventIDQuery = `SELECT COALESCE(MAX(id), 0) FROM song_events WHERE txid < pg_snapshot_xmin(pg_current_snapshot())`
+
+// SQLite serializes write transactions, so its ids follow the commit order.
+const (
+	sqliteEventsQuery      = `SELECT id, type, song_id, data, created_at FROM song_events WHERE id > $1 ORDER BY id LIMIT $2`
+	sqliteLastEventIDQuery = `SELECT COALESCE(MAX(id), 0) FROM song_events`
+)
+
+// eventData encodes the song for an outbox event. Lyrics are not part of it.
+func eventData(song *model.Song) (string, error) {
+	data := *song
+	data.Text = ""
+
+	encoded, err := json.Marshal(&data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
+
+func splitVerses(lyrics string) []string {
+	return strings.Split(lyrics, "\n\n")
+}
+
+// checkTranslation checks that a translation to lang with translated verses
+// fits the original lyrics of a song, which are in language original and have
+// the given number of verses.
+func checkTranslation(original, lang string, verses, translated int) error {
+	if lang == "" || lang == original {
+		return ErrOriginalLanguage
+	}
+	if verses == 0 {
+		return ErrNoLyrics
+	}
+	if translated != verses {
+		return fmt.Errorf("%w: the translation has %d verses, the original %d", ErrVerseCountMismatch, translated, verses)
+	}
+	return nil
+}
+
+// isTranslationError reports whether err is an expected rejection of a translation.
+func isTranslationError(err error) bool {
+	return errors.Is(err, ErrSongNotFound) || errors.Is(err, ErrNoLyrics) ||
+		errors.Is(err, ErrVerseCountMismatch) || errors.Is(err, ErrOriginalLanguage)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSong(row rowScanner) (*model.Song, error) {
+	var song model.Song
+
+	err := row.Scan(&song.ID, &song.Song, &song.Group, &song.ReleaseDate, &song.Link, &song.Version, &song.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &song, nil
+}
+
+type rowsScanner interface {
+	rowScanner
+	Next() bool
+	Err() error
+}
+
+// scanSongWithLyrics reads the rows of songWithLyricsQuery or songWithVersesCountQuery.
+func scanSongWithLyrics(rows rowsScanner, withLyrics bool) (*model.SongWithLyrics, error) {
+	var song *model.SongWithLyrics
+
+	for rows.Next() {
+		var current model.SongWithLyrics
+		var number sql.NullInt64
+		var lyrics sql.NullString
+
+		dest := []any{&current.ID, &current.Song.Song, &current.Group, &current.ReleaseDate, &current.Link,
+			&current.Version, &current.UpdatedAt, &current.Language, &current.VersesCount}
+		if withLyrics {
+			dest = append(dest, &number, &lyrics)
+		}
+
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		if song == nil {
+			song = &current
+		}
+		if number.Valid {
+			song.Lyrics = append(song.Lyrics, &model.Verse{Number: int(number.Int64), Lyrics: lyrics.String, Language: song.Language})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if song == nil {
+		return nil, ErrSongNotFound
+	}
+
+	return song, nil
+}
+
+// scanEvents reads the rows of eventsQuery.
+func scanEvents(rows rowsScanner) ([]*model.Event, error) {
+	var events []*model.Event
+
+	for rows.Next() {
+		var event model.Event
+		var data []byte
+
+		err := rows.Scan(&event.ID, &event.Type, &event.SongID, &data, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Data = data
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
+
+// scanLyrics reads the rows of lyricsQuery.
+func scanLyrics(rows rowsScanner) ([]*model.Verse, error) {
+	var verses []*model.Verse
+
+	for rows.Next() {
+		var verse model.Verse
+
+		err := rows.Scan(&verse.Number, &verse.Lyrics, &verse.Language)
+		if err != nil {
+			return nil, err
+		}
+
+		verses = append(verses, &verse)
+	}
+
+	return verses, rows.Err()
+}
+
+// scanTranslations reads the rows of translationsQuery.
+func scanTranslations(rows rowsScanner) ([]string, error) {
+	translations := make([]string, 0)
+
+	for rows.Next() {
+		var lang string
+
+		err := rows.Scan(&lang)
+		if err != nil {
+			return nil, err
+		}
+
+		translations = append(translations, lang)
+	}
+
+	return translations, rows.Err()
+}
+
+// scanLyricsBySongIDs reads the rows of lyricsBySongIDsQuery into lyrics.
+func scanLyricsBySongIDs(rows rowsScanner, lyrics map[int][]*model.Verse) error {
+	for rows.Next() {
+		var songID int
+		var verse model.Verse
+
+		err := rows.Scan(&songID, &verse.Number, &verse.Lyrics)
+		if err != nil {
+			return err
+		}
+
+		lyrics[songID] = append(lyrics[songID], &verse)
+	}
+
+	return rows.Err()
+}
+
+// scanDuplicates reads the rows of duplicatesQuery.
+func scanDuplicates(rows rowsScanner) ([]*model.DuplicateSongs, error) {
+	var duplicates []*model.DuplicateSongs
+
+	for rows.Next() {
+		var a, b model.Song
+		var similarity float64
+
+		err := rows.Scan(&a.ID, &a.Song, &a.Group, &a.ReleaseDate, &a.Link, &a.Version, &a.UpdatedAt,
+			&b.ID, &b.Song, &b.Group, &b.ReleaseDate, &b.Link, &b.Version, &b.UpdatedAt, &similarity)
+		if err != nil {
+			return nil, err
+		}
+
+		duplicates = append(duplicates, &model.DuplicateSongs{Song: &a, Duplicate: &b, Similarity: similarity})
+	}
+
+	return duplicates, rows.Err()
+}
+
+func scanLink(row rowScanner) (*model.Link, error) {
+	var link model.Link
+	var checkedAt sql.NullTime
+
+	err := row.Scan(&link.ID, &link.SongID, &link.Provider, &link.URL, &link.Status, &checkedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkedAt.Valid {
+		link.CheckedAt = &checkedAt.Time
+	}
+
+	return &link, nil
+}
+
+// scanLinks reads the rows of songLinksQuery and linksToCheckQuery.
+func scanLinks(rows rowsScanner) ([]*model.Link, error) {
+	links := make([]*model.Link, 0)
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// scanTags reads the rows of tagsQuery and songTagsQuery.
+func scanTags(rows rowsScanner) ([]*model.Tag, error) {
+	tags := make([]*model.Tag, 0)
+
+	for rows.Next() {
+		var tag model.Tag
+
+		err := rows.Scan(&tag.ID, &tag.Name, &tag.Kind)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, &tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// scanFacet reads the rows of a query returned by facetsQueries.
+func scanFacet(rows rowsScanner) ([]*model.FacetCount, error) {
+	facet := make([]*model.FacetCount, 0)
+
+	for rows.Next() {
+		var count model.FacetCount
+
+		err := rows.Scan(&count.Value, &count.Kind, &count.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		facet = append(facet, &count)
+	}
+
+	return facet, rows.Err()
+}
@@ -3,39 +3,82 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"github.com/aaanger/music-library/internal/dto"
 	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/pkg/langdetect"
+	"github.com/aaanger/music-library/pkg/medialink"
+	"github.com/aaanger/music-library/pkg/translit"
 	"github.com/sirupsen/logrus"
-	"strings"
+	"strconv"
+	"time"
 )
 
 type IMusicRepository interface {
 	AddSong(ctx context.Context, req *model.Song) (*model.Song, error)
 	AddLyrics(ctx context.Context, songID int, lyrics string) error
+	// IngestSong adds a song together with its lyrics and media link, nil
+	// for none, atomically and returns it with its final version.
+	IngestSong(ctx context.Context, song *model.Song, lyrics string, link *model.Link) (*model.Song, error)
 	GetSongsList(ctx context.Context, req *dto.GetSongsListReq) ([]*model.Song, error)
-	GetSongLyrics(ctx context.Context, songID, limit, offset int) ([]*model.Verse, error)
+	CountSongs(ctx context.Context, req *dto.GetSongsListReq) (int, error)
+	GetSongLyrics(ctx context.Context, songID int, lang string, limit, offset int) ([]*model.Verse, error)
+	GetLyricsBySongIDs(ctx context.Context, songIDs []int) (map[int][]*model.Verse, error)
 	UpdateSong(ctx context.Context, songID int, req *dto.UpdateSongReq) error
+	// UpdateSongWithLyrics applies the fields of req, the lyrics of req.Text
+	// and the media link, each unless nil, atomically and bound to
+	// req.Version if it is set.
+	UpdateSongWithLyrics(ctx context.Context, songID int, req *dto.UpdateSongReq, link *model.Link) error
 	DeleteSong(ctx context.Context, songID int) error
+	GetSong(ctx context.Context, songID int) (*model.Song, error)
+	GetSongWithLyrics(ctx context.Context, songID int, withLyrics bool) (*model.SongWithLyrics, error)
+	GetEvents(ctx context.Context, afterID int64, limit int) ([]*model.Event, error)
+	LastEventID(ctx context.Context) (int64, error)
+	FindSong(ctx context.Context, group, song string) (*model.Song, error)
+	FindDuplicates(ctx context.Context, threshold float64, limit int) ([]*model.DuplicateSongs, error)
+	MergeSongs(ctx context.Context, targetID, sourceID int, version *int) (*model.Song, error)
+	TagSong(ctx context.Context, songID int, name, kind string) error
+	UntagSong(ctx context.Context, songID int, name string) error
+	GetSongTags(ctx context.Context, songID int) ([]*model.Tag, error)
+	ListTags(ctx context.Context, kind string) ([]*model.Tag, error)
+	GetFacets(ctx context.Context, req *dto.GetSongsListReq, limit int) (*model.Facets, error)
+	GetLyricsLanguages(ctx context.Context, songID int) (*model.LyricsLanguages, error)
+	SetTranslation(ctx context.Context, songID int, lang, lyrics string) error
+	DeleteTranslation(ctx context.Context, songID int, lang string) error
+	GetSongLinks(ctx context.Context, songID int) ([]*model.Link, error)
+	AddSongLink(ctx context.Context, link *model.Link) (*model.Link, error)
+	DeleteSongLink(ctx context.Context, songID, linkID int) error
+	LinksToCheck(ctx context.Context, domains []string, checkedBefore time.Time, limit int) ([]*model.Link, error)
+	SetLinkStatus(ctx context.Context, linkID int, status string, checkedAt time.Time) error
 }
 
 type MusicRepository struct {
-	db  *sql.DB
-	log *logrus.Logger
+	db *sql.DB
+	// eventsQuery and lastEventIDQuery read the event log in commit order.
+	eventsQuery      string
+	lastEventIDQuery string
+	log              *logrus.Logger
 }
 
 func NewMusicRepository(db *sql.DB, log *logrus.Logger) *MusicRepository {
 	return &MusicRepository{
-		db:  db,
-		log: log,
+		db:               db,
+		eventsQuery:      eventsQuery,
+		lastEventIDQuery: lastEventIDQuery,
+		log:              log,
 	}
 }
 
+// AddSong inserts the song and its song.created event in one transaction.
+// ErrDuplicateSong is returned if the library already has the song.
 func (r *MusicRepository) AddSong(ctx context.Context, song *model.Song) (*model.Song, error) {
-	row := r.db.QueryRowContext(ctx, `INSERT INTO songs (song, artist, release_date, link) VALUES($1, $2, $3, $4) RETURNING id;`,
-		song.Song, song.Group, song.ReleaseDate, song.Link)
-
-	err := row.Scan(&song.ID)
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		return insertSong(ctx, tx, song)
+	})
+	if isUniqueViolation(err) {
+		r.log.Debugf("AddSong repository: song %s - %s already exists", song.Group, song.Song)
+		return nil, ErrDuplicateSong
+	}
 	if err != nil {
 		r.log.Errorf("AddSong repository error: %s", err)
 		return nil, err
@@ -45,52 +88,149 @@ func (r *MusicRepository) AddSong(ctx context.Context, song *model.Song) (*model
 	return song, nil
 }
 
-func (r *MusicRepository) AddLyrics(ctx context.Context, songID int, lyrics string) error {
-	verses := strings.Split(lyrics, "\n\n")
-	query := `INSERT INTO verses (song_id, verse_number, verse_lyrics) VALUES($1, $2, $3)`
+// IngestSong adds a song with its lyrics and the link, if it isn't nil, in
+// one transaction, so a failure doesn't leave a half-added song behind. The
+// returned song has the version after the lyrics are stored.
+// ErrDuplicateSong is returned if the library already has the song.
+func (r *MusicRepository) IngestSong(ctx context.Context, song *model.Song, lyrics string, link *model.Link) (*model.Song, error) {
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := insertSong(ctx, tx, song)
+		if err != nil {
+			return err
+		}
 
-	for i, verse := range verses {
-		_, err := r.db.ExecContext(ctx, query, songID, i+1, verse)
+		stored, err := insertLyrics(ctx, tx, song.ID, lyrics, nil)
 		if err != nil {
-			r.log.Errorf("AddLyrics repository error: %s", err)
 			return err
 		}
+		song.Version, song.UpdatedAt = stored.Version, stored.UpdatedAt
+
+		if link == nil {
+			return nil
+		}
+		link.SongID = song.ID
+		_, err = insertLink(ctx, tx, link)
+		return err
+	})
+	if isUniqueViolation(err) {
+		r.log.Debugf("IngestSong repository: song %s - %s already exists", song.Group, song.Song)
+		return nil, ErrDuplicateSong
+	}
+	if err != nil {
+		r.log.Errorf("IngestSong repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully ingested song to DB: %+v", song)
+	return song, nil
+}
+
+// insertSong inserts the song and its song.created event, setting its id and version.
+func insertSong(ctx context.Context, tx *sql.Tx, song *model.Song) error {
+	row := tx.QueryRowContext(ctx, insertSongQuery, song.Song, song.Group, song.ReleaseDate, song.Link,
+		normalizeKey(song.Group), normalizeKey(song.Song), translit.Fold(song.Group), translit.Fold(song.Song))
+
+	err := row.Scan(&song.ID, &song.Version, &song.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return insertEvent(ctx, tx, model.EventSongCreated, song)
+}
+
+// AddLyrics inserts the verses of a song, replacing the lyrics it had, stores
+// the detected language of the lyrics and records a song.enriched event.
+// Translations which no longer have as many verses are deleted.
+func (r *MusicRepository) AddLyrics(ctx context.Context, songID int, lyrics string) error {
+	verses := splitVerses(lyrics)
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := insertLyrics(ctx, tx, songID, lyrics, nil)
+		return err
+	})
+	if errors.Is(err, ErrSongNotFound) {
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("AddLyrics repository error: %s", err)
+		return err
 	}
 
 	r.log.Infof("Successfully added %d verses for song id %d", len(verses), songID)
 	return nil
 }
 
-func (r *MusicRepository) GetSongsList(ctx context.Context, req *dto.GetSongsListReq) ([]*model.Song, error) {
-	query := `SELECT id, song, artist, release_date, link FROM songs`
+// insertLyrics replaces the lyrics of the song like AddLyrics and returns
+// the song with its new version. The verses are inserted through one
+// prepared statement. Unless version is nil, ErrVersionMismatch is returned
+// if the song has a different version.
+func insertLyrics(ctx context.Context, tx *sql.Tx, songID int, lyrics string, version *int) (*model.Song, error) {
+	verses := splitVerses(lyrics)
+
+	err := deleteLyrics(ctx, tx, songID, len(verses))
+	if err != nil {
+		return nil, err
+	}
 
-	keys := make([]string, 0)
-	values := make([]interface{}, 0)
-	arg := 1
+	stmt, err := tx.PrepareContext(ctx, insertVerseQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for i, verse := range verses {
+		_, err = stmt.ExecContext(ctx, songID, i+1, verse, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, setLanguageQuery, langdetect.Detect(lyrics), songID)
+	if err != nil {
+		return nil, err
+	}
 
-	if req.Song != nil {
-		keys = append(keys, fmt.Sprintf("song=$%d", arg))
-		values = append(values, *req.Song)
-		arg++
+	var row *sql.Row
+	if version != nil {
+		row = tx.QueryRowContext(ctx, touchSongVersionQuery, songID, *version)
+	} else {
+		row = tx.QueryRowContext(ctx, touchSongQuery, songID)
 	}
-	if req.Group != nil {
-		keys = append(keys, fmt.Sprintf("artist=$%d", arg))
-		values = append(values, *req.Group)
-		arg++
+	song, err := scanSong(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVersionMismatch
 	}
-	if req.ReleaseDate != nil {
-		keys = append(keys, fmt.Sprintf("release_date=$%d", arg))
-		values = append(values, *req.ReleaseDate)
-		arg++
+	if err != nil {
+		return nil, err
 	}
 
-	if len(keys) > 0 {
-		query += " WHERE " + strings.Join(keys, " AND ")
+	return song, insertEvent(ctx, tx, model.EventSongEnriched, song)
+}
+
+// deleteLyrics deletes the original lyrics of the song and the translations
+// which don't have the given number of verses. ErrSongNotFound is returned if
+// the song doesn't exist.
+func deleteLyrics(ctx context.Context, tx *sql.Tx, songID, verses int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM songs WHERE id=$1`, songID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSongNotFound
+	}
+	if err != nil {
+		return err
 	}
 
-	query += fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", arg, arg+1)
+	_, err = tx.ExecContext(ctx, deleteLyricsQuery, songID)
+	if err != nil {
+		return err
+	}
 
-	values = append(values, req.Limit, req.Offset)
+	_, err = tx.ExecContext(ctx, deleteStaleTranslationsQuery, songID, verses)
+	return err
+}
+
+func (r *MusicRepository) GetSongsList(ctx context.Context, req *dto.GetSongsListReq) ([]*model.Song, error) {
+	query, values := songsListQuery(req)
 
 	r.log.Debugf("GetSongsList repository filters: song - %v group - %v releaseDate - %v limit - %v offset - %v", req.Song, req.Group, req.ReleaseDate, req.Limit, req.Offset)
 	r.log.Debugf("GetSongsList repository: executing sql query: %s", query)
@@ -104,81 +244,172 @@ func (r *MusicRepository) GetSongsList(ctx context.Context, req *dto.GetSongsLis
 	var songs []*model.Song
 
 	for rows.Next() {
-		var song model.Song
-		err = rows.Scan(&song.ID, &song.Song, &song.Group, &song.ReleaseDate, &song.Link)
+		song, err := scanSong(rows)
 		if err != nil {
 			r.log.Errorf("GetSongsList repository error: %s", err)
 			return nil, err
 		}
 
-		songs = append(songs, &song)
+		songs = append(songs, song)
 	}
 
 	r.log.Infof("Successfully got songs list: %+v", songs)
 	return songs, nil
 }
 
-func (r *MusicRepository) GetSongLyrics(ctx context.Context, songID, limit, offset int) ([]*model.Verse, error) {
-	r.log.Debugf("GetSongLyrics repository: songID - %d limit - %d offset - %d", songID, limit, offset)
+// GetSongLyrics returns a page of the verses in language lang, or of the
+// original lyrics if lang is empty or the song has no such translation.
+func (r *MusicRepository) GetSongLyrics(ctx context.Context, songID int, lang string, limit, offset int) ([]*model.Verse, error) {
+	r.log.Debugf("GetSongLyrics repository: songID - %d lang - %q limit - %d offset - %d", songID, lang, limit, offset)
 
-	rows, err := r.db.QueryContext(ctx, `SELECT verse_number, verse_lyrics FROM verses WHERE song_id=$1 ORDER BY verse_number LIMIT $2 OFFSET $3`,
-		songID, limit, offset)
+	rows, err := r.db.QueryContext(ctx, lyricsQuery, songID, lang, limit, offset)
 	if err != nil {
 		r.log.Errorf("GetSongLyrics repository error: %s", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var verses []*model.Verse
+	verses, err := scanLyrics(rows)
+	if err != nil {
+		r.log.Errorf("GetSongLyrics repository error: %s", err)
+		return nil, err
+	}
 
-	for rows.Next() {
-		var verse model.Verse
+	r.log.Infof("Successfully got lyrics for song id %d: %+v", songID, verses)
+	return verses, nil
+}
 
-		err := rows.Scan(&verse.Number, &verse.Lyrics)
-		if err != nil {
-			r.log.Errorf("GetSongLyrics repository error: %s", err)
-			return nil, err
-		}
+// GetLyricsBySongIDs returns all verses of the given songs in a single query,
+// keyed by song id. Songs without lyrics are missing from the map.
+func (r *MusicRepository) GetLyricsBySongIDs(ctx context.Context, songIDs []int) (map[int][]*model.Verse, error) {
+	lyrics := make(map[int][]*model.Verse, len(songIDs))
+	if len(songIDs) == 0 {
+		return lyrics, nil
+	}
 
-		verses = append(verses, &verse)
+	r.log.Debugf("GetLyricsBySongIDs repository: songIDs - %v", songIDs)
+
+	query, values := lyricsBySongIDsQuery(songIDs)
+
+	rows, err := r.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		r.log.Errorf("GetLyricsBySongIDs repository error: %s", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	r.log.Infof("Successfully got lyrics for song id %d: %+v", songID, verses)
-	return verses, nil
+	err = scanLyricsBySongIDs(rows, lyrics)
+	if err != nil {
+		r.log.Errorf("GetLyricsBySongIDs repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully got lyrics for %d songs", len(lyrics))
+	return lyrics, nil
 }
 
 func (r *MusicRepository) UpdateSong(ctx context.Context, songID int, req *dto.UpdateSongReq) error {
-	keys := make([]string, 0)
-	values := make([]interface{}, 0)
-	arg := 1
+	r.log.Debugf("UpdateSong repository input parameters: song - %v group - %v releaseDate - %v link - %v", req.Song, req.Group, req.ReleaseDate, req.Link)
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := updateSong(ctx, tx, songID, req)
+		return err
+	})
+	if errors.Is(err, ErrVersionMismatch) {
+		r.log.Debugf("UpdateSong repository: song id %d doesn't have version %d", songID, *req.Version)
+		return err
+	}
+	if isUniqueViolation(err) {
+		r.log.Debugf("UpdateSong repository: song id %d would duplicate another song", songID)
+		return ErrDuplicateSong
+	}
+	if err != nil {
+		r.log.Errorf("UpdateSong repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully updated song with id %d", songID)
+	return nil
+}
 
-	if req.Song != nil {
-		keys = append(keys, fmt.Sprintf("song=$%d", arg))
-		values = append(values, *req.Song)
-		arg++
+// updateSong applies the fields of req to the song and records a
+// song.updated event. It returns the updated song, or nil if the song
+// doesn't exist and req has no version.
+func updateSong(ctx context.Context, tx *sql.Tx, songID int, req *dto.UpdateSongReq) (*model.Song, error) {
+	query, values := updateSongQuery(songID, req)
+
+	res, err := tx.ExecContext(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
 	}
-	if req.Group != nil {
-		keys = append(keys, fmt.Sprintf("artist=$%d", arg))
-		values = append(values, *req.Group)
-		arg++
+	if affected == 0 && req.Version != nil {
+		return nil, ErrVersionMismatch
 	}
-	if req.ReleaseDate != nil {
-		keys = append(keys, fmt.Sprintf("release_date=$%d", arg))
-		values = append(values, *req.ReleaseDate)
-		arg++
+	if affected == 0 {
+		return nil, nil
 	}
 
-	joinKeys := strings.Join(keys, ", ")
+	song, err := scanSong(tx.QueryRowContext(ctx, `SELECT `+songColumns+` FROM songs WHERE id=$1`, songID))
+	if err != nil {
+		return nil, err
+	}
 
-	r.log.Debugf("UpdateSong repository input parameters: song - %v group - %v releaseDate - %v", req.Song, req.Group, req.ReleaseDate)
+	return song, insertEvent(ctx, tx, model.EventSongUpdated, song)
+}
 
-	query := fmt.Sprintf("UPDATE songs SET %s WHERE id=$%d", joinKeys, arg)
+// UpdateSongWithLyrics applies the fields of req, replaces the lyrics with
+// req.Text and adds the media link, each unless nil, in one transaction.
+// If req.Version is set, ErrVersionMismatch is returned and nothing is
+// written unless the song still has that version. A link the song already
+// has is kept.
+func (r *MusicRepository) UpdateSongWithLyrics(ctx context.Context, songID int, req *dto.UpdateSongReq, link *model.Link) error {
+	r.log.Debugf("UpdateSongWithLyrics repository input parameters: song - %v group - %v releaseDate - %v link - %v text - %t", req.Song, req.Group, req.ReleaseDate, req.Link, req.Text != nil)
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		version := req.Version
+
+		if hasSongFields(req) {
+			song, err := updateSong(ctx, tx, songID, req)
+			if err != nil {
+				return err
+			}
+			if song == nil {
+				return ErrSongNotFound
+			}
+			version = &song.Version
+		}
 
-	values = append(values, songID)
+		if req.Text != nil {
+			_, err := insertLyrics(ctx, tx, songID, *req.Text, version)
+			if err != nil {
+				return err
+			}
+		}
 
-	_, err := r.db.ExecContext(ctx, query, values...)
+		if link == nil {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, keepLinkQuery, songID, link.Provider, link.URL, medialink.Host(link.URL))
+		return err
+	})
+	if errors.Is(err, ErrVersionMismatch) {
+		r.log.Debugf("UpdateSongWithLyrics repository: song id %d doesn't have version %d", songID, *req.Version)
+		return err
+	}
+	if errors.Is(err, ErrSongNotFound) {
+		return err
+	}
+	if isUniqueViolation(err) {
+		r.log.Debugf("UpdateSongWithLyrics repository: song id %d would duplicate another song", songID)
+		return ErrDuplicateSong
+	}
 	if err != nil {
-		r.log.Errorf("UpdateSong repository error: %s", err)
+		r.log.Errorf("UpdateSongWithLyrics repository error: %s", err)
 		return err
 	}
 
@@ -186,8 +417,24 @@ func (r *MusicRepository) UpdateSong(ctx context.Context, songID int, req *dto.U
 	return nil
 }
 
+// DeleteSong removes the song and records its last state in a song.deleted event.
 func (r *MusicRepository) DeleteSong(ctx context.Context, songID int) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM songs WHERE id = $1;`, songID)
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		song, err := scanSong(tx.QueryRowContext(ctx, `SELECT `+songColumns+` FROM songs WHERE id=$1`, songID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM songs WHERE id = $1;`, songID)
+		if err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, model.EventSongDeleted, song)
+	})
 	if err != nil {
 		r.log.Errorf("DeleteSong repository error: %s", err)
 		return err
@@ -196,3 +443,491 @@ func (r *MusicRepository) DeleteSong(ctx context.Context, songID int) error {
 	r.log.Infof("Successfully deleted song with id %d", songID)
 	return nil
 }
+
+func (r *MusicRepository) GetSong(ctx context.Context, songID int) (*model.Song, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+songColumns+` FROM songs WHERE id=$1`, songID)
+
+	song, err := scanSong(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSongNotFound
+	}
+	if err != nil {
+		r.log.Errorf("GetSong repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully got song with id %d", songID)
+	return song, nil
+}
+
+func (r *MusicRepository) GetSongWithLyrics(ctx context.Context, songID int, withLyrics bool) (*model.SongWithLyrics, error) {
+	query := songWithVersesCountQuery
+	if withLyrics {
+		query = songWithLyricsQuery
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, songID)
+	if err != nil {
+		r.log.Errorf("GetSongWithLyrics repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	song, err := scanSongWithLyrics(rows, withLyrics)
+	if errors.Is(err, ErrSongNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		r.log.Errorf("GetSongWithLyrics repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully got song with id %d and %d verses", songID, song.VersesCount)
+	return song, nil
+}
+
+func (r *MusicRepository) CountSongs(ctx context.Context, req *dto.GetSongsListReq) (int, error) {
+	query, values := countSongsQuery(req)
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, values...).Scan(&count)
+	if err != nil {
+		r.log.Errorf("CountSongs repository error: %s", err)
+		return 0, err
+	}
+
+	r.log.Debugf("CountSongs repository: %d songs", count)
+	return count, nil
+}
+
+// GetEvents returns up to limit events of the outbox following the event
+// afterID. Events of transactions running concurrently with older ones are
+// held back until those commit, so readers never skip an event.
+func (r *MusicRepository) GetEvents(ctx context.Context, afterID int64, limit int) ([]*model.Event, error) {
+	rows, err := r.db.QueryContext(ctx, r.eventsQuery, afterID, limit)
+	if err != nil {
+		r.log.Errorf("GetEvents repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		r.log.Errorf("GetEvents repository error: %s", err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// LastEventID returns the id of the latest event, 0 if there are none.
+func (r *MusicRepository) LastEventID(ctx context.Context) (int64, error) {
+	var id int64
+
+	err := r.db.QueryRowContext(ctx, r.lastEventIDQuery).Scan(&id)
+	if err != nil {
+		r.log.Errorf("LastEventID repository error: %s", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// FindSong returns the song with the same normalized artist and title.
+func (r *MusicRepository) FindSong(ctx context.Context, group, song string) (*model.Song, error) {
+	found, err := scanSong(r.db.QueryRowContext(ctx, findSongQuery, normalizeKey(group), normalizeKey(song)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSongNotFound
+	}
+	if err != nil {
+		r.log.Errorf("FindSong repository error: %s", err)
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// FindDuplicates returns up to limit pairs of songs with a trigram similarity
+// of at least threshold, the most similar first.
+func (r *MusicRepository) FindDuplicates(ctx context.Context, threshold float64, limit int) ([]*model.DuplicateSongs, error) {
+	var duplicates []*model.DuplicateSongs
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		// The threshold of the % operator is a setting, it's reset with the transaction.
+		_, err := tx.ExecContext(ctx, `SELECT set_config('pg_trgm.similarity_threshold', $1, true)`, strconv.FormatFloat(threshold, 'f', -1, 64))
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, duplicatesQuery, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		duplicates, err = scanDuplicates(rows)
+		return err
+	})
+	if err != nil {
+		r.log.Errorf("FindDuplicates repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully found %d duplicate songs", len(duplicates))
+	return duplicates, nil
+}
+
+// MergeSongs merges song sourceID into song targetID and deletes it. The
+// target keeps its title and artist, an empty release date or link is taken
+// from the source, and so are the lyrics with their translations if the
+// target has none. The target gets the tags and links of both songs. The
+// change is recorded as song.updated and song.deleted events. The ids must
+// differ.
+func (r *MusicRepository) MergeSongs(ctx context.Context, targetID, sourceID int, version *int) (*model.Song, error) {
+	var merged *model.Song
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		source, err := scanSong(tx.QueryRowContext(ctx, `SELECT `+songColumns+` FROM songs WHERE id=$1`, sourceID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		query, values := mergeSongQuery(targetID, sourceID, version)
+
+		merged, err = scanSong(tx.QueryRowContext(ctx, query, values...))
+		if errors.Is(err, sql.ErrNoRows) && version != nil {
+			return ErrVersionMismatch
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, moveVersesQuery, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, mergeTagsQuery, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, moveLinksQuery, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+
+		// The source may have been deleted since it was read.
+		res, err := tx.ExecContext(ctx, `DELETE FROM songs WHERE id = $1;`, sourceID)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrSongNotFound
+		}
+
+		err = insertEvent(ctx, tx, model.EventSongUpdated, merged)
+		if err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, model.EventSongDeleted, source)
+	})
+	if errors.Is(err, ErrSongNotFound) || errors.Is(err, ErrVersionMismatch) {
+		r.log.Debugf("MergeSongs repository: can't merge song id %d into %d: %s", sourceID, targetID, err)
+		return nil, err
+	}
+	if err != nil {
+		r.log.Errorf("MergeSongs repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully merged song id %d into %d", sourceID, targetID)
+	return merged, nil
+}
+
+// TagSong adds the tag to the song, creating the tag if it doesn't exist. An
+// empty kind keeps the kind of an existing tag, new tags default to model.TagKindTag.
+func (r *MusicRepository) TagSong(ctx context.Context, songID int, name, kind string) error {
+	name = normalizeKey(name)
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var id int
+		err := tx.QueryRowContext(ctx, `SELECT id FROM songs WHERE id=$1`, songID).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, insertTagQuery, name, tagKind(kind))
+		if err != nil {
+			return err
+		}
+
+		if kind != "" {
+			_, err = tx.ExecContext(ctx, `UPDATE tags SET kind=$1 WHERE name=$2`, kind, name)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, tagSongQuery, songID, name)
+		return err
+	})
+	if errors.Is(err, ErrSongNotFound) {
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("TagSong repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully tagged song id %d with %s", songID, name)
+	return nil
+}
+
+func (r *MusicRepository) UntagSong(ctx context.Context, songID int, name string) error {
+	_, err := r.db.ExecContext(ctx, untagSongQuery, songID, normalizeKey(name))
+	if err != nil {
+		r.log.Errorf("UntagSong repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully untagged song id %d", songID)
+	return nil
+}
+
+func (r *MusicRepository) GetSongTags(ctx context.Context, songID int) ([]*model.Tag, error) {
+	rows, err := r.db.QueryContext(ctx, songTagsQuery, songID)
+	if err != nil {
+		r.log.Errorf("GetSongTags repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags, err := scanTags(rows)
+	if err != nil {
+		r.log.Errorf("GetSongTags repository error: %s", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// ListTags returns the tags of the kind ordered by name, all tags if kind is empty.
+func (r *MusicRepository) ListTags(ctx context.Context, kind string) ([]*model.Tag, error) {
+	query, values := tagsQuery(kind)
+
+	rows, err := r.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		r.log.Errorf("ListTags repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags, err := scanTags(rows)
+	if err != nil {
+		r.log.Errorf("ListTags repository error: %s", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// GetFacets counts the songs matching the filters of req by tag, artist and
+// release year. Pagination of req is ignored, limit applies to every facet.
+func (r *MusicRepository) GetFacets(ctx context.Context, req *dto.GetSongsListReq, limit int) (*model.Facets, error) {
+	tags, artists, years, values := facetsQueries(req, limit)
+
+	var facets model.Facets
+	for _, facet := range []struct {
+		query string
+		dest  *[]*model.FacetCount
+	}{
+		{tags, &facets.Tags},
+		{artists, &facets.Artists},
+		{years, &facets.Years},
+	} {
+		rows, err := r.db.QueryContext(ctx, facet.query, values...)
+		if err != nil {
+			r.log.Errorf("GetFacets repository error: %s", err)
+			return nil, err
+		}
+
+		*facet.dest, err = scanFacet(rows)
+		rows.Close()
+		if err != nil {
+			r.log.Errorf("GetFacets repository error: %s", err)
+			return nil, err
+		}
+	}
+
+	return &facets, nil
+}
+
+// inTx runs fn in a transaction, which is committed if fn succeeds.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertEvent writes an event to the outbox within the transaction of the change.
+func insertEvent(ctx context.Context, tx *sql.Tx, eventType string, song *model.Song) error {
+	data, err := eventData(song)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertEventQuery, eventType, song.ID, data)
+	return err
+}
+
+func (r *MusicRepository) GetLyricsLanguages(ctx context.Context, songID int) (*model.LyricsLanguages, error) {
+	var languages model.LyricsLanguages
+
+	err := r.db.QueryRowContext(ctx, songLanguageQuery, songID).Scan(&languages.Original)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSongNotFound
+	}
+	if err != nil {
+		r.log.Errorf("GetLyricsLanguages repository error: %s", err)
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, translationsQuery, songID)
+	if err != nil {
+		r.log.Errorf("GetLyricsLanguages repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	languages.Translations, err = scanTranslations(rows)
+	if err != nil {
+		r.log.Errorf("GetLyricsLanguages repository error: %s", err)
+		return nil, err
+	}
+
+	return &languages, nil
+}
+
+// SetTranslation replaces the translation of the lyrics to lang. Its verses
+// are aligned with the verses of the original by number, so there must be as
+// many of them. The change bumps the song version and records a song.updated event.
+func (r *MusicRepository) SetTranslation(ctx context.Context, songID int, lang, lyrics string) error {
+	verses := splitVerses(lyrics)
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var original string
+		err := tx.QueryRowContext(ctx, songLanguageQuery, songID).Scan(&original)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var count int
+		err = tx.QueryRowContext(ctx, originalVersesCountQuery, songID).Scan(&count)
+		if err != nil {
+			return err
+		}
+
+		err = checkTranslation(original, lang, count, len(verses))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, deleteTranslationQuery, songID, lang)
+		if err != nil {
+			return err
+		}
+
+		for i, verse := range verses {
+			_, err = tx.ExecContext(ctx, insertVerseQuery, songID, i+1, verse, lang)
+			if err != nil {
+				return err
+			}
+		}
+
+		song, err := scanSong(tx.QueryRowContext(ctx, touchSongQuery, songID))
+		if err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, model.EventSongUpdated, song)
+	})
+	if isTranslationError(err) {
+		r.log.Debugf("SetTranslation repository: song id %d: %s", songID, err)
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("SetTranslation repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully set %s translation of song id %d", lang, songID)
+	return nil
+}
+
+// DeleteTranslation deletes the translation of the lyrics to lang, bumping
+// the song version and recording a song.updated event.
+func (r *MusicRepository) DeleteTranslation(ctx context.Context, songID int, lang string) error {
+	if lang == "" {
+		return ErrTranslationNotFound
+	}
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, deleteTranslationQuery, songID, lang)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrTranslationNotFound
+		}
+
+		song, err := scanSong(tx.QueryRowContext(ctx, touchSongQuery, songID))
+		if err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, model.EventSongUpdated, song)
+	})
+	if errors.Is(err, ErrTranslationNotFound) {
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("DeleteTranslation repository error: %s", err)
+		return err
+	}
+
+	r.log.Infof("Successfully deleted %s translation of song id %d", lang, songID)
+	return nil
+}
@@ -0,0 +1,166 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+)
+
+func testDuplicateSong(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	added := addSong(t, repo, "Muse", "Supermassive Black Hole", "16.07.2006")
+	other := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	_, err := repo.AddSong(ctx, &model.Song{Song: " SUPERMASSIVE  black hole", Group: "muse "})
+	if !errors.Is(err, repository.ErrDuplicateSong) {
+		t.Fatalf("expected ErrDuplicateSong for a differently spelled song, got %v", err)
+	}
+
+	found, err := repo.FindSong(ctx, "MUSE", "supermassive black  hole")
+	if err != nil {
+		t.Fatalf("FindSong: %s", err)
+	}
+	if found.ID != added.ID {
+		t.Errorf("expected FindSong to return song %d, got %+v", added.ID, found)
+	}
+
+	_, err = repo.FindSong(ctx, "Muse", "Starlight")
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Errorf("expected ErrSongNotFound for an unknown song, got %v", err)
+	}
+
+	title := "Supermassive black hole"
+	err = repo.UpdateSong(ctx, other.ID, &dto.UpdateSongReq{Song: &title})
+	if !errors.Is(err, repository.ErrDuplicateSong) {
+		t.Fatalf("expected ErrDuplicateSong for an update to an existing song, got %v", err)
+	}
+	if song := getSong(t, repo, other.ID); song.Song != other.Song {
+		t.Errorf("duplicate update was applied: %+v", song)
+	}
+
+	err = repo.UpdateSong(ctx, added.ID, &dto.UpdateSongReq{Song: &title})
+	if err != nil {
+		t.Fatalf("expected a song to be respelled, got %s", err)
+	}
+
+	songs := listSongs(t, repo, &dto.GetSongsListReq{Limit: 10})
+	if len(songs) != 2 {
+		t.Errorf("expected 2 songs, got %d", len(songs))
+	}
+}
+
+func testFindDuplicates(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	song := addSong(t, repo, "Muse", "Supermassive Black Hole", "16.07.2006")
+	duplicate := addSong(t, repo, "Muse", "Supermasive Black Hole", "")
+	addSong(t, repo, "Kino", "Gruppa krovi", "01.01.1988")
+
+	duplicates, err := repo.FindDuplicates(ctx, 0.5, 10)
+	if err != nil {
+		t.Fatalf("FindDuplicates: %s", err)
+	}
+	if len(duplicates) != 1 {
+		t.Fatalf("expected 1 pair of duplicates, got %d", len(duplicates))
+	}
+
+	pair := duplicates[0]
+	if pair.Song.ID != song.ID || pair.Duplicate.ID != duplicate.ID {
+		t.Errorf("expected songs %d and %d, got %d and %d", song.ID, duplicate.ID, pair.Song.ID, pair.Duplicate.ID)
+	}
+	if pair.Similarity < 0.85 || pair.Similarity > 0.95 {
+		t.Errorf("expected a similarity of 0.9, got %f", pair.Similarity)
+	}
+
+	duplicates, err = repo.FindDuplicates(ctx, 0.95, 10)
+	if err != nil {
+		t.Fatalf("FindDuplicates: %s", err)
+	}
+	if len(duplicates) != 0 {
+		t.Errorf("expected no duplicates above 0.95, got %d", len(duplicates))
+	}
+}
+
+func testMergeSongs(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	target := addSong(t, repo, "Muse", "Supermassive Black Hole", "")
+	source := addSong(t, repo, "Muse", "Supermasive Black Hole", "16.07.2006")
+
+	err := repo.AddLyrics(ctx, source.ID, "first\n\nsecond")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	stale := target.Version + 1
+	_, err = repo.MergeSongs(ctx, target.ID, source.ID, &stale)
+	if !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch for stale version, got %v", err)
+	}
+
+	last, err := repo.LastEventID(ctx)
+	if err != nil {
+		t.Fatalf("LastEventID: %s", err)
+	}
+
+	merged, err := repo.MergeSongs(ctx, target.ID, source.ID, &target.Version)
+	if err != nil {
+		t.Fatalf("MergeSongs: %s", err)
+	}
+
+	if merged.ID != target.ID || merged.Song != target.Song || merged.Link != target.Link {
+		t.Errorf("expected the target to keep its title and link, got %+v", merged)
+	}
+	if merged.ReleaseDate != source.ReleaseDate || merged.Version != target.Version+1 {
+		t.Errorf("expected release date %q and version %d, got %+v", source.ReleaseDate, target.Version+1, merged)
+	}
+
+	_, err = repo.GetSong(ctx, source.ID)
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Errorf("expected the source to be deleted, got %v", err)
+	}
+
+	verses, err := repo.GetSongLyrics(ctx, target.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 || verses[0].Lyrics != "first" {
+		t.Errorf("expected the verses of the source to move to the target, got %+v", verses)
+	}
+
+	events, err := repo.GetEvents(ctx, last, 10)
+	if err != nil {
+		t.Fatalf("GetEvents: %s", err)
+	}
+	if len(events) != 2 || events[0].Type != model.EventSongUpdated || events[0].SongID != target.ID ||
+		events[1].Type != model.EventSongDeleted || events[1].SongID != source.ID {
+		t.Errorf("expected song.updated and song.deleted events, got %+v", events)
+	}
+
+	// A target with lyrics keeps them, the verses of the source are dropped.
+	other := addSong(t, repo, "Muse", "Super Massive Black Hole", "")
+
+	err = repo.AddLyrics(ctx, other.ID, "other")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	_, err = repo.MergeSongs(ctx, target.ID, other.ID, nil)
+	if err != nil {
+		t.Fatalf("MergeSongs: %s", err)
+	}
+
+	verses, err = repo.GetSongLyrics(ctx, target.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 || verses[0].Lyrics != "first" {
+		t.Errorf("expected the target to keep its verses, got %+v", verses)
+	}
+
+	_, err = repo.MergeSongs(ctx, target.ID, other.ID, nil)
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Errorf("expected ErrSongNotFound for a deleted source, got %v", err)
+	}
+}
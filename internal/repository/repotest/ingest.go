@@ -0,0 +1,86 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+)
+
+func testIngestSong(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+
+	song, err := repo.IngestSong(ctx, &model.Song{
+		Song:        "Uprising",
+		Group:       "Muse",
+		ReleaseDate: "07.09.2009",
+		Link:        "https://www.youtube.com/watch?v=w8KQmps-Sog",
+	}, "First verse\n\nSecond verse", &model.Link{Provider: "youtube", URL: "https://www.youtube.com/watch?v=w8KQmps-Sog"})
+	if err != nil {
+		t.Fatalf("IngestSong: %s", err)
+	}
+
+	stored := getSong(t, repo, song.ID)
+	if song.Version != stored.Version || stored.Version < 2 {
+		t.Errorf("expected the version after the lyrics are stored, got %d, stored %d", song.Version, stored.Version)
+	}
+
+	verses, err := repo.GetSongLyrics(ctx, song.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 || verses[1].Lyrics != "Second verse" {
+		t.Errorf("expected the 2 ingested verses, got %+v", verses)
+	}
+
+	links, err := repo.GetSongLinks(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	if len(links) != 1 || links[0].SongID != song.ID || links[0].Provider != "youtube" {
+		t.Errorf("expected the ingested link, got %+v", links)
+	}
+
+	events, err := repo.GetEvents(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("GetEvents: %s", err)
+	}
+	if len(events) != 2 || events[0].Type != model.EventSongCreated || events[1].Type != model.EventSongEnriched {
+		t.Fatalf("expected song.created and song.enriched events, got %+v", events)
+	}
+
+	// Without a link only the song and its lyrics are stored.
+	other, err := repo.IngestSong(ctx, &model.Song{Song: "Starlight", Group: "Muse", ReleaseDate: "04.09.2006"}, "Only verse", nil)
+	if err != nil {
+		t.Fatalf("IngestSong without a link: %s", err)
+	}
+	links, err = repo.GetSongLinks(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	if len(links) != 0 {
+		t.Errorf("expected no links, got %+v", links)
+	}
+
+	_, err = repo.IngestSong(ctx, &model.Song{Song: "uprising", Group: "MUSE", ReleaseDate: "07.09.2009"}, "Another verse", nil)
+	if !errors.Is(err, repository.ErrDuplicateSong) {
+		t.Fatalf("expected ErrDuplicateSong, got %v", err)
+	}
+
+	// The rejected song left nothing behind.
+	verses, err = repo.GetSongLyrics(ctx, song.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 {
+		t.Errorf("expected the lyrics of the saved song to stay, got %+v", verses)
+	}
+	last, err := repo.LastEventID(ctx)
+	if err != nil {
+		t.Fatalf("LastEventID: %s", err)
+	}
+	if last != events[1].ID+2 {
+		t.Errorf("expected no events of the rejected song, last event id %d", last)
+	}
+}
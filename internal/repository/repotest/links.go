@@ -0,0 +1,173 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+	"time"
+)
+
+func addLink(t *testing.T, repo repository.IMusicRepository, songID int, provider, url string) *model.Link {
+	t.Helper()
+
+	link, err := repo.AddSongLink(context.Background(), &model.Link{SongID: songID, Provider: provider, URL: url})
+	if err != nil {
+		t.Fatalf("AddSongLink: %s", err)
+	}
+	return link
+}
+
+func linkURLs(links []*model.Link) []string {
+	urls := make([]string, 0, len(links))
+	for _, link := range links {
+		urls = append(urls, link.URL)
+	}
+	return urls
+}
+
+func testLinks(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	song := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	youtube := addLink(t, repo, song.ID, "youtube", "https://www.youtube.com/watch?v=w8KQmps-Sog")
+	if youtube.ID == 0 || youtube.SongID != song.ID || youtube.Provider != "youtube" ||
+		youtube.Status != model.LinkStatusUnknown || youtube.CheckedAt != nil {
+		t.Fatalf("unexpected added link %+v", youtube)
+	}
+	spotify := addLink(t, repo, song.ID, "spotify", "https://open.spotify.com/track/4VqPOruhp5EdPBeR92t6lQ")
+
+	_, err := repo.AddSongLink(ctx, &model.Link{SongID: song.ID, Provider: "youtube", URL: youtube.URL})
+	if !errors.Is(err, repository.ErrDuplicateLink) {
+		t.Fatalf("expected ErrDuplicateLink, got %v", err)
+	}
+	_, err = repo.AddSongLink(ctx, &model.Link{SongID: song.ID + 100, Provider: "youtube", URL: youtube.URL})
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Fatalf("expected ErrSongNotFound for a missing song, got %v", err)
+	}
+
+	links, err := repo.GetSongLinks(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	if urls := linkURLs(links); len(urls) != 2 || urls[0] != youtube.URL || urls[1] != spotify.URL {
+		t.Fatalf("expected the links in the order they were added, got %v", urls)
+	}
+
+	err = repo.DeleteSongLink(ctx, song.ID+100, youtube.ID)
+	if !errors.Is(err, repository.ErrLinkNotFound) {
+		t.Errorf("expected ErrLinkNotFound for a link of another song, got %v", err)
+	}
+	err = repo.DeleteSongLink(ctx, song.ID, youtube.ID)
+	if err != nil {
+		t.Fatalf("DeleteSongLink: %s", err)
+	}
+	err = repo.DeleteSongLink(ctx, song.ID, youtube.ID)
+	if !errors.Is(err, repository.ErrLinkNotFound) {
+		t.Errorf("expected ErrLinkNotFound for a deleted link, got %v", err)
+	}
+
+	err = repo.DeleteSong(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("DeleteSong: %s", err)
+	}
+	links, err = repo.GetSongLinks(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	if len(links) != 0 {
+		t.Errorf("expected the links to be deleted with the song, got %v", linkURLs(links))
+	}
+}
+
+func testLinksToCheck(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	song := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	checked := addLink(t, repo, song.ID, "youtube", "https://www.youtube.com/watch?v=1")
+	stale := addLink(t, repo, song.ID, "spotify", "https://open.spotify.com/track/2")
+	fresh := addLink(t, repo, song.ID, "bandcamp", "https://muse.bandcamp.com/track/3")
+	unchecked := addLink(t, repo, song.ID, "youtube", "https://youtu.be/4")
+	addLink(t, repo, song.ID, "other", "https://notyoutube.com/5")
+
+	now := time.Now()
+	for _, update := range []struct {
+		link      *model.Link
+		checkedAt time.Time
+	}{
+		{checked, now.Add(-2 * time.Hour)},
+		{stale, now.Add(-3 * time.Hour)},
+		{fresh, now},
+	} {
+		err := repo.SetLinkStatus(ctx, update.link.ID, model.LinkStatusOK, update.checkedAt)
+		if err != nil {
+			t.Fatalf("SetLinkStatus: %s", err)
+		}
+	}
+
+	domains := []string{"youtube.com", "youtu.be", "spotify.com", "bandcamp.com"}
+	links, err := repo.LinksToCheck(ctx, domains, now.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("LinksToCheck: %s", err)
+	}
+	if urls := linkURLs(links); len(urls) != 3 || urls[0] != unchecked.URL || urls[1] != stale.URL || urls[2] != checked.URL {
+		t.Fatalf("expected the unchecked link and then the oldest checked ones, got %v", urls)
+	}
+	if links[1].Status != model.LinkStatusOK || links[1].CheckedAt == nil {
+		t.Errorf("expected the status of a checked link, got %+v", links[1])
+	}
+
+	links, err = repo.LinksToCheck(ctx, []string{"spotify.com"}, now.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("LinksToCheck: %s", err)
+	}
+	if urls := linkURLs(links); len(urls) != 1 || urls[0] != stale.URL {
+		t.Errorf("expected only the links on the allowed domains, got %v", urls)
+	}
+
+	links, err = repo.LinksToCheck(ctx, domains, now.Add(-time.Hour), 1)
+	if err != nil {
+		t.Fatalf("LinksToCheck: %s", err)
+	}
+	if len(links) != 1 {
+		t.Errorf("expected the limit to apply, got %v", linkURLs(links))
+	}
+
+	err = repo.SetLinkStatus(ctx, unchecked.ID, model.LinkStatusDead, now)
+	if err != nil {
+		t.Fatalf("SetLinkStatus: %s", err)
+	}
+	songLinks, err := repo.GetSongLinks(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	for _, link := range songLinks {
+		if link.ID == unchecked.ID && (link.Status != model.LinkStatusDead || link.CheckedAt == nil) {
+			t.Errorf("expected the link to be marked dead, got %+v", link)
+		}
+	}
+}
+
+func testMergeSongsLinks(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	target := addSong(t, repo, "Muse", "Supermassive Black Hole", "")
+	source := addSong(t, repo, "Muse", "Supermasive Black Hole", "")
+
+	addLink(t, repo, target.ID, "youtube", "https://youtu.be/Xsp3_a-PMTw")
+	addLink(t, repo, source.ID, "youtube", "https://youtu.be/Xsp3_a-PMTw")
+	addLink(t, repo, source.ID, "spotify", "https://open.spotify.com/track/3lPr8ghNDBLc2uZovNyLs9")
+
+	_, err := repo.MergeSongs(ctx, target.ID, source.ID, nil)
+	if err != nil {
+		t.Fatalf("MergeSongs: %s", err)
+	}
+
+	links, err := repo.GetSongLinks(ctx, target.ID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	if urls := linkURLs(links); len(urls) != 2 || urls[0] != "https://youtu.be/Xsp3_a-PMTw" {
+		t.Errorf("expected the links of both songs, got %v", urls)
+	}
+}
@@ -0,0 +1,526 @@
+// Package repotest provides a conformance suite shared by all
+// repository.IMusicRepository implementations, so the memory and Postgres
+// repositories stay in lockstep.
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+)
+
+// Factory returns an empty repository. Every subtest calls it once, so
+// implementations backed by a database must truncate their tables.
+type Factory func(t *testing.T) repository.IMusicRepository
+
+func Run(t *testing.T, newRepo Factory) {
+	t.Run("AddSong", func(t *testing.T) { testAddSong(t, newRepo(t)) })
+	t.Run("IngestSong", func(t *testing.T) { testIngestSong(t, newRepo(t)) })
+	t.Run("GetSongsListFilters", func(t *testing.T) { testGetSongsListFilters(t, newRepo(t)) })
+	t.Run("GetSongsListPagination", func(t *testing.T) { testGetSongsListPagination(t, newRepo(t)) })
+	t.Run("GetSongLyrics", func(t *testing.T) { testGetSongLyrics(t, newRepo(t)) })
+	t.Run("UpdateSong", func(t *testing.T) { testUpdateSong(t, newRepo(t)) })
+	t.Run("UpdateSongWithLyrics", func(t *testing.T) { testUpdateSongWithLyrics(t, newRepo(t)) })
+	t.Run("DeleteSongCascades", func(t *testing.T) { testDeleteSongCascades(t, newRepo(t)) })
+	t.Run("GetSong", func(t *testing.T) { testGetSong(t, newRepo(t)) })
+	t.Run("GetSongWithLyrics", func(t *testing.T) { testGetSongWithLyrics(t, newRepo(t)) })
+	t.Run("GetLyricsBySongIDs", func(t *testing.T) { testGetLyricsBySongIDs(t, newRepo(t)) })
+	t.Run("Versioning", func(t *testing.T) { testVersioning(t, newRepo(t)) })
+	t.Run("Events", func(t *testing.T) { testEvents(t, newRepo(t)) })
+	t.Run("DuplicateSong", func(t *testing.T) { testDuplicateSong(t, newRepo(t)) })
+	t.Run("FindDuplicates", func(t *testing.T) { testFindDuplicates(t, newRepo(t)) })
+	t.Run("MergeSongs", func(t *testing.T) { testMergeSongs(t, newRepo(t)) })
+	t.Run("Tags", func(t *testing.T) { testTags(t, newRepo(t)) })
+	t.Run("TagFilters", func(t *testing.T) { testTagFilters(t, newRepo(t)) })
+	t.Run("Facets", func(t *testing.T) { testFacets(t, newRepo(t)) })
+	t.Run("MergeSongsTags", func(t *testing.T) { testMergeSongsTags(t, newRepo(t)) })
+	t.Run("Translations", func(t *testing.T) { testTranslations(t, newRepo(t)) })
+	t.Run("MergeSongsTranslations", func(t *testing.T) { testMergeSongsTranslations(t, newRepo(t)) })
+	t.Run("ReplaceLyrics", func(t *testing.T) { testReplaceLyrics(t, newRepo(t)) })
+	t.Run("TranslitFilters", func(t *testing.T) { testTranslitFilters(t, newRepo(t)) })
+	t.Run("Links", func(t *testing.T) { testLinks(t, newRepo(t)) })
+	t.Run("LinksToCheck", func(t *testing.T) { testLinksToCheck(t, newRepo(t)) })
+	t.Run("MergeSongsLinks", func(t *testing.T) { testMergeSongsLinks(t, newRepo(t)) })
+}
+
+func addSong(t *testing.T, repo repository.IMusicRepository, group, song, releaseDate string) *model.Song {
+	t.Helper()
+
+	saved, err := repo.AddSong(context.Background(), &model.Song{
+		Song:        song,
+		Group:       group,
+		ReleaseDate: releaseDate,
+		Link:        "https://example.com/" + song,
+	})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+
+	return saved
+}
+
+func listSongs(t *testing.T, repo repository.IMusicRepository, req *dto.GetSongsListReq) []*model.Song {
+	t.Helper()
+
+	songs, err := repo.GetSongsList(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetSongsList: %s", err)
+	}
+
+	return songs
+}
+
+func songIDs(songs []*model.Song) []int {
+	ids := make([]int, 0, len(songs))
+	for _, song := range songs {
+		ids = append(ids, song.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testAddSong(t *testing.T, repo repository.IMusicRepository) {
+	first := addSong(t, repo, "Muse", "Supermassive Black Hole", "16.07.2006")
+	second := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	if first.ID <= 0 || second.ID <= first.ID {
+		t.Fatalf("expected increasing positive ids, got %d and %d", first.ID, second.ID)
+	}
+
+	songs := listSongs(t, repo, &dto.GetSongsListReq{Limit: 10})
+	if len(songs) != 2 {
+		t.Fatalf("expected 2 songs, got %d", len(songs))
+	}
+
+	got := songs[0]
+	if got.Song != first.Song || got.Group != first.Group || got.ReleaseDate != first.ReleaseDate || got.Link != first.Link {
+		t.Errorf("stored song %+v doesn't match added %+v", got, first)
+	}
+}
+
+func testGetSongsListFilters(t *testing.T, repo repository.IMusicRepository) {
+	a := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+	b := addSong(t, repo, "Muse", "Starlight", "03.09.2007")
+	c := addSong(t, repo, "Kino", "Starlight", "03.09.2007")
+
+	group := "Muse"
+	song := "Starlight"
+	releaseDate := "03.09.2007"
+
+	cases := []struct {
+		name string
+		req  dto.GetSongsListReq
+		want []int
+	}{
+		{"NoFilters", dto.GetSongsListReq{}, []int{a.ID, b.ID, c.ID}},
+		{"Group", dto.GetSongsListReq{Group: &group}, []int{a.ID, b.ID}},
+		{"Song", dto.GetSongsListReq{Song: &song}, []int{b.ID, c.ID}},
+		{"ReleaseDate", dto.GetSongsListReq{ReleaseDate: &releaseDate}, []int{b.ID, c.ID}},
+		{"Combined", dto.GetSongsListReq{Group: &group, Song: &song}, []int{b.ID}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.req
+			req.Limit = 10
+
+			got := songIDs(listSongs(t, repo, &req))
+			if !equalIDs(got, tc.want) {
+				t.Errorf("expected ids %v, got %v", tc.want, got)
+			}
+
+			count, err := repo.CountSongs(context.Background(), &req)
+			if err != nil {
+				t.Fatalf("CountSongs: %s", err)
+			}
+			if count != len(tc.want) {
+				t.Errorf("expected count %d, got %d", len(tc.want), count)
+			}
+		})
+	}
+}
+
+func testGetSongsListPagination(t *testing.T, repo repository.IMusicRepository) {
+	var ids []int
+	for _, title := range []string{"One", "Two", "Three", "Four", "Five"} {
+		ids = append(ids, addSong(t, repo, "Band", title, "").ID)
+	}
+
+	got := songIDs(listSongs(t, repo, &dto.GetSongsListReq{Limit: 2, Offset: 2}))
+	if !equalIDs(got, ids[2:4]) {
+		t.Errorf("expected ids %v, got %v", ids[2:4], got)
+	}
+
+	got = songIDs(listSongs(t, repo, &dto.GetSongsListReq{Limit: 10, Offset: 4}))
+	if !equalIDs(got, ids[4:]) {
+		t.Errorf("expected ids %v, got %v", ids[4:], got)
+	}
+
+	if songs := listSongs(t, repo, &dto.GetSongsListReq{Limit: 10, Offset: 5}); len(songs) != 0 {
+		t.Errorf("expected no songs past the end, got %d", len(songs))
+	}
+}
+
+func testGetSongLyrics(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	song := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	err := repo.AddLyrics(ctx, song.ID, "first\n\nsecond\n\nthird")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	verses, err := repo.GetSongLyrics(ctx, song.ID, "", 2, 1)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+
+	want := []model.Verse{{Number: 2, Lyrics: "second"}, {Number: 3, Lyrics: "third"}}
+	if len(verses) != len(want) {
+		t.Fatalf("expected %d verses, got %d", len(want), len(verses))
+	}
+	for i := range want {
+		if *verses[i] != want[i] {
+			t.Errorf("verse %d: expected %+v, got %+v", i, want[i], *verses[i])
+		}
+	}
+
+	verses, err = repo.GetSongLyrics(ctx, song.ID+1000, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics for unknown song: %s", err)
+	}
+	if len(verses) != 0 {
+		t.Errorf("expected no verses for unknown song, got %d", len(verses))
+	}
+
+	err = repo.AddLyrics(ctx, song.ID+1000, "first")
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Errorf("AddLyrics for unknown song: expected ErrSongNotFound, got %v", err)
+	}
+}
+
+func testUpdateSong(t *testing.T, repo repository.IMusicRepository) {
+	song := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+	other := addSong(t, repo, "Kino", "Gruppa krovi", "01.01.1988")
+
+	title := "Resistance"
+	link := ""
+	err := repo.UpdateSong(context.Background(), song.ID, &dto.UpdateSongReq{Song: &title, Link: &link})
+	if err != nil {
+		t.Fatalf("UpdateSong: %s", err)
+	}
+
+	songs := listSongs(t, repo, &dto.GetSongsListReq{Limit: 10})
+	if len(songs) != 2 {
+		t.Fatalf("expected 2 songs, got %d", len(songs))
+	}
+
+	if songs[0].Song != title || songs[0].Group != song.Group || songs[0].ReleaseDate != song.ReleaseDate || songs[0].Link != link {
+		t.Errorf("unexpected updated song %+v", songs[0])
+	}
+	if songs[1].Song != other.Song {
+		t.Errorf("update leaked into song %+v", songs[1])
+	}
+}
+
+func testDeleteSongCascades(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	song := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+	other := addSong(t, repo, "Kino", "Gruppa krovi", "01.01.1988")
+
+	for _, s := range []*model.Song{song, other} {
+		err := repo.AddLyrics(ctx, s.ID, "first\n\nsecond")
+		if err != nil {
+			t.Fatalf("AddLyrics: %s", err)
+		}
+	}
+
+	err := repo.DeleteSong(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("DeleteSong: %s", err)
+	}
+
+	got := songIDs(listSongs(t, repo, &dto.GetSongsListReq{Limit: 10}))
+	if !equalIDs(got, []int{other.ID}) {
+		t.Errorf("expected ids %v after delete, got %v", []int{other.ID}, got)
+	}
+
+	verses, err := repo.GetSongLyrics(ctx, song.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 0 {
+		t.Errorf("expected verses to be deleted with the song, got %d", len(verses))
+	}
+
+	verses, err = repo.GetSongLyrics(ctx, other.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 {
+		t.Errorf("expected other song to keep 2 verses, got %d", len(verses))
+	}
+}
+
+func testGetSong(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	added := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	song, err := repo.GetSong(ctx, added.ID)
+	if err != nil {
+		t.Fatalf("GetSong: %s", err)
+	}
+	if song.ID != added.ID || song.Song != added.Song || song.Group != added.Group || song.Version != added.Version {
+		t.Errorf("GetSong returned %+v, expected %+v", song, added)
+	}
+
+	_, err = repo.GetSong(ctx, added.ID+1000)
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Errorf("expected ErrSongNotFound for unknown song, got %v", err)
+	}
+}
+
+func testGetSongWithLyrics(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	song := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+	empty := addSong(t, repo, "Kino", "Gruppa krovi", "01.01.1988")
+
+	err := repo.AddLyrics(ctx, song.ID, "first\n\nsecond\n\nthird")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	got, err := repo.GetSongWithLyrics(ctx, song.ID, true)
+	if err != nil {
+		t.Fatalf("GetSongWithLyrics: %s", err)
+	}
+	if got.ID != song.ID || got.Song.Song != song.Song || got.VersesCount != 3 || len(got.Lyrics) != 3 {
+		t.Fatalf("unexpected song with lyrics %+v", got)
+	}
+	for i, verse := range got.Lyrics {
+		if verse.Number != i+1 {
+			t.Errorf("expected verse %d at position %d, got %d", i+1, i, verse.Number)
+		}
+	}
+
+	got, err = repo.GetSongWithLyrics(ctx, song.ID, false)
+	if err != nil {
+		t.Fatalf("GetSongWithLyrics without lyrics: %s", err)
+	}
+	if got.VersesCount != 3 || len(got.Lyrics) != 0 {
+		t.Errorf("expected only the verse count, got %+v", got)
+	}
+
+	got, err = repo.GetSongWithLyrics(ctx, empty.ID, true)
+	if err != nil {
+		t.Fatalf("GetSongWithLyrics for song without lyrics: %s", err)
+	}
+	if got.ID != empty.ID || got.VersesCount != 0 || len(got.Lyrics) != 0 {
+		t.Errorf("unexpected song without lyrics %+v", got)
+	}
+
+	_, err = repo.GetSongWithLyrics(ctx, song.ID+1000, true)
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Errorf("expected ErrSongNotFound for unknown song, got %v", err)
+	}
+}
+
+func testGetLyricsBySongIDs(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	first := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+	second := addSong(t, repo, "Muse", "Resistance", "07.09.2009")
+	empty := addSong(t, repo, "Kino", "Gruppa krovi", "01.01.1988")
+
+	err := repo.AddLyrics(ctx, first.ID, "first\n\nsecond\n\nthird")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+	err = repo.AddLyrics(ctx, second.ID, "only")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	lyrics, err := repo.GetLyricsBySongIDs(ctx, []int{first.ID, empty.ID, first.ID + 1000})
+	if err != nil {
+		t.Fatalf("GetLyricsBySongIDs: %s", err)
+	}
+	if len(lyrics) != 1 || len(lyrics[first.ID]) != 3 {
+		t.Fatalf("expected only the 3 verses of song %d, got %+v", first.ID, lyrics)
+	}
+	for i, verse := range lyrics[first.ID] {
+		if verse.Number != i+1 {
+			t.Errorf("expected verse %d at position %d, got %d", i+1, i, verse.Number)
+		}
+	}
+
+	lyrics, err = repo.GetLyricsBySongIDs(ctx, []int{second.ID, first.ID})
+	if err != nil {
+		t.Fatalf("GetLyricsBySongIDs: %s", err)
+	}
+	if len(lyrics[first.ID]) != 3 || len(lyrics[second.ID]) != 1 || lyrics[second.ID][0].Lyrics != "only" {
+		t.Errorf("unexpected lyrics for two songs %+v", lyrics)
+	}
+
+	lyrics, err = repo.GetLyricsBySongIDs(ctx, nil)
+	if err != nil || len(lyrics) != 0 {
+		t.Errorf("expected no lyrics for no songs, got %+v, %v", lyrics, err)
+	}
+}
+
+func getSong(t *testing.T, repo repository.IMusicRepository, songID int) *model.Song {
+	t.Helper()
+
+	song, err := repo.GetSong(context.Background(), songID)
+	if err != nil {
+		t.Fatalf("GetSong: %s", err)
+	}
+
+	return song
+}
+
+func testVersioning(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	added := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	if added.Version != 1 || added.UpdatedAt.IsZero() {
+		t.Fatalf("expected a new song to have version 1 and updated_at, got %d and %s", added.Version, added.UpdatedAt)
+	}
+
+	err := repo.AddLyrics(ctx, added.ID, "first\n\nsecond")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	version := getSong(t, repo, added.ID).Version
+	if version <= added.Version {
+		t.Fatalf("expected AddLyrics to bump the version past %d, got %d", added.Version, version)
+	}
+
+	title := "Resistance"
+	stale := added.Version
+	err = repo.UpdateSong(ctx, added.ID, &dto.UpdateSongReq{Song: &title, Version: &stale})
+	if !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch for stale version, got %v", err)
+	}
+	if song := getSong(t, repo, added.ID); song.Song != added.Song {
+		t.Errorf("stale update was applied: %+v", song)
+	}
+
+	err = repo.UpdateSong(ctx, added.ID, &dto.UpdateSongReq{Song: &title, Version: &version})
+	if err != nil {
+		t.Fatalf("UpdateSong with current version: %s", err)
+	}
+
+	song := getSong(t, repo, added.ID)
+	if song.Song != title || song.Version != version+1 {
+		t.Errorf("expected title %q and version %d, got %+v", title, version+1, song)
+	}
+}
+
+func testEvents(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+
+	last, err := repo.LastEventID(ctx)
+	if err != nil {
+		t.Fatalf("LastEventID: %s", err)
+	}
+	if last != 0 {
+		t.Fatalf("expected no events in an empty repository, got last id %d", last)
+	}
+
+	added := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	err = repo.AddLyrics(ctx, added.ID, "First verse\n\nSecond verse")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	title := "Starlight"
+	err = repo.UpdateSong(ctx, added.ID, &dto.UpdateSongReq{Song: &title})
+	if err != nil {
+		t.Fatalf("UpdateSong: %s", err)
+	}
+
+	err = repo.DeleteSong(ctx, added.ID)
+	if err != nil {
+		t.Fatalf("DeleteSong: %s", err)
+	}
+
+	events, err := repo.GetEvents(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("GetEvents: %s", err)
+	}
+
+	want := []string{model.EventSongCreated, model.EventSongEnriched, model.EventSongUpdated, model.EventSongDeleted}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(events))
+	}
+	for i, event := range events {
+		if event.Type != want[i] || event.SongID != added.ID || event.CreatedAt.IsZero() {
+			t.Errorf("expected %s event for song %d, got %+v", want[i], added.ID, event)
+		}
+		if i > 0 && event.ID <= events[i-1].ID {
+			t.Errorf("expected increasing event ids, got %d after %d", event.ID, events[i-1].ID)
+		}
+	}
+
+	var enriched model.Song
+	err = json.Unmarshal(events[1].Data, &enriched)
+	if err != nil {
+		t.Fatalf("decode event data %s: %s", events[1].Data, err)
+	}
+	if enriched.Version != 2 || enriched.Link != added.Link {
+		t.Errorf("expected song.enriched data with version 2, got %s", events[1].Data)
+	}
+
+	last, err = repo.LastEventID(ctx)
+	if err != nil {
+		t.Fatalf("LastEventID: %s", err)
+	}
+	if last != events[3].ID {
+		t.Errorf("expected last event id %d, got %d", events[3].ID, last)
+	}
+
+	page, err := repo.GetEvents(ctx, events[0].ID, 2)
+	if err != nil {
+		t.Fatalf("GetEvents: %s", err)
+	}
+	if len(page) != 2 || page[0].ID != events[1].ID || page[1].ID != events[2].ID {
+		t.Errorf("expected events %d and %d after %d, got %+v", events[1].ID, events[2].ID, events[0].ID, page)
+	}
+
+	page, err = repo.GetEvents(ctx, last, 10)
+	if err != nil {
+		t.Fatalf("GetEvents: %s", err)
+	}
+	if len(page) != 0 {
+		t.Errorf("expected no events after the last one, got %d", len(page))
+	}
+}
+
+// ResetPostgres empties the Postgres tables used by the repositories, so a
+// Factory backed by a shared database hands out a clean repository.
+func ResetPostgres(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	_, err := db.ExecContext(context.Background(), `TRUNCATE songs, verses, tags, song_tags, song_links, song_events, webhooks, webhook_deliveries, upstream_cache RESTART IDENTITY CASCADE`)
+	if err != nil {
+		t.Fatalf("reset postgres: %s", err)
+	}
+}
@@ -0,0 +1,49 @@
+package repotest
+
+import (
+	"database/sql"
+	"github.com/aaanger/music-library/pkg/db"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// OpenSQLite returns a database in a temporary file with the Up statements of
+// the SQLite migrations applied. The migrations are found relative to this
+// file, so tests of any package can use it.
+func OpenSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	sqliteDB, err := db.OpenSQLite(db.SQLiteConfig{Path: filepath.Join(t.TempDir(), "music.db")})
+	if err != nil {
+		t.Fatalf("open sqlite: %s", err)
+	}
+	t.Cleanup(func() { sqliteDB.Close() })
+
+	_, file, _, _ := runtime.Caller(0)
+	migrations, err := filepath.Glob(filepath.Join(filepath.Dir(file), "../../../pkg/db/migrations/sqlite/*.sql"))
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("find sqlite migrations: %v", err)
+	}
+
+	for _, migration := range migrations {
+		data, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatalf("read %s: %s", migration, err)
+		}
+
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		for _, part := range strings.Split(up, "-- +goose StatementBegin")[1:] {
+			statement, _, _ := strings.Cut(part, "-- +goose StatementEnd")
+
+			_, err = sqliteDB.Exec(statement)
+			if err != nil {
+				t.Fatalf("apply %s: %s", migration, err)
+			}
+		}
+	}
+
+	return sqliteDB
+}
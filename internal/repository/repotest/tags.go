@@ -0,0 +1,213 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+)
+
+func tagSong(t *testing.T, repo repository.IMusicRepository, songID int, name, kind string) {
+	t.Helper()
+
+	err := repo.TagSong(context.Background(), songID, name, kind)
+	if err != nil {
+		t.Fatalf("TagSong: %s", err)
+	}
+}
+
+func tagNames(tags []*model.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func facetString(facet []*model.FacetCount) string {
+	var s string
+	for _, count := range facet {
+		s += fmt.Sprintf("%+v ", *count)
+	}
+	return s
+}
+
+func testTags(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	song := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	tagSong(t, repo, song.ID, "Rock", model.TagKindGenre)
+	tagSong(t, repo, song.ID, " alternative   ROCK ", "")
+	tagSong(t, repo, song.ID, "rock", "")
+
+	err := repo.TagSong(ctx, song.ID+100, "rock", "")
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Fatalf("expected ErrSongNotFound for a missing song, got %v", err)
+	}
+
+	tags, err := repo.GetSongTags(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongTags: %s", err)
+	}
+	if names := tagNames(tags); len(names) != 2 || names[0] != "alternative rock" || names[1] != "rock" {
+		t.Fatalf("expected normalized tags [alternative rock rock], got %v", names)
+	}
+	if tags[1].Kind != model.TagKindGenre || tags[0].Kind != model.TagKindTag {
+		t.Errorf("expected rock to stay a genre, got %+v %+v", tags[0], tags[1])
+	}
+
+	genres, err := repo.ListTags(ctx, model.TagKindGenre)
+	if err != nil {
+		t.Fatalf("ListTags: %s", err)
+	}
+	if names := tagNames(genres); len(names) != 1 || names[0] != "rock" {
+		t.Errorf("expected genres [rock], got %v", names)
+	}
+
+	err = repo.UntagSong(ctx, song.ID, "ROCK")
+	if err != nil {
+		t.Fatalf("UntagSong: %s", err)
+	}
+
+	tags, err = repo.GetSongTags(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongTags: %s", err)
+	}
+	if names := tagNames(tags); len(names) != 1 || names[0] != "alternative rock" {
+		t.Errorf("expected [alternative rock] after untagging, got %v", names)
+	}
+
+	all, err := repo.ListTags(ctx, "")
+	if err != nil {
+		t.Fatalf("ListTags: %s", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("expected untagged tags to be kept, got %v", tagNames(all))
+	}
+
+	err = repo.DeleteSong(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("DeleteSong: %s", err)
+	}
+
+	tags, err = repo.GetSongTags(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongTags: %s", err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("expected tags of a deleted song to be removed, got %v", tagNames(tags))
+	}
+}
+
+func testTagFilters(t *testing.T, repo repository.IMusicRepository) {
+	group := "Kino"
+	uprising := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+	starlight := addSong(t, repo, "Muse", "Starlight", "04.09.2006")
+	kino := addSong(t, repo, "Kino", "Gruppa krovi", "01.01.1988")
+
+	tagSong(t, repo, uprising.ID, "rock", model.TagKindGenre)
+	tagSong(t, repo, uprising.ID, "live", "")
+	tagSong(t, repo, starlight.ID, "rock", "")
+	tagSong(t, repo, kino.ID, "post-punk", model.TagKindGenre)
+
+	cases := []struct {
+		name string
+		req  *dto.GetSongsListReq
+		want []int
+	}{
+		{"any", &dto.GetSongsListReq{Tags: []string{"live", "Post-Punk"}, Limit: 10}, []int{uprising.ID, kino.ID}},
+		{"all", &dto.GetSongsListReq{Tags: []string{"rock", "live"}, TagMode: dto.TagModeAll, Limit: 10}, []int{uprising.ID}},
+		{"all repeated", &dto.GetSongsListReq{Tags: []string{"rock", "ROCK"}, TagMode: dto.TagModeAll, Limit: 10}, []int{uprising.ID, starlight.ID}},
+		{"unknown", &dto.GetSongsListReq{Tags: []string{"jazz"}, Limit: 10}, nil},
+		{"with group", &dto.GetSongsListReq{Group: &group, Tags: []string{"rock", "post-punk"}, Limit: 10}, []int{kino.ID}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := songIDs(listSongs(t, repo, tc.req))
+			if !equalIDs(got, tc.want) {
+				t.Errorf("expected songs %v, got %v", tc.want, got)
+			}
+
+			count, err := repo.CountSongs(context.Background(), tc.req)
+			if err != nil {
+				t.Fatalf("CountSongs: %s", err)
+			}
+			if count != len(tc.want) {
+				t.Errorf("expected count %d, got %d", len(tc.want), count)
+			}
+		})
+	}
+}
+
+func testFacets(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	group := "Muse"
+	uprising := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+	starlight := addSong(t, repo, "Muse", "Starlight", "2006-09-04")
+	kino := addSong(t, repo, "Kino", "Gruppa krovi", "01.01.1988")
+	addSong(t, repo, "Kino", "Zvezda", "unknown")
+
+	tagSong(t, repo, uprising.ID, "rock", model.TagKindGenre)
+	tagSong(t, repo, starlight.ID, "rock", "")
+	tagSong(t, repo, kino.ID, "rock", "")
+	tagSong(t, repo, kino.ID, "post-punk", model.TagKindGenre)
+
+	facets, err := repo.GetFacets(ctx, &dto.GetSongsListReq{}, 10)
+	if err != nil {
+		t.Fatalf("GetFacets: %s", err)
+	}
+
+	if len(facets.Tags) != 2 || *facets.Tags[0] != (model.FacetCount{Value: "rock", Kind: model.TagKindGenre, Count: 3}) ||
+		*facets.Tags[1] != (model.FacetCount{Value: "post-punk", Kind: model.TagKindGenre, Count: 1}) {
+		t.Errorf("unexpected tag facets: %s", facetString(facets.Tags))
+	}
+	if len(facets.Artists) != 2 || *facets.Artists[0] != (model.FacetCount{Value: "Kino", Count: 2}) ||
+		*facets.Artists[1] != (model.FacetCount{Value: "Muse", Count: 2}) {
+		t.Errorf("unexpected artist facets: %s", facetString(facets.Artists))
+	}
+	if len(facets.Years) != 3 || facets.Years[0].Value != "1988" || facets.Years[1].Value != "2006" || facets.Years[2].Value != "2009" {
+		t.Errorf("unexpected year facets: %s", facetString(facets.Years))
+	}
+
+	facets, err = repo.GetFacets(ctx, &dto.GetSongsListReq{Group: &group, Tags: []string{"rock"}}, 1)
+	if err != nil {
+		t.Fatalf("GetFacets: %s", err)
+	}
+
+	if len(facets.Tags) != 1 || *facets.Tags[0] != (model.FacetCount{Value: "rock", Kind: model.TagKindGenre, Count: 2}) {
+		t.Errorf("unexpected filtered tag facets: %s", facetString(facets.Tags))
+	}
+	if len(facets.Artists) != 1 || *facets.Artists[0] != (model.FacetCount{Value: "Muse", Count: 2}) {
+		t.Errorf("unexpected filtered artist facets: %s", facetString(facets.Artists))
+	}
+	if len(facets.Years) != 1 || *facets.Years[0] != (model.FacetCount{Value: "2006", Count: 1}) {
+		t.Errorf("unexpected filtered year facets: %s", facetString(facets.Years))
+	}
+}
+
+func testMergeSongsTags(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	target := addSong(t, repo, "Muse", "Supermassive Black Hole", "")
+	source := addSong(t, repo, "Muse", "Supermasive Black Hole", "")
+
+	tagSong(t, repo, target.ID, "rock", "")
+	tagSong(t, repo, source.ID, "rock", "")
+	tagSong(t, repo, source.ID, "live", "")
+
+	_, err := repo.MergeSongs(ctx, target.ID, source.ID, nil)
+	if err != nil {
+		t.Fatalf("MergeSongs: %s", err)
+	}
+
+	tags, err := repo.GetSongTags(ctx, target.ID)
+	if err != nil {
+		t.Fatalf("GetSongTags: %s", err)
+	}
+	if names := tagNames(tags); len(names) != 2 || names[0] != "live" || names[1] != "rock" {
+		t.Errorf("expected the tags of both songs, got %v", names)
+	}
+}
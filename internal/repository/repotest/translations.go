@@ -0,0 +1,229 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+)
+
+const (
+	russianLyrics = "Группа крови на рукаве,\nмой порядковый номер на рукаве\n\n" +
+		"Пожелай мне удачи в бою,\nпожелай мне не остаться в этой траве"
+	englishLyrics = "Blood type on my sleeve,\nmy serial number on my sleeve\n\n" +
+		"Wish me luck in the fight,\nwish me not to stay in this grass"
+)
+
+func testTranslations(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	song := addSong(t, repo, "Кино", "Группа крови", "01.01.1988")
+
+	err := repo.SetTranslation(ctx, song.ID, "en", englishLyrics)
+	if !errors.Is(err, repository.ErrNoLyrics) {
+		t.Fatalf("expected ErrNoLyrics before the lyrics are added, got %v", err)
+	}
+
+	err = repo.AddLyrics(ctx, song.ID, russianLyrics)
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	languages, err := repo.GetLyricsLanguages(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetLyricsLanguages: %s", err)
+	}
+	if languages.Original != "ru" || len(languages.Translations) != 0 {
+		t.Fatalf("expected detected ru lyrics without translations, got %+v", languages)
+	}
+
+	err = repo.SetTranslation(ctx, song.ID+100, "en", englishLyrics)
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Fatalf("expected ErrSongNotFound for a missing song, got %v", err)
+	}
+	err = repo.SetTranslation(ctx, song.ID, "ru", englishLyrics)
+	if !errors.Is(err, repository.ErrOriginalLanguage) {
+		t.Fatalf("expected ErrOriginalLanguage, got %v", err)
+	}
+	err = repo.SetTranslation(ctx, song.ID, "en", "Blood type on my sleeve")
+	if !errors.Is(err, repository.ErrVerseCountMismatch) {
+		t.Fatalf("expected ErrVerseCountMismatch, got %v", err)
+	}
+
+	before, err := repo.GetSong(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSong: %s", err)
+	}
+
+	err = repo.SetTranslation(ctx, song.ID, "en", englishLyrics)
+	if err != nil {
+		t.Fatalf("SetTranslation: %s", err)
+	}
+	err = repo.SetTranslation(ctx, song.ID, "de", "Blutgruppe\n\nWünsch mir Glück")
+	if err != nil {
+		t.Fatalf("SetTranslation: %s", err)
+	}
+
+	after, err := repo.GetSong(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSong: %s", err)
+	}
+	if after.Version <= before.Version {
+		t.Errorf("expected a translation to bump the song version, got %d after %d", after.Version, before.Version)
+	}
+
+	verses, err := repo.GetSongLyrics(ctx, song.ID, "en", 1, 1)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 1 || verses[0].Number != 2 || verses[0].Language != "en" ||
+		verses[0].Lyrics != "Wish me luck in the fight,\nwish me not to stay in this grass" {
+		t.Fatalf("expected the second english verse, got %+v", verses)
+	}
+
+	verses, err = repo.GetSongLyrics(ctx, song.ID, "fr", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 || verses[0].Language != "ru" || verses[0].Lyrics != "Группа крови на рукаве,\nмой порядковый номер на рукаве" {
+		t.Fatalf("expected a missing translation to fall back to the original, got %+v", verses)
+	}
+
+	withLyrics, err := repo.GetSongWithLyrics(ctx, song.ID, true)
+	if err != nil {
+		t.Fatalf("GetSongWithLyrics: %s", err)
+	}
+	if withLyrics.Language != "ru" || withLyrics.VersesCount != 2 || len(withLyrics.Lyrics) != 2 {
+		t.Fatalf("expected only the original lyrics with the song, got %+v", withLyrics)
+	}
+
+	err = repo.SetTranslation(ctx, song.ID, "en", "Blood type\n\nWish me luck")
+	if err != nil {
+		t.Fatalf("SetTranslation: %s", err)
+	}
+
+	verses, err = repo.GetSongLyrics(ctx, song.ID, "en", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 || verses[0].Lyrics != "Blood type" {
+		t.Fatalf("expected the translation to be replaced, got %+v", verses)
+	}
+
+	languages, err = repo.GetLyricsLanguages(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetLyricsLanguages: %s", err)
+	}
+	if len(languages.Translations) != 2 || languages.Translations[0] != "de" || languages.Translations[1] != "en" {
+		t.Fatalf("expected translations [de en], got %v", languages.Translations)
+	}
+
+	err = repo.DeleteTranslation(ctx, song.ID, "de")
+	if err != nil {
+		t.Fatalf("DeleteTranslation: %s", err)
+	}
+	err = repo.DeleteTranslation(ctx, song.ID, "de")
+	if !errors.Is(err, repository.ErrTranslationNotFound) {
+		t.Fatalf("expected ErrTranslationNotFound for a deleted translation, got %v", err)
+	}
+	err = repo.DeleteTranslation(ctx, song.ID, "")
+	if !errors.Is(err, repository.ErrTranslationNotFound) {
+		t.Fatalf("expected ErrTranslationNotFound for the original lyrics, got %v", err)
+	}
+
+	verses, err = repo.GetSongLyrics(ctx, song.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 {
+		t.Fatalf("expected the original lyrics to stay, got %+v", verses)
+	}
+
+	_, err = repo.GetLyricsLanguages(ctx, song.ID+100)
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Fatalf("expected ErrSongNotFound for a missing song, got %v", err)
+	}
+}
+
+func testMergeSongsTranslations(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	target := addSong(t, repo, "Кино", "Группа крови", "01.01.1988")
+	source := addSong(t, repo, "Kino", "Gruppa krovi", "01.01.1988")
+
+	err := repo.AddLyrics(ctx, source.ID, russianLyrics)
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+	err = repo.SetTranslation(ctx, source.ID, "en", englishLyrics)
+	if err != nil {
+		t.Fatalf("SetTranslation: %s", err)
+	}
+
+	_, err = repo.MergeSongs(ctx, target.ID, source.ID, nil)
+	if err != nil {
+		t.Fatalf("MergeSongs: %s", err)
+	}
+
+	languages, err := repo.GetLyricsLanguages(ctx, target.ID)
+	if err != nil {
+		t.Fatalf("GetLyricsLanguages: %s", err)
+	}
+	if languages.Original != "ru" || len(languages.Translations) != 1 || languages.Translations[0] != "en" {
+		t.Fatalf("expected the lyrics and translations to move to the target, got %+v", languages)
+	}
+
+	verses, err := repo.GetSongLyrics(ctx, target.ID, "en", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 || verses[0].Language != "en" {
+		t.Fatalf("expected the english translation of the target, got %+v", verses)
+	}
+}
+
+func testReplaceLyrics(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+	song := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+
+	err := repo.AddLyrics(ctx, song.ID, "first\n\nsecond")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+	err = repo.SetTranslation(ctx, song.ID, "de", "erste\n\nzweite")
+	if err != nil {
+		t.Fatalf("SetTranslation: %s", err)
+	}
+
+	err = repo.AddLyrics(ctx, song.ID, "one\n\ntwo")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	verses, err := repo.GetSongLyrics(ctx, song.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 || verses[0].Lyrics != "one" || verses[1].Lyrics != "two" {
+		t.Fatalf("expected the lyrics to be replaced, got %v", verses)
+	}
+
+	languages, err := repo.GetLyricsLanguages(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetLyricsLanguages: %s", err)
+	}
+	if len(languages.Translations) != 1 {
+		t.Errorf("expected the translation with as many verses to stay, got %v", languages.Translations)
+	}
+
+	err = repo.AddLyrics(ctx, song.ID, "one\n\ntwo\n\nthree")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+
+	languages, err = repo.GetLyricsLanguages(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetLyricsLanguages: %s", err)
+	}
+	if len(languages.Translations) != 0 {
+		t.Errorf("expected the translation with other verses to be deleted, got %v", languages.Translations)
+	}
+}
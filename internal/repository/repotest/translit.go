@@ -0,0 +1,65 @@
+package repotest
+
+import (
+	"context"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+)
+
+func testTranslitFilters(t *testing.T, repo repository.IMusicRepository) {
+	a := addSong(t, repo, "Кино", "Группа крови", "01.01.1988")
+	b := addSong(t, repo, "Beyoncé", "Déjà Vu", "24.06.2006")
+	c := addSong(t, repo, "Kino", "Kukushka", "01.01.1990")
+
+	cases := []struct {
+		name        string
+		group, song string
+		want        []int
+	}{
+		{"Latin", "kino", "", []int{a.ID, c.ID}},
+		{"Cyrillic", "КИНО", "", []int{a.ID, c.ID}},
+		{"CyrillicTitle", "", "gruppa  krovi", []int{a.ID}},
+		{"Diacritics", "Beyonce", "deja vu", []int{b.ID}},
+		{"Decomposed", "Beyoncé", "", []int{b.ID}},
+		{"FullWidth", "ＫＩＮＯ", "Кукушка", []int{c.ID}},
+		{"NoMatch", "Kin", "", []int{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := dto.GetSongsListReq{Limit: 10}
+			if tc.group != "" {
+				req.Group = &tc.group
+			}
+			if tc.song != "" {
+				req.Song = &tc.song
+			}
+
+			got := songIDs(listSongs(t, repo, &req))
+			if !equalIDs(got, tc.want) {
+				t.Errorf("expected ids %v, got %v", tc.want, got)
+			}
+
+			count, err := repo.CountSongs(context.Background(), &req)
+			if err != nil {
+				t.Fatalf("CountSongs: %s", err)
+			}
+			if count != len(tc.want) {
+				t.Errorf("expected count %d, got %d", len(tc.want), count)
+			}
+		})
+	}
+
+	group := "Beyonce"
+	err := repo.UpdateSong(context.Background(), a.ID, &dto.UpdateSongReq{Group: &group, Song: &group})
+	if err != nil {
+		t.Fatalf("UpdateSong: %s", err)
+	}
+
+	song := "BEYONCÉ"
+	got := songIDs(listSongs(t, repo, &dto.GetSongsListReq{Song: &song, Limit: 10}))
+	if !equalIDs(got, []int{a.ID}) {
+		t.Errorf("expected the updated title to be found, got ids %v", got)
+	}
+}
@@ -0,0 +1,115 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+)
+
+func testUpdateSongWithLyrics(t *testing.T, repo repository.IMusicRepository) {
+	ctx := context.Background()
+
+	song := addSong(t, repo, "Muse", "Uprising", "07.09.2009")
+	err := repo.AddLyrics(ctx, song.ID, "First verse")
+	if err != nil {
+		t.Fatalf("AddLyrics: %s", err)
+	}
+	song = getSong(t, repo, song.ID)
+
+	releaseDate, link, text := "08.09.2009", "https://www.youtube.com/watch?v=w8KQmps-Sog", "New first verse\n\nNew second verse"
+	err = repo.UpdateSongWithLyrics(ctx, song.ID, &dto.UpdateSongReq{
+		ReleaseDate: &releaseDate,
+		Link:        &link,
+		Text:        &text,
+		Version:     &song.Version,
+	}, &model.Link{Provider: "youtube", URL: link})
+	if err != nil {
+		t.Fatalf("UpdateSongWithLyrics: %s", err)
+	}
+
+	updated := getSong(t, repo, song.ID)
+	if updated.ReleaseDate != releaseDate || updated.Link != link || updated.Version <= song.Version {
+		t.Errorf("expected the new release date, link and a newer version, got %+v", updated)
+	}
+	verses, err := repo.GetSongLyrics(ctx, song.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 || verses[0].Lyrics != "New first verse" {
+		t.Errorf("expected the new lyrics, got %+v", verses)
+	}
+	links, err := repo.GetSongLinks(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	if len(links) != 1 || links[0].URL != link {
+		t.Errorf("expected the new media link, got %+v", links)
+	}
+
+	// A link the song already has is kept.
+	err = repo.UpdateSongWithLyrics(ctx, song.ID, &dto.UpdateSongReq{Link: &link, Version: &updated.Version}, &model.Link{Provider: "youtube", URL: link})
+	if err != nil {
+		t.Fatalf("UpdateSongWithLyrics with a known link: %s", err)
+	}
+	links, err = repo.GetSongLinks(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	if len(links) != 1 {
+		t.Errorf("expected the link once, got %+v", links)
+	}
+	updated = getSong(t, repo, song.ID)
+
+	// A stale version writes nothing, with or without changes of the song fields.
+	last, err := repo.LastEventID(ctx)
+	if err != nil {
+		t.Fatalf("LastEventID: %s", err)
+	}
+	stale := song.Version
+	staleText := "Stale verse"
+	err = repo.UpdateSongWithLyrics(ctx, song.ID, &dto.UpdateSongReq{Text: &staleText, Version: &stale}, nil)
+	if !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Fatalf("UpdateSongWithLyrics of the lyrics with a stale version: want ErrVersionMismatch, got %v", err)
+	}
+	staleDate := "01.01.2000"
+	err = repo.UpdateSongWithLyrics(ctx, song.ID, &dto.UpdateSongReq{ReleaseDate: &staleDate, Text: &staleText, Version: &stale}, nil)
+	if !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Fatalf("UpdateSongWithLyrics with a stale version: want ErrVersionMismatch, got %v", err)
+	}
+
+	current := getSong(t, repo, song.ID)
+	if current.Version != updated.Version || current.ReleaseDate != releaseDate {
+		t.Errorf("expected the song to stay unchanged after a version mismatch, got %+v", current)
+	}
+	verses, err = repo.GetSongLyrics(ctx, song.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetSongLyrics: %s", err)
+	}
+	if len(verses) != 2 || verses[0].Lyrics != "New first verse" {
+		t.Errorf("expected the lyrics to stay unchanged after a version mismatch, got %+v", verses)
+	}
+	after, err := repo.LastEventID(ctx)
+	if err != nil {
+		t.Fatalf("LastEventID: %s", err)
+	}
+	if after != last {
+		t.Errorf("expected no events after a version mismatch, last event id %d, was %d", after, last)
+	}
+
+	// The current version updates the lyrics alone.
+	err = repo.UpdateSongWithLyrics(ctx, song.ID, &dto.UpdateSongReq{Text: &staleText, Version: &current.Version}, nil)
+	if err != nil {
+		t.Fatalf("UpdateSongWithLyrics of the lyrics: %s", err)
+	}
+	if getSong(t, repo, song.ID).Version <= current.Version {
+		t.Error("expected the lyrics to bump the version")
+	}
+
+	err = repo.UpdateSongWithLyrics(ctx, song.ID+100, &dto.UpdateSongReq{Text: &text}, nil)
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Fatalf("UpdateSongWithLyrics of an unknown song: want ErrSongNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+	"time"
+)
+
+// UpstreamCacheFactory returns an empty upstream cache repository.
+type UpstreamCacheFactory func(t *testing.T) repository.IUpstreamCacheRepository
+
+func RunUpstreamCache(t *testing.T, newRepo UpstreamCacheFactory) {
+	t.Run("UpstreamResponses", func(t *testing.T) { testUpstreamResponses(t, newRepo(t)) })
+	t.Run("PurgeUpstreamResponses", func(t *testing.T) { testPurgeUpstreamResponses(t, newRepo(t)) })
+}
+
+func putUpstreamResponse(t *testing.T, repo repository.IUpstreamCacheRepository, response *model.UpstreamResponse) {
+	t.Helper()
+
+	err := repo.PutUpstreamResponse(context.Background(), response)
+	if err != nil {
+		t.Fatalf("PutUpstreamResponse: %s", err)
+	}
+}
+
+func testUpstreamResponses(t *testing.T, repo repository.IUpstreamCacheRepository) {
+	ctx := context.Background()
+	fetchedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	_, err := repo.GetUpstreamResponse(ctx, "api", "Muse", "Uprising")
+	if !errors.Is(err, repository.ErrUpstreamResponseNotFound) {
+		t.Fatalf("GetUpstreamResponse of an empty cache: want ErrUpstreamResponseNotFound, got %v", err)
+	}
+
+	putUpstreamResponse(t, repo, &model.UpstreamResponse{
+		Provider:    "api",
+		Group:       "Muse",
+		Song:        "Uprising",
+		ReleaseDate: "16.07.2006",
+		Text:        "verse one\n\nverse two",
+		Link:        "https://example.com/uprising",
+		FetchedAt:   fetchedAt,
+	})
+
+	got, err := repo.GetUpstreamResponse(ctx, "api", "  muse ", "UPRISING")
+	if err != nil {
+		t.Fatalf("GetUpstreamResponse by normalized key: %s", err)
+	}
+	if got.Group != "Muse" || got.Song != "Uprising" || got.ReleaseDate != "16.07.2006" ||
+		got.Text != "verse one\n\nverse two" || got.Link != "https://example.com/uprising" || got.NotFound {
+		t.Fatalf("GetUpstreamResponse = %+v", got)
+	}
+	if !got.FetchedAt.Equal(fetchedAt) {
+		t.Fatalf("FetchedAt = %s, want %s", got.FetchedAt, fetchedAt)
+	}
+
+	_, err = repo.GetUpstreamResponse(ctx, "musicbrainz", "Muse", "Uprising")
+	if !errors.Is(err, repository.ErrUpstreamResponseNotFound) {
+		t.Fatalf("GetUpstreamResponse of another provider: want ErrUpstreamResponseNotFound, got %v", err)
+	}
+
+	// A not found response replaces the cached one.
+	putUpstreamResponse(t, repo, &model.UpstreamResponse{
+		Provider:  "api",
+		Group:     "MUSE",
+		Song:      "Uprising",
+		NotFound:  true,
+		FetchedAt: fetchedAt.Add(time.Hour),
+	})
+
+	got, err = repo.GetUpstreamResponse(ctx, "api", "Muse", "Uprising")
+	if err != nil {
+		t.Fatalf("GetUpstreamResponse after replace: %s", err)
+	}
+	if !got.NotFound || got.Text != "" || got.Group != "MUSE" || !got.FetchedAt.Equal(fetchedAt.Add(time.Hour)) {
+		t.Fatalf("GetUpstreamResponse after replace = %+v", got)
+	}
+}
+
+func testPurgeUpstreamResponses(t *testing.T, repo repository.IUpstreamCacheRepository) {
+	ctx := context.Background()
+	old := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := old.Add(48 * time.Hour)
+
+	for _, response := range []*model.UpstreamResponse{
+		{Provider: "api", Group: "Muse", Song: "Uprising", ReleaseDate: "16.07.2006", FetchedAt: old},
+		{Provider: "api", Group: "Muse", Song: "Starlight", NotFound: true, FetchedAt: recent},
+		{Provider: "api", Group: "Queen", Song: "Bohemian Rhapsody", NotFound: true, FetchedAt: old},
+		{Provider: "musicbrainz", Group: "Muse", Song: "Uprising", ReleaseDate: "16.07.2006", FetchedAt: recent},
+	} {
+		putUpstreamResponse(t, repo, response)
+	}
+
+	purge := func(filter *model.UpstreamCacheFilter) int {
+		t.Helper()
+
+		purged, err := repo.PurgeUpstreamResponses(ctx, filter)
+		if err != nil {
+			t.Fatalf("PurgeUpstreamResponses(%+v): %s", filter, err)
+		}
+		return purged
+	}
+	cached := func(provider, group, song string) bool {
+		t.Helper()
+
+		_, err := repo.GetUpstreamResponse(ctx, provider, group, song)
+		if errors.Is(err, repository.ErrUpstreamResponseNotFound) {
+			return false
+		}
+		if err != nil {
+			t.Fatalf("GetUpstreamResponse: %s", err)
+		}
+		return true
+	}
+
+	if purged := purge(&model.UpstreamCacheFilter{Provider: "api", NotFoundOnly: true, FetchedBefore: old.Add(time.Hour)}); purged != 1 {
+		t.Fatalf("purging old not found responses: purged %d, want 1", purged)
+	}
+	if cached("api", "Queen", "Bohemian Rhapsody") || !cached("api", "Muse", "Starlight") || !cached("api", "Muse", "Uprising") {
+		t.Fatal("purging old not found responses: wrong responses purged")
+	}
+
+	if purged := purge(&model.UpstreamCacheFilter{Group: "muse", Song: "uprising"}); purged != 2 {
+		t.Fatalf("purging a song: purged %d, want 2", purged)
+	}
+	if cached("api", "Muse", "Uprising") || cached("musicbrainz", "Muse", "Uprising") || !cached("api", "Muse", "Starlight") {
+		t.Fatal("purging a song: wrong responses purged")
+	}
+
+	if purged := purge(&model.UpstreamCacheFilter{}); purged != 1 {
+		t.Fatalf("purging all responses: purged %d, want 1", purged)
+	}
+	if purged := purge(&model.UpstreamCacheFilter{}); purged != 0 {
+		t.Fatalf("purging an empty cache: purged %d, want 0", purged)
+	}
+}
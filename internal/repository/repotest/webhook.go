@@ -0,0 +1,326 @@
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"testing"
+	"time"
+)
+
+// WebhookFactory returns an empty music repository and the webhook repository
+// dispatching its events.
+type WebhookFactory func(t *testing.T) (repository.IMusicRepository, repository.IWebhookRepository)
+
+func RunWebhooks(t *testing.T, newRepos WebhookFactory) {
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newRepos) })
+	t.Run("EnqueueDeliveries", func(t *testing.T) { testEnqueueDeliveries(t, newRepos) })
+	t.Run("EnqueueLimit", func(t *testing.T) { testEnqueueLimit(t, newRepos) })
+	t.Run("DeliveryLifecycle", func(t *testing.T) { testDeliveryLifecycle(t, newRepos) })
+	t.Run("DeleteWebhookCascades", func(t *testing.T) { testDeleteWebhookCascades(t, newRepos) })
+}
+
+func addWebhook(t *testing.T, repo repository.IWebhookRepository, url string, events ...string) *model.Webhook {
+	t.Helper()
+
+	webhook, err := repo.AddWebhook(context.Background(), &model.Webhook{
+		URL:    url,
+		Secret: "0123456789abcdef",
+		Events: events,
+	})
+	if err != nil {
+		t.Fatalf("AddWebhook: %s", err)
+	}
+
+	return webhook
+}
+
+func enqueue(t *testing.T, repo repository.IWebhookRepository, limit int) int {
+	t.Helper()
+
+	enqueued, err := repo.EnqueueDeliveries(context.Background(), limit)
+	if err != nil {
+		t.Fatalf("EnqueueDeliveries: %s", err)
+	}
+
+	return enqueued
+}
+
+func claim(t *testing.T, repo repository.IWebhookRepository) []*model.WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := repo.ClaimDeliveries(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDeliveries: %s", err)
+	}
+
+	return deliveries
+}
+
+func deadLetters(t *testing.T, repo repository.IWebhookRepository) []*model.WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := repo.ListDeadLetters(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %s", err)
+	}
+
+	count, err := repo.CountDeadLetters(context.Background())
+	if err != nil {
+		t.Fatalf("CountDeadLetters: %s", err)
+	}
+	if count != len(deliveries) {
+		t.Fatalf("CountDeadLetters returned %d, ListDeadLetters %d deliveries", count, len(deliveries))
+	}
+
+	return deliveries
+}
+
+func testWebhooks(t *testing.T, newRepos WebhookFactory) {
+	_, repo := newRepos(t)
+
+	a := addWebhook(t, repo, "https://example.com/a")
+	b := addWebhook(t, repo, "https://example.com/b", model.EventSongCreated, model.EventSongDeleted)
+	if a.ID <= 0 || b.ID <= a.ID {
+		t.Fatalf("expected increasing positive ids, got %d and %d", a.ID, b.ID)
+	}
+
+	webhooks, err := repo.ListWebhooks(context.Background())
+	if err != nil {
+		t.Fatalf("ListWebhooks: %s", err)
+	}
+	if len(webhooks) != 2 {
+		t.Fatalf("expected 2 webhooks, got %d", len(webhooks))
+	}
+	if webhooks[0].URL != a.URL || len(webhooks[0].Events) != 0 || webhooks[0].Secret != a.Secret {
+		t.Errorf("stored webhook %+v doesn't match added %+v", webhooks[0], a)
+	}
+	if len(webhooks[1].Events) != 2 || webhooks[1].Events[1] != model.EventSongDeleted {
+		t.Errorf("expected events of %+v, got %v", b, webhooks[1].Events)
+	}
+
+	err = repo.DeleteWebhook(context.Background(), a.ID)
+	if err != nil {
+		t.Fatalf("DeleteWebhook: %s", err)
+	}
+
+	err = repo.DeleteWebhook(context.Background(), a.ID)
+	if !errors.Is(err, repository.ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound for deleted webhook, got %v", err)
+	}
+
+	webhooks, err = repo.ListWebhooks(context.Background())
+	if err != nil {
+		t.Fatalf("ListWebhooks: %s", err)
+	}
+	if len(webhooks) != 1 || webhooks[0].ID != b.ID {
+		t.Errorf("expected only webhook %d to remain, got %+v", b.ID, webhooks)
+	}
+}
+
+func testEnqueueDeliveries(t *testing.T, newRepos WebhookFactory) {
+	music, repo := newRepos(t)
+	ctx := context.Background()
+
+	all := addWebhook(t, repo, "https://example.com/all")
+	deleted := addWebhook(t, repo, "https://example.com/deleted", model.EventSongDeleted)
+
+	song := addSong(t, music, "Muse", "Uprising", "07.09.2009")
+
+	title := "Starlight"
+	err := music.UpdateSong(ctx, song.ID, &dto.UpdateSongReq{Song: &title})
+	if err != nil {
+		t.Fatalf("UpdateSong: %s", err)
+	}
+
+	// Updating or deleting a missing song changes nothing and records no event.
+	err = music.UpdateSong(ctx, song.ID+100, &dto.UpdateSongReq{Song: &title})
+	if err != nil {
+		t.Fatalf("UpdateSong: %s", err)
+	}
+	err = music.DeleteSong(ctx, song.ID+100)
+	if err != nil {
+		t.Fatalf("DeleteSong: %s", err)
+	}
+
+	err = music.DeleteSong(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("DeleteSong: %s", err)
+	}
+
+	if enqueued := enqueue(t, repo, 10); enqueued != 4 {
+		t.Fatalf("expected 4 deliveries for 3 events, got %d", enqueued)
+	}
+	if enqueued := enqueue(t, repo, 10); enqueued != 0 {
+		t.Fatalf("expected dispatched events to be skipped, got %d deliveries", enqueued)
+	}
+
+	deliveries := claim(t, repo)
+	if len(deliveries) != 4 {
+		t.Fatalf("expected 4 claimed deliveries, got %d", len(deliveries))
+	}
+
+	var types []string
+	for _, delivery := range deliveries {
+		if delivery.Webhook.ID == all.ID {
+			types = append(types, delivery.Event.Type)
+		} else if delivery.Webhook.ID != deleted.ID || delivery.Event.Type != model.EventSongDeleted {
+			t.Errorf("unexpected delivery of %s to webhook %d", delivery.Event.Type, delivery.Webhook.ID)
+		}
+
+		if delivery.Status != model.DeliveryPending || delivery.Attempts != 0 || delivery.Webhook.Secret == "" {
+			t.Errorf("unexpected claimed delivery %+v", delivery)
+		}
+		if delivery.Event.SongID != song.ID {
+			t.Errorf("expected event for song %d, got %d", song.ID, delivery.Event.SongID)
+		}
+
+		var data model.Song
+		err = json.Unmarshal(delivery.Event.Data, &data)
+		if err != nil {
+			t.Fatalf("decode event data %s: %s", delivery.Event.Data, err)
+		}
+		if data.ID != song.ID || data.Group != "Muse" {
+			t.Errorf("unexpected event data %s", delivery.Event.Data)
+		}
+		if delivery.Event.Type != model.EventSongCreated && data.Song != title {
+			t.Errorf("expected %s event with updated title, got %s", delivery.Event.Type, delivery.Event.Data)
+		}
+	}
+
+	want := []string{model.EventSongCreated, model.EventSongUpdated, model.EventSongDeleted}
+	if len(types) != len(want) {
+		t.Fatalf("expected events %v for webhook %d, got %v", want, all.ID, types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("expected events %v in order, got %v", want, types)
+		}
+	}
+
+	if claimed := claim(t, repo); len(claimed) != 0 {
+		t.Errorf("expected leased deliveries to be skipped, got %d", len(claimed))
+	}
+}
+
+func testEnqueueLimit(t *testing.T, newRepos WebhookFactory) {
+	music, repo := newRepos(t)
+
+	addWebhook(t, repo, "https://example.com/all")
+	for _, title := range []string{"Uprising", "Starlight", "Resistance"} {
+		addSong(t, music, "Muse", title, "07.09.2009")
+	}
+
+	if enqueued := enqueue(t, repo, 2); enqueued != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", enqueued)
+	}
+	if enqueued := enqueue(t, repo, 2); enqueued != 1 {
+		t.Fatalf("expected the remaining delivery, got %d", enqueued)
+	}
+}
+
+func testDeliveryLifecycle(t *testing.T, newRepos WebhookFactory) {
+	music, repo := newRepos(t)
+	ctx := context.Background()
+
+	webhook := addWebhook(t, repo, "https://example.com/all")
+	addSong(t, music, "Muse", "Uprising", "07.09.2009")
+	addSong(t, music, "Muse", "Starlight", "03.09.2007")
+	enqueue(t, repo, 10)
+
+	deliveries := claim(t, repo)
+	if len(deliveries) != 2 {
+		t.Fatalf("expected 2 claimed deliveries, got %d", len(deliveries))
+	}
+	delivered, failed := deliveries[0], deliveries[1]
+
+	err := repo.MarkDelivered(ctx, delivered.ID)
+	if err != nil {
+		t.Fatalf("MarkDelivered: %s", err)
+	}
+
+	err = repo.MarkFailed(ctx, failed.ID, "503 Service Unavailable", time.Now().Add(-time.Second), false)
+	if err != nil {
+		t.Fatalf("MarkFailed: %s", err)
+	}
+
+	deliveries = claim(t, repo)
+	if len(deliveries) != 1 || deliveries[0].ID != failed.ID {
+		t.Fatalf("expected only failed delivery %d to be due, got %+v", failed.ID, deliveries)
+	}
+	if deliveries[0].Attempts != 1 || deliveries[0].LastError != "503 Service Unavailable" {
+		t.Errorf("expected 1 failed attempt, got %+v", deliveries[0])
+	}
+
+	if dead := deadLetters(t, repo); len(dead) != 0 {
+		t.Fatalf("expected no dead letters, got %d", len(dead))
+	}
+
+	err = repo.MarkFailed(ctx, failed.ID, "timeout", time.Now().Add(-time.Second), true)
+	if err != nil {
+		t.Fatalf("MarkFailed: %s", err)
+	}
+
+	if claimed := claim(t, repo); len(claimed) != 0 {
+		t.Fatalf("expected dead delivery not to be claimed, got %d", len(claimed))
+	}
+
+	dead := deadLetters(t, repo)
+	if len(dead) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(dead))
+	}
+	if dead[0].ID != failed.ID || dead[0].Attempts != 2 || dead[0].LastError != "timeout" || dead[0].Status != model.DeliveryDead ||
+		dead[0].Webhook.ID != webhook.ID || dead[0].Webhook.URL != webhook.URL || dead[0].Event.Type != model.EventSongCreated {
+		t.Errorf("unexpected dead letter %+v", dead[0])
+	}
+
+	err = repo.RetryDelivery(ctx, delivered.ID)
+	if !errors.Is(err, repository.ErrDeliveryNotFound) {
+		t.Errorf("expected ErrDeliveryNotFound for delivered delivery, got %v", err)
+	}
+
+	err = repo.RetryDelivery(ctx, failed.ID)
+	if err != nil {
+		t.Fatalf("RetryDelivery: %s", err)
+	}
+
+	if dead := deadLetters(t, repo); len(dead) != 0 {
+		t.Fatalf("expected retried delivery to leave the dead letters, got %d", len(dead))
+	}
+
+	deliveries = claim(t, repo)
+	if len(deliveries) != 1 || deliveries[0].ID != failed.ID || deliveries[0].Attempts != 0 {
+		t.Fatalf("expected retried delivery %d with no attempts, got %+v", failed.ID, deliveries)
+	}
+}
+
+func testDeleteWebhookCascades(t *testing.T, newRepos WebhookFactory) {
+	music, repo := newRepos(t)
+	ctx := context.Background()
+
+	webhook := addWebhook(t, repo, "https://example.com/all")
+	addSong(t, music, "Muse", "Uprising", "07.09.2009")
+	enqueue(t, repo, 10)
+
+	deliveries := claim(t, repo)
+	if len(deliveries) != 1 {
+		t.Fatalf("expected 1 claimed delivery, got %d", len(deliveries))
+	}
+
+	err := repo.MarkFailed(ctx, deliveries[0].ID, "timeout", time.Now(), true)
+	if err != nil {
+		t.Fatalf("MarkFailed: %s", err)
+	}
+
+	err = repo.DeleteWebhook(ctx, webhook.ID)
+	if err != nil {
+		t.Fatalf("DeleteWebhook: %s", err)
+	}
+
+	if dead := deadLetters(t, repo); len(dead) != 0 {
+		t.Errorf("expected deliveries of deleted webhook to be removed, got %d", len(dead))
+	}
+}
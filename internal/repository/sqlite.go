@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// SQLiteMusicRepository is an IMusicRepository for SQLite. The queries are shared
+// with MusicRepository since SQLite understands $N placeholders and RETURNING.
+type SQLiteMusicRepository struct {
+	*MusicRepository
+}
+
+func NewSQLiteMusicRepository(db *sql.DB, log *logrus.Logger) *SQLiteMusicRepository {
+	repo := NewMusicRepository(db, log)
+	repo.eventsQuery = sqliteEventsQuery
+	repo.lastEventIDQuery = sqliteLastEventIDQuery
+
+	return &SQLiteMusicRepository{
+		MusicRepository: repo,
+	}
+}
+
+// FindDuplicates compares the songs in memory, as SQLite has no trigram index.
+func (r *SQLiteMusicRepository) FindDuplicates(ctx context.Context, threshold float64, limit int) ([]*model.DuplicateSongs, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+songColumns+` FROM songs`)
+	if err != nil {
+		r.log.Errorf("FindDuplicates repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []*model.Song
+
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			r.log.Errorf("FindDuplicates repository error: %s", err)
+			return nil, err
+		}
+
+		songs = append(songs, song)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("FindDuplicates repository error: %s", err)
+		return nil, err
+	}
+
+	duplicates := findDuplicates(songs, threshold, limit)
+
+	r.log.Infof("Successfully found %d duplicate songs", len(duplicates))
+	return duplicates, nil
+}
@@ -0,0 +1,175 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/repository/repotest"
+	"testing"
+)
+
+func TestSQLiteMusicRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.IMusicRepository {
+		return repository.NewSQLiteMusicRepository(repotest.OpenSQLite(t), testLogger())
+	})
+}
+
+func TestSQLiteWebhookRepository(t *testing.T) {
+	repotest.RunWebhooks(t, func(t *testing.T) (repository.IMusicRepository, repository.IWebhookRepository) {
+		sqliteDB := repotest.OpenSQLite(t)
+		return repository.NewSQLiteMusicRepository(sqliteDB, testLogger()), repository.NewSQLiteWebhookRepository(sqliteDB, testLogger())
+	})
+}
+
+func TestSQLiteUpstreamCacheRepository(t *testing.T) {
+	repotest.RunUpstreamCache(t, func(t *testing.T) repository.IUpstreamCacheRepository {
+		return repository.NewUpstreamCacheRepository(repotest.OpenSQLite(t), testLogger())
+	})
+}
+
+// testIngestSongRollback checks that IngestSong doesn't leave a song behind
+// if storing its link fails, failLinks makes the link inserts fail.
+func testIngestSongRollback(t *testing.T, repo repository.IMusicRepository, failLinks func(t *testing.T)) {
+	ctx := context.Background()
+	failLinks(t)
+
+	_, err := repo.IngestSong(ctx, &model.Song{Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009"},
+		"First verse", &model.Link{Provider: "youtube", URL: "https://www.youtube.com/watch?v=w8KQmps-Sog"})
+	if err == nil {
+		t.Fatal("IngestSong with failing link inserts: want an error")
+	}
+
+	_, err = repo.FindSong(ctx, "Muse", "Uprising")
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Fatalf("FindSong after a failed IngestSong: want ErrSongNotFound, got %v", err)
+	}
+	last, err := repo.LastEventID(ctx)
+	if err != nil {
+		t.Fatalf("LastEventID: %s", err)
+	}
+	if last != 0 {
+		t.Fatalf("LastEventID after a failed IngestSong = %d, want no events", last)
+	}
+}
+
+func TestSQLiteIngestSongRollback(t *testing.T) {
+	sqliteDB := repotest.OpenSQLite(t)
+
+	testIngestSongRollback(t, repository.NewSQLiteMusicRepository(sqliteDB, testLogger()), func(t *testing.T) {
+		_, err := sqliteDB.Exec(`CREATE TRIGGER fail_links BEFORE INSERT ON song_links BEGIN SELECT RAISE(ABORT, 'link insert failed'); END`)
+		if err != nil {
+			t.Fatalf("create trigger: %s", err)
+		}
+	})
+}
+
+// testUpdateSongWithLyricsRollback checks that UpdateSongWithLyrics doesn't
+// keep the new release date if storing the lyrics fails, failVerses makes
+// the verse inserts fail.
+func testUpdateSongWithLyricsRollback(t *testing.T, repo repository.IMusicRepository, failVerses func(t *testing.T)) {
+	ctx := context.Background()
+
+	song, err := repo.AddSong(ctx, &model.Song{Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009"})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+	failVerses(t)
+
+	releaseDate, text := "08.09.2009", "First verse"
+	err = repo.UpdateSongWithLyrics(ctx, song.ID, &dto.UpdateSongReq{ReleaseDate: &releaseDate, Text: &text, Version: &song.Version}, nil)
+	if err == nil {
+		t.Fatal("UpdateSongWithLyrics with failing verse inserts: want an error")
+	}
+
+	stored, err := repo.GetSong(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSong: %s", err)
+	}
+	if stored.ReleaseDate != song.ReleaseDate || stored.Version != song.Version {
+		t.Fatalf("GetSong after a failed UpdateSongWithLyrics = %+v, want the song unchanged", stored)
+	}
+}
+
+func TestSQLiteUpdateSongWithLyricsRollback(t *testing.T) {
+	sqliteDB := repotest.OpenSQLite(t)
+
+	testUpdateSongWithLyricsRollback(t, repository.NewSQLiteMusicRepository(sqliteDB, testLogger()), func(t *testing.T) {
+		_, err := sqliteDB.Exec(`CREATE TRIGGER fail_verses BEFORE INSERT ON verses BEGIN SELECT RAISE(ABORT, 'verse insert failed'); END`)
+		if err != nil {
+			t.Fatalf("create trigger: %s", err)
+		}
+	})
+}
+
+// translitRepository is a repository which backfills transliterated titles.
+type translitRepository interface {
+	repository.IMusicRepository
+	repository.TranslitBackfiller
+}
+
+// testBackfillTranslit checks that BackfillTranslit only fills in the songs
+// added before the transliterated columns, which markStale simulates, and
+// not the songs whose titles fold to an empty string.
+func testBackfillTranslit(t *testing.T, repo translitRepository, markStale func(t *testing.T, songID int)) {
+	ctx := context.Background()
+
+	song, err := repo.AddSong(ctx, &model.Song{Song: "Группа крови", Group: "Кино", ReleaseDate: "05.01.1988"})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+	// Hard signs have no transliteration.
+	_, err = repo.AddSong(ctx, &model.Song{Song: "Ъ", Group: "Ь", ReleaseDate: "01.01.2000"})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+
+	count, err := repo.BackfillTranslit(ctx)
+	if err != nil {
+		t.Fatalf("BackfillTranslit: %s", err)
+	}
+	if count != 0 {
+		t.Fatalf("BackfillTranslit of new songs = %d, want 0", count)
+	}
+
+	markStale(t, song.ID)
+
+	group := "Kino"
+	songs, err := repo.GetSongsList(ctx, &dto.GetSongsListReq{Group: &group, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetSongsList: %s", err)
+	}
+	if len(songs) != 0 {
+		t.Fatalf("GetSongsList before the backfill = %d songs, want 0", len(songs))
+	}
+
+	for _, want := range []int{1, 0} {
+		count, err = repo.BackfillTranslit(ctx)
+		if err != nil {
+			t.Fatalf("BackfillTranslit: %s", err)
+		}
+		if count != want {
+			t.Fatalf("BackfillTranslit = %d, want %d", count, want)
+		}
+	}
+
+	songs, err = repo.GetSongsList(ctx, &dto.GetSongsListReq{Group: &group, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetSongsList: %s", err)
+	}
+	if len(songs) != 1 || songs[0].ID != song.ID {
+		t.Fatalf("GetSongsList after the backfill = %+v, want song %d", songs, song.ID)
+	}
+}
+
+func TestSQLiteBackfillTranslit(t *testing.T) {
+	sqliteDB := repotest.OpenSQLite(t)
+
+	testBackfillTranslit(t, repository.NewSQLiteMusicRepository(sqliteDB, testLogger()), func(t *testing.T, songID int) {
+		_, err := sqliteDB.Exec(`UPDATE songs SET artist_translit=NULL, song_translit=NULL WHERE id=$1`, songID)
+		if err != nil {
+			t.Fatalf("mark song stale: %s", err)
+		}
+	})
+}
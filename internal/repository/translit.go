@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aaanger/music-library/pkg/translit"
+	"github.com/jackc/pgx/v5"
+)
+
+// TranslitBackfiller is implemented by the repositories storing the
+// transliterated titles and artists of the songs. Songs added before the
+// columns existed have to be filled in once, which is done on startup.
+type TranslitBackfiller interface {
+	BackfillTranslit(ctx context.Context) (int, error)
+}
+
+type staleTranslit struct {
+	id           int
+	song, artist string
+}
+
+// scanStaleTranslit reads the rows of staleTranslitQuery. The rows are read
+// before updating any of them, SQLite has a single connection.
+func scanStaleTranslit(rows rowsScanner) ([]staleTranslit, error) {
+	var songs []staleTranslit
+
+	for rows.Next() {
+		var song staleTranslit
+
+		err := rows.Scan(&song.id, &song.song, &song.artist)
+		if err != nil {
+			return nil, err
+		}
+
+		songs = append(songs, song)
+	}
+
+	return songs, rows.Err()
+}
+
+// BackfillTranslit fills in the transliterated title and artist of the songs
+// which don't have them and returns the number of updated songs.
+func (r *MusicRepository) BackfillTranslit(ctx context.Context) (int, error) {
+	var count int
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, staleTranslitQuery)
+		if err != nil {
+			return err
+		}
+
+		songs, err := scanStaleTranslit(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, song := range songs {
+			_, err = tx.ExecContext(ctx, setTranslitQuery, translit.Fold(song.artist), translit.Fold(song.song), song.id)
+			if err != nil {
+				return err
+			}
+		}
+
+		count = len(songs)
+		return nil
+	})
+	if err != nil {
+		r.log.Errorf("BackfillTranslit repository error: %s", err)
+		return 0, err
+	}
+
+	if count > 0 {
+		r.log.Infof("Successfully backfilled transliterated titles of %d songs", count)
+	}
+	return count, nil
+}
+
+// BackfillTranslit fills in the transliterated title and artist of the songs
+// which don't have them and returns the number of updated songs.
+func (r *PgxMusicRepository) BackfillTranslit(ctx context.Context) (int, error) {
+	var count int
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, staleTranslitQuery)
+		if err != nil {
+			return err
+		}
+
+		songs, err := scanStaleTranslit(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		count = len(songs)
+		if count == 0 {
+			return nil
+		}
+
+		batch := &pgx.Batch{}
+		for _, song := range songs {
+			batch.Queue(setTranslitQuery, translit.Fold(song.artist), translit.Fold(song.song), song.id)
+		}
+
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		r.log.Errorf("BackfillTranslit repository error: %s", err)
+		return 0, err
+	}
+
+	if count > 0 {
+		r.log.Infof("Successfully backfilled transliterated titles of %d songs", count)
+	}
+	return count, nil
+}
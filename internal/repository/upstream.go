@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+// IUpstreamCacheRepository caches the responses of the metadata providers,
+// keyed by the provider and the normalized group and song.
+type IUpstreamCacheRepository interface {
+	// GetUpstreamResponse returns ErrUpstreamResponseNotFound if the response
+	// isn't cached, regardless of its age.
+	GetUpstreamResponse(ctx context.Context, provider, group, song string) (*model.UpstreamResponse, error)
+	// PutUpstreamResponse adds the response or replaces the cached one.
+	PutUpstreamResponse(ctx context.Context, response *model.UpstreamResponse) error
+	// PurgeUpstreamResponses deletes the cached responses matching the filter
+	// and returns their number.
+	PurgeUpstreamResponses(ctx context.Context, filter *model.UpstreamCacheFilter) (int, error)
+}
+
+// UpstreamCacheRepository is an IUpstreamCacheRepository on database/sql, for
+// both Postgres and SQLite.
+type UpstreamCacheRepository struct {
+	db  *sql.DB
+	log *logrus.Logger
+}
+
+func NewUpstreamCacheRepository(db *sql.DB, log *logrus.Logger) *UpstreamCacheRepository {
+	return &UpstreamCacheRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+const upstreamResponseColumns = `provider, group_name, song, release_date, text, link, not_found, fetched_at`
+
+func (r *UpstreamCacheRepository) GetUpstreamResponse(ctx context.Context, provider, group, song string) (*model.UpstreamResponse, error) {
+	var response model.UpstreamResponse
+
+	err := r.db.QueryRowContext(ctx, `SELECT `+upstreamResponseColumns+` FROM upstream_cache WHERE provider=$1 AND group_key=$2 AND song_key=$3`,
+		provider, normalizeKey(group), normalizeKey(song)).
+		Scan(&response.Provider, &response.Group, &response.Song, &response.ReleaseDate, &response.Text, &response.Link,
+			&response.NotFound, &response.FetchedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUpstreamResponseNotFound
+	}
+	if err != nil {
+		r.log.Errorf("GetUpstreamResponse repository error: %s", err)
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (r *UpstreamCacheRepository) PutUpstreamResponse(ctx context.Context, response *model.UpstreamResponse) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO upstream_cache (group_key, song_key, `+upstreamResponseColumns+`)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (provider, group_key, song_key) DO UPDATE SET group_name=EXCLUDED.group_name, song=EXCLUDED.song,
+release_date=EXCLUDED.release_date, text=EXCLUDED.text, link=EXCLUDED.link, not_found=EXCLUDED.not_found, fetched_at=EXCLUDED.fetched_at`,
+		normalizeKey(response.Group), normalizeKey(response.Song), response.Provider, response.Group, response.Song,
+		response.ReleaseDate, response.Text, response.Link, response.NotFound, response.FetchedAt.UTC())
+	if err != nil {
+		r.log.Errorf("PutUpstreamResponse repository error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *UpstreamCacheRepository) PurgeUpstreamResponses(ctx context.Context, filter *model.UpstreamCacheFilter) (int, error) {
+	var conditions []string
+	var values []interface{}
+
+	where := func(condition string, value interface{}) {
+		values = append(values, value)
+		conditions = append(conditions, condition+"$"+strconv.Itoa(len(values)))
+	}
+
+	if filter.Provider != "" {
+		where("provider=", filter.Provider)
+	}
+	if filter.Group != "" {
+		where("group_key=", normalizeKey(filter.Group))
+	}
+	if filter.Song != "" {
+		where("song_key=", normalizeKey(filter.Song))
+	}
+	if filter.NotFoundOnly {
+		where("not_found=", true)
+	}
+	if !filter.FetchedBefore.IsZero() {
+		where("fetched_at<", filter.FetchedBefore.UTC())
+	}
+
+	query := `DELETE FROM upstream_cache`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	res, err := r.db.ExecContext(ctx, query, values...)
+	if err != nil {
+		r.log.Errorf("PurgeUpstreamResponses repository error: %s", err)
+		return 0, err
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		r.log.Errorf("PurgeUpstreamResponses repository error: %s", err)
+		return 0, err
+	}
+
+	r.log.Infof("Successfully purged %d cached upstream responses", purged)
+	return int(purged), nil
+}
@@ -0,0 +1,367 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// IWebhookRepository stores webhook subscriptions and the deliveries of
+// outbox events to them.
+type IWebhookRepository interface {
+	AddWebhook(ctx context.Context, webhook *model.Webhook) (*model.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID int) error
+	// EnqueueDeliveries creates a pending delivery for every webhook subscribed
+	// to each of up to limit undispatched events and marks the events dispatched.
+	EnqueueDeliveries(ctx context.Context, limit int) (int, error)
+	// ClaimDeliveries returns up to limit pending deliveries which are due and
+	// postpones them by lease, so concurrent dispatchers skip them while in flight.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, deliveryID int64) error
+	// MarkFailed counts a failed attempt, the delivery is retried at nextAttemptAt
+	// or moved to the dead letters if dead is set.
+	MarkFailed(ctx context.Context, deliveryID int64, lastError string, nextAttemptAt time.Time, dead bool) error
+	ListDeadLetters(ctx context.Context, limit, offset int) ([]*model.WebhookDelivery, error)
+	CountDeadLetters(ctx context.Context) (int, error)
+	// RetryDelivery moves a dead delivery back to pending with a fresh attempts count.
+	RetryDelivery(ctx context.Context, deliveryID int64) error
+}
+
+// WebhookRepository is an IWebhookRepository on database/sql.
+type WebhookRepository struct {
+	db *sql.DB
+	// lockClause is appended to the queries selecting outbox rows to process.
+	lockClause string
+	log        *logrus.Logger
+}
+
+// NewWebhookRepository returns a repository for Postgres, concurrent
+// dispatchers skip rows locked by each other.
+func NewWebhookRepository(db *sql.DB, log *logrus.Logger) *WebhookRepository {
+	return &WebhookRepository{
+		db:         db,
+		lockClause: " FOR UPDATE SKIP LOCKED",
+		log:        log,
+	}
+}
+
+// NewSQLiteWebhookRepository returns a repository for SQLite, which has no
+// row locks but serializes write transactions anyway.
+func NewSQLiteWebhookRepository(db *sql.DB, log *logrus.Logger) *WebhookRepository {
+	return &WebhookRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *WebhookRepository) AddWebhook(ctx context.Context, webhook *model.Webhook) (*model.Webhook, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO webhooks (url, secret, events, created_at) VALUES($1, $2, $3, CURRENT_TIMESTAMP) RETURNING id, created_at`,
+		webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","))
+
+	err := row.Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		r.log.Errorf("AddWebhook repository error: %s", err)
+		return nil, err
+	}
+
+	r.log.Infof("Successfully added webhook %d for %s", webhook.ID, webhook.URL)
+	return webhook, nil
+}
+
+func (r *WebhookRepository) ListWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	webhooks, err := r.listWebhooks(ctx, r.db)
+	if err != nil {
+		r.log.Errorf("ListWebhooks repository error: %s", err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (r *WebhookRepository) listWebhooks(ctx context.Context, q queryer) ([]*model.Webhook, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, url, secret, events, created_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*model.Webhook
+
+	for rows.Next() {
+		var webhook model.Webhook
+		var events string
+
+		err := rows.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &events, &webhook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		webhook.Events = splitEvents(events)
+		webhooks = append(webhooks, &webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, webhookID int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, webhookID)
+	if err != nil {
+		r.log.Errorf("DeleteWebhook repository error: %s", err)
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		r.log.Errorf("DeleteWebhook repository error: %s", err)
+		return err
+	}
+	if affected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	r.log.Infof("Successfully deleted webhook with id %d", webhookID)
+	return nil
+}
+
+func (r *WebhookRepository) EnqueueDeliveries(ctx context.Context, limit int) (int, error) {
+	var enqueued int
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT id, type FROM song_events WHERE dispatched_at IS NULL ORDER BY id LIMIT $1`+r.lockClause, limit)
+		if err != nil {
+			return err
+		}
+
+		var events []*model.Event
+		for rows.Next() {
+			var event model.Event
+			err = rows.Scan(&event.ID, &event.Type)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			events = append(events, &event)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		webhooks, err := r.listWebhooks(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		eventIDs := make([]any, 0, len(events))
+
+		for _, event := range events {
+			for _, webhook := range webhooks {
+				if !webhook.Subscribed(event.Type) {
+					continue
+				}
+
+				_, err = tx.ExecContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, event_id, next_attempt_at, updated_at) VALUES($1, $2, $3, $3)`,
+					webhook.ID, event.ID, now)
+				if err != nil {
+					return err
+				}
+				enqueued++
+			}
+			eventIDs = append(eventIDs, event.ID)
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE song_events SET dispatched_at = $1 WHERE id IN (`+placeholders(2, len(eventIDs))+`)`,
+			append([]any{now}, eventIDs...)...)
+		return err
+	})
+	if err != nil {
+		r.log.Errorf("EnqueueDeliveries repository error: %s", err)
+		return 0, err
+	}
+
+	if enqueued > 0 {
+		r.log.Infof("Successfully enqueued %d webhook deliveries", enqueued)
+	}
+	return enqueued, nil
+}
+
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+
+	lockClause := r.lockClause
+	if lockClause != "" {
+		lockClause = " FOR UPDATE OF d SKIP LOCKED"
+	}
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		now := time.Now().UTC()
+
+		rows, err := tx.QueryContext(ctx, `SELECT d.id, d.status, d.attempts, d.last_error, d.next_attempt_at, d.updated_at,
+			w.id, w.url, w.secret, w.events, w.created_at, e.id, e.type, e.song_id, e.data, e.created_at
+			FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			JOIN song_events e ON e.id = d.event_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1
+			ORDER BY d.id LIMIT $2`+lockClause, now, limit)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			delivery := model.WebhookDelivery{Webhook: &model.Webhook{}, Event: &model.Event{}}
+			var events string
+			var data []byte
+
+			err = rows.Scan(&delivery.ID, &delivery.Status, &delivery.Attempts, &delivery.LastError, &delivery.NextAttemptAt, &delivery.UpdatedAt,
+				&delivery.Webhook.ID, &delivery.Webhook.URL, &delivery.Webhook.Secret, &events, &delivery.Webhook.CreatedAt,
+				&delivery.Event.ID, &delivery.Event.Type, &delivery.Event.SongID, &data, &delivery.Event.CreatedAt)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			delivery.Webhook.Events = splitEvents(events)
+			delivery.Event.Data = data
+			deliveries = append(deliveries, &delivery)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]any, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id IN (`+placeholders(2, len(ids))+`)`,
+			append([]any{now.Add(lease)}, ids...)...)
+		return err
+	})
+	if err != nil {
+		r.log.Errorf("ClaimDeliveries repository error: %s", err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, deliveryID int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, last_error = '', updated_at = $2 WHERE id = $3`,
+		model.DeliveryDelivered, time.Now().UTC(), deliveryID)
+	if err != nil {
+		r.log.Errorf("MarkDelivered repository error: %s", err)
+		return err
+	}
+
+	r.log.Debugf("MarkDelivered repository: delivery %d delivered", deliveryID)
+	return nil
+}
+
+func (r *WebhookRepository) MarkFailed(ctx context.Context, deliveryID int64, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := model.DeliveryPending
+	if dead {
+		status = model.DeliveryDead
+	}
+
+	_, err := r.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, last_error = $2, next_attempt_at = $3, updated_at = $4 WHERE id = $5`,
+		status, lastError, nextAttemptAt.UTC(), time.Now().UTC(), deliveryID)
+	if err != nil {
+		r.log.Errorf("MarkFailed repository error: %s", err)
+		return err
+	}
+
+	r.log.Debugf("MarkFailed repository: delivery %d is %s", deliveryID, status)
+	return nil
+}
+
+func (r *WebhookRepository) ListDeadLetters(ctx context.Context, limit, offset int) ([]*model.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, webhook_id, url, event_id, event_type, attempts, last_error, updated_at
+		FROM webhook_dead_letters ORDER BY id LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		r.log.Errorf("ListDeadLetters repository error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+
+	for rows.Next() {
+		delivery := model.WebhookDelivery{Webhook: &model.Webhook{}, Event: &model.Event{}, Status: model.DeliveryDead}
+
+		err := rows.Scan(&delivery.ID, &delivery.Webhook.ID, &delivery.Webhook.URL, &delivery.Event.ID, &delivery.Event.Type,
+			&delivery.Attempts, &delivery.LastError, &delivery.UpdatedAt)
+		if err != nil {
+			r.log.Errorf("ListDeadLetters repository error: %s", err)
+			return nil, err
+		}
+
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("ListDeadLetters repository error: %s", err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) CountDeadLetters(ctx context.Context) (int, error) {
+	var count int
+
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_dead_letters`).Scan(&count)
+	if err != nil {
+		r.log.Errorf("CountDeadLetters repository error: %s", err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *WebhookRepository) RetryDelivery(ctx context.Context, deliveryID int64) error {
+	now := time.Now().UTC()
+
+	res, err := r.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = $1, attempts = 0, next_attempt_at = $2, updated_at = $2 WHERE id = $3 AND status = $4`,
+		model.DeliveryPending, now, deliveryID, model.DeliveryDead)
+	if err != nil {
+		r.log.Errorf("RetryDelivery repository error: %s", err)
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		r.log.Errorf("RetryDelivery repository error: %s", err)
+		return err
+	}
+	if affected == 0 {
+		return ErrDeliveryNotFound
+	}
+
+	r.log.Infof("Successfully requeued webhook delivery %d", deliveryID)
+	return nil
+}
+
+func splitEvents(events string) []string {
+	if events == "" {
+		return []string{}
+	}
+	return strings.Split(events, ",")
+}
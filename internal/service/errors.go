@@ -0,0 +1,31 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch document")
+	ErrPatchResult  = errors.New("patched song is invalid")
+	ErrSelfMerge    = errors.New("song can't be merged into itself")
+	// ErrProviderMismatch is returned if the provider given for a link
+	// differs from the one of its host.
+	ErrProviderMismatch = errors.New("provider doesn't match the link")
+)
+
+// DuplicateSongError is returned by AddSong if the library already has the
+// song, it matches repository.ErrDuplicateSong.
+type DuplicateSongError struct {
+	Song *model.Song
+}
+
+func (e *DuplicateSongError) Error() string {
+	return fmt.Sprintf("song already exists with id %d", e.Song.ID)
+}
+
+func (e *DuplicateSongError) Unwrap() error {
+	return repository.ErrDuplicateSong
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/validation"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// PatchSong applies an RFC 7386 merge patch or an RFC 6902 JSON patch to the
+// current song. The update is bound to the version the patch was applied to,
+// so concurrent changes are reported as repository.ErrVersionMismatch.
+func (s *MusicService) PatchSong(ctx context.Context, songID int, contentType string, patch []byte, version *int) (*model.Song, error) {
+	s.log.Debugf("PatchSong service: songID=%d, contentType=%s, patch=%s", songID, contentType, patch)
+
+	song, err := s.repo.GetSong(ctx, songID)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != nil && *version != song.Version {
+		return nil, repository.ErrVersionMismatch
+	}
+
+	original, err := json.Marshal(dto.SongDocument{
+		Song:        song.Song,
+		Group:       song.Group,
+		ReleaseDate: song.ReleaseDate,
+		Link:        song.Link,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+
+	switch contentType {
+	case MergePatchContentType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case JSONPatchContentType:
+		var ops jsonpatch.Patch
+		ops, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = ops.Apply(original)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported content type %s", ErrInvalidPatch, contentType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+
+	var doc dto.SongDocument
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPatchResult, err)
+	}
+
+	err = validation.Struct(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPatchResult, err)
+	}
+
+	update := &dto.UpdateSongReq{
+		Song:        &doc.Song,
+		Group:       &doc.Group,
+		ReleaseDate: &doc.ReleaseDate,
+		Link:        &doc.Link,
+		Version:     &song.Version,
+	}
+	normalizeUpdate(update)
+
+	err = s.repo.UpdateSong(ctx, songID, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetSong(ctx, songID)
+}
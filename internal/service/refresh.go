@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/validation"
+	"github.com/aaanger/music-library/pkg/medialink"
+	"github.com/aaanger/music-library/pkg/translit"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval is the minimum time between two requests to the
+// metadata providers when songs are refreshed.
+const DefaultRefreshInterval = 250 * time.Millisecond
+
+// throttle spaces calls out by at least interval, across all callers.
+type throttle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the caller's turn or until ctx is done.
+func (t *throttle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	start := time.Now()
+	if t.next.After(start) {
+		start = t.next
+	}
+	t.next = start.Add(t.interval)
+	t.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// SetRefreshInterval changes the minimum time between two requests to the
+// metadata providers when songs are refreshed.
+func (s *MusicService) SetRefreshInterval(interval time.Duration) {
+	s.refreshThrottle.mu.Lock()
+	defer s.refreshThrottle.mu.Unlock()
+
+	s.refreshThrottle.interval = interval
+}
+
+// RefreshSong fetches the details of the song from the metadata providers
+// again and reports the fields which differ, applying the changes unless
+// req.DryRun is set.
+func (s *MusicService) RefreshSong(ctx context.Context, songID int, req *dto.RefreshSongsReq) (*model.SongRefresh, error) {
+	s.log.Infof("RefreshSong service: refreshing song ID=%d, fields - %v, dry run - %t", songID, req.Fields, req.DryRun)
+
+	song, err := s.repo.GetSongWithLyrics(ctx, songID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refresh(ctx, song, req)
+}
+
+// RefreshSongs refreshes a page of the songs matching the filters like
+// RefreshSong and returns the results with the total number of matching
+// songs. Songs whose details couldn't be fetched have the error set.
+func (s *MusicService) RefreshSongs(ctx context.Context, filter *dto.GetSongsListReq, req *dto.RefreshSongsReq) ([]*model.SongRefresh, int, error) {
+	s.log.Infof("RefreshSongs service: refreshing songs - %+v, fields - %v, dry run - %t", filter, req.Fields, req.DryRun)
+
+	songs, total, err := s.ListSongs(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]*model.SongRefresh, 0, len(songs))
+	for _, found := range songs {
+		song, err := s.repo.GetSongWithLyrics(ctx, found.ID, true)
+		if errors.Is(err, repository.ErrSongNotFound) {
+			// Deleted in the meantime.
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		result, err := s.refresh(ctx, song, req)
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		if err != nil {
+			result = &model.SongRefresh{
+				SongID:  song.ID,
+				Song:    song.Song.Song,
+				Group:   song.Group,
+				Changes: make([]*model.FieldChange, 0),
+				Error:   err.Error(),
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, total, nil
+}
+
+func (s *MusicService) refresh(ctx context.Context, song *model.SongWithLyrics, req *dto.RefreshSongsReq) (*model.SongRefresh, error) {
+	err := s.refreshThrottle.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cached responses would report no changes, the providers are asked again.
+	details, err := s.metadata.Fetch(metadata.SkipCache(ctx), song.Group, song.Song.Song)
+	if err != nil {
+		s.log.Errorf("Error fetching song details for group=%s, song=%s: %s", song.Group, song.Song.Song, err)
+		return nil, err
+	}
+
+	verses := make([]string, 0, len(song.Lyrics))
+	for _, verse := range song.Lyrics {
+		verses = append(verses, verse.Lyrics)
+	}
+
+	result := &model.SongRefresh{
+		SongID:  song.ID,
+		Song:    song.Song.Song,
+		Group:   song.Group,
+		Changes: make([]*model.FieldChange, 0),
+	}
+
+	for _, change := range []*model.FieldChange{
+		{Field: metadata.FieldReleaseDate, Old: song.ReleaseDate, New: details.ReleaseDate},
+		{Field: metadata.FieldText, Old: strings.Join(verses, "\n\n"), New: translit.Normalize(details.Text)},
+		{Field: metadata.FieldLink, Old: song.Link, New: details.Link},
+	} {
+		if !refreshField(req.Fields, change.Field) || change.New == "" || change.New == change.Old {
+			continue
+		}
+		if change.Field == metadata.FieldReleaseDate && !validation.IsReleaseDate(change.New) ||
+			change.Field == metadata.FieldLink && !validation.IsLink(change.New) {
+			s.log.Warnf("RefreshSong service: ignoring invalid %s %q of song ID=%d", change.Field, change.New, song.ID)
+			continue
+		}
+		result.Changes = append(result.Changes, change)
+	}
+
+	if req.DryRun || len(result.Changes) == 0 {
+		return result, nil
+	}
+
+	err = s.applyChanges(ctx, song, result.Changes)
+	if errors.Is(err, repository.ErrVersionMismatch) {
+		s.log.Infof("RefreshSong service: song ID=%d was modified during the refresh", song.ID)
+		result.Error = err.Error()
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Applied = true
+
+	s.log.Infof("RefreshSong service: applied %d changes to song ID=%d", len(result.Changes), song.ID)
+	return result, nil
+}
+
+// applyChanges stores the changes of a refresh in one repository
+// transaction bound to the version of the song the changes were computed
+// from, like PatchSong, so a song edited in the meantime is reported as
+// repository.ErrVersionMismatch and left untouched.
+func (s *MusicService) applyChanges(ctx context.Context, song *model.SongWithLyrics, changes []*model.FieldChange) error {
+	update := dto.UpdateSongReq{Version: &song.Version}
+
+	for _, change := range changes {
+		switch change.Field {
+		case metadata.FieldReleaseDate:
+			update.ReleaseDate = &change.New
+		case metadata.FieldLink:
+			update.Link = &change.New
+		case metadata.FieldText:
+			update.Text = &change.New
+		}
+	}
+
+	// The new link is kept among the media links of the song as well.
+	var link *model.Link
+	if update.Link != nil {
+		link = &model.Link{
+			SongID:   song.ID,
+			Provider: medialink.Detect(*update.Link),
+			URL:      *update.Link,
+		}
+	}
+
+	return s.repo.UpdateSongWithLyrics(ctx, song.ID, &update, link)
+}
+
+// refreshField reports whether field is one of the fields to refresh, all
+// fields are if none are given.
+func refreshField(fields []string, field string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
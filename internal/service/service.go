@@ -4,61 +4,120 @@ import (
 	"context"
 	"errors"
 	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/metadata"
 	"github.com/aaanger/music-library/internal/model"
 	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/validation"
+	"github.com/aaanger/music-library/pkg/medialink"
+	"github.com/aaanger/music-library/pkg/translit"
 	"github.com/sirupsen/logrus"
+	"strings"
 )
 
 type IMusicService interface {
 	AddSong(ctx context.Context, req *dto.AddSongReq) (*model.Song, error)
 	GetSongsList(ctx context.Context, req *dto.GetSongsListReq) ([]*model.Song, error)
-	GetSongLyrics(ctx context.Context, songID, limit, offset int) ([]*model.Verse, error)
+	GetSongLyrics(ctx context.Context, songID int, lang string, limit, offset int) ([]*model.Verse, error)
 	UpdateSong(ctx context.Context, songID int, req *dto.UpdateSongReq) error
 	DeleteSong(ctx context.Context, songID int) error
+	GetSong(ctx context.Context, songID int) (*model.Song, error)
+	GetSongWithLyrics(ctx context.Context, songID int, withLyrics bool) (*model.SongWithLyrics, error)
+	ListSongs(ctx context.Context, req *dto.GetSongsListReq) ([]*model.Song, int, error)
+	ListVerses(ctx context.Context, songID int, lang string, limit, offset int) ([]*model.Verse, int, error)
+	GetLyricsBySongIDs(ctx context.Context, songIDs []int) (map[int][]*model.Verse, error)
+	PatchSong(ctx context.Context, songID int, contentType string, patch []byte, version *int) (*model.Song, error)
+	GetEvents(ctx context.Context, afterID int64, limit int) ([]*model.Event, error)
+	LastEventID(ctx context.Context) (int64, error)
+	FindDuplicates(ctx context.Context, threshold float64, limit int) ([]*model.DuplicateSongs, error)
+	MergeSongs(ctx context.Context, targetID, sourceID int, version *int) (*model.Song, error)
+	TagSong(ctx context.Context, songID int, req *dto.TagSongReq) ([]*model.Tag, error)
+	UntagSong(ctx context.Context, songID int, name string) error
+	GetSongTags(ctx context.Context, songID int) ([]*model.Tag, error)
+	ListTags(ctx context.Context, kind string) ([]*model.Tag, error)
+	GetFacets(ctx context.Context, req *dto.GetSongsListReq, limit int) (*model.Facets, error)
+	GetLyricsLanguages(ctx context.Context, songID int) (*model.LyricsLanguages, error)
+	SetTranslation(ctx context.Context, songID int, lang string, req *dto.SetTranslationReq) (*model.LyricsLanguages, error)
+	DeleteTranslation(ctx context.Context, songID int, lang string) error
+	GetSongLinks(ctx context.Context, songID int) ([]*model.Link, error)
+	AddSongLink(ctx context.Context, songID int, req *dto.AddLinkReq) (*model.Link, error)
+	DeleteSongLink(ctx context.Context, songID, linkID int) error
+	RefreshSong(ctx context.Context, songID int, req *dto.RefreshSongsReq) (*model.SongRefresh, error)
+	RefreshSongs(ctx context.Context, filter *dto.GetSongsListReq, req *dto.RefreshSongsReq) ([]*model.SongRefresh, int, error)
 }
 
 type MusicService struct {
-	repo   repository.IMusicRepository
-	apiURL string
-	log    *logrus.Logger
+	repo            repository.IMusicRepository
+	metadata        *metadata.Registry
+	refreshThrottle *throttle
+	log             *logrus.Logger
 }
 
-func NewMusicService(repo repository.IMusicRepository, apiURL string, log *logrus.Logger) *MusicService {
+func NewMusicService(repo repository.IMusicRepository, metadata *metadata.Registry, log *logrus.Logger) *MusicService {
 	return &MusicService{
-		repo:   repo,
-		apiURL: apiURL,
-		log:    log,
+		repo:            repo,
+		metadata:        metadata,
+		refreshThrottle: &throttle{interval: DefaultRefreshInterval},
+		log:             log,
 	}
 }
 
+// AddSong fetches the details of a song from the metadata providers and saves it.
+// A *DuplicateSongError with the saved song is returned if the library
+// already has the song.
 func (s *MusicService) AddSong(ctx context.Context, req *dto.AddSongReq) (*model.Song, error) {
 	s.log.Infof("AddSong service: adding song - %s group - %s", req.Song, req.Group)
 
-	songDetails, err := s.fetchSongFromAPI(req.Group, req.Song)
+	req.Song, req.Group = translit.Normalize(req.Song), translit.Normalize(req.Group)
+
+	existing, err := s.repo.FindSong(ctx, req.Group, req.Song)
+	if err == nil {
+		return nil, &DuplicateSongError{Song: existing}
+	}
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		return nil, err
+	}
+
+	songDetails, err := s.metadata.Fetch(ctx, req.Group, req.Song)
 	if err != nil {
+		s.log.Errorf("Error fetching song details for group=%s, song=%s: %s", req.Group, req.Song, err)
 		return nil, err
 	}
 
-	s.log.Debugf("AddSong service: fetched from API - %+v", songDetails)
+	s.log.Debugf("AddSong service: fetched song details - %+v", songDetails)
 
 	song := model.Song{
 		Song:        req.Song,
 		Group:       req.Group,
 		ReleaseDate: songDetails.ReleaseDate,
-		Text:        songDetails.Text,
+		Text:        translit.Normalize(songDetails.Text),
 		Link:        songDetails.Link,
 	}
 
-	savedSong, err := s.repo.AddSong(ctx, &song)
-	if err != nil {
-		return nil, err
+	// The upstream link becomes the first media link of the song.
+	var link *model.Link
+	if validation.IsLink(song.Link) {
+		link = &model.Link{
+			Provider: medialink.Detect(song.Link),
+			URL:      song.Link,
+		}
 	}
-	s.log.Infof("AddSong service: song successfully saved with ID - %d", savedSong.ID)
 
-	err = s.repo.AddLyrics(ctx, savedSong.ID, savedSong.Text)
+	// The song, its lyrics and link are stored in one transaction, a failure
+	// doesn't leave a song without lyrics behind. The returned song has the
+	// version after the lyrics are stored, so its ETag works with If-Match.
+	savedSong, err := s.repo.IngestSong(ctx, &song, song.Text, link)
+	if errors.Is(err, repository.ErrDuplicateSong) {
+		// The song was added concurrently.
+		existing, findErr := s.repo.FindSong(ctx, req.Group, req.Song)
+		if findErr != nil {
+			return nil, err
+		}
+		return nil, &DuplicateSongError{Song: existing}
+	}
 	if err != nil {
 		return nil, err
 	}
+	s.log.Infof("AddSong service: song successfully saved with ID - %d", savedSong.ID)
 
 	return savedSong, nil
 }
@@ -82,10 +141,12 @@ func (s *MusicService) GetSongsList(ctx context.Context, req *dto.GetSongsListRe
 	return songs, nil
 }
 
-func (s *MusicService) GetSongLyrics(ctx context.Context, songID, limit, offset int) ([]*model.Verse, error) {
-	s.log.Debugf("GetSongLyrics service: songID=%d, limit=%d, offset=%d", songID, limit, offset)
+// GetSongLyrics returns a page of the lyrics in the language lang, falling
+// back to the original lyrics if the song has no such translation.
+func (s *MusicService) GetSongLyrics(ctx context.Context, songID int, lang string, limit, offset int) ([]*model.Verse, error) {
+	s.log.Debugf("GetSongLyrics service: songID=%d, lang=%q, limit=%d, offset=%d", songID, lang, limit, offset)
 
-	verses, err := s.repo.GetSongLyrics(ctx, songID, limit, offset)
+	verses, err := s.repo.GetSongLyrics(ctx, songID, lang, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +160,8 @@ func (s *MusicService) GetSongLyrics(ctx context.Context, songID, limit, offset
 
 func (s *MusicService) UpdateSong(ctx context.Context, songID int, req *dto.UpdateSongReq) error {
 	s.log.Debugf("UpdateSong service: updating song with id %d with data - %+v", songID, req)
+
+	normalizeUpdate(req)
 	return s.repo.UpdateSong(ctx, songID, req)
 }
 
@@ -106,3 +169,209 @@ func (s *MusicService) DeleteSong(ctx context.Context, songID int) error {
 	s.log.Infof("DeleteSong service: deleting song ID=%d", songID)
 	return s.repo.DeleteSong(ctx, songID)
 }
+
+func (s *MusicService) GetSong(ctx context.Context, songID int) (*model.Song, error) {
+	s.log.Debugf("GetSong service: songID=%d", songID)
+	return s.repo.GetSong(ctx, songID)
+}
+
+func (s *MusicService) GetSongWithLyrics(ctx context.Context, songID int, withLyrics bool) (*model.SongWithLyrics, error) {
+	s.log.Debugf("GetSongWithLyrics service: songID=%d, withLyrics=%t", songID, withLyrics)
+	return s.repo.GetSongWithLyrics(ctx, songID, withLyrics)
+}
+
+// ListSongs returns a page of songs together with the total number of songs
+// matching the filters. Unlike GetSongsList an empty page is not an error.
+func (s *MusicService) ListSongs(ctx context.Context, req *dto.GetSongsListReq) ([]*model.Song, int, error) {
+	s.log.Debugf("ListSongs service: filters - %+v", req)
+
+	total, err := s.repo.CountSongs(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	songs, err := s.repo.GetSongsList(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return songs, total, nil
+}
+
+// ListVerses returns a page of verses together with the number of verses of the song.
+func (s *MusicService) ListVerses(ctx context.Context, songID int, lang string, limit, offset int) ([]*model.Verse, int, error) {
+	s.log.Debugf("ListVerses service: songID=%d, lang=%q, limit=%d, offset=%d", songID, lang, limit, offset)
+
+	song, err := s.repo.GetSongWithLyrics(ctx, songID, false)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	verses, err := s.repo.GetSongLyrics(ctx, songID, lang, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return verses, song.VersesCount, nil
+}
+
+// GetLyricsBySongIDs returns the full lyrics of several songs with one repository call.
+func (s *MusicService) GetLyricsBySongIDs(ctx context.Context, songIDs []int) (map[int][]*model.Verse, error) {
+	s.log.Debugf("GetLyricsBySongIDs service: songIDs=%v", songIDs)
+	return s.repo.GetLyricsBySongIDs(ctx, songIDs)
+}
+
+// GetEvents returns up to limit library changes following the event afterID.
+func (s *MusicService) GetEvents(ctx context.Context, afterID int64, limit int) ([]*model.Event, error) {
+	return s.repo.GetEvents(ctx, afterID, limit)
+}
+
+// LastEventID returns the id of the latest library change, 0 if there are none.
+func (s *MusicService) LastEventID(ctx context.Context) (int64, error) {
+	return s.repo.LastEventID(ctx)
+}
+
+// FindDuplicates returns up to limit pairs of songs which are likely the same
+// song, with a trigram similarity of at least threshold.
+func (s *MusicService) FindDuplicates(ctx context.Context, threshold float64, limit int) ([]*model.DuplicateSongs, error) {
+	s.log.Debugf("FindDuplicates service: threshold=%f, limit=%d", threshold, limit)
+	return s.repo.FindDuplicates(ctx, threshold, limit)
+}
+
+// MergeSongs merges song sourceID into song targetID and deletes it. If
+// version is set, the target must have that version.
+func (s *MusicService) MergeSongs(ctx context.Context, targetID, sourceID int, version *int) (*model.Song, error) {
+	s.log.Infof("MergeSongs service: merging song ID=%d into ID=%d", sourceID, targetID)
+
+	if targetID == sourceID {
+		return nil, ErrSelfMerge
+	}
+
+	return s.repo.MergeSongs(ctx, targetID, sourceID, version)
+}
+
+// TagSong adds a tag or genre to the song, creating it if it's new, and
+// returns the tags of the song.
+func (s *MusicService) TagSong(ctx context.Context, songID int, req *dto.TagSongReq) ([]*model.Tag, error) {
+	s.log.Infof("TagSong service: tagging song ID=%d with %q", songID, req.Name)
+
+	err := s.repo.TagSong(ctx, songID, req.Name, req.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetSongTags(ctx, songID)
+}
+
+// UntagSong removes a tag from the song, the tag itself is kept.
+func (s *MusicService) UntagSong(ctx context.Context, songID int, name string) error {
+	s.log.Infof("UntagSong service: untagging song ID=%d from %q", songID, name)
+
+	_, err := s.repo.GetSong(ctx, songID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.UntagSong(ctx, songID, name)
+}
+
+func (s *MusicService) GetSongTags(ctx context.Context, songID int) ([]*model.Tag, error) {
+	s.log.Debugf("GetSongTags service: songID=%d", songID)
+
+	_, err := s.repo.GetSong(ctx, songID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetSongTags(ctx, songID)
+}
+
+// ListTags returns the tags of the given kind, all tags if kind is empty.
+func (s *MusicService) ListTags(ctx context.Context, kind string) ([]*model.Tag, error) {
+	s.log.Debugf("ListTags service: kind=%q", kind)
+	return s.repo.ListTags(ctx, kind)
+}
+
+// GetFacets counts the songs matching the filters by tag, artist and release
+// year, returning up to limit most common values of each.
+func (s *MusicService) GetFacets(ctx context.Context, req *dto.GetSongsListReq, limit int) (*model.Facets, error) {
+	s.log.Debugf("GetFacets service: filters - %+v, limit=%d", req, limit)
+	return s.repo.GetFacets(ctx, req, limit)
+}
+
+// GetLyricsLanguages returns the language of the original lyrics of the song
+// and the languages it has translations to.
+func (s *MusicService) GetLyricsLanguages(ctx context.Context, songID int) (*model.LyricsLanguages, error) {
+	s.log.Debugf("GetLyricsLanguages service: songID=%d", songID)
+	return s.repo.GetLyricsLanguages(ctx, songID)
+}
+
+// SetTranslation adds or replaces the translation of the lyrics of the song to
+// lang and returns the languages of the song.
+func (s *MusicService) SetTranslation(ctx context.Context, songID int, lang string, req *dto.SetTranslationReq) (*model.LyricsLanguages, error) {
+	s.log.Infof("SetTranslation service: setting %s translation of song ID=%d", lang, songID)
+
+	// Translations usually come from files ending with a newline, which
+	// would end up in the last verse.
+	err := s.repo.SetTranslation(ctx, songID, lang, translit.Normalize(strings.TrimSpace(req.Lyrics)))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetLyricsLanguages(ctx, songID)
+}
+
+func (s *MusicService) DeleteTranslation(ctx context.Context, songID int, lang string) error {
+	s.log.Infof("DeleteTranslation service: deleting %s translation of song ID=%d", lang, songID)
+	return s.repo.DeleteTranslation(ctx, songID, lang)
+}
+
+func (s *MusicService) GetSongLinks(ctx context.Context, songID int) ([]*model.Link, error) {
+	s.log.Debugf("GetSongLinks service: songID=%d", songID)
+
+	_, err := s.repo.GetSong(ctx, songID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetSongLinks(ctx, songID)
+}
+
+// AddSongLink adds a media link to the song. The provider is detected from the
+// host of the link, a provider given in the request must match it unless the
+// host isn't known.
+func (s *MusicService) AddSongLink(ctx context.Context, songID int, req *dto.AddLinkReq) (*model.Link, error) {
+	s.log.Infof("AddSongLink service: adding link %s to song ID=%d", req.URL, songID)
+
+	provider := medialink.Detect(req.URL)
+	if req.Provider != "" {
+		if provider != medialink.Other && req.Provider != provider {
+			return nil, ErrProviderMismatch
+		}
+		provider = req.Provider
+	}
+
+	return s.repo.AddSongLink(ctx, &model.Link{
+		SongID:   songID,
+		Provider: provider,
+		URL:      req.URL,
+	})
+}
+
+func (s *MusicService) DeleteSongLink(ctx context.Context, songID, linkID int) error {
+	s.log.Infof("DeleteSongLink service: deleting link ID=%d of song ID=%d", linkID, songID)
+	return s.repo.DeleteSongLink(ctx, songID, linkID)
+}
+
+// normalizeUpdate brings the new title and artist of a song into the
+// normalization form they are stored in.
+func normalizeUpdate(req *dto.UpdateSongReq) {
+	if req.Song != nil {
+		song := translit.Normalize(*req.Song)
+		req.Song = &song
+	}
+	if req.Group != nil {
+		group := translit.Normalize(*req.Group)
+		req.Group = &group
+	}
+}
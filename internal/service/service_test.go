@@ -0,0 +1,108 @@
+package service_test
+
+import (
+	"context"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/repository/repotest"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/internal/service/servicetest"
+	"github.com/sirupsen/logrus"
+	"io"
+	"testing"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+func TestMemoryAddSong(t *testing.T) {
+	servicetest.RunAddSong(t, func(t *testing.T) repository.IMusicRepository {
+		return repository.NewMemoryMusicRepository(testLogger())
+	})
+}
+
+func TestSQLiteAddSong(t *testing.T) {
+	servicetest.RunAddSong(t, func(t *testing.T) repository.IMusicRepository {
+		return repository.NewSQLiteMusicRepository(repotest.OpenSQLite(t), testLogger())
+	})
+}
+
+// editingProvider returns new lyrics for every song, editing the song first
+// if edit is set, as if it was changed while its details were fetched.
+type editingProvider struct {
+	edit func(ctx context.Context)
+}
+
+func (p *editingProvider) Name() string { return "editing" }
+
+func (p *editingProvider) Fetch(ctx context.Context, group, song string) (*dto.SongDetail, error) {
+	if p.edit != nil {
+		p.edit(ctx)
+	}
+	return &dto.SongDetail{ReleaseDate: "08.09.2009", Text: "Refreshed verse"}, nil
+}
+
+func TestRefreshSong(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryMusicRepository(testLogger())
+	provider := &editingProvider{}
+	registry := metadata.NewRegistry(testLogger())
+	registry.Register(provider, 0)
+	svc := service.NewMusicService(repo, registry, testLogger())
+
+	song, err := repo.IngestSong(ctx, &model.Song{Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009"}, "First verse", nil)
+	if err != nil {
+		t.Fatalf("IngestSong: %s", err)
+	}
+
+	t.Run("VersionMismatch", func(t *testing.T) {
+		// Only the lyrics differ once the release date was edited concurrently.
+		releaseDate := "08.09.2009"
+		provider.edit = func(ctx context.Context) {
+			err := repo.UpdateSong(ctx, song.ID, &dto.UpdateSongReq{ReleaseDate: &releaseDate})
+			if err != nil {
+				t.Errorf("UpdateSong: %s", err)
+			}
+		}
+		defer func() { provider.edit = nil }()
+
+		result, err := svc.RefreshSong(ctx, song.ID, &dto.RefreshSongsReq{})
+		if err != nil {
+			t.Fatalf("RefreshSong: %s", err)
+		}
+		if result.Applied || result.Error != repository.ErrVersionMismatch.Error() {
+			t.Fatalf("RefreshSong of a song edited meanwhile = %+v, want the version mismatch", result)
+		}
+
+		verses, err := repo.GetSongLyrics(ctx, song.ID, "", 10, 0)
+		if err != nil {
+			t.Fatalf("GetSongLyrics: %s", err)
+		}
+		if len(verses) != 1 || verses[0].Lyrics != "First verse" {
+			t.Fatalf("lyrics after a version mismatch = %+v, want them unchanged", verses)
+		}
+	})
+
+	t.Run("Applied", func(t *testing.T) {
+		result, err := svc.RefreshSong(ctx, song.ID, &dto.RefreshSongsReq{})
+		if err != nil {
+			t.Fatalf("RefreshSong: %s", err)
+		}
+		if !result.Applied || result.Error != "" {
+			t.Fatalf("RefreshSong = %+v, want the changes applied", result)
+		}
+
+		verses, err := repo.GetSongLyrics(ctx, song.ID, "", 10, 0)
+		if err != nil {
+			t.Fatalf("GetSongLyrics: %s", err)
+		}
+		if len(verses) != 1 || verses[0].Lyrics != "Refreshed verse" {
+			t.Fatalf("lyrics after the refresh = %+v, want the refreshed verse", verses)
+		}
+	})
+}
@@ -0,0 +1,220 @@
+// Package servicetest runs MusicService.AddSong end to end against the mock
+// upstream API, on any repository.IMusicRepository.
+package servicetest
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/mockupstream"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/repository/repotest"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type env struct {
+	service  *service.MusicService
+	repo     repository.IMusicRepository
+	upstream *mockupstream.TestServer
+}
+
+// newEnv returns a service on the repository with the mock upstream as its
+// metadata provider, behind an in-memory response cache if cached is set.
+func newEnv(t *testing.T, repo repository.IMusicRepository, cached bool) *env {
+	t.Helper()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	upstream := mockupstream.Start(t, nil)
+
+	var provider metadata.Provider = metadata.NewAPIProvider(upstream.URL, &http.Client{Timeout: 5 * time.Second})
+	if cached {
+		// Every cached response is expired, so the upstream is always asked.
+		provider = metadata.NewCachedProvider(provider, repository.NewMemoryUpstreamCacheRepository(log), time.Nanosecond, time.Hour, log)
+	}
+
+	registry := metadata.NewRegistry(log)
+	registry.Register(provider, 0)
+
+	return &env{
+		service:  service.NewMusicService(repo, registry, log),
+		repo:     repo,
+		upstream: upstream,
+	}
+}
+
+func RunAddSong(t *testing.T, newRepo repotest.Factory) {
+	t.Run("AddSong", func(t *testing.T) { testAddSong(t, newEnv(t, newRepo(t), false)) })
+	t.Run("AddSongAcrossScripts", func(t *testing.T) { testAddSongAcrossScripts(t, newEnv(t, newRepo(t), false)) })
+	t.Run("AddSongDuplicate", func(t *testing.T) { testAddSongDuplicate(t, newEnv(t, newRepo(t), false)) })
+	t.Run("AddSongNotFound", func(t *testing.T) { testAddSongNotFound(t, newEnv(t, newRepo(t), false)) })
+	t.Run("AddSongUpstreamError", func(t *testing.T) { testAddSongUpstreamError(t, newEnv(t, newRepo(t), false)) })
+	t.Run("AddSongMalformedJSON", func(t *testing.T) { testAddSongMalformedJSON(t, newEnv(t, newRepo(t), false)) })
+	t.Run("AddSongTimeout", func(t *testing.T) { testAddSongTimeout(t, newEnv(t, newRepo(t), false)) })
+	t.Run("AddSongCacheFallback", func(t *testing.T) { testAddSongCacheFallback(t, newEnv(t, newRepo(t), true)) })
+}
+
+// assertNotAdded fails the test if the library has the song.
+func assertNotAdded(t *testing.T, e *env, group, song string) {
+	t.Helper()
+
+	_, err := e.repo.FindSong(context.Background(), group, song)
+	if !errors.Is(err, repository.ErrSongNotFound) {
+		t.Fatalf("FindSong(%s, %s): want ErrSongNotFound, got %v", group, song, err)
+	}
+}
+
+func testAddSong(t *testing.T, e *env) {
+	ctx := context.Background()
+
+	song, err := e.service.AddSong(ctx, &dto.AddSongReq{Group: "Muse", Song: "Uprising"})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+	if song.ReleaseDate != "07.09.2009" || song.Link != "https://www.youtube.com/watch?v=w8KQmps-Sog" {
+		t.Fatalf("AddSong = %+v, want the details of the fixture", song)
+	}
+
+	saved, err := e.repo.GetSongWithLyrics(ctx, song.ID, true)
+	if err != nil {
+		t.Fatalf("GetSongWithLyrics: %s", err)
+	}
+	if song.Version != saved.Version {
+		t.Fatalf("AddSong version = %d, want the stored version %d", song.Version, saved.Version)
+	}
+	if len(saved.Lyrics) != 2 || saved.Lyrics[0].Lyrics != "Paranoia is in bloom\nThe PR transmissions will resume" {
+		t.Fatalf("lyrics = %+v, want the 2 verses of the fixture", saved.Lyrics)
+	}
+
+	links, err := e.repo.GetSongLinks(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongLinks: %s", err)
+	}
+	if len(links) != 1 || links[0].URL != song.Link || links[0].Provider != "youtube" {
+		t.Fatalf("links = %+v, want the upstream link", links)
+	}
+
+	if requests := e.upstream.Requests(); requests != 1 {
+		t.Fatalf("upstream requests = %d, want 1", requests)
+	}
+}
+
+func testAddSongAcrossScripts(t *testing.T, e *env) {
+	song, err := e.service.AddSong(context.Background(), &dto.AddSongReq{Group: "Kino", Song: "Gruppa krovi"})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+	if song.Group != "Kino" || song.ReleaseDate != "05.01.1988" {
+		t.Fatalf("AddSong = %+v, want the requested title with the details of the fixture", song)
+	}
+}
+
+func testAddSongDuplicate(t *testing.T, e *env) {
+	ctx := context.Background()
+
+	first, err := e.service.AddSong(ctx, &dto.AddSongReq{Group: "Muse", Song: "Uprising"})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+
+	_, err = e.service.AddSong(ctx, &dto.AddSongReq{Group: "muse", Song: " UPRISING "})
+	var duplicate *service.DuplicateSongError
+	if !errors.As(err, &duplicate) || duplicate.Song.ID != first.ID {
+		t.Fatalf("AddSong of a saved song: want DuplicateSongError for id %d, got %v", first.ID, err)
+	}
+
+	// Saved songs aren't looked up again.
+	if requests := e.upstream.Requests(); requests != 1 {
+		t.Fatalf("upstream requests = %d, want 1", requests)
+	}
+}
+
+func testAddSongNotFound(t *testing.T, e *env) {
+	_, err := e.service.AddSong(context.Background(), &dto.AddSongReq{Group: "Muse", Song: "Unknown"})
+	if !errors.Is(err, metadata.ErrNotFound) {
+		t.Fatalf("AddSong of an unknown song: want metadata.ErrNotFound, got %v", err)
+	}
+
+	assertNotAdded(t, e, "Muse", "Unknown")
+}
+
+func testAddSongUpstreamError(t *testing.T, e *env) {
+	e.upstream.SetFaults(mockupstream.Faults{ErrorRate: 1, ErrorStatus: http.StatusServiceUnavailable})
+
+	_, err := e.service.AddSong(context.Background(), &dto.AddSongReq{Group: "Muse", Song: "Uprising"})
+	if err == nil || errors.Is(err, metadata.ErrNotFound) {
+		t.Fatalf("AddSong with the upstream failing: want an upstream error, got %v", err)
+	}
+
+	assertNotAdded(t, e, "Muse", "Uprising")
+}
+
+func testAddSongMalformedJSON(t *testing.T, e *env) {
+	e.upstream.SetFaults(mockupstream.Faults{MalformedRate: 1})
+
+	_, err := e.service.AddSong(context.Background(), &dto.AddSongReq{Group: "Muse", Song: "Uprising"})
+	if err == nil {
+		t.Fatal("AddSong with malformed upstream JSON: want an error")
+	}
+
+	assertNotAdded(t, e, "Muse", "Uprising")
+}
+
+func testAddSongTimeout(t *testing.T, e *env) {
+	e.upstream.SetFaults(mockupstream.Faults{Latency: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := e.service.AddSong(ctx, &dto.AddSongReq{Group: "Muse", Song: "Uprising"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AddSong with a slow upstream: want context.DeadlineExceeded, got %v", err)
+	}
+
+	assertNotAdded(t, e, "Muse", "Uprising")
+}
+
+func testAddSongCacheFallback(t *testing.T, e *env) {
+	ctx := context.Background()
+
+	song, err := e.service.AddSong(ctx, &dto.AddSongReq{Group: "Muse", Song: "Uprising"})
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+	_, err = e.service.AddSong(ctx, &dto.AddSongReq{Group: "Muse", Song: "Unknown"})
+	if !errors.Is(err, metadata.ErrNotFound) {
+		t.Fatalf("AddSong of an unknown song: want metadata.ErrNotFound, got %v", err)
+	}
+
+	err = e.service.DeleteSong(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("DeleteSong: %s", err)
+	}
+
+	e.upstream.SetFaults(mockupstream.Faults{ErrorRate: 1})
+
+	readded, err := e.service.AddSong(ctx, &dto.AddSongReq{Group: "Muse", Song: "Uprising"})
+	if err != nil {
+		t.Fatalf("AddSong with the upstream down: want the cached details, got %v", err)
+	}
+	if readded.ReleaseDate != song.ReleaseDate || readded.Link != song.Link {
+		t.Fatalf("AddSong with the upstream down = %+v, want the cached details of %+v", readded, song)
+	}
+
+	// The not found response is cached for an hour, the upstream isn't asked.
+	requests := e.upstream.Requests()
+	_, err = e.service.AddSong(ctx, &dto.AddSongReq{Group: "Muse", Song: "Unknown"})
+	if !errors.Is(err, metadata.ErrNotFound) {
+		t.Fatalf("AddSong of a cached unknown song: want metadata.ErrNotFound, got %v", err)
+	}
+	if e.upstream.Requests() != requests {
+		t.Fatal("AddSong of a cached unknown song asked the upstream")
+	}
+}
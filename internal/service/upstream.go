@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+type IUpstreamCacheService interface {
+	PurgeUpstreamCache(ctx context.Context, filter *model.UpstreamCacheFilter) (*model.UpstreamCachePurge, error)
+}
+
+// UpstreamCacheService manages the cached responses of the metadata providers.
+type UpstreamCacheService struct {
+	repo repository.IUpstreamCacheRepository
+	log  *logrus.Logger
+}
+
+func NewUpstreamCacheService(repo repository.IUpstreamCacheRepository, log *logrus.Logger) *UpstreamCacheService {
+	return &UpstreamCacheService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+// PurgeUpstreamCache deletes the cached responses matching the filter, so the
+// songs are looked up in the providers again.
+func (s *UpstreamCacheService) PurgeUpstreamCache(ctx context.Context, filter *model.UpstreamCacheFilter) (*model.UpstreamCachePurge, error) {
+	s.log.Infof("PurgeUpstreamCache service: purging cached responses - %+v", filter)
+
+	purged, err := s.repo.PurgeUpstreamResponses(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UpstreamCachePurge{Purged: purged}, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+type IWebhookService interface {
+	AddWebhook(ctx context.Context, req *dto.AddWebhookReq) (*model.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID int) error
+	ListDeadLetters(ctx context.Context, limit, offset int) ([]*model.WebhookDelivery, int, error)
+	RetryDelivery(ctx context.Context, deliveryID int64) error
+}
+
+type WebhookService struct {
+	repo repository.IWebhookRepository
+	// allowPrivateTargets skips the check of webhook.CheckTarget.
+	allowPrivateTargets bool
+	log                 *logrus.Logger
+}
+
+func NewWebhookService(repo repository.IWebhookRepository, log *logrus.Logger) *WebhookService {
+	return &WebhookService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+// SetAllowPrivateTargets lets webhooks point to loopback, private and
+// link-local addresses, for development.
+func (s *WebhookService) SetAllowPrivateTargets(allow bool) {
+	s.allowPrivateTargets = allow
+}
+
+// AddWebhook subscribes a URL to catalogue events. The returned webhook is the
+// only place the secret is shown, it's generated if the request has none.
+// URLs resolving to non-public addresses are rejected with webhook.ErrPrivateTarget.
+func (s *WebhookService) AddWebhook(ctx context.Context, req *dto.AddWebhookReq) (*model.Webhook, error) {
+	s.log.Infof("AddWebhook service: adding webhook for %s, events - %v", req.URL, req.Events)
+
+	if !s.allowPrivateTargets {
+		err := webhook.CheckTarget(ctx, req.URL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(key)
+	}
+
+	events := req.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	return s.repo.AddWebhook(ctx, &model.Webhook{
+		URL:    req.URL,
+		Secret: secret,
+		Events: events,
+	})
+}
+
+// ListWebhooks returns all webhooks without their secrets.
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	webhooks, err := s.repo.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, webhookID int) error {
+	s.log.Infof("DeleteWebhook service: deleting webhook %d", webhookID)
+	return s.repo.DeleteWebhook(ctx, webhookID)
+}
+
+// ListDeadLetters returns a page of deliveries which ran out of attempts
+// together with the number of all of them.
+func (s *WebhookService) ListDeadLetters(ctx context.Context, limit, offset int) ([]*model.WebhookDelivery, int, error) {
+	s.log.Debugf("ListDeadLetters service: limit=%d, offset=%d", limit, offset)
+
+	total, err := s.repo.CountDeadLetters(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deliveries, err := s.repo.ListDeadLetters(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// RetryDelivery schedules a dead delivery for another round of attempts.
+func (s *WebhookService) RetryDelivery(ctx context.Context, deliveryID int64) error {
+	s.log.Infof("RetryDelivery service: requeueing delivery %d", deliveryID)
+	return s.repo.RetryDelivery(ctx, deliveryID)
+}
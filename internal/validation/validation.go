@@ -0,0 +1,169 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aaanger/music-library/pkg/medialink"
+	"github.com/go-playground/validator/v10"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ReleaseDateLayouts are the accepted release date formats: the upstream API
+// uses day.month.year, ISO dates are accepted as well.
+var ReleaseDateLayouts = []string{"02.01.2006", "2006-01-02"}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+
+	err := Register(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// Register adds the custom validations and reports fields by their JSON names.
+// It's used for the gin binding engine and for the package validator.
+func Register(v *validator.Validate) error {
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	err := v.RegisterValidation("release_date", func(fl validator.FieldLevel) bool {
+		return IsReleaseDate(fl.Field().String())
+	})
+	if err != nil {
+		return err
+	}
+
+	err = v.RegisterValidation("link", func(fl validator.FieldLevel) bool {
+		return IsLink(fl.Field().String())
+	})
+	if err != nil {
+		return err
+	}
+
+	err = v.RegisterValidation("provider", func(fl validator.FieldLevel) bool {
+		return medialink.IsProvider(fl.Field().String())
+	})
+	if err != nil {
+		return err
+	}
+
+	return v.RegisterValidation("tag", func(fl validator.FieldLevel) bool {
+		return IsTag(fl.Field().String())
+	})
+}
+
+func IsReleaseDate(value string) bool {
+	for _, layout := range ReleaseDateLayouts {
+		_, err := time.Parse(layout, value)
+		if err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLink accepts absolute http and https URLs.
+func IsLink(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// MaxTagLength is the maximum number of characters of a tag name.
+const MaxTagLength = 64
+
+// IsTag accepts names which aren't blank and fit into MaxTagLength characters
+// once their whitespace is collapsed. Tags are addressed by name in URL paths,
+// so slashes aren't allowed.
+func IsTag(value string) bool {
+	name := strings.Join(strings.Fields(value), " ")
+	return name != "" && utf8.RuneCountInString(name) <= MaxTagLength && !strings.Contains(name, "/")
+}
+
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// IsLanguage accepts lower case language codes like en, pt-br or zh-hant:
+// an ISO 639 code optionally followed by a region or script subtag.
+func IsLanguage(value string) bool {
+	return languagePattern.MatchString(value)
+}
+
+// Struct validates obj using its binding tags.
+func Struct(obj any) error {
+	return validate.Struct(obj)
+}
+
+// FieldErrors converts validator errors into per-field errors, ok is false
+// if err doesn't come from the validator.
+func FieldErrors(err error) ([]FieldError, bool) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil, false
+	}
+
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, FieldError{
+			Field:   fieldErr.Field(),
+			Code:    fieldErr.Tag(),
+			Message: message(fieldErr),
+		})
+	}
+
+	return fields, true
+}
+
+func message(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "field is required"
+	case "max":
+		return fmt.Sprintf("must be at most %s characters long", fieldErr.Param())
+	case "min":
+		if fieldErr.Param() == "1" {
+			return "can't be empty"
+		}
+		return fmt.Sprintf("must be at least %s characters long", fieldErr.Param())
+	case "release_date":
+		return fmt.Sprintf("must be a date in one of the formats %s", strings.Join(ReleaseDateLayouts, ", "))
+	case "link":
+		return "must be an absolute http or https URL"
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "tag":
+		return fmt.Sprintf("must be a name of 1 to %d characters without slashes", MaxTagLength)
+	case "provider":
+		return fmt.Sprintf("must be one of %s", strings.Join(medialink.Providers, ", "))
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	default:
+		return fmt.Sprintf("failed on the %s rule", fieldErr.Tag())
+	}
+}
@@ -0,0 +1,202 @@
+// Package webhook delivers the catalogue events recorded in the outbox to
+// the subscribed webhooks.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/aaanger/music-library/internal/model"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Interval between polls of the outbox, 1s by default.
+	Interval time.Duration
+	// BatchSize is the maximum number of events and deliveries handled per poll, 100 by default.
+	BatchSize int
+	// MaxAttempts after which a delivery is moved to the dead letters, 8 by default.
+	MaxAttempts int
+	// MinBackoff is the delay after the first failed attempt, doubled after
+	// every following one up to MaxBackoff. 1s and 1h by default.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Timeout of a single delivery request, 10s by default.
+	Timeout time.Duration
+	// AllowPrivateTargets lets deliveries connect to loopback, private and
+	// link-local addresses, for development.
+	AllowPrivateTargets bool
+}
+
+type Dispatcher struct {
+	repo       repository.IWebhookRepository
+	cfg        Config
+	httpClient *http.Client
+	log        *logrus.Logger
+}
+
+func NewDispatcher(repo repository.IWebhookRepository, cfg Config, log *logrus.Logger) *Dispatcher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &Dispatcher{
+		repo:       repo,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout, Transport: newTransport(cfg.AllowPrivateTargets)},
+		log:        log,
+	}
+}
+
+// Run dispatches events every Interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := d.Dispatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				d.log.Errorf("Webhook dispatcher error: %s", err)
+			}
+		}
+	}
+}
+
+// Dispatch turns new outbox events into deliveries and sends the deliveries
+// which are due, concurrently. Failed deliveries are rescheduled with an
+// exponential backoff.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	_, err := d.repo.EnqueueDeliveries(ctx, d.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	// Deliveries stay claimed for a whole request timeout, so no other
+	// dispatcher sends them while this one waits for the response.
+	deliveries, err := d.repo.ClaimDeliveries(ctx, d.cfg.BatchSize, 2*d.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	err := d.send(ctx, delivery)
+	if err == nil {
+		err = d.repo.MarkDelivered(ctx, delivery.ID)
+		if err != nil {
+			d.log.Errorf("Webhook dispatcher error: %s", err)
+		}
+		return
+	}
+
+	// Shutting down, the delivery is sent again once its claim expires.
+	if ctx.Err() != nil {
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	dead := attempts >= d.cfg.MaxAttempts
+	d.log.Warnf("Webhook delivery %d of event %d to %s failed (attempt %d, dead - %t): %s",
+		delivery.ID, delivery.Event.ID, delivery.Webhook.URL, attempts, dead, err)
+
+	err = d.repo.MarkFailed(ctx, delivery.ID, err.Error(), time.Now().Add(d.backoff(attempts)), dead)
+	if err != nil {
+		d.log.Errorf("Webhook dispatcher error: %s", err)
+	}
+}
+
+// send posts the event to the webhook, any 2xx response is a success.
+func (d *Dispatcher) send(ctx context.Context, delivery *model.WebhookDelivery) error {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "music-library-webhooks")
+	req.Header.Set("X-Webhook-ID", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Event", delivery.Event.Type)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(delivery.Webhook.Secret, timestamp, body))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt after the given number of failed ones.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.MinBackoff
+	for i := 1; i < attempts && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.cfg.MaxBackoff)
+}
+
+// Sign returns the X-Webhook-Signature header of a payload: the hex encoded
+// HMAC-SHA256 of "timestamp.body" keyed with the webhook secret. Receivers
+// should recompute it and reject requests with an old timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
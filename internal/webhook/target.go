@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateTarget is returned for webhook URLs which resolve to loopback,
+// private, link-local or other non-public addresses, subscribers could reach
+// the internal network of the service through them otherwise.
+var ErrPrivateTarget = errors.New("webhook url must resolve to public addresses")
+
+// sharedAddressSpace is the carrier-grade NAT range, netip doesn't count it as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublic reports whether webhooks may be delivered to addr.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// CheckTarget resolves the host of a webhook URL and returns an error
+// matching ErrPrivateTarget unless all of its addresses are public.
+func CheckTarget(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, err)
+	}
+
+	host := u.Hostname()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, err)
+	}
+
+	for _, addr := range addrs {
+		if !isPublic(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrPrivateTarget, host, addr)
+		}
+	}
+
+	return nil
+}
+
+// publicOnly refuses connections to non-public addresses, so hosts which
+// resolve differently after the check of CheckTarget, and redirects, can't
+// point deliveries to the internal network.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublic(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, address)
+	}
+	return nil
+}
+
+// newTransport returns the transport of the deliveries. Proxies from the
+// environment aren't used, the dialed address must be the webhook itself.
+func newTransport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if !allowPrivate {
+		dialer.Control = publicOnly
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
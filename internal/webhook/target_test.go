@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckTarget(t *testing.T) {
+	private := []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://10.0.0.5/hook",
+		"http://172.16.3.4/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[fd00::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+	}
+	for _, target := range private {
+		err := CheckTarget(context.Background(), target)
+		if !errors.Is(err, ErrPrivateTarget) {
+			t.Errorf("CheckTarget(%s): want ErrPrivateTarget, got %v", target, err)
+		}
+	}
+
+	public := []string{
+		"https://93.184.215.14/hook",
+		"https://[2606:4700::1111]/hook",
+	}
+	for _, target := range public {
+		err := CheckTarget(context.Background(), target)
+		if err != nil {
+			t.Errorf("CheckTarget(%s): %s", target, err)
+		}
+	}
+}
+
+func TestTransportRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newTransport(false)}
+	_, err := client.Get(srv.URL)
+	if !errors.Is(err, ErrPrivateTarget) {
+		t.Fatalf("delivery to %s: want ErrPrivateTarget, got %v", srv.URL, err)
+	}
+
+	client = &http.Client{Transport: newTransport(true)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("delivery to %s with private targets allowed: %s", srv.URL, err)
+	}
+	resp.Body.Close()
+}
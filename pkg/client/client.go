@@ -0,0 +1,159 @@
+// Package client is a typed Go client for the music library HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v2"
+
+// Client calls the /api/v2 endpoints of the music library.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	retry      RetryPolicy
+}
+
+type Option func(*Client)
+
+// WithAPIKey sends key in the X-API-Key header of every request.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient replaces the default http.Client, which has a 30 second timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New returns a client for the service running at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retry:      DefaultRetryPolicy,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// envelope is the body of successful /api/v2 responses.
+type envelope struct {
+	Data       json.RawMessage `json:"data"`
+	Pagination *Pagination     `json:"pagination"`
+}
+
+// do sends a JSON request to an /api/v2 endpoint and decodes the envelope of
+// the response, data may be nil if the caller doesn't need it.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body, data any) (*Pagination, error) {
+	var encoded []byte
+	if body != nil {
+		var err error
+		encoded, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if header == nil {
+			header = http.Header{}
+		}
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "application/json")
+		}
+	}
+
+	resp, err := c.send(ctx, method, apiPrefix+path, query, header, encoded)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent || data == nil {
+		return nil, nil
+	}
+
+	var env envelope
+	err = json.NewDecoder(resp.Body).Decode(&env)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+
+	err = json.Unmarshal(env.Data, data)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+
+	return env.Pagination, nil
+}
+
+// send performs the request, retrying it according to the retry policy.
+// Responses with an error status are returned as *Error, otherwise the
+// caller must close the response body.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		req.Header.Set("Accept", "application/json")
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+
+		if err == nil {
+			apiErr := newError(resp)
+			resp.Body.Close()
+			err = apiErr
+		}
+
+		wait, retry := c.retry.next(attempt, method, resp, err)
+		if !retry {
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
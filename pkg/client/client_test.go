@@ -0,0 +1,358 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aaanger/music-library/pkg/client"
+	"github.com/aaanger/music-library/pkg/client/clienttest"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRetryPolicy retries quickly so the tests don't wait for the backoff.
+var testRetryPolicy = client.RetryPolicy{
+	MaxRetries: 3,
+	MinBackoff: time.Millisecond,
+	MaxBackoff: 10 * time.Millisecond,
+}
+
+// proxy forwards requests to the service, failing the next failures of them
+// with status instead.
+type proxy struct {
+	URL string
+
+	mu       sync.Mutex
+	status   int
+	failures int
+	requests int
+}
+
+func newProxy(t *testing.T, target string) *proxy {
+	t.Helper()
+
+	u, err := url.Parse(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forward := httputil.NewSingleHostReverseProxy(u)
+
+	p := &proxy{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		p.requests++
+		fail := p.failures != 0
+		if p.failures > 0 {
+			p.failures--
+		}
+		status := p.status
+		p.mu.Unlock()
+
+		if fail {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			http.Error(w, `{"error": "`+http.StatusText(status)+`"}`, status)
+			return
+		}
+		forward.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	p.URL = srv.URL
+	return p
+}
+
+// fail makes the next n requests fail with status, or all of them if n is negative.
+func (p *proxy) fail(status, n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.status = status
+	p.failures = n
+	p.requests = 0
+}
+
+func (p *proxy) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.requests
+}
+
+// newTestClient returns a client calling the service through a proxy.
+func newTestClient(t *testing.T, policy client.RetryPolicy) (*client.Client, *proxy) {
+	t.Helper()
+
+	srv := clienttest.NewServer(t)
+	p := newProxy(t, srv.URL)
+
+	return client.New(p.URL, client.WithRetryPolicy(policy)), p
+}
+
+func TestRetry(t *testing.T) {
+	statuses := []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+
+	for _, status := range statuses {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			ctx := context.Background()
+			c, p := newTestClient(t, testRetryPolicy)
+
+			added, err := c.AddSong(ctx, "Muse", "Uprising")
+			if err != nil {
+				t.Fatalf("AddSong: %s", err)
+			}
+
+			p.fail(status, 2)
+			song, err := c.GetSong(ctx, added.ID)
+			if err != nil {
+				t.Fatalf("GetSong after 2 failures: %s", err)
+			}
+			if song.ID != added.ID {
+				t.Fatalf("GetSong = %+v, want song %d", song, added.ID)
+			}
+			if requests := p.count(); requests != 3 {
+				t.Fatalf("requests = %d, want 3", requests)
+			}
+		})
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	c, p := newTestClient(t, testRetryPolicy)
+
+	p.fail(http.StatusServiceUnavailable, -1)
+	_, err := c.GetSong(context.Background(), 1)
+	if !errors.Is(err, client.ErrServer) {
+		t.Fatalf("GetSong: want ErrServer, got %v", err)
+	}
+	if requests := p.count(); requests != testRetryPolicy.MaxRetries+1 {
+		t.Fatalf("requests = %d, want %d", requests, testRetryPolicy.MaxRetries+1)
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("NotIdempotent", func(t *testing.T) {
+		c, p := newTestClient(t, testRetryPolicy)
+
+		p.fail(http.StatusServiceUnavailable, -1)
+		_, err := c.AddSong(ctx, "Muse", "Uprising")
+		if !errors.Is(err, client.ErrServer) {
+			t.Fatalf("AddSong: want ErrServer, got %v", err)
+		}
+		if requests := p.count(); requests != 1 {
+			t.Fatalf("requests = %d, want 1", requests)
+		}
+	})
+
+	t.Run("InternalServerError", func(t *testing.T) {
+		c, p := newTestClient(t, testRetryPolicy)
+
+		p.fail(http.StatusInternalServerError, -1)
+		_, err := c.GetSong(ctx, 1)
+		if !errors.Is(err, client.ErrServer) {
+			t.Fatalf("GetSong: want ErrServer, got %v", err)
+		}
+		if requests := p.count(); requests != 1 {
+			t.Fatalf("requests = %d, want 1", requests)
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		c, p := newTestClient(t, client.RetryPolicy{MaxRetries: 3, MinBackoff: time.Hour, MaxBackoff: time.Hour})
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		p.fail(http.StatusBadGateway, -1)
+		_, err := c.GetSong(ctx, 1)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("GetSong: want context.DeadlineExceeded while waiting to retry, got %v", err)
+		}
+	})
+}
+
+func TestSongsIterator(t *testing.T) {
+	ctx := context.Background()
+	c, p := newTestClient(t, client.RetryPolicy{})
+
+	titles := []string{"Uprising", "Hysteria", "Starlight", "Resistance", "Madness"}
+	for _, title := range titles {
+		_, err := c.AddSong(ctx, "Muse", title)
+		if err != nil {
+			t.Fatalf("AddSong(%s): %s", title, err)
+		}
+	}
+
+	p.fail(0, 0)
+	seen := map[int]bool{}
+	for song, err := range c.Songs(ctx, &client.ListSongsParams{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("Songs: %s", err)
+		}
+		if seen[song.ID] {
+			t.Fatalf("Songs yielded song %d twice", song.ID)
+		}
+		seen[song.ID] = true
+	}
+	if len(seen) != len(titles) {
+		t.Fatalf("Songs yielded %d songs, want %d", len(seen), len(titles))
+	}
+	if requests := p.count(); requests != 3 {
+		t.Fatalf("requests = %d, want 3 pages", requests)
+	}
+
+	// Breaking out of the loop doesn't request the following pages.
+	p.fail(0, 0)
+	for _, err := range c.Songs(ctx, &client.ListSongsParams{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("Songs: %s", err)
+		}
+		break
+	}
+	if requests := p.count(); requests != 1 {
+		t.Fatalf("requests after break = %d, want 1", requests)
+	}
+}
+
+func TestVersesIterator(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, client.RetryPolicy{})
+
+	song, err := c.AddSong(ctx, "Muse", "Uprising")
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+
+	want := []string{"First verse", "Second verse", "Third verse"}
+	var got []string
+	for verse, err := range c.Verses(ctx, song.ID, 2) {
+		if err != nil {
+			t.Fatalf("Verses: %s", err)
+		}
+		got = append(got, verse.Lyrics)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Verses = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Verses = %q, want %q", got, want)
+		}
+	}
+
+	var errs int
+	for verse, err := range c.Verses(ctx, song.ID+1, 2) {
+		if !errors.Is(err, client.ErrNotFound) || verse != nil {
+			t.Fatalf("Verses of an unknown song: want ErrNotFound, got %v, %v", verse, err)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Fatalf("Verses of an unknown song yielded %d errors, want 1", errs)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	c, p := newTestClient(t, client.RetryPolicy{})
+
+	song, err := c.AddSong(ctx, "Muse", "Uprising")
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := c.GetSong(ctx, song.ID+1)
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("GetSong of an unknown song: want *Error with status 404, got %v", err)
+		}
+		if !errors.Is(err, client.ErrNotFound) {
+			t.Fatalf("GetSong of an unknown song: want ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		_, err := c.AddSong(ctx, "Muse", "Uprising")
+		var apiErr *client.Error
+		if !errors.Is(err, client.ErrConflict) || !errors.As(err, &apiErr) {
+			t.Fatalf("AddSong of a saved song: want ErrConflict, got %v", err)
+		}
+		if apiErr.Existing == nil || apiErr.Existing.ID != song.ID {
+			t.Fatalf("AddSong of a saved song: Existing = %+v, want song %d", apiErr.Existing, song.ID)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := c.AddSong(ctx, "", "Uprising")
+		var apiErr *client.Error
+		if !errors.Is(err, client.ErrValidation) || !errors.As(err, &apiErr) {
+			t.Fatalf("AddSong without a group: want ErrValidation, got %v", err)
+		}
+		if len(apiErr.Fields) == 0 {
+			t.Fatal("AddSong without a group: want the rejected fields")
+		}
+	})
+
+	t.Run("VersionMismatch", func(t *testing.T) {
+		stale := song.Version - 1
+		_, err := c.PatchSong(ctx, song.ID, client.MergePatchContentType, []byte(`{"link": "https://example.com/new"}`), &stale)
+		if !errors.Is(err, client.ErrVersionMismatch) {
+			t.Fatalf("PatchSong with a stale version: want ErrVersionMismatch, got %v", err)
+		}
+	})
+
+	t.Run("BadRequest", func(t *testing.T) {
+		_, err := c.PatchSong(ctx, song.ID, client.JSONPatchContentType, []byte(`[{"op": "explode", "path": "/song"}]`), nil)
+		if !errors.Is(err, client.ErrBadRequest) {
+			t.Fatalf("PatchSong with an invalid operation: want ErrBadRequest, got %v", err)
+		}
+	})
+
+	t.Run("RateLimited", func(t *testing.T) {
+		p.fail(http.StatusTooManyRequests, 1)
+		_, err := c.GetSong(ctx, song.ID)
+		if !errors.Is(err, client.ErrRateLimited) {
+			t.Fatalf("GetSong: want ErrRateLimited, got %v", err)
+		}
+	})
+}
+
+func TestPatchSong(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, client.RetryPolicy{})
+
+	song, err := c.AddSong(ctx, "Muse", "Uprising")
+	if err != nil {
+		t.Fatalf("AddSong: %s", err)
+	}
+
+	patched, err := c.PatchSong(ctx, song.ID, client.MergePatchContentType, []byte(`{"release_date": "01.01.2010"}`), &song.Version)
+	if err != nil {
+		t.Fatalf("PatchSong with a merge patch: %s", err)
+	}
+	if patched.ReleaseDate != "01.01.2010" || patched.Version <= song.Version {
+		t.Fatalf("PatchSong with a merge patch = %+v, want the new release date and version", patched)
+	}
+
+	patched, err = c.PatchSong(ctx, song.ID, client.JSONPatchContentType, []byte(`[{"op": "replace", "path": "/song", "value": "Resistance"}]`), &patched.Version)
+	if err != nil {
+		t.Fatalf("PatchSong with a JSON patch: %s", err)
+	}
+	if patched.Song != "Resistance" || patched.ReleaseDate != "01.01.2010" {
+		t.Fatalf("PatchSong with a JSON patch = %+v, want the new title", patched)
+	}
+}
@@ -0,0 +1,76 @@
+// Package clienttest runs the real HTTP handlers of the service on an
+// in-memory repository, so code built on pkg/client can be tested end to end.
+package clienttest
+
+import (
+	"encoding/json"
+	"github.com/aaanger/music-library/internal/dto"
+	"github.com/aaanger/music-library/internal/handler"
+	"github.com/aaanger/music-library/internal/metadata"
+	"github.com/aaanger/music-library/internal/repository"
+	"github.com/aaanger/music-library/internal/service"
+	"github.com/aaanger/music-library/pkg/client"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// Lyrics is the text the fake upstream API returns for every song.
+const Lyrics = "First verse\n\nSecond verse\n\nThird verse"
+
+type Server struct {
+	// URL is the base URL of the service, to be passed to client.New.
+	URL string
+	// Repo is the repository behind the service, for seeding and assertions.
+	Repo *repository.MemoryMusicRepository
+}
+
+// NewServer starts the service handlers returned by InitRoutes together with
+// a fake upstream music API, both are closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	upstream := httptest.NewServer(http.HandlerFunc(songInfo))
+	t.Cleanup(upstream.Close)
+
+	repo := repository.NewMemoryMusicRepository(log)
+	registry := metadata.NewRegistry(log)
+	registry.Register(metadata.NewAPIProvider(upstream.URL, nil), 0)
+	musicService := service.NewMusicService(repo, registry, log)
+
+	srv := httptest.NewServer(handler.NewMusicHandler(musicService, log).InitRoutes())
+	t.Cleanup(srv.Close)
+
+	return &Server{
+		URL:  srv.URL,
+		Repo: repo,
+	}
+}
+
+// NewClient returns a client for the server. Retries are disabled unless
+// opts set a retry policy.
+func (s *Server) NewClient(opts ...client.Option) *client.Client {
+	opts = append([]client.Option{client.WithRetryPolicy(client.RetryPolicy{})}, opts...)
+	return client.New(s.URL, opts...)
+}
+
+// songInfo answers GET /info like the upstream API, with fixed details.
+func songInfo(w http.ResponseWriter, r *http.Request) {
+	detail := dto.SongDetail{
+		ReleaseDate: "16.07.2006",
+		Text:        Lyrics,
+		Link:        "https://example.com/" + url.PathEscape(r.URL.Query().Get("group")) + "/" + url.PathEscape(r.URL.Query().Get("song")),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(detail)
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Errors matching *Error responses with errors.Is.
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrNotFound        = errors.New("song not found")
+	ErrVersionMismatch = errors.New("song was modified")
+	ErrConflict        = errors.New("song already exists")
+	ErrValidation      = errors.New("validation failed")
+	ErrRateLimited     = errors.New("rate limited")
+	ErrServer          = errors.New("server error")
+)
+
+// FieldError describes a field rejected by the service validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error response of the service.
+type Error struct {
+	StatusCode int
+	Message    string
+	Fields     []FieldError
+	// Existing is the song a new song duplicates, set for ErrConflict
+	// responses of AddSong.
+	Existing *Song
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("music library: %d %s", e.StatusCode, e.Message)
+	for _, field := range e.Fields {
+		msg += fmt.Sprintf("; %s: %s", field.Field, field.Message)
+	}
+	return msg
+}
+
+// Is reports whether the status code of the response corresponds to target.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrVersionMismatch:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrValidation:
+		return e.StatusCode == http.StatusUnprocessableEntity
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+func newError(resp *http.Response) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+	}
+
+	var body struct {
+		Error    string       `json:"error"`
+		Fields   []FieldError `json:"fields"`
+		Existing *Song        `json:"existing"`
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err == nil && json.Unmarshal(data, &body) == nil && body.Error != "" {
+		apiErr.Message = body.Error
+		apiErr.Fields = body.Fields
+		apiErr.Existing = body.Existing
+	}
+
+	return apiErr
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"iter"
+)
+
+// defaultIterPageSize is the page size used by the iterators if none is given.
+const defaultIterPageSize = 100
+
+// Songs iterates over all songs matching params, requesting the following
+// pages as needed. params.Page is the first page to read. Iteration stops
+// after the first error, which is yielded with a nil song.
+func (c *Client) Songs(ctx context.Context, params *ListSongsParams) iter.Seq2[*Song, error] {
+	p := ListSongsParams{}
+	if params != nil {
+		p = *params
+	}
+	if p.Limit <= 0 {
+		p.Limit = defaultIterPageSize
+	}
+	if p.Page <= 0 {
+		p.Page = 1
+	}
+
+	return func(yield func(*Song, error) bool) {
+		for {
+			page, err := c.ListSongs(ctx, &p)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, song := range page.Songs {
+				if !yield(song, nil) {
+					return
+				}
+			}
+
+			if len(page.Songs) < p.Limit || p.Page >= page.Pagination.Pages {
+				return
+			}
+			p.Page++
+		}
+	}
+}
+
+// Verses iterates over all verses of a song, reading pageSize verses per request.
+// A pageSize of 0 uses the default of 100.
+func (c *Client) Verses(ctx context.Context, songID, pageSize int) iter.Seq2[*Verse, error] {
+	return c.TranslatedVerses(ctx, songID, "", pageSize)
+}
+
+// TranslatedVerses is like Verses for the translation of a song to lang,
+// falling back to the original lyrics like ListTranslatedVerses.
+func (c *Client) TranslatedVerses(ctx context.Context, songID int, lang string, pageSize int) iter.Seq2[*Verse, error] {
+	if pageSize <= 0 {
+		pageSize = defaultIterPageSize
+	}
+
+	return func(yield func(*Verse, error) bool) {
+		for page := 1; ; page++ {
+			verses, err := c.ListTranslatedVerses(ctx, songID, lang, pageSize, page)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, verse := range verses.Verses {
+				if !yield(verse, nil) {
+					return
+				}
+			}
+
+			if len(verses.Verses) < pageSize || page >= verses.Pagination.Pages {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+func (c *Client) SongLinks(ctx context.Context, songID int) ([]*Link, error) {
+	var links []*Link
+	_, err := c.do(ctx, http.MethodGet, songPath(songID)+"/links", nil, nil, nil, &links)
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// AddLink adds a media link to a song. The provider is detected by the
+// service from the host of the link if it's empty, a given provider must
+// match the detected one unless the host isn't known.
+func (c *Client) AddLink(ctx context.Context, songID int, linkURL, provider string) (*Link, error) {
+	req := struct {
+		URL      string `json:"url"`
+		Provider string `json:"provider,omitempty"`
+	}{
+		URL:      linkURL,
+		Provider: provider,
+	}
+
+	var link Link
+	_, err := c.do(ctx, http.MethodPost, songPath(songID)+"/links", nil, nil, req, &link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+func (c *Client) DeleteLink(ctx context.Context, songID, linkID int) error {
+	_, err := c.do(ctx, http.MethodDelete, songPath(songID)+"/links/"+strconv.Itoa(linkID), nil, nil, nil, nil)
+	return err
+}
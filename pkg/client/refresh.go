@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// RefreshSong fetches the details of a song from the metadata providers of
+// the service again and returns the changed fields, applied unless
+// opts.DryRun is set.
+func (c *Client) RefreshSong(ctx context.Context, songID int, opts RefreshOptions) (*SongRefresh, error) {
+	var result SongRefresh
+	_, err := c.do(ctx, http.MethodPost, songPath(songID)+"/refresh", nil, nil, opts, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// RefreshSongs refreshes a page of the songs matching the filters of params,
+// the service refreshes at most 20 songs per request. Songs whose details
+// couldn't be fetched have Error set.
+func (c *Client) RefreshSongs(ctx context.Context, params *ListSongsParams, opts RefreshOptions) (*RefreshPage, error) {
+	query := songsQuery(params)
+	if params != nil {
+		setPage(query, params.Limit, params.Page)
+	}
+
+	var page RefreshPage
+	pagination, err := c.do(ctx, http.MethodPost, "/songs/refresh", query, nil, opts, &page.Results)
+	if err != nil {
+		return nil, err
+	}
+	if pagination != nil {
+		page.Pagination = *pagination
+	}
+
+	return &page, nil
+}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// AddSong adds a song, the service fetches its details and lyrics from the
+// upstream music API. If the library already has the song, the error matches
+// ErrConflict and its Existing field holds the song.
+func (c *Client) AddSong(ctx context.Context, group, song string) (*Song, error) {
+	req := struct {
+		Group string `json:"group"`
+		Song  string `json:"song"`
+	}{
+		Group: group,
+		Song:  song,
+	}
+
+	var added Song
+	_, err := c.do(ctx, http.MethodPost, "/songs", nil, nil, req, &added)
+	if err != nil {
+		return nil, err
+	}
+
+	return &added, nil
+}
+
+func (c *Client) ListSongs(ctx context.Context, params *ListSongsParams) (*SongsPage, error) {
+	query := songsQuery(params)
+	if params != nil {
+		setPage(query, params.Limit, params.Page)
+	}
+
+	var page SongsPage
+	pagination, err := c.do(ctx, http.MethodGet, "/songs", query, nil, nil, &page.Songs)
+	if err != nil {
+		return nil, err
+	}
+	if pagination != nil {
+		page.Pagination = *pagination
+	}
+
+	return &page, nil
+}
+
+func (c *Client) GetSong(ctx context.Context, songID int) (*Song, error) {
+	var song Song
+	_, err := c.do(ctx, http.MethodGet, songPath(songID), nil, nil, nil, &song)
+	if err != nil {
+		return nil, err
+	}
+
+	return &song, nil
+}
+
+// GetSongWithLyrics returns a song with the number of its verses, and the
+// verses themselves if withLyrics is set.
+func (c *Client) GetSongWithLyrics(ctx context.Context, songID int, withLyrics bool) (*SongWithLyrics, error) {
+	query := url.Values{}
+	if withLyrics {
+		query.Set("include", "lyrics")
+	}
+
+	var song SongWithLyrics
+	_, err := c.do(ctx, http.MethodGet, songPath(songID), query, nil, nil, &song)
+	if err != nil {
+		return nil, err
+	}
+
+	return &song, nil
+}
+
+// ListVerses returns a page of verses of a song. Zero limit and page use the service defaults.
+func (c *Client) ListVerses(ctx context.Context, songID, limit, page int) (*VersesPage, error) {
+	return c.ListTranslatedVerses(ctx, songID, "", limit, page)
+}
+
+// ListTranslatedVerses returns a page of verses of the translation of a song
+// to lang, or of the original lyrics if there is no such translation. The
+// Language of the verses tells which one was returned.
+func (c *Client) ListTranslatedVerses(ctx context.Context, songID int, lang string, limit, page int) (*VersesPage, error) {
+	query := url.Values{}
+	if lang != "" {
+		query.Set("lang", lang)
+	}
+	setPage(query, limit, page)
+
+	var verses VersesPage
+	pagination, err := c.do(ctx, http.MethodGet, songPath(songID)+"/verses", query, nil, nil, &verses.Verses)
+	if err != nil {
+		return nil, err
+	}
+	if pagination != nil {
+		verses.Pagination = *pagination
+	}
+
+	return &verses, nil
+}
+
+// UpdateSong changes the given fields of a song. If version is not nil the
+// update is applied only if the song still has that version.
+func (c *Client) UpdateSong(ctx context.Context, songID int, update *SongUpdate, version *int) (*Song, error) {
+	header := http.Header{}
+	if version != nil {
+		header.Set("If-Match", fmt.Sprintf(`"%d-%d"`, songID, *version))
+	}
+
+	var song Song
+	_, err := c.do(ctx, http.MethodPatch, songPath(songID), nil, header, update, &song)
+	if err != nil {
+		return nil, err
+	}
+
+	return &song, nil
+}
+
+// PatchSong applies a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) to
+// the {"song", "group", "release_date", "link"} document of a song, contentType
+// is MergePatchContentType or JSONPatchContentType. If version is not nil the
+// patch is applied only if the song still has that version.
+func (c *Client) PatchSong(ctx context.Context, songID int, contentType string, patch []byte, version *int) (*Song, error) {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	if version != nil {
+		header.Set("If-Match", fmt.Sprintf(`"%d-%d"`, songID, *version))
+	}
+
+	var song Song
+	_, err := c.do(ctx, http.MethodPatch, songPath(songID), nil, header, json.RawMessage(patch), &song)
+	if err != nil {
+		return nil, err
+	}
+
+	return &song, nil
+}
+
+// FindDuplicates returns up to limit pairs of songs with a trigram similarity
+// of at least threshold, the most similar first. Zero values use the service defaults.
+func (c *Client) FindDuplicates(ctx context.Context, threshold float64, limit int) ([]*DuplicateSongs, error) {
+	query := url.Values{}
+	if threshold > 0 {
+		query.Set("threshold", strconv.FormatFloat(threshold, 'f', -1, 64))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var duplicates []*DuplicateSongs
+	_, err := c.do(ctx, http.MethodGet, "/songs/duplicates", query, nil, nil, &duplicates)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicates, nil
+}
+
+// MergeSongs merges song sourceID into song targetID and deletes it. If
+// version is not nil the merge is applied only if the target still has that version.
+func (c *Client) MergeSongs(ctx context.Context, targetID, sourceID int, version *int) (*Song, error) {
+	header := http.Header{}
+	if version != nil {
+		header.Set("If-Match", fmt.Sprintf(`"%d-%d"`, targetID, *version))
+	}
+
+	req := struct {
+		SourceID int `json:"source_id"`
+	}{
+		SourceID: sourceID,
+	}
+
+	var song Song
+	_, err := c.do(ctx, http.MethodPost, songPath(targetID)+"/merge", nil, header, req, &song)
+	if err != nil {
+		return nil, err
+	}
+
+	return &song, nil
+}
+
+func (c *Client) DeleteSong(ctx context.Context, songID int) error {
+	_, err := c.do(ctx, http.MethodDelete, songPath(songID), nil, nil, nil, nil)
+	return err
+}
+
+func songPath(songID int) string {
+	return "/songs/" + strconv.Itoa(songID)
+}
+
+// songsQuery returns the filter parameters of params.
+func songsQuery(params *ListSongsParams) url.Values {
+	query := url.Values{}
+	if params == nil {
+		return query
+	}
+
+	if params.Song != nil {
+		query.Set("song", *params.Song)
+	}
+	if params.Group != nil {
+		query.Set("group", *params.Group)
+	}
+	if params.ReleaseDate != nil {
+		query.Set("release_date", *params.ReleaseDate)
+	}
+	for _, tag := range params.Tags {
+		query.Add("tag", tag)
+	}
+	if params.TagMode != "" {
+		query.Set("tag_mode", params.TagMode)
+	}
+
+	return query
+}
+
+func setPage(query url.Values, limit, page int) {
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+}
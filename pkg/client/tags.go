@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListTags returns the tags of the given kind, all tags if kind is empty.
+func (c *Client) ListTags(ctx context.Context, kind string) ([]*Tag, error) {
+	query := url.Values{}
+	if kind != "" {
+		query.Set("kind", kind)
+	}
+
+	var tags []*Tag
+	_, err := c.do(ctx, http.MethodGet, "/tags", query, nil, nil, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (c *Client) SongTags(ctx context.Context, songID int) ([]*Tag, error) {
+	var tags []*Tag
+	_, err := c.do(ctx, http.MethodGet, songPath(songID)+"/tags", nil, nil, nil, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// TagSong adds a tag to a song and returns the tags of the song. A new tag is
+// created with the given kind, empty kind being TagKindTag, an existing tag
+// changes its kind unless kind is empty.
+func (c *Client) TagSong(ctx context.Context, songID int, name, kind string) ([]*Tag, error) {
+	req := struct {
+		Name string `json:"name"`
+		Kind string `json:"kind,omitempty"`
+	}{
+		Name: name,
+		Kind: kind,
+	}
+
+	var tags []*Tag
+	_, err := c.do(ctx, http.MethodPost, songPath(songID)+"/tags", nil, nil, req, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (c *Client) UntagSong(ctx context.Context, songID int, name string) error {
+	_, err := c.do(ctx, http.MethodDelete, songPath(songID)+"/tags/"+url.PathEscape(name), nil, nil, nil, nil)
+	return err
+}
+
+// GetFacets counts the songs matching the filters of params by tag, artist
+// and release year, up to limit values each. The pagination of params is
+// ignored, zero limit uses the service default.
+func (c *Client) GetFacets(ctx context.Context, params *ListSongsParams, limit int) (*Facets, error) {
+	query := songsQuery(params)
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var facets Facets
+	_, err := c.do(ctx, http.MethodGet, "/songs/facets", query, nil, nil, &facets)
+	if err != nil {
+		return nil, err
+	}
+
+	return &facets, nil
+}
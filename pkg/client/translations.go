@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) LyricsLanguages(ctx context.Context, songID int) (*LyricsLanguages, error) {
+	var languages LyricsLanguages
+	_, err := c.do(ctx, http.MethodGet, songPath(songID)+"/translations", nil, nil, nil, &languages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &languages, nil
+}
+
+// SetTranslation adds or replaces the translation of the lyrics of a song to
+// lang and returns the languages of the song. The translation is split into
+// verses by blank lines and must have as many verses as the original lyrics.
+func (c *Client) SetTranslation(ctx context.Context, songID int, lang, lyrics string) (*LyricsLanguages, error) {
+	req := struct {
+		Lyrics string `json:"lyrics"`
+	}{
+		Lyrics: lyrics,
+	}
+
+	var languages LyricsLanguages
+	_, err := c.do(ctx, http.MethodPut, songPath(songID)+"/translations/"+url.PathEscape(lang), nil, nil, req, &languages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &languages, nil
+}
+
+func (c *Client) DeleteTranslation(ctx context.Context, songID int, lang string) error {
+	_, err := c.do(ctx, http.MethodDelete, songPath(songID)+"/translations/"+url.PathEscape(lang), nil, nil, nil, nil)
+	return err
+}
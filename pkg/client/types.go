@@ -0,0 +1,167 @@
+package client
+
+import (
+	"time"
+)
+
+type Song struct {
+	ID          int       `json:"id"`
+	Song        string    `json:"song"`
+	Group       string    `json:"group"`
+	ReleaseDate string    `json:"release_date"`
+	Link        string    `json:"link"`
+	Version     int       `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Verse struct {
+	Number   int    `json:"number"`
+	Lyrics   string `json:"lyrics"`
+	Language string `json:"language,omitempty"`
+}
+
+type SongWithLyrics struct {
+	Song
+	Language    string   `json:"language,omitempty"`
+	VersesCount int      `json:"verses_count"`
+	Lyrics      []*Verse `json:"lyrics,omitempty"`
+}
+
+type Pagination struct {
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+	Total int `json:"total"`
+	Pages int `json:"pages"`
+}
+
+// ListSongsParams filters the songs list, nil filters are not applied.
+// Zero Limit and Page use the service defaults.
+type ListSongsParams struct {
+	Song        *string
+	Group       *string
+	ReleaseDate *string
+	// Tags keeps the songs with any of the tags, or all of them if TagMode
+	// is TagModeAll.
+	Tags    []string
+	TagMode string
+	Limit   int
+	Page    int
+}
+
+const (
+	TagModeAny = "any"
+	TagModeAll = "all"
+)
+
+const (
+	TagKindTag   = "tag"
+	TagKindGenre = "genre"
+)
+
+type SongsPage struct {
+	Songs      []*Song
+	Pagination Pagination
+}
+
+type VersesPage struct {
+	Verses     []*Verse
+	Pagination Pagination
+}
+
+// SongUpdate holds the fields to change, nil fields are left untouched.
+type SongUpdate struct {
+	Song        *string `json:"song,omitempty"`
+	Group       *string `json:"group,omitempty"`
+	ReleaseDate *string `json:"releaseDate,omitempty"`
+	Link        *string `json:"link,omitempty"`
+}
+
+// DuplicateSongs is a pair of songs which are likely the same song spelled differently.
+type DuplicateSongs struct {
+	Song       *Song   `json:"song"`
+	Duplicate  *Song   `json:"duplicate"`
+	Similarity float64 `json:"similarity"`
+}
+
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+const (
+	LinkStatusUnknown = "unknown"
+	LinkStatusOK      = "ok"
+	LinkStatusDead    = "dead"
+)
+
+// Link is a link to a song on a streaming or video service. Status is
+// LinkStatusUnknown until the link is checked by the service.
+type Link struct {
+	ID        int        `json:"id"`
+	SongID    int        `json:"song_id"`
+	Provider  string     `json:"provider"`
+	URL       string     `json:"url"`
+	Status    string     `json:"status"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// The fields of a song refreshed from the metadata providers.
+const (
+	FieldReleaseDate = "release_date"
+	FieldText        = "text"
+	FieldLink        = "link"
+)
+
+// RefreshOptions select the fields to refresh, all of them if Fields is
+// empty. DryRun only reports the changes.
+type RefreshOptions struct {
+	Fields []string `json:"fields,omitempty"`
+	DryRun bool     `json:"dry_run"`
+}
+
+// FieldChange is a field of a song whose stored value differs from the one
+// of the metadata providers.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type SongRefresh struct {
+	SongID  int            `json:"song_id"`
+	Song    string         `json:"song"`
+	Group   string         `json:"group"`
+	Changes []*FieldChange `json:"changes"`
+	// Applied is false for dry runs and songs without changes.
+	Applied bool `json:"applied"`
+	// Error is set if the details of the song couldn't be fetched.
+	Error string `json:"error,omitempty"`
+}
+
+type RefreshPage struct {
+	Results    []*SongRefresh
+	Pagination Pagination
+}
+
+// FacetCount is the number of songs with a tag, artist or release year.
+type FacetCount struct {
+	Value string `json:"value"`
+	Kind  string `json:"kind,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Facets counts the songs matching a filter by tag, artist and release year,
+// the most common values first.
+type Facets struct {
+	Tags    []*FacetCount `json:"tags"`
+	Artists []*FacetCount `json:"artists"`
+	Years   []*FacetCount `json:"years"`
+}
+
+// LyricsLanguages are the language of the original lyrics of a song, empty if
+// it couldn't be detected, and the languages of its translations.
+type LyricsLanguages struct {
+	Original     string   `json:"original"`
+	Translations []string `json:"translations"`
+}
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- The keys are normalized by the application, existing rows are backfilled the same way.
+ALTER TABLE songs
+    ADD COLUMN artist_key TEXT NOT NULL DEFAULT '',
+    ADD COLUMN song_key TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+UPDATE songs SET
+    artist_key = lower(btrim(regexp_replace(artist, '\s+', ' ', 'g'))),
+    song_key = lower(btrim(regexp_replace(song, '\s+', ' ', 'g')));
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- Songs added twice before the constraint get a distinct key, they are left to the duplicate finder.
+UPDATE songs SET song_key = song_key || ' #' || id
+WHERE EXISTS (SELECT 1 FROM songs d WHERE d.artist_key = songs.artist_key AND d.song_key = songs.song_key AND d.id < songs.id);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE UNIQUE INDEX songs_keys_idx ON songs (artist_key, song_key);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX songs_keys_trgm_idx ON songs USING GIN ((artist_key || ' ' || song_key) gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX songs_keys_trgm_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP INDEX songs_keys_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE songs
+    DROP COLUMN artist_key,
+    DROP COLUMN song_key;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The transliterated forms are computed by the application, which fills in
+-- existing rows on startup. They are NULL until then, an empty string is
+-- the folded form of a title without letters.
+ALTER TABLE songs
+    ADD COLUMN artist_translit TEXT,
+    ADD COLUMN song_translit TEXT;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX songs_artist_translit_idx ON songs (artist_translit);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX songs_song_translit_idx ON songs (song_translit);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX songs_song_translit_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP INDEX songs_artist_translit_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE songs
+    DROP COLUMN artist_translit,
+    DROP COLUMN song_translit;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE song_links (
+    id SERIAL PRIMARY KEY,
+    song_id INT NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    url TEXT NOT NULL,
+    -- The host of the url, the link checker only checks allowlisted hosts.
+    host TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'unknown',
+    checked_at TIMESTAMP,
+    UNIQUE (song_id, url)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX song_links_checked_at_idx ON song_links (checked_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE song_links;
+-- +goose StatementEnd
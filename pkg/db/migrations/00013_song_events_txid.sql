@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The transaction which recorded the event. Ids are taken from the sequence
+-- before commit, so readers of the log skip the events of transactions newer
+-- than the oldest running one, otherwise an event committed late could get
+-- an id they have already moved past. The change the event records assigns
+-- the id of the transaction before the event is inserted.
+ALTER TABLE song_events ADD COLUMN txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE song_events DROP COLUMN txid;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE songs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    song VARCHAR(255) NOT NULL,
+    artist VARCHAR(255) NOT NULL,
+    release_date VARCHAR(255),
+    link TEXT
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE songs;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE lyrics (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    song_id INTEGER REFERENCES songs(id) ON DELETE CASCADE,
+    verse_number INTEGER,
+    verse_lyrics TEXT
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE lyrics;
+-- +goose StatementEnd
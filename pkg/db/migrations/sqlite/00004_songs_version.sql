@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE songs ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- SQLite doesn't allow a non-constant default in ADD COLUMN, existing rows are backfilled instead.
+ALTER TABLE songs ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+-- +goose StatementEnd
+-- +goose StatementBegin
+UPDATE songs SET updated_at = CURRENT_TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE songs DROP COLUMN updated_at;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE songs DROP COLUMN version;
+-- +goose StatementEnd
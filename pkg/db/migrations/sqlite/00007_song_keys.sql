@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE songs ADD COLUMN artist_key TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE songs ADD COLUMN song_key TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- SQLite can't collapse inner whitespace and lower() only folds ASCII letters,
+-- rows differing from the keys of the application are reported by the duplicate finder.
+UPDATE songs SET artist_key = lower(trim(artist)), song_key = lower(trim(song));
+-- +goose StatementEnd
+-- +goose StatementBegin
+UPDATE songs SET song_key = song_key || ' #' || id
+WHERE EXISTS (SELECT 1 FROM songs d WHERE d.artist_key = songs.artist_key AND d.song_key = songs.song_key AND d.id < songs.id);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE UNIQUE INDEX songs_keys_idx ON songs (artist_key, song_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX songs_keys_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE songs DROP COLUMN song_key;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE songs DROP COLUMN artist_key;
+-- +goose StatementEnd
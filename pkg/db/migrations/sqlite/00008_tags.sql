@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    kind VARCHAR(16) NOT NULL DEFAULT 'tag'
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE song_tags (
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, tag_id)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX song_tags_tag_idx ON song_tags (tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE song_tags;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE tags;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE songs ADD COLUMN language TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- An empty lang marks the original lyrics, translations have the language code.
+ALTER TABLE verses ADD COLUMN lang TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX verses_song_lang_idx ON verses (song_id, lang, verse_number);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX verses_song_lang_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DELETE FROM verses WHERE lang <> '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE verses DROP COLUMN lang;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE songs DROP COLUMN language;
+-- +goose StatementEnd
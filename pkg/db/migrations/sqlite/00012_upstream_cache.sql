@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE upstream_cache (
+    provider VARCHAR(32) NOT NULL,
+    -- The group and song lowercased with collapsed whitespace, as in songs.
+    group_key TEXT NOT NULL,
+    song_key TEXT NOT NULL,
+    group_name TEXT NOT NULL,
+    song TEXT NOT NULL,
+    release_date TEXT NOT NULL DEFAULT '',
+    text TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '',
+    -- Set if the provider doesn't know the song.
+    not_found BOOLEAN NOT NULL DEFAULT FALSE,
+    fetched_at DATETIME NOT NULL,
+    PRIMARY KEY (provider, group_key, song_key)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX upstream_cache_fetched_at_idx ON upstream_cache (fetched_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE upstream_cache;
+-- +goose StatementEnd
@@ -1,9 +1,15 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"net"
+	"net/url"
+	"time"
 )
 
 type PostgresConfig struct {
@@ -13,18 +19,95 @@ type PostgresConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// Pool settings, zero values keep the driver defaults. MinConns is only
+	// used by OpenPool, database/sql has no minimum of open connections.
+	MaxConns        int
+	MinConns        int
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+
+	// StatementCacheCapacity is only used by OpenPool. A negative value disables
+	// prepared statement caching, which is required behind pgbouncer in transaction mode.
+	StatementCacheCapacity int
+}
+
+// DSN returns a connection URL with every component escaped, so credentials
+// containing spaces or special characters don't break the connection string.
+func (cfg PostgresConfig) DSN() string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(cfg.Username, cfg.Password),
+		Host:   net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:   "/" + cfg.DBName,
+	}
+
+	if cfg.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {cfg.SSLMode}}.Encode()
+	}
+
+	return dsn.String()
 }
 
 func Open(cfg PostgresConfig) (*sql.DB, error) {
-	db, err := sql.Open("pgx", fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.DBName, cfg.SSLMode))
+	db, err := sql.Open("pgx", cfg.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("db open: %w", err)
 	}
 
+	if cfg.MaxConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.MaxConnLifetime)
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.MaxConnIdleTime)
+	}
+
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("db ping: %w", err)
 	}
 
 	return db, nil
 }
+
+func OpenPool(ctx context.Context, cfg PostgresConfig) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("db parse config: %w", err)
+	}
+
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxConns)
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = int32(cfg.MinConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+
+	switch {
+	case cfg.StatementCacheCapacity > 0:
+		poolCfg.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	case cfg.StatementCacheCapacity < 0:
+		poolCfg.ConnConfig.StatementCacheCapacity = 0
+		poolCfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("db pool open: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("db ping: %w", err)
+	}
+
+	return pool, nil
+}
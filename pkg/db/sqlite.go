@@ -0,0 +1,33 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	_ "modernc.org/sqlite"
+	"net/url"
+)
+
+type SQLiteConfig struct {
+	Path string
+}
+
+func OpenSQLite(cfg SQLiteConfig) (*sql.DB, error) {
+	// Foreign keys are off by default in SQLite, they are needed for ON DELETE CASCADE.
+	dsn := fmt.Sprintf("file:%s?%s", cfg.Path, url.Values{
+		"_pragma": {"foreign_keys(1)", "busy_timeout(5000)"},
+	}.Encode())
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("db open: %w", err)
+	}
+
+	// SQLite allows a single writer, one connection avoids SQLITE_BUSY errors.
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("db ping: %w", err)
+	}
+
+	return db, nil
+}
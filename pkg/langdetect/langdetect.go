@@ -0,0 +1,171 @@
+// Package langdetect guesses the language of song lyrics. The script of the
+// text decides between most languages, languages sharing the Latin or
+// Cyrillic script are told apart by their common words and letters.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLetters is the number of letters below which no language is guessed.
+const minLetters = 10
+
+// scripts maps the scripts used by a single language to its ISO 639-1 code.
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Greek, "el"},
+	{unicode.Armenian, "hy"},
+	{unicode.Georgian, "ka"},
+	{unicode.Hebrew, "he"},
+	{unicode.Arabic, "ar"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+	{unicode.Hangul, "ko"},
+}
+
+// stopwords are frequent words of the languages written in Latin script.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "you", "i", "to", "my", "me", "of", "is", "it", "in", "your", "that", "we", "on", "for", "be", "all", "love", "with"},
+	"es": {"el", "la", "que", "de", "y", "en", "no", "te", "me", "mi", "los", "las", "por", "una", "con", "es", "tu", "yo", "amor", "para"},
+	"fr": {"le", "la", "les", "et", "je", "tu", "de", "que", "qui", "pas", "est", "un", "une", "des", "dans", "moi", "toi", "pour", "mon", "ne"},
+	"de": {"der", "die", "das", "und", "ich", "du", "nicht", "ist", "ein", "eine", "mich", "dich", "mit", "auf", "wir", "zu", "es", "sie", "mein", "den"},
+	"it": {"il", "la", "che", "di", "e", "non", "un", "una", "per", "mi", "ti", "io", "tu", "sei", "sono", "con", "come", "amore", "del", "gli"},
+	"pt": {"o", "a", "que", "de", "e", "não", "eu", "você", "um", "uma", "com", "meu", "minha", "para", "se", "te", "do", "da", "em", "amor"},
+	"nl": {"de", "het", "en", "ik", "je", "niet", "een", "van", "is", "dat", "wat", "mijn", "met", "op", "zijn", "we", "maar", "voor", "jij", "mij"},
+	"pl": {"i", "nie", "się", "w", "to", "na", "że", "jak", "ja", "mnie", "ty", "jest", "tak", "z", "do", "co", "mi", "tylko", "już", "czy"},
+	"sv": {"och", "jag", "du", "det", "att", "inte", "en", "är", "på", "som", "med", "för", "min", "mig", "dig", "vi", "har", "till", "av", "om"},
+	"tr": {"ve", "bir", "bu", "ben", "sen", "ne", "de", "da", "için", "gibi", "çok", "beni", "seni", "var", "yok", "mi", "değil", "aşk", "her", "ama"},
+}
+
+// Detect returns the ISO 639-1 code of the language of text, or an empty
+// string if it can't tell.
+func Detect(text string) string {
+	var letters, latin, cyrillic, kana, han int
+	counts := make(map[string]int)
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		default:
+			for _, script := range scripts {
+				if unicode.Is(script.table, r) {
+					counts[script.lang]++
+					break
+				}
+			}
+		}
+	}
+
+	if letters < minLetters {
+		return ""
+	}
+
+	// Japanese mixes kana with kanji, Chinese has no kana at all.
+	if kana > 0 {
+		counts["ja"] = kana + han
+	} else {
+		counts["zh"] = han
+	}
+	counts["latin"] = latin
+	counts["cyrillic"] = cyrillic
+
+	lang, most := "", 0
+	for candidate, count := range counts {
+		if count > most || count == most && candidate < lang {
+			lang, most = candidate, count
+		}
+	}
+
+	// Most letters have to be of the script, mixed texts aren't guessed.
+	if most*2 < letters {
+		return ""
+	}
+
+	switch lang {
+	case "latin":
+		return detectLatin(text)
+	case "cyrillic":
+		return detectCyrillic(text)
+	}
+	return lang
+}
+
+// detectLatin picks the language with the most stopwords in text.
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+
+	scores := make(map[string]int)
+	for _, word := range words {
+		for lang, list := range stopwords {
+			for _, stopword := range list {
+				if word == stopword {
+					scores[lang]++
+					break
+				}
+			}
+		}
+	}
+
+	lang, best := "", 0
+	for candidate, score := range scores {
+		if score > best || score == best && candidate < lang {
+			lang, best = candidate, score
+		}
+	}
+
+	// A couple of short words shared by several languages prove nothing.
+	if best*20 < len(words) {
+		return ""
+	}
+	return lang
+}
+
+// detectCyrillic tells Russian from Ukrainian, Belarusian and Bulgarian by
+// the letters only some of them use.
+func detectCyrillic(text string) string {
+	var uk, be, ru, bg int
+
+	for _, r := range strings.ToLower(text) {
+		switch r {
+		case 'ї', 'є', 'ґ':
+			uk += 2
+		case 'і':
+			uk++
+			be++
+		case 'ў':
+			be += 2
+		case 'ы', 'э', 'ё':
+			ru++
+			be++
+		case 'ъ':
+			bg++
+		}
+	}
+
+	switch {
+	case be > uk && be > ru:
+		return "be"
+	case uk > ru:
+		return "uk"
+	case ru == 0 && bg > 0:
+		return "bg"
+	}
+	return "ru"
+}
@@ -0,0 +1,55 @@
+package langdetect
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "empty", text: "", want: ""},
+		{name: "too short", text: "Hey you", want: ""},
+		{name: "punctuation only", text: "... !!! ??? --- 123 456", want: ""},
+
+		{name: "english", text: "Is this the real life? Is this just fantasy? Caught in a landslide, no escape from reality", want: "en"},
+		{name: "spanish", text: "Quiero que me digas que me quieres, que no me olvides, mi amor", want: "es"},
+		{name: "french", text: "Non, je ne regrette rien, ni le bien qu'on m'a fait, ni le mal, tout ça m'est bien égal", want: "fr"},
+		{name: "german", text: "Ich will, dass ihr mir vertraut, ich will, dass ihr mir glaubt, und du bist nicht allein", want: "de"},
+		{name: "italian", text: "Nel blu dipinto di blu, felice di stare lassù, e volavo volavo felice più in alto del sole", want: "it"},
+		{name: "portuguese", text: "Eu sei que vou te amar, por toda a minha vida eu vou te amar, em cada despedida", want: "pt"},
+		{name: "dutch", text: "Ik hou van jou, het is niet wat je denkt, maar ik ben voor altijd van jou", want: "nl"},
+		{name: "polish", text: "Nie ma mnie tu, już nie ma, tylko ja i ty, czy to jest tak jak się wydaje", want: "pl"},
+		{name: "swedish", text: "Jag är inte som du, och det är inte för mig, men jag har dig", want: "sv"},
+		{name: "turkish", text: "Sen benim için çok değerlisin, ama ben seni her gün bir daha seviyorum", want: "tr"},
+		{name: "latin without stopwords", text: "Supercalifragilisticexpialidocious xylophone zephyr quixotic", want: ""},
+
+		{name: "russian", text: "Группа крови на рукаве, мой порядковый номер на рукаве, пожелай мне удачи в бою", want: "ru"},
+		{name: "russian without distinctive letters", text: "Кино, звезда по имени Солнце, война, песня", want: "ru"},
+		{name: "ukrainian", text: "Ще не вмерла України і слава, і воля, ще нам, браття молодії, усміхнеться доля", want: "uk"},
+		{name: "belarusian", text: "Магутны Божа, ўладару сусветаў, вялікіх сонцаў і сэрцаў малых", want: "be"},
+		{name: "bulgarian", text: "Горда Стара планина, до ней Дунава синей, слънце Тракия огрява", want: "bg"},
+
+		{name: "greek", text: "Σ' αγαπώ σαν τον ήλιο που βγαίνει κάθε πρωί", want: "el"},
+		{name: "armenian", text: "Մեր Հայրենիք, ազատ անկախ, որ ապրել է դարեդար", want: "hy"},
+		{name: "georgian", text: "ჩემი მაღალი ცა, ჩემი მშობლიური მიწა", want: "ka"},
+		{name: "hebrew", text: "כל עוד בלבב פנימה נפש יהודי הומיה", want: "he"},
+		{name: "arabic", text: "بلادي بلادي بلادي لك حبي وفؤادي", want: "ar"},
+		{name: "hindi", text: "जन गण मन अधिनायक जय हे भारत भाग्य विधाता", want: "hi"},
+		{name: "thai", text: "ประเทศไทยรวมเลือดเนื้อชาติเชื้อไทย", want: "th"},
+		{name: "korean", text: "동해 물과 백두산이 마르고 닳도록 하느님이 보우하사", want: "ko"},
+		{name: "japanese", text: "君が代は千代に八千代にさざれ石の巌となりて", want: "ja"},
+		{name: "chinese", text: "起来不愿做奴隶的人们把我们的血肉筑成我们新的长城", want: "zh"},
+
+		{name: "mixed scripts", text: "Hello world привет мир γειά σου κόσμε שלום עולם", want: ""},
+		{name: "mostly latin with a cyrillic word", text: "I love you more than anything in the world, любовь", want: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.want {
+				t.Fatalf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package medialink tells which streaming or video service a link to a song
+// belongs to.
+package medialink
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	YouTube      = "youtube"
+	YouTubeMusic = "youtube_music"
+	Spotify      = "spotify"
+	AppleMusic   = "apple_music"
+	Bandcamp     = "bandcamp"
+	SoundCloud   = "soundcloud"
+	Deezer       = "deezer"
+	Tidal        = "tidal"
+	YandexMusic  = "yandex_music"
+	// Other is the provider of links to any other site.
+	Other = "other"
+)
+
+// Providers are the known providers, Other included.
+var Providers = []string{YouTube, YouTubeMusic, Spotify, AppleMusic, Bandcamp, SoundCloud, Deezer, Tidal, YandexMusic, Other}
+
+// domains maps the domains of the providers to them, a domain covers its
+// subdomains as well. More specific domains go first.
+var domains = []struct {
+	domain   string
+	provider string
+}{
+	{"music.youtube.com", YouTubeMusic},
+	{"youtube.com", YouTube},
+	{"youtu.be", YouTube},
+	{"spotify.com", Spotify},
+	{"spotify.link", Spotify},
+	{"music.apple.com", AppleMusic},
+	{"itunes.apple.com", AppleMusic},
+	{"bandcamp.com", Bandcamp},
+	{"soundcloud.com", SoundCloud},
+	{"deezer.com", Deezer},
+	{"deezer.page.link", Deezer},
+	{"tidal.com", Tidal},
+	{"music.yandex.ru", YandexMusic},
+	{"music.yandex.com", YandexMusic},
+}
+
+// IsProvider reports whether provider is one of Providers.
+func IsProvider(provider string) bool {
+	for _, known := range Providers {
+		if provider == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Host returns the lower case host name of rawURL without the port, an empty
+// string if it isn't a valid URL.
+func Host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// Detect returns the provider of the link, Other if the host isn't known.
+func Detect(rawURL string) string {
+	host := Host(rawURL)
+
+	for _, d := range domains {
+		if MatchDomain(host, d.domain) {
+			return d.provider
+		}
+	}
+	return Other
+}
+
+// Domains returns the domains of the known providers.
+func Domains() []string {
+	list := make([]string, 0, len(domains))
+	for _, d := range domains {
+		list = append(list, d.domain)
+	}
+	return list
+}
+
+// MatchDomain reports whether host is domain or one of its subdomains.
+func MatchDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// Allowed reports whether host matches one of the domains of the allowlist.
+func Allowed(host string, allowlist []string) bool {
+	for _, domain := range allowlist {
+		if MatchDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package medialink
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://www.youtube.com/watch?v=w8KQmps-Sog", want: YouTube},
+		{url: "https://m.youtube.com/watch?v=w8KQmps-Sog", want: YouTube},
+		{url: "https://youtu.be/w8KQmps-Sog", want: YouTube},
+		{url: "https://music.youtube.com/watch?v=w8KQmps-Sog", want: YouTubeMusic},
+		{url: "https://open.spotify.com/track/4VqPOruhp5EdPBeR92t6lQ", want: Spotify},
+		{url: "https://spotify.link/abc", want: Spotify},
+		{url: "https://music.apple.com/us/album/uprising/1", want: AppleMusic},
+		{url: "https://itunes.apple.com/us/album/uprising/1", want: AppleMusic},
+		{url: "https://www.apple.com/music/", want: Other},
+		{url: "https://muse.bandcamp.com/track/uprising", want: Bandcamp},
+		{url: "https://soundcloud.com/muse/uprising", want: SoundCloud},
+		{url: "https://www.deezer.com/track/1", want: Deezer},
+		{url: "https://deezer.page.link/abc", want: Deezer},
+		{url: "https://tidal.com/browse/track/1", want: Tidal},
+		{url: "https://music.yandex.ru/album/1/track/2", want: YandexMusic},
+		{url: "https://music.yandex.com/album/1/track/2", want: YandexMusic},
+		{url: "https://yandex.ru/music", want: Other},
+		{url: "HTTPS://WWW.YOUTUBE.COM/watch?v=w8KQmps-Sog", want: YouTube},
+		{url: "https://www.youtube.com.:443/watch?v=w8KQmps-Sog", want: YouTube},
+		{url: "https://notyoutube.com/watch", want: Other},
+		{url: "https://youtube.com.evil.example/watch", want: Other},
+		{url: "https://example.com/?next=https://youtube.com", want: Other},
+		{url: "not a url", want: Other},
+		{url: "", want: Other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := Detect(tt.url); got != tt.want {
+				t.Fatalf("Detect(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://Music.YouTube.com/watch", want: "music.youtube.com"},
+		{url: "http://example.com:8080/path", want: "example.com"},
+		{url: "https://example.com./", want: "example.com"},
+		{url: "http://127.0.0.1:9000/", want: "127.0.0.1"},
+		{url: "http://[::1]:9000/", want: "::1"},
+		{url: "/relative/path", want: ""},
+		{url: "://broken", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := Host(tt.url); got != tt.want {
+				t.Fatalf("Host(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	allowlist := []string{"youtube.com", "bandcamp.com"}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "youtube.com", want: true},
+		{host: "www.youtube.com", want: true},
+		{host: "a.b.bandcamp.com", want: true},
+		{host: "notyoutube.com", want: false},
+		{host: "youtube.com.evil.example", want: false},
+		{host: "com", want: false},
+		{host: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := Allowed(tt.host, allowlist); got != tt.want {
+				t.Fatalf("Allowed(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+
+	if Allowed("youtube.com", nil) {
+		t.Fatal("Allowed with an empty allowlist = true, want false")
+	}
+}
+
+func TestIsProvider(t *testing.T) {
+	for _, provider := range Providers {
+		if !IsProvider(provider) {
+			t.Fatalf("IsProvider(%q) = false, want true", provider)
+		}
+	}
+	for _, provider := range []string{"", "YouTube", "vimeo"} {
+		if IsProvider(provider) {
+			t.Fatalf("IsProvider(%q) = true, want false", provider)
+		}
+	}
+}
+
+func TestDomainsDetected(t *testing.T) {
+	// Every known domain detects a provider other than Other.
+	for _, domain := range Domains() {
+		if got := Detect("https://" + domain + "/"); got == Other {
+			t.Fatalf("Detect of domain %s = %q", domain, got)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// PostgresStore shares the buckets between instances of the service through
+// the rate_limit_buckets table, for deployments without Redis.
+type PostgresStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{
+		db:        db,
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now().UTC()
+	s.sweep(ctx, now)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	// The insert makes sure there is a row to lock, concurrent requests for
+	// the same key wait for each other on it.
+	_, err = tx.ExecContext(ctx, `INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at) VALUES($1, $2, $3, $3)
+		ON CONFLICT (key) DO NOTHING`, key, limit.Requests, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var tokens float64
+	var updated time.Time
+
+	err = tx.QueryRowContext(ctx, `SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`, key).Scan(&tokens, &updated)
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokens, res := take(limit, refill(limit, tokens, updated, now))
+
+	_, err = tx.ExecContext(ctx, `UPDATE rate_limit_buckets SET tokens = $1, updated_at = $2, full_at = $3 WHERE key = $4`,
+		tokens, now, now.Add(res.Reset), key)
+	if err != nil {
+		return Result{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return res, nil
+}
+
+// sweep deletes the buckets which are full again once a minute.
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	// A failed sweep only leaves a few rows behind until the next one.
+	_, _ = s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE full_at <= $1`, now)
+}
@@ -0,0 +1,73 @@
+// Package ratelimit implements token buckets kept in memory, Redis or Postgres.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests requests per Period. Unused requests accumulate up to
+// Requests, so a client may spend its whole budget at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// rate returns the number of tokens added to a bucket per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of requests that can be made right away.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, 0 if Allowed.
+	RetryAfter time.Duration
+}
+
+type Store interface {
+	// Take takes a token from the bucket of key.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// refill returns the tokens of a bucket which had tokens at updated.
+func refill(limit Limit, tokens float64, updated, now time.Time) float64 {
+	elapsed := now.Sub(updated).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Requests), tokens+elapsed*limit.rate())
+}
+
+// take takes a token from a bucket holding tokens, returning the tokens left.
+func take(limit Limit, tokens float64) (float64, Result) {
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	return tokens, result(limit, tokens, allowed)
+}
+
+func result(limit Limit, tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Requests) - tokens) / limit.rate()),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
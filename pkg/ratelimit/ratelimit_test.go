@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefill(t *testing.T) {
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+	updated := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{name: "no time passed", tokens: 3, elapsed: 0, want: 3},
+		{name: "one token per second", tokens: 3, elapsed: 2 * time.Second, want: 5},
+		{name: "fractional tokens", tokens: 0, elapsed: 500 * time.Millisecond, want: 0.5},
+		{name: "capped at requests", tokens: 8, elapsed: time.Minute, want: 10},
+		{name: "clock went backwards", tokens: 4, elapsed: -time.Second, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := refill(limit, tt.tokens, updated, updated.Add(tt.elapsed))
+			if got != tt.want {
+				t.Fatalf("refill = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTake(t *testing.T) {
+	limit := Limit{Requests: 4, Period: 4 * time.Second}
+
+	tests := []struct {
+		name       string
+		tokens     float64
+		wantTokens float64
+		want       Result
+	}{
+		{
+			name:       "full bucket",
+			tokens:     4,
+			wantTokens: 3,
+			want:       Result{Allowed: true, Limit: 4, Remaining: 3, Reset: time.Second},
+		},
+		{
+			name:       "last token",
+			tokens:     1.5,
+			wantTokens: 0.5,
+			want:       Result{Allowed: true, Limit: 4, Remaining: 0, Reset: 3500 * time.Millisecond},
+		},
+		{
+			name:       "empty bucket",
+			tokens:     0,
+			wantTokens: 0,
+			want:       Result{Allowed: false, Limit: 4, Remaining: 0, Reset: 4 * time.Second, RetryAfter: time.Second},
+		},
+		{
+			name:       "partly refilled bucket",
+			tokens:     0.75,
+			wantTokens: 0.75,
+			want:       Result{Allowed: false, Limit: 4, Remaining: 0, Reset: 3250 * time.Millisecond, RetryAfter: 250 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, res := take(limit, tt.tokens)
+			if tokens != tt.wantTokens {
+				t.Fatalf("tokens = %v, want %v", tokens, tt.wantTokens)
+			}
+			if res != tt.want {
+				t.Fatalf("result = %+v, want %+v", res, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 2, Period: time.Hour}
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false, false} {
+		res, err := store.Take(ctx, "a", limit)
+		if err != nil {
+			t.Fatalf("Take error: %s", err)
+		}
+		if res.Allowed != want {
+			t.Fatalf("request %d: allowed = %v, want %v", i+1, res.Allowed, want)
+		}
+		if !res.Allowed && res.RetryAfter <= 0 {
+			t.Fatalf("request %d: RetryAfter = %s, want > 0", i+1, res.RetryAfter)
+		}
+	}
+
+	res, err := store.Take(ctx, "b", limit)
+	if err != nil {
+		t.Fatalf("Take error: %s", err)
+	}
+	if !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("other key: %+v, want its own budget", res)
+	}
+}
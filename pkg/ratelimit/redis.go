@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/aaanger/music-library/pkg/cache"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// takeScript refills and takes from the bucket atomically. The bucket expires
+// once it's full again, as a missing bucket is treated as a full one.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore shares the buckets between instances of the service through Redis.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(ctx context.Context, cfg cache.RedisConfig) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis ping: %w", err)
+	}
+
+	return &RedisStore{client: client}, nil
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	// The script works in milliseconds.
+	rate := limit.rate() / 1000
+	now := time.Now().UnixMilli()
+
+	reply, err := takeScript.Run(ctx, s.client, []string{"ratelimit:" + key}, limit.Requests, rate, now).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	encoded, _ := reply[1].(string)
+
+	tokens, err := strconv.ParseFloat(encoded, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+
+	return result(limit, tokens, allowed == 1), nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
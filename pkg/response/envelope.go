@@ -0,0 +1,31 @@
+package response
+
+import "github.com/gin-gonic/gin"
+
+type Pagination struct {
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+	Total int `json:"total"`
+	Pages int `json:"pages"`
+}
+
+type Envelope struct {
+	Data       any         `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+func Data(c *gin.Context, code int, data any) {
+	c.JSON(code, Envelope{Data: data})
+}
+
+func Page(c *gin.Context, code int, data any, page, limit, total int) {
+	c.JSON(code, Envelope{
+		Data: data,
+		Pagination: &Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+			Pages: (total + limit - 1) / limit,
+		},
+	})
+}
@@ -1,9 +1,37 @@
 package response
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
 
 func Error(c *gin.Context, code int, message string) {
 	c.JSON(code, gin.H{
 		"error": message,
 	})
 }
+
+type ValidationErrorResponse struct {
+	Error  string `json:"error"`
+	Fields any    `json:"fields"`
+}
+
+func ValidationError(c *gin.Context, fields any) {
+	c.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+		Error:  "validation failed",
+		Fields: fields,
+	})
+}
+
+type ConflictResponse struct {
+	Error    string `json:"error"`
+	Existing any    `json:"existing"`
+}
+
+// Conflict answers 409 with the resource the request collides with.
+func Conflict(c *gin.Context, message string, existing any) {
+	c.JSON(http.StatusConflict, ConflictResponse{
+		Error:    message,
+		Existing: existing,
+	})
+}
@@ -0,0 +1,66 @@
+// Package translit normalizes song titles and artists and folds them into a
+// Latin search form, so "Кино", "KINO" and "Kino" or "Beyoncé" and "Beyonce"
+// are found by the same filter.
+package translit
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// letters maps the lower case letters without a Latin decomposition to their
+// transliteration. Cyrillic follows the common passport style romanization,
+// Greek the ELOT 743 one.
+var letters = map[rune]string{
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u", 'ђ': "dj", 'ј': "j",
+	'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz", 'ѓ': "gj", 'ќ': "kj", 'ѕ': "dz",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+	// Latin letters which aren't a base letter with a diacritic
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th",
+	'ı': "i", 'ŋ': "n",
+}
+
+// Normalize returns s in Unicode normalization form C, the form titles,
+// artists and lyrics are stored in.
+func Normalize(s string) string {
+	return norm.NFC.String(s)
+}
+
+// Fold returns the search form of s: compatibility characters are replaced
+// (NFKC), letters are lower cased, transliterated to Latin and stripped of
+// diacritics, and runs of whitespace are collapsed to a single space.
+func Fold(s string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(norm.NFKC.String(s)) {
+		if latin, ok := letters[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+
+		// Letters with diacritics are looked up by their base letter, e.g. the
+		// Greek ά, the Latin ones are only stripped of the diacritics.
+		for _, d := range norm.NFD.String(string(r)) {
+			if unicode.Is(unicode.Mn, d) {
+				continue
+			}
+			if latin, ok := letters[d]; ok {
+				b.WriteString(latin)
+				continue
+			}
+			b.WriteRune(d)
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,42 @@
+package translit
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "cyrillic", in: "Кино", want: "kino"},
+		{name: "latin", in: "Kino", want: "kino"},
+		{name: "upper case", in: "KINO", want: "kino"},
+		{name: "compatibility characters", in: "ＫＩＮＯ", want: "kino"},
+		{name: "multi letter transliteration", in: "Щелкунчик", want: "shchelkunchik"},
+		{name: "yo", in: "Ёлка", want: "elka"},
+		{name: "hard and soft signs", in: "Ъ Ь", want: ""},
+		{name: "acute accent", in: "Beyoncé", want: "beyonce"},
+		{name: "decomposed accent", in: "Beyoncé", want: "beyonce"},
+		{name: "umlaut", in: "Mötley Crüe", want: "motley crue"},
+		{name: "sharp s", in: "Die Ärzte ß", want: "die arzte ss"},
+		{name: "ligatures and stroke letters", in: "Œuvre Øystein Łódź", want: "oeuvre oystein lodz"},
+		{name: "greek with tonos", in: "Άλφα", want: "alfa"},
+		{name: "whitespace collapsed", in: "  Группа \t крови\n ", want: "gruppa krovi"},
+		{name: "punctuation kept", in: "AC/DC", want: "ac/dc"},
+		{name: "empty", in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fold(tt.in); got != tt.want {
+				t.Fatalf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("Beyoncé"); got != "Beyoncé" {
+		t.Fatalf("Normalize = %q, want the composed form", got)
+	}
+}